@@ -0,0 +1,102 @@
+package main
+
+import "sync"
+
+// aimdController实现加性增乘性减（AIMD）的自适应并发上限：成功率和延迟正常时每次
+// 只加少量（increaseStep），一旦观察到超时或403之类的拥塞信号就按decreaseFactor
+// 成倍砍掉，这是TCP拥塞控制的经典策略——"谨慎探测上限、遇阻立刻大幅退让"比固定并发数
+// 更适合IP随时可能被目标站点限速/封禁的爬取场景。
+type aimdController struct {
+	mu    sync.Mutex
+	limit float64
+	min   float64
+	max   float64
+
+	increaseStep   float64
+	decreaseFactor float64
+
+	// onResize在limit变化后被调用，用于把新的上限同步给实际持有并发槽位的对象
+	// （如taskScheduler.SetCapacity），为空表示调用方只是轮询Limit()而不需要推送通知。
+	onResize func(limit int)
+}
+
+// newAIMDController创建一个初始上限为initial、允许范围[min,max]的控制器，
+// increaseStep是每次OnSuccess的加性增量，decreaseFactor是每次OnFailure的乘性系数（0~1之间）。
+func newAIMDController(initial, min, max int, increaseStep, decreaseFactor float64, onResize func(int)) *aimdController {
+	if min < 1 {
+		min = 1
+	}
+	if max < min {
+		max = min
+	}
+	if initial < min {
+		initial = min
+	}
+	if initial > max {
+		initial = max
+	}
+	return &aimdController{
+		limit:          float64(initial),
+		min:            float64(min),
+		max:            float64(max),
+		increaseStep:   increaseStep,
+		decreaseFactor: decreaseFactor,
+		onResize:       onResize,
+	}
+}
+
+// OnSuccess把上限加性增加increaseStep，触顶后保持在max不再继续增长
+func (a *aimdController) OnSuccess() {
+	a.mu.Lock()
+	a.limit += a.increaseStep
+	if a.limit > a.max {
+		a.limit = a.max
+	}
+	newLimit := int(a.limit)
+	a.mu.Unlock()
+	a.notify(newLimit)
+}
+
+// OnFailure把上限乘性降低到decreaseFactor倍，触底后保持在min不再继续收缩
+func (a *aimdController) OnFailure() {
+	a.mu.Lock()
+	a.limit *= a.decreaseFactor
+	if a.limit < a.min {
+		a.limit = a.min
+	}
+	newLimit := int(a.limit)
+	a.mu.Unlock()
+	a.notify(newLimit)
+}
+
+func (a *aimdController) notify(limit int) {
+	if a.onResize != nil {
+		a.onResize(limit)
+	}
+}
+
+// Limit返回当前并发上限（向下取整）
+func (a *aimdController) Limit() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return int(a.limit)
+}
+
+// SetMax在运行期调整允许探测到的上限（如配置热重载时PoolConfig.Concurrency发生变化），
+// min保持不变；当前limit超过新max时立即收紧到新max并通知onResize，不等下一次OnFailure。
+func (a *aimdController) SetMax(max int) {
+	if max < 1 {
+		max = 1
+	}
+	a.mu.Lock()
+	a.max = float64(max)
+	if a.max < a.min {
+		a.min = a.max
+	}
+	if a.limit > a.max {
+		a.limit = a.max
+	}
+	newLimit := int(a.limit)
+	a.mu.Unlock()
+	a.notify(newLimit)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// authMetadataKey 是客户端携带共享密钥的gRPC metadata字段名
+const authMetadataKey = "authorization"
+
+// authClientIDContextKey 是checkAuthToken在配置了TaskAPIConfig.ClientTokens时，把token对应的
+// 已认证ClientID塞进ctx用的key，quotaClientID据此取值而不是信任请求体自报的ClientID；
+// 未配置ClientTokens（单一共享密钥模式下没有区分客户端身份的手段）时ctx里不会有这个值。
+type authClientIDContextKey struct{}
+
+// contextServerStream用authedCtx覆盖底层ServerStream.Context()，使authStreamInterceptor
+// 能把checkAuthToken解析出的已认证ClientID传给流式handler，用法与官方grpc-go
+// grpc_middleware族拦截器里的WrappedServerStream是同一个思路。
+type contextServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextServerStream) Context() context.Context { return s.ctx }
+
+// authUnaryInterceptor 在TaskAPIConfig.AuthToken或ClientTokens非空时，要求一元RPC请求携带匹配的
+// authorization metadata，两者都为空时表示未启用鉴权，对旧客户端保持兼容
+func authUnaryInterceptor(c *Crawler) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		authedCtx, err := checkAuthToken(ctx, c)
+		if err != nil {
+			atomic.AddInt64(&c.stats.AuthRejected, 1)
+			return nil, err
+		}
+		return handler(authedCtx, req)
+	}
+}
+
+// authStreamInterceptor 是authUnaryInterceptor的流式版本，应用于ExecuteStream/TaskStream/ExecuteBatch
+func authStreamInterceptor(c *Crawler) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		authedCtx, err := checkAuthToken(ss.Context(), c)
+		if err != nil {
+			atomic.AddInt64(&c.stats.AuthRejected, 1)
+			return err
+		}
+		return handler(srv, &contextServerStream{ServerStream: ss, ctx: authedCtx})
+	}
+}
+
+// checkAuthToken校验请求鉴权。配置了TaskAPIConfig.ClientTokens时，要求metadata里的token
+// 精确匹配某一项，并把该项对应的ClientID写进返回的ctx供quotaClientID取用——这样配额才能
+// 按"provisioning时发给哪个客户端的token"计费，而不是按客户端在TaskRequest里自报的ClientID
+// （自报字段可以被随意更换，无法用来限流）。未配置ClientTokens时退回TaskAPIConfig.AuthToken
+// 的单一共享密钥模式（旧行为），返回的ctx不携带已认证ClientID。
+func checkAuthToken(ctx context.Context, c *Crawler) (context.Context, error) {
+	tokens := c.config.TaskAPI.ClientTokens
+	if len(tokens) == 0 {
+		return ctx, checkSharedToken(ctx, c.config.TaskAPI.AuthToken)
+	}
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx, status.Error(codes.Unauthenticated, "缺少鉴权metadata")
+	}
+	values := md.Get(authMetadataKey)
+	if len(values) == 0 {
+		return ctx, status.Error(codes.Unauthenticated, "鉴权token无效")
+	}
+	presented := []byte(values[0])
+	for token, clientID := range tokens {
+		if subtle.ConstantTimeCompare(presented, []byte(token)) == 1 {
+			return context.WithValue(ctx, authClientIDContextKey{}, clientID), nil
+		}
+	}
+	return ctx, status.Error(codes.Unauthenticated, "鉴权token无效")
+}
+
+// quotaClientID返回本次请求应该用来计费PerClientMaxConcurrency/PerClientRequestsPerSecond配额的
+// ClientID：ctx里有checkAuthToken在ClientTokens模式下写入的已认证ClientID时优先使用它；否则
+// （未配置ClientTokens）退回reqClientID，与引入ClientTokens之前的行为一致。
+func quotaClientID(ctx context.Context, reqClientID string) string {
+	if id, ok := ctx.Value(authClientIDContextKey{}).(string); ok && id != "" {
+		return id
+	}
+	return reqClientID
+}
+
+// coordinatorAuthInterceptor 要求Coordinator的RegisterWorker/Heartbeat/ReportIPFindings/
+// GetKnownIPs这几个一元RPC携带匹配CoordinatorConfig.AuthToken的authorization metadata，
+// 用法与authUnaryInterceptor对TaskService的校验完全一致，只是换了一个配置项里的共享密钥
+// ——coordinator和worker是两个独立的fleet-wide数据面（前者是任务分片/IP合并，后者是
+// 抓取任务本身），不应该共用同一个token。
+func coordinatorAuthInterceptor(c *Crawler) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := checkSharedToken(ctx, c.config.Coordinator.AuthToken); err != nil {
+			atomic.AddInt64(&c.stats.AuthRejected, 1)
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// checkSharedToken校验ctx的incoming metadata里authMetadataKey字段是否匹配token，token为空
+// 表示调用方未启用鉴权，直接放行；TaskService（checkAuthToken）、IPSync的PushEvents
+// （见ipsync.go）和coordinatorAuthInterceptor共用这同一种"共享密钥换准入"的鉴权方式。
+func checkSharedToken(ctx context.Context, token string) error {
+	if token == "" {
+		return nil
+	}
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "缺少鉴权metadata")
+	}
+	values := md.Get(authMetadataKey)
+	// 用subtle.ConstantTimeCompare而不是直接用!=比较，避免逐字节比较的提前返回给攻击者
+	// 留下可用于猜测token内容的时间侧信道
+	if len(values) == 0 || subtle.ConstantTimeCompare([]byte(values[0]), []byte(token)) != 1 {
+		return status.Error(codes.Unauthenticated, "鉴权token无效")
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"sync/atomic"
+)
+
+// quicCertReloader把QUIC服务端证书和（可选的）客户端CA证书池包装成可原子替换的状态，
+// 供tls.Config.GetCertificate/GetConfigForClient在每次握手时读取最新值——这样SIGHUP
+// 触发的证书轮换（见config_reload.go的reloadConfig）不需要重建quic.Listener或断开
+// 已有连接，新证书只在下一次握手时生效，与applyReloadableConfig"安全子集热生效"是
+// 同一个思路。certFile/keyFile/caFile为空（自签名证书场景）时Reload直接返回nil，
+// 因为压根没有文件可供重新读取。
+type quicCertReloader struct {
+	certFile string
+	keyFile  string
+	caFile   string
+
+	cert   atomic.Pointer[tls.Certificate]
+	caPool atomic.Pointer[x509.CertPool]
+}
+
+// newQUICCertReloader用initialCert/initialCAPool作为起始状态构造一个reloader，
+// certFile/keyFile/caFile记录后续Reload()应该从哪里重新读取，留空表示对应部分
+// 不支持重载（自签名证书或未配置CA的场景）。
+func newQUICCertReloader(certFile, keyFile, caFile string, initialCert tls.Certificate, initialCAPool *x509.CertPool) *quicCertReloader {
+	r := &quicCertReloader{certFile: certFile, keyFile: keyFile, caFile: caFile}
+	r.cert.Store(&initialCert)
+	if initialCAPool != nil {
+		r.caPool.Store(initialCAPool)
+	}
+	return r
+}
+
+// GetCertificate实现tls.Config.GetCertificate签名，每次握手都读取当前存活的证书。
+func (r *quicCertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.cert.Load(), nil
+}
+
+// ClientCAs返回当前存活的客户端CA证书池，未配置QUICCAFile时为nil。
+func (r *quicCertReloader) ClientCAs() *x509.CertPool {
+	return r.caPool.Load()
+}
+
+// Reload重新读取certFile/keyFile（以及caFile，如果配置了的话）并原子替换当前值，
+// 读取或解析失败时保留原有证书不动、返回错误，调用方（reloadConfig）负责记日志。
+func (r *quicCertReloader) Reload() error {
+	if r.certFile == "" || r.keyFile == "" {
+		return nil
+	}
+
+	newCert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("重新加载 QUIC TLS 证书失败: %w", err)
+	}
+
+	var newPool *x509.CertPool
+	if r.caFile != "" {
+		newPool, err = loadCertPool(r.caFile)
+		if err != nil {
+			return fmt.Errorf("重新加载 QUIC 客户端CA证书失败: %w", err)
+		}
+	}
+
+	r.cert.Store(&newCert)
+	if newPool != nil {
+		r.caPool.Store(newPool)
+	}
+	return nil
+}
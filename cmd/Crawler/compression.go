@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// zstdSuffix 是压缩后数据文件使用的后缀，与压缩前的原始文件名对应
+const zstdSuffix = ".zst"
+
+// zstdEncoderLevel 把配置里1-4的简化档位映射为zstd.EncoderLevel，
+// 0或超出范围的值一律按默认档位处理，兼顾压缩率和CPU开销
+func zstdEncoderLevel(level int) zstd.EncoderLevel {
+	switch level {
+	case 1:
+		return zstd.SpeedFastest
+	case 2:
+		return zstd.SpeedDefault
+	case 3:
+		return zstd.SpeedBetterCompression
+	case 4:
+		return zstd.SpeedBestCompression
+	default:
+		return zstd.SpeedDefault
+	}
+}
+
+// compressZstd 把data压缩为zstd格式。抓取到的很多body本身是未压缩的protobuf，
+// 压缩后通常能缩小5-10倍
+func compressZstd(data []byte, level int) ([]byte, error) {
+	enc, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstdEncoderLevel(level)))
+	if err != nil {
+		return nil, fmt.Errorf("创建zstd编码器失败: %w", err)
+	}
+	defer enc.Close()
+	return enc.EncodeAll(data, make([]byte, 0, len(data))), nil
+}
+
+// decompressZstd 透明解压compressZstd压缩写入的数据
+func decompressZstd(data []byte) ([]byte, error) {
+	dec, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建zstd解码器失败: %w", err)
+	}
+	defer dec.Close()
+	return dec.DecodeAll(data, nil)
+}
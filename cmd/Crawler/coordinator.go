@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+
+	"google.golang.org/grpc"
+
+	"utlsProxy/internal/coordapi"
+	"utlsProxy/internal/taskapi"
+)
+
+// coordinator.go实现Coordinator.Role="coordinator"一侧的逻辑：在ListenPort上起一个
+// internal/coordapi的JSON-codec gRPC服务，给先后注册的worker按ShardCount分配八叉树
+// 根节点前缀（"0"~"7"），并把各worker陆续上报的新发现白/黑名单IP合并进本实例自己的
+// ipAccessControl，再原样提供给所有worker拉取——合并后的结果就是coordinator自己的白/
+// 黑名单，不另外引入一份独立存储，coordinator本身也按普通单机Crawler一样正常抓取。
+
+// coordinatorWorker记录一个已注册worker的分片分配，仅用于日志与重复注册时复用已分配的分片
+type coordinatorWorker struct {
+	shardIndex   int
+	pathPrefixes []string
+}
+
+// coordinatorState维护已注册的worker列表，并按注册顺序轮转分配分片
+type coordinatorState struct {
+	mu         sync.Mutex
+	shardCount int
+	workers    map[string]*coordinatorWorker // key为WorkerID
+	nextShard  int
+}
+
+func newCoordinatorState(shardCount int) *coordinatorState {
+	return &coordinatorState{
+		shardCount: shardCount,
+		workers:    make(map[string]*coordinatorWorker),
+	}
+}
+
+// assign按ShardIndex = 已注册worker数 % ShardCount分配分片，workerID重复注册时返回此前
+// 分配的同一个分片，不重新轮转（worker重启重连后应拿到同一份工作，避免分片被反复打乱）。
+func (s *coordinatorState) assign(workerID string) *coordinatorWorker {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if w, ok := s.workers[workerID]; ok {
+		return w
+	}
+
+	shardIndex := s.nextShard % s.shardCount
+	s.nextShard++
+
+	var prefixes []string
+	for d := 0; d < 8; d++ {
+		if d%s.shardCount == shardIndex {
+			prefixes = append(prefixes, strconv.Itoa(d))
+		}
+	}
+
+	w := &coordinatorWorker{shardIndex: shardIndex, pathPrefixes: prefixes}
+	s.workers[workerID] = w
+	return w
+}
+
+// coordinatorService实现coordapi.CoordinatorServiceServer，把RPC直接转发给所属Crawler
+type coordinatorService struct {
+	coordapi.UnimplementedCoordinatorServiceServer
+	crawler *Crawler
+	state   *coordinatorState
+}
+
+func (s *coordinatorService) RegisterWorker(ctx context.Context, req *coordapi.RegisterWorkerRequest) (*coordapi.RegisterWorkerResponse, error) {
+	if req == nil || req.WorkerID == "" {
+		return nil, fmt.Errorf("worker_id 不能为空")
+	}
+	w := s.state.assign(req.WorkerID)
+	log.Printf("[协调节点] worker %q（地址 %s）已注册，分配分片 %d/%d，前缀 %v", req.WorkerID, req.Address, w.shardIndex, s.state.shardCount, w.pathPrefixes)
+	return &coordapi.RegisterWorkerResponse{
+		ShardIndex:   int32(w.shardIndex),
+		ShardCount:   int32(s.state.shardCount),
+		PathPrefixes: w.pathPrefixes,
+	}, nil
+}
+
+func (s *coordinatorService) Heartbeat(ctx context.Context, req *coordapi.HeartbeatRequest) (*coordapi.HeartbeatResponse, error) {
+	return &coordapi.HeartbeatResponse{Acknowledged: true}, nil
+}
+
+// ReportIPFindings把worker上报的新发现IP合并进coordinator自己的ipAccessControl，
+// 合并即生效——下一次GetKnownIPs或coordinator自身下一次调度都会立刻用上这些IP。
+func (s *coordinatorService) ReportIPFindings(ctx context.Context, req *coordapi.ReportIPFindingsRequest) (*coordapi.ReportIPFindingsResponse, error) {
+	if req == nil {
+		return nil, fmt.Errorf("请求不能为空")
+	}
+	for _, ip := range req.NewlyWhitelisted {
+		s.crawler.ipAccessControl.AddIP(ip, true)
+	}
+	for _, ip := range req.NewlyBlacklisted {
+		s.crawler.ipAccessControl.AddIP(ip, false)
+	}
+	if len(req.NewlyWhitelisted) > 0 || len(req.NewlyBlacklisted) > 0 {
+		log.Printf("[协调节点] worker %q 上报新发现：白名单+%d，黑名单+%d", req.WorkerID, len(req.NewlyWhitelisted), len(req.NewlyBlacklisted))
+	}
+	return &coordapi.ReportIPFindingsResponse{Accepted: true}, nil
+}
+
+func (s *coordinatorService) GetKnownIPs(ctx context.Context, req *coordapi.GetKnownIPsRequest) (*coordapi.GetKnownIPsResponse, error) {
+	return &coordapi.GetKnownIPsResponse{
+		WhitelistedIPs: s.crawler.ipAccessControl.GetAllowedIPs(),
+		BlacklistedIPs: s.crawler.ipAccessControl.GetBlockedIPs(),
+	}, nil
+}
+
+// startCoordinator在Coordinator.Enabled且Role="coordinator"时起一个独立的JSON-codec gRPC
+// 服务监听ListenPort，Role不是"coordinator"（包括Enabled=false、Role="worker"）时直接返回nil，
+// 与startGRPCServer/startHealthServer等"按配置决定是否启动"的约定一致。
+func (c *Crawler) startCoordinator() error {
+	if !c.config.Coordinator.Enabled || c.config.Coordinator.Role != "coordinator" {
+		return nil
+	}
+
+	address := fmt.Sprintf(":%d", c.config.Coordinator.ListenPort)
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("监听协调节点gRPC端口失败: %w", err)
+	}
+
+	state := newCoordinatorState(c.config.Coordinator.GetShardCount())
+	server := taskapi.NewJSONServer(grpc.UnaryInterceptor(coordinatorAuthInterceptor(c)))
+	coordapi.RegisterCoordinatorServiceServer(server, &coordinatorService{crawler: c, state: state})
+	log.Printf("协调节点gRPC服务启动（JSON编解码），地址 %s，分片数 %d", address, state.shardCount)
+
+	c.coordinatorServer = server
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		if err := server.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			log.Printf("协调节点gRPC服务异常退出: %v", err)
+		}
+	}()
+
+	return nil
+}
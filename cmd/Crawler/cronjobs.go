@@ -0,0 +1,340 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"utlsProxy/config"
+	"utlsProxy/internal/taskapi"
+)
+
+// cronjobs.go实现config.CrawlJobConfig描述的按cron表达式定时触发的命名抓取任务：一个独立的
+// 标准5字段cron解析器（不引入第三方cron库），加上一个按分钟粒度轮询各任务到期时间的
+// jobScheduler，到期的任务各自起一个octreeCrawler遍历（见octree.go），与TaskService现有的
+// ListCrawlJobs/ControlCrawlJob RPC配合，在运行期查询状态或暂停/恢复。
+
+// cronField是单个cron字段（分/时/日/月/周）解析出的允许取值集合
+type cronField map[int]bool
+
+// parseCronField解析单个cron字段，支持"*"、"*/N"、"a-b"、逗号分隔的列表以及纯数字，取值范围[min,max]
+func parseCronField(field string, min, max int) (cronField, error) {
+	result := make(cronField)
+	for _, part := range strings.Split(field, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		step := 1
+		rangePart := part
+		if idx := strings.IndexByte(part, '/'); idx >= 0 {
+			rangePart = part[:idx]
+			n, err := strconv.Atoi(part[idx+1:])
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("非法的步长 %q", part)
+			}
+			step = n
+		}
+
+		var lo, hi int
+		switch {
+		case rangePart == "*":
+			lo, hi = min, max
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			a, errA := strconv.Atoi(bounds[0])
+			b, errB := strconv.Atoi(bounds[1])
+			if errA != nil || errB != nil || a > b {
+				return nil, fmt.Errorf("非法的范围 %q", rangePart)
+			}
+			lo, hi = a, b
+		default:
+			n, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return nil, fmt.Errorf("非法的字段值 %q", rangePart)
+			}
+			lo, hi = n, n
+		}
+		if lo < min || hi > max {
+			return nil, fmt.Errorf("字段值 %q 超出允许范围 [%d,%d]", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			result[v] = true
+		}
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("字段 %q 未解析出任何取值", field)
+	}
+	return result, nil
+}
+
+// cronSchedule是解析后的标准5字段cron表达式（分 时 日 月 周），Next据此计算下一次触发时间
+type cronSchedule struct {
+	minute cronField
+	hour   cronField
+	dom    cronField
+	month  cronField
+	dow    cronField
+}
+
+// parseCronExpr解析一个标准5字段cron表达式，字段顺序为：分(0-59) 时(0-23) 日(1-31) 月(1-12) 周(0-6，0为周日)
+func parseCronExpr(expr string) (*cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron表达式 %q 必须恰好有5个字段（分 时 日 月 周），实际有%d个", expr, len(fields))
+	}
+	minute, err := parseCronField(fields[0], 0, 59)
+	if err != nil {
+		return nil, fmt.Errorf("解析分钟字段失败: %w", err)
+	}
+	hour, err := parseCronField(fields[1], 0, 23)
+	if err != nil {
+		return nil, fmt.Errorf("解析小时字段失败: %w", err)
+	}
+	dom, err := parseCronField(fields[2], 1, 31)
+	if err != nil {
+		return nil, fmt.Errorf("解析日字段失败: %w", err)
+	}
+	month, err := parseCronField(fields[3], 1, 12)
+	if err != nil {
+		return nil, fmt.Errorf("解析月字段失败: %w", err)
+	}
+	dow, err := parseCronField(fields[4], 0, 6)
+	if err != nil {
+		return nil, fmt.Errorf("解析星期字段失败: %w", err)
+	}
+	return &cronSchedule{minute: minute, hour: hour, dom: dom, month: month, dow: dow}, nil
+}
+
+// cronScheduleLookahead是Next查找下一次触发时间的搜索上限，超过这个跨度找不到匹配分钟
+// 说明表达式本身有问题（比如2月31日），直接返回零值避免无限循环
+const cronScheduleLookahead = 4 * 366 * 24 * time.Hour
+
+// Next返回从from之后（不含from本身所在分钟）最近一个满足表达式的时间点，分钟粒度，秒/纳秒清零
+func (s *cronSchedule) Next(from time.Time) time.Time {
+	t := from.Truncate(time.Minute).Add(time.Minute)
+	deadline := from.Add(cronScheduleLookahead)
+	for t.Before(deadline) {
+		if s.month[int(t.Month())] && s.dom[t.Day()] && s.dow[int(t.Weekday())] && s.hour[t.Hour()] && s.minute[t.Minute()] {
+			return t
+		}
+		t = t.Add(time.Minute)
+	}
+	return time.Time{}
+}
+
+// cronJob包装一个config.CrawlJobConfig及其运行期状态，paused为true时jobScheduler跳过到期触发
+// 但不影响已经在执行中的那一轮遍历。
+type cronJob struct {
+	cfg      config.CrawlJobConfig
+	schedule *cronSchedule
+
+	mu        sync.Mutex
+	paused    bool
+	lastRun   time.Time
+	nextRun   time.Time
+	running   bool
+	runCount  int64
+	failCount int64
+}
+
+func (j *cronJob) toStatus() *taskapi.CrawlJobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	status := &taskapi.CrawlJobStatus{
+		Name:         j.cfg.Name,
+		CronExpr:     j.cfg.CronExpr,
+		PathTemplate: j.cfg.PathTemplate,
+		Paused:       j.paused,
+		RunCount:     atomic.LoadInt64(&j.runCount),
+		FailCount:    atomic.LoadInt64(&j.failCount),
+	}
+	if !j.lastRun.IsZero() {
+		status.LastRunUnix = j.lastRun.Unix()
+	}
+	if !j.nextRun.IsZero() {
+		status.NextRunUnix = j.nextRun.Unix()
+	}
+	return status
+}
+
+// jobScheduler按分钟粒度轮询jobs，到期且未暂停、未在执行中的任务各自起一个goroutine跑一轮
+// octreeCrawler遍历，任务之间互不阻塞（慢任务不会推迟其他任务的触发）。
+type jobScheduler struct {
+	c    *Crawler
+	jobs []*cronJob
+}
+
+// newJobScheduler按cfgs逐个解析CronExpr，解析失败的任务记录警告并跳过（不影响其余任务加载），
+// 与newPathDedupIndex等处"单个子功能初始化失败不拖累整个进程启动"的处理方式一致。
+func newJobScheduler(c *Crawler, cfgs []config.CrawlJobConfig) *jobScheduler {
+	js := &jobScheduler{c: c}
+	now := time.Now()
+	for _, cfg := range cfgs {
+		schedule, err := parseCronExpr(cfg.CronExpr)
+		if err != nil {
+			log.Printf("警告: 定时抓取任务 [%s] 的CronExpr无效，已跳过: %v", cfg.Name, err)
+			continue
+		}
+		job := &cronJob{cfg: cfg, schedule: schedule}
+		job.nextRun = schedule.Next(now)
+		js.jobs = append(js.jobs, job)
+		log.Printf("[定时任务] 已加载 [%s]，CronExpr=%q，下次触发时间=%s", cfg.Name, cfg.CronExpr, job.nextRun.Format(time.RFC3339))
+	}
+	return js
+}
+
+func (js *jobScheduler) find(name string) *cronJob {
+	for _, job := range js.jobs {
+		if job.cfg.Name == name {
+			return job
+		}
+	}
+	return nil
+}
+
+// Pause暂停name对应的任务，此后到期不再触发新一轮遍历，但不中断已经在执行中的那一轮
+func (js *jobScheduler) Pause(name string) error {
+	job := js.find(name)
+	if job == nil {
+		return fmt.Errorf("定时抓取任务 %q 不存在", name)
+	}
+	job.mu.Lock()
+	job.paused = true
+	job.mu.Unlock()
+	return nil
+}
+
+// Resume恢复name对应的任务，并立即按其CronExpr重新计算下一次触发时间
+func (js *jobScheduler) Resume(name string) error {
+	job := js.find(name)
+	if job == nil {
+		return fmt.Errorf("定时抓取任务 %q 不存在", name)
+	}
+	job.mu.Lock()
+	job.paused = false
+	job.nextRun = job.schedule.Next(time.Now())
+	job.mu.Unlock()
+	return nil
+}
+
+// List返回全部已加载任务的当前状态
+func (js *jobScheduler) List() []*taskapi.CrawlJobStatus {
+	statuses := make([]*taskapi.CrawlJobStatus, 0, len(js.jobs))
+	for _, job := range js.jobs {
+		statuses = append(statuses, job.toStatus())
+	}
+	return statuses
+}
+
+// tick检查每个任务是否到期，到期的起一个goroutine异步执行，避免单个任务的遍历耗时影响
+// 其余任务的触发节奏；调用方（runScheduledJobs）按分钟粒度重复调用。
+func (js *jobScheduler) tick(now time.Time) {
+	for _, job := range js.jobs {
+		job.mu.Lock()
+		due := !job.paused && !job.running && !job.nextRun.IsZero() && !now.Before(job.nextRun)
+		if due {
+			job.running = true
+		}
+		job.mu.Unlock()
+		if !due {
+			continue
+		}
+
+		js.c.wg.Add(1)
+		go func(job *cronJob) {
+			defer js.c.wg.Done()
+			js.runJob(job)
+
+			job.mu.Lock()
+			job.running = false
+			job.lastRun = time.Now()
+			job.nextRun = job.schedule.Next(job.lastRun)
+			job.mu.Unlock()
+		}(job)
+	}
+}
+
+// runJob按job.cfg起一轮octreeCrawler遍历，Depth/Concurrency<=0时分别沿用
+// RockTreeDataConfig.MaxTraversalDepth和当前白名单IP数量，StorageTarget非空时
+// 持久化本轮遍历抓到的NodeData/Imagery原始字节。
+func (js *jobScheduler) runJob(job *cronJob) {
+	c := js.c
+	log.Printf("[定时任务] [%s] 开始执行本轮遍历（起始路径=%q）", job.cfg.Name, job.cfg.PathTemplate)
+
+	allowedIPs := c.ipAccessControl.GetAllowedIPs()
+	if len(allowedIPs) == 0 {
+		log.Printf("[定时任务] [%s] 白名单为空，本轮跳过", job.cfg.Name)
+		atomic.AddInt64(&job.failCount, 1)
+		return
+	}
+
+	maxDepth := job.cfg.Depth
+	if maxDepth <= 0 {
+		maxDepth = c.config.RockTreeDataConfig.MaxTraversalDepth
+		if maxDepth <= 0 {
+			maxDepth = octreeLevelsPerBulkPacket
+		}
+	}
+
+	initialConcurrency := job.cfg.Concurrency
+	if initialConcurrency <= 0 {
+		initialConcurrency = len(allowedIPs)
+	}
+
+	dedupIndexPath := c.config.RockTreeDataConfig.DedupIndexPath
+	if dedupIndexPath == "" {
+		dedupIndexPath = fmt.Sprintf("%s/dedup_index.log", c.dataDir)
+	}
+	dedup, err := newPathDedupIndex(c.config.RockTreeDataConfig.DedupEnabled, dedupIndexPath)
+	if err != nil {
+		log.Printf("[定时任务] [%s] 警告: 初始化去重索引失败，本轮遍历将不做持久化去重: %v", job.cfg.Name, err)
+		dedup, _ = newPathDedupIndex(false, "")
+	}
+	defer dedup.Close()
+
+	fetchLimiter := newTaskScheduler(initialConcurrency)
+	fetchAIMD := newAIMDController(initialConcurrency, 1, initialConcurrency*4, 1, 0.5, fetchLimiter.SetCapacity)
+
+	oc := &octreeCrawler{
+		c:                c,
+		maxDepth:         maxDepth,
+		allowedIPs:       c.ipAccessControl.GetAllowedIPs,
+		dedup:            dedup,
+		fetchLimiter:     fetchLimiter,
+		fetchAIMD:        fetchAIMD,
+		storageKeyPrefix: job.cfg.StorageTarget,
+	}
+
+	oc.run(job.cfg.PathTemplate)
+
+	atomic.AddInt64(&job.runCount, 1)
+	log.Printf("[定时任务] [%s] 本轮遍历完成: BulkMetadata=%d, NodeData=%d, Imagery=%d, 失败=%d",
+		job.cfg.Name, atomic.LoadInt64(&oc.bulkFetched), atomic.LoadInt64(&oc.nodesFetched),
+		atomic.LoadInt64(&oc.imageFetched), atomic.LoadInt64(&oc.fetchFailures))
+}
+
+// runScheduledJobs每分钟检查一次各定时抓取任务是否到期，与cleanupTempFiles/monitorMemory等
+// 后台goroutine在Start()中以同样的c.wg.Add(1)+go方式启动，c.stopChan关闭时退出。
+func (c *Crawler) runScheduledJobs() {
+	defer c.wg.Done()
+	if c.jobScheduler == nil || len(c.jobScheduler.jobs) == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.jobScheduler.tick(time.Now())
+		case <-c.stopChan:
+			return
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+)
+
+// dedup.go 为octreeCrawler提供跨进程重启仍然有效的路径去重：一层内存里的布隆过滤器做快速的
+// "大概率没抓过"判断，未命中布隆过滤器时一定没抓取过，可以直接放行；命中时布隆过滤器可能误判，
+// 再查一遍加载进内存的精确集合确认。索引文件是按行追加的已抓取路径列表，进程启动时整份
+// 加载进布隆过滤器和精确集合，运行期间只做追加写。
+//
+// octreeCrawler.seen（sync.Map）解决的是同一次遍历内部"同一路径被多个父节点都展开到"的问题，
+// 这里的pathDedupIndex解决的是"上一次遍历已经抓过，这一次还要不要再抓"的问题，两者职责不同，
+// 因此没有合并成一个结构。
+
+const (
+	dedupBloomBits   = 1 << 24 // 16Mbit位图，约2MB内存
+	dedupBloomHashes = 4
+)
+
+// bloomFilter是一个不支持删除的标准布隆过滤器，只用于"大概率没见过"的快速排除。
+// Add/MightContain会被多个octree worker goroutine并发调用（见pathDedupIndex.Mark/Seen），
+// 自带一把mu保护bits，不依赖调用方加锁——pathDedupIndex.mu只保护磁盘索引文件的追加写，
+// 两把锁职责不重叠。
+type bloomFilter struct {
+	mu   sync.RWMutex
+	bits []uint64
+}
+
+func newBloomFilter(numBits int) *bloomFilter {
+	return &bloomFilter{bits: make([]uint64, (numBits+63)/64)}
+}
+
+func (b *bloomFilter) indexes(key string) [dedupBloomHashes]uint64 {
+	h1 := fnv1aHash(key)
+	h2 := fnv1aHash("salt:" + key)
+	total := uint64(len(b.bits) * 64)
+	var idx [dedupBloomHashes]uint64
+	for i := 0; i < dedupBloomHashes; i++ {
+		idx[i] = (h1 + uint64(i)*h2) % total
+	}
+	return idx
+}
+
+func (b *bloomFilter) Add(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, i := range b.indexes(key) {
+		b.bits[i/64] |= 1 << (i % 64)
+	}
+}
+
+func (b *bloomFilter) MightContain(key string) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, i := range b.indexes(key) {
+		if b.bits[i/64]&(1<<(i%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func fnv1aHash(s string) uint64 {
+	const offset64 = 14695981039346656037
+	const prime64 = 1099511628211
+	h := uint64(offset64)
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= prime64
+	}
+	return h
+}
+
+// pathDedupIndex是crawlBulkMetadataBatch对外暴露的去重入口：Seen判断key是否已经抓取过，
+// Mark把新抓取成功的key记入布隆过滤器并追加写入磁盘索引。enabled为false时Seen恒返回false、
+// Mark什么也不做，行为与去重功能加入之前完全一致。
+type pathDedupIndex struct {
+	enabled bool
+	bloom   *bloomFilter
+	exact   sync.Map // 命中布隆过滤器时用来排除假阳性，同时也是索引文件加载进内存后的唯一真源
+
+	mu   sync.Mutex // 保护file的追加写
+	file *os.File
+
+	checked int64
+	hits    int64
+}
+
+// newPathDedupIndex按enabled/indexPath构造去重索引；enabled为false时直接返回一个空索引，
+// 不读写任何文件。
+func newPathDedupIndex(enabled bool, indexPath string) (*pathDedupIndex, error) {
+	idx := &pathDedupIndex{enabled: enabled, bloom: newBloomFilter(dedupBloomBits)}
+	if !enabled {
+		return idx, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(indexPath), 0755); err != nil {
+		return nil, fmt.Errorf("创建去重索引目录失败: %w", err)
+	}
+
+	if f, err := os.Open(indexPath); err == nil {
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		loaded := 0
+		for scanner.Scan() {
+			key := scanner.Text()
+			if key == "" {
+				continue
+			}
+			idx.bloom.Add(key)
+			idx.exact.Store(key, struct{}{})
+			loaded++
+		}
+		f.Close()
+		log.Printf("[去重索引] 已从 %s 加载 %d 条已抓取路径", indexPath, loaded)
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("读取去重索引文件失败: %w", err)
+	}
+
+	file, err := os.OpenFile(indexPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("打开去重索引文件失败: %w", err)
+	}
+	idx.file = file
+	return idx, nil
+}
+
+// Seen判断key是否已经抓取过
+func (d *pathDedupIndex) Seen(key string) bool {
+	if !d.enabled {
+		return false
+	}
+	atomic.AddInt64(&d.checked, 1)
+	if !d.bloom.MightContain(key) {
+		return false
+	}
+	_, ok := d.exact.Load(key)
+	if ok {
+		atomic.AddInt64(&d.hits, 1)
+	}
+	return ok
+}
+
+// Mark把key记为已抓取：先更新内存态，再追加写入磁盘索引。进程异常退出时最坏情况下
+// 丢失最后几条尚未写盘的记录，下次启动会重新抓一次，这比引入事务性存储的复杂度更划算。
+func (d *pathDedupIndex) Mark(key string) {
+	if !d.enabled {
+		return
+	}
+	if _, loaded := d.exact.LoadOrStore(key, struct{}{}); loaded {
+		return
+	}
+	d.bloom.Add(key)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.file != nil {
+		if _, err := d.file.WriteString(key + "\n"); err != nil {
+			log.Printf("[去重索引] 警告: 写入去重索引失败: %v", err)
+		}
+	}
+}
+
+// Stats返回去重检查次数和命中次数，命中即代表省掉了一次重复抓取
+func (d *pathDedupIndex) Stats() (checked, hits int64) {
+	return atomic.LoadInt64(&d.checked), atomic.LoadInt64(&d.hits)
+}
+
+func (d *pathDedupIndex) Close() error {
+	if d.file == nil {
+		return nil
+	}
+	return d.file.Close()
+}
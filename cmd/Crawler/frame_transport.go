@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"utlsProxy/internal/taskapi"
+)
+
+// frameConn 抽象一个基于"4字节大端长度前缀 + payload"成帧协议的双向字节流。
+// taskFrameHandler只依赖这个接口处理一问一答，不关心底层是QUIC流、KCP流还是普通TCP连接，
+// 新增一种裸字节流传输时只需要实现这个接口去适配具体的conn/stream类型，
+// 不需要重新实现请求解析、executeTask调用和错误/指标处理这些各传输间完全相同的逻辑。
+type frameConn interface {
+	ReadFrame() ([]byte, error)
+	WriteFrame(payload []byte) error
+	RemoteAddr() string
+}
+
+// lengthPrefixedConn 用4字节大端长度前缀给任意io.Reader/io.Writer包出frameConn，
+// maxFrameSize限制单帧payload大小，超出视为协议错误而不是尝试分配任意大小的缓冲区。
+type lengthPrefixedConn struct {
+	reader       *bufio.Reader
+	writer       io.Writer
+	remote       string
+	maxFrameSize uint32
+}
+
+// newLengthPrefixedConn 包装r/w为一个frameConn，remote仅用于日志，maxFrameSize为单帧payload上限
+func newLengthPrefixedConn(r io.Reader, w io.Writer, remote string, maxFrameSize uint32) *lengthPrefixedConn {
+	return &lengthPrefixedConn{reader: bufio.NewReader(r), writer: w, remote: remote, maxFrameSize: maxFrameSize}
+}
+
+func (c *lengthPrefixedConn) ReadFrame() ([]byte, error) {
+	var lengthBuf [4]byte
+	if _, err := io.ReadFull(c.reader, lengthBuf[:]); err != nil {
+		return nil, err
+	}
+
+	payloadLen := binary.BigEndian.Uint32(lengthBuf[:])
+	if payloadLen == 0 {
+		return nil, fmt.Errorf("请求负载为空")
+	}
+	if payloadLen > c.maxFrameSize {
+		return nil, fmt.Errorf("请求体过大（%d 字节）", payloadLen)
+	}
+
+	payload := make([]byte, payloadLen)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+func (c *lengthPrefixedConn) WriteFrame(payload []byte) error {
+	writer := bufio.NewWriter(c.writer)
+	var header [4]byte
+	binary.BigEndian.PutUint32(header[:], uint32(len(payload)))
+	if _, err := writer.Write(header[:]); err != nil {
+		return err
+	}
+	if _, err := writer.Write(payload); err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+func (c *lengthPrefixedConn) RemoteAddr() string { return c.remote }
+
+// taskFrameHandler 把一个frameConn上收到的成帧payload解码为taskapi.TaskRequest，
+// 经由Crawler.executeTask执行（与Execute等gRPC方法共享同一套配额、调度和统计逻辑），
+// 再把taskapi.TaskResponse编码回一帧写回去。各传输的接入代码（quic_server.go等）
+// 只需要负责listener/conn/stream的建立和frameConn适配，一问一答的处理逻辑都委托给这里，
+// 使下一种传输（WebTransport、unix socket、KCP……）只需要实现frameConn适配层。
+type taskFrameHandler struct {
+	crawler   *Crawler
+	transport transportKind
+	label     string
+}
+
+// newTaskFrameHandler 为指定transport创建一个taskFrameHandler，label用于日志前缀
+func newTaskFrameHandler(c *Crawler, transport transportKind) *taskFrameHandler {
+	return &taskFrameHandler{crawler: c, transport: transport, label: "[" + transport.label() + "]"}
+}
+
+// Handle 读取一帧请求、执行、写回一帧响应；调用方负责在处理结束后自行关闭底层连接/流
+func (h *taskFrameHandler) Handle(ctx context.Context, conn frameConn) {
+	start := time.Now()
+
+	payload, err := conn.ReadFrame()
+	if err != nil {
+		h.writeError(conn, "读取请求失败", err, start, 0)
+		return
+	}
+
+	var req taskapi.TaskRequest
+	if err := json.Unmarshal(payload, &req); err != nil {
+		h.writeError(conn, "请求体解码失败", err, start, int64(len(payload)))
+		return
+	}
+
+	taskCtx, cancel := context.WithTimeout(ctx, maxTaskDuration)
+	defer cancel()
+
+	resp, err := h.crawler.executeTask(taskCtx, h.transport, &req, int64(len(payload)))
+	if err != nil && (resp == nil || resp.ErrorMessage == "") {
+		if resp == nil {
+			resp = &taskapi.TaskResponse{ClientID: req.ClientID}
+		}
+		resp.ErrorMessage = err.Error()
+	}
+
+	responsePayload, err := json.Marshal(resp)
+	if err != nil {
+		h.writeError(conn, "响应编码失败", err, start, int64(len(payload)))
+		return
+	}
+
+	if err := conn.WriteFrame(responsePayload); err != nil {
+		log.Printf("%s 发送响应失败: %v (remote=%s)", h.label, err, conn.RemoteAddr())
+	}
+}
+
+// writeError 统计一次失败请求并尽力把错误信息作为一帧TaskResponse写回
+func (h *taskFrameHandler) writeError(conn frameConn, message string, err error, start time.Time, requestBytes int64) {
+	ts := h.crawler.statsFor(h.transport)
+	fullMsg := message
+	if err != nil {
+		fullMsg = fmt.Sprintf("%s: %v", message, err)
+	}
+	log.Printf("%s %s (remote=%s)", h.label, fullMsg, conn.RemoteAddr())
+
+	atomic.AddInt64(&ts.Requests, 1)
+	atomic.AddInt64(&ts.Failed, 1)
+	if requestBytes > 0 {
+		atomic.AddInt64(&ts.RequestBytes, requestBytes)
+	}
+
+	resp := &taskapi.TaskResponse{ErrorMessage: fullMsg}
+	responsePayload, marshalErr := json.Marshal(resp)
+	if marshalErr != nil {
+		log.Printf("%s 编码错误响应失败: %v", h.label, marshalErr)
+		h.crawler.addTransportDuration(&ts.DurationMicros, start)
+		return
+	}
+
+	atomic.AddInt64(&ts.ResponseBytes, int64(len(responsePayload)))
+	if err := conn.WriteFrame(responsePayload); err != nil {
+		log.Printf("%s 发送错误响应失败: %v", h.label, err)
+	}
+
+	h.crawler.addTransportDuration(&ts.DurationMicros, start)
+}
@@ -4,8 +4,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -37,7 +43,10 @@ func (c *Crawler) startGRPCServer() error {
 		return fmt.Errorf("创建 gRPC TCP 监听器失败: listener 为 nil")
 	}
 
-	server := taskapi.NewServer()
+	server := taskapi.NewServer(
+		grpc.UnaryInterceptor(authUnaryInterceptor(c)),
+		grpc.StreamInterceptor(authStreamInterceptor(c)),
+	)
 	if server == nil {
 		return fmt.Errorf("创建 gRPC TCP 服务器失败: server 为 nil")
 	}
@@ -66,7 +75,440 @@ func (s *taskService) Execute(ctx context.Context, req *taskapi.TaskRequest) (*t
 	return s.crawler.executeTask(ctx, transportGRPC, req, rawBytes)
 }
 
-func (c *Crawler) handleTaskRequest(ctx context.Context, transportLabel string, transportPrefix string, clientID, path string) (int, []byte, error) {
+// executeStreamChunkSize 是ExecuteStream每个分片携带的最大字节数，
+// 取值小于gRPC默认的4MB单消息上限，避免单个分片本身就撞上限。
+const executeStreamChunkSize = 1 * 1024 * 1024 // 1MB
+
+// ExecuteStream 与Execute执行同一次任务，但把响应体切分为多个TaskResponseChunk依次发送，
+// 使几十MB量级的响应体不必在单条gRPC消息里整体缓冲和传输。
+func (s *taskService) ExecuteStream(req *taskapi.TaskRequest, stream taskapi.TaskService_ExecuteStreamServer) error {
+	ctx := stream.Context()
+
+	var rawBytes int64
+	if req != nil {
+		rawBytes = int64(len(req.Path) + len(req.ClientID))
+	}
+	resp, err := s.crawler.executeTask(ctx, transportGRPC, req, rawBytes)
+	if err != nil {
+		return err
+	}
+
+	clientID := ""
+	if req != nil {
+		clientID = req.ClientID
+	}
+
+	if resp.ErrorMessage != "" {
+		return stream.Send(&taskapi.TaskResponseChunk{
+			ClientID:     clientID,
+			StatusCode:   resp.StatusCode,
+			ChunkIndex:   0,
+			IsLast:       true,
+			ErrorMessage: resp.ErrorMessage,
+		})
+	}
+
+	body := resp.Body
+	if len(body) == 0 {
+		return stream.Send(&taskapi.TaskResponseChunk{
+			ClientID:   clientID,
+			StatusCode: resp.StatusCode,
+			ChunkIndex: 0,
+			IsLast:     true,
+		})
+	}
+
+	for index := 0; ; index++ {
+		end := index*executeStreamChunkSize + executeStreamChunkSize
+		if end > len(body) {
+			end = len(body)
+		}
+		start := index * executeStreamChunkSize
+		chunk := &taskapi.TaskResponseChunk{
+			ClientID:   clientID,
+			ChunkIndex: int32(index),
+			Data:       body[start:end],
+			IsLast:     end == len(body),
+		}
+		if index == 0 {
+			chunk.StatusCode = resp.StatusCode
+		}
+		if err := stream.Send(chunk); err != nil {
+			return err
+		}
+		if chunk.IsLast {
+			return nil
+		}
+	}
+}
+
+// fetchFileChunkSize 是FetchFile未指定ChunkSize时每个分片携带的字节数
+const fetchFileChunkSize = 256 * 1024 // 256KB
+
+// FetchFile 按FilePath流式读取task_executor.go落盘的大响应体文件并分片发送，
+// 全程只在内存里保留单个分片，服务端RSS不随文件大小增长。出于安全考虑，
+// 只允许读取c.crawler.tempFileDir目录下的文件，拒绝任何试图跳出该目录的路径。
+func (s *taskService) FetchFile(req *taskapi.FetchFileRequest, stream taskapi.TaskService_FetchFileServer) error {
+	if req == nil || req.FilePath == "" {
+		return stream.Send(&taskapi.FileChunk{IsLast: true, ErrorMessage: "file_path 不能为空"})
+	}
+
+	absTempDir, err := filepath.Abs(s.crawler.tempFileDir)
+	if err != nil {
+		return stream.Send(&taskapi.FileChunk{IsLast: true, ErrorMessage: fmt.Sprintf("解析临时文件目录失败: %v", err)})
+	}
+	absPath, err := filepath.Abs(req.FilePath)
+	if err != nil || (absPath != absTempDir && !strings.HasPrefix(absPath, absTempDir+string(filepath.Separator))) {
+		return stream.Send(&taskapi.FileChunk{IsLast: true, ErrorMessage: "file_path 不在允许的临时文件目录内"})
+	}
+
+	f, err := os.Open(absPath)
+	if err != nil {
+		return stream.Send(&taskapi.FileChunk{IsLast: true, ErrorMessage: fmt.Sprintf("打开文件失败: %v", err)})
+	}
+	defer f.Close()
+
+	if req.Offset > 0 {
+		if _, err := f.Seek(req.Offset, io.SeekStart); err != nil {
+			return stream.Send(&taskapi.FileChunk{IsLast: true, ErrorMessage: fmt.Sprintf("定位偏移量失败: %v", err)})
+		}
+	}
+
+	chunkSize := int(req.ChunkSize)
+	if chunkSize <= 0 {
+		chunkSize = fetchFileChunkSize
+	}
+
+	offset := req.Offset
+	buf := make([]byte, chunkSize)
+	for {
+		n, readErr := f.Read(buf)
+		if n > 0 {
+			isLast := readErr == io.EOF
+			chunk := &taskapi.FileChunk{
+				Data:   append([]byte(nil), buf[:n]...),
+				Offset: offset,
+				IsLast: isLast,
+			}
+			if err := stream.Send(chunk); err != nil {
+				return err
+			}
+			offset += int64(n)
+			if isLast {
+				return nil
+			}
+		}
+		if readErr != nil {
+			if readErr == io.EOF {
+				return stream.Send(&taskapi.FileChunk{Offset: offset, IsLast: true})
+			}
+			return stream.Send(&taskapi.FileChunk{Offset: offset, IsLast: true, ErrorMessage: fmt.Sprintf("读取文件失败: %v", readErr)})
+		}
+	}
+}
+
+// TaskStream 是双向流式RPC：在一条长连接上持续接收带CorrelationID的TaskRequest，
+// 为每个请求起一个goroutine异步执行，完成后把携带同一个CorrelationID的TaskResponse发送回去，
+// 响应顺序与请求到达顺序无关。所有TaskStream连接的请求都经由executeTask争用同一个
+// c.scheduler执行槽位，按优先级和ClientID公平调度，不会出现某个连接持续提交请求
+// 而挤占其他连接计算资源的情况。
+func (s *taskService) TaskStream(stream taskapi.TaskService_TaskStreamServer) error {
+	ctx := stream.Context()
+	var sendMu sync.Mutex
+	var wg sync.WaitGroup
+
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			wg.Wait()
+			return err
+		}
+
+		wg.Add(1)
+		go func(req *taskapi.TaskRequest) {
+			defer wg.Done()
+
+			rawBytes := int64(len(req.Path) + len(req.ClientID))
+			resp, _ := s.crawler.executeTask(ctx, transportGRPC, req, rawBytes)
+			resp.CorrelationID = req.CorrelationID
+
+			sendMu.Lock()
+			sendErr := stream.Send(resp)
+			sendMu.Unlock()
+			if sendErr != nil {
+				log.Printf("TaskStream 发送响应失败 [CorrelationID: %s]: %v", req.CorrelationID, sendErr)
+			}
+		}(req)
+	}
+
+	wg.Wait()
+	return nil
+}
+
+// executeBatchMaxConcurrency 限制单次ExecuteBatch内部同时执行的path数量，
+// 避免一个batch请求把c.scheduler的执行槽位全部占满，导致其他客户端的请求被饿死。
+const executeBatchMaxConcurrency = 50
+
+// ExecuteBatch 在一次RPC里批量执行一组path，内部以有界并发fan-out到各个目标IP
+// （通过executeTask沿用与Execute相同的IP轮询和执行槽位逻辑），并按完成顺序把每个
+// path各自的TaskBatchResult流式发送回去，避免50000个path各自发起一次RPC的往返开销。
+func (s *taskService) ExecuteBatch(req *taskapi.TaskBatchRequest, stream taskapi.TaskService_ExecuteBatchServer) error {
+	ctx := stream.Context()
+	if req == nil || len(req.Paths) == 0 {
+		return nil
+	}
+
+	sem := make(chan struct{}, executeBatchMaxConcurrency)
+	var sendMu sync.Mutex
+	var wg sync.WaitGroup
+	var errMu sync.Mutex
+	var firstErr error
+
+	for _, path := range req.Paths {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		}
+
+		wg.Add(1)
+		go func(path string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			rawBytes := int64(len(path) + len(req.ClientID))
+			resp, _ := s.crawler.executeTask(ctx, transportGRPC, &taskapi.TaskRequest{ClientID: req.ClientID, Path: path}, rawBytes)
+
+			sendMu.Lock()
+			sendErr := stream.Send(&taskapi.TaskBatchResult{
+				Path:         path,
+				StatusCode:   resp.StatusCode,
+				Body:         resp.Body,
+				ErrorMessage: resp.ErrorMessage,
+			})
+			sendMu.Unlock()
+			if sendErr != nil {
+				errMu.Lock()
+				if firstErr == nil {
+					firstErr = sendErr
+				}
+				errMu.Unlock()
+			}
+		}(path)
+	}
+
+	wg.Wait()
+	return firstErr
+}
+
+// RestartSubsystem 重启单个子系统（DomainMonitor、连接池或QUIC监听器），
+// 避免为了恢复某个子系统而重启整个进程，缩小运维操作的影响范围。
+func (s *taskService) RestartSubsystem(ctx context.Context, req *taskapi.RestartRequest) (*taskapi.RestartResponse, error) {
+	if req == nil || req.Subsystem == "" {
+		return nil, fmt.Errorf("subsystem 不能为空")
+	}
+
+	var result RestartResult
+	switch req.Subsystem {
+	case "domain_monitor":
+		result = s.crawler.RestartDomainMonitor()
+	case "pool":
+		result = s.crawler.RestartPool()
+	case "quic_listener":
+		result = s.crawler.RestartQUICListener()
+	default:
+		return nil, fmt.Errorf("未知的子系统: %s", req.Subsystem)
+	}
+
+	resp := &taskapi.RestartResponse{
+		Subsystem: result.Subsystem,
+		Success:   result.Success,
+	}
+	if result.Success {
+		resp.Message = result.Message
+	} else {
+		resp.ErrorMessage = result.Message
+	}
+	return resp, nil
+}
+
+// benchmarkTimeout 是Benchmark单次采样请求的超时时间，比handleTaskRequest的serverTimeout更宽松，
+// 因为运维在评估新目标主机时更关心真实延迟分布而不是快速失败重试。
+const benchmarkTimeout = 5 * time.Second
+
+// Benchmark 对Path采集N*M个延迟/状态样本，不经过executeTask的并发槽位、配额和常规统计，
+// 用运维自己的显式IP选择取代handleTaskRequest的轮询调度，供评估新目标主机或配置变更使用。
+func (s *taskService) Benchmark(ctx context.Context, req *taskapi.BenchmarkRequest) (*taskapi.BenchmarkResponse, error) {
+	if req == nil || req.Path == "" {
+		return nil, fmt.Errorf("path 不能为空")
+	}
+
+	pathSuffix := req.Path
+	if pathSuffix[0] != '/' {
+		pathSuffix = "/" + pathSuffix
+	}
+
+	allowedIPs := s.crawler.ipAccessControl.GetAllowedIPs()
+	if len(allowedIPs) == 0 {
+		return nil, fmt.Errorf("白名单为空，无法采样")
+	}
+
+	if req.M > 0 && int(req.M) < len(allowedIPs) {
+		allowedIPs = allowedIPs[:req.M]
+	}
+
+	n := int(req.N)
+	if n <= 0 {
+		n = 1
+	}
+
+	resp := &taskapi.BenchmarkResponse{ClientID: req.ClientID, Path: req.Path}
+
+	for _, targetIP := range allowedIPs {
+		for attempt := 1; attempt <= n; attempt++ {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			default:
+			}
+
+			workID := fmt.Sprintf("benchmark-%s-%s-%d", req.ClientID, targetIP, attempt)
+			result, _, err, duration := s.crawler.performRequestAttempt(0, 0, attempt, targetIP, pathSuffix, workID, benchmarkTimeout, taskRequestOptions{})
+
+			sample := &taskapi.BenchmarkSample{
+				IP:            targetIP,
+				LatencyMicros: duration.Microseconds(),
+			}
+			if err != nil {
+				sample.ErrorMessage = err.Error()
+			} else if result != nil {
+				sample.StatusCode = int32(result.StatusCode)
+			}
+			resp.Samples = append(resp.Samples, sample)
+		}
+	}
+
+	return resp, nil
+}
+
+// EnqueueTask 把任务提交到持久化任务队列并立即返回TaskID，不等待任务执行完成；
+// 任务的实际执行仍会经过executeTask的配额、调度和常规统计，只是调用时机由队列异步驱动。
+func (s *taskService) EnqueueTask(ctx context.Context, req *taskapi.EnqueueTaskRequest) (*taskapi.EnqueueTaskResponse, error) {
+	if req == nil || req.Request == nil {
+		return nil, fmt.Errorf("request 不能为空")
+	}
+
+	id, err := s.crawler.taskQueue.EnqueueAndRun(req.Request)
+	if err != nil {
+		return nil, err
+	}
+	return &taskapi.EnqueueTaskResponse{TaskID: id}, nil
+}
+
+// TaskStatus 查询EnqueueTask提交的任务当前的执行状态
+func (s *taskService) TaskStatus(ctx context.Context, req *taskapi.TaskStatusRequest) (*taskapi.TaskStatusResponse, error) {
+	if req == nil || req.TaskID == "" {
+		return nil, fmt.Errorf("task_id 不能为空")
+	}
+
+	entry, ok := s.crawler.taskQueue.Status(req.TaskID)
+	if !ok {
+		return nil, fmt.Errorf("任务 %s 不存在", req.TaskID)
+	}
+	return entry.toStatusResponse(), nil
+}
+
+// DrainTasks 取走并移除所有已经跑到终态（done/failed）的任务及其结果
+func (s *taskService) DrainTasks(ctx context.Context, req *taskapi.DrainTasksRequest) (*taskapi.DrainTasksResponse, error) {
+	drained := s.crawler.taskQueue.Drain()
+	resp := &taskapi.DrainTasksResponse{Entries: make([]*taskapi.TaskStatusResponse, 0, len(drained))}
+	for _, entry := range drained {
+		resp.Entries = append(resp.Entries, entry.toStatusResponse())
+	}
+	return resp, nil
+}
+
+// ListCrawlJobs 列出config.Config.CrawlJobs中配置的全部定时抓取任务及其当前运行期状态
+func (s *taskService) ListCrawlJobs(ctx context.Context, req *taskapi.ListCrawlJobsRequest) (*taskapi.ListCrawlJobsResponse, error) {
+	if s.crawler.jobScheduler == nil {
+		return &taskapi.ListCrawlJobsResponse{}, nil
+	}
+	return &taskapi.ListCrawlJobsResponse{Jobs: s.crawler.jobScheduler.List()}, nil
+}
+
+// ControlCrawlJob 按JobName暂停或恢复一个定时抓取任务
+func (s *taskService) ControlCrawlJob(ctx context.Context, req *taskapi.CrawlJobControlRequest) (*taskapi.CrawlJobControlResponse, error) {
+	if req == nil || req.JobName == "" {
+		return nil, fmt.Errorf("job_name 不能为空")
+	}
+	if s.crawler.jobScheduler == nil {
+		return nil, fmt.Errorf("未配置任何定时抓取任务")
+	}
+
+	var err error
+	switch req.Action {
+	case "pause":
+		err = s.crawler.jobScheduler.Pause(req.JobName)
+	case "resume":
+		err = s.crawler.jobScheduler.Resume(req.JobName)
+	default:
+		return nil, fmt.Errorf("未知的action: %s（应为pause或resume）", req.Action)
+	}
+
+	resp := &taskapi.CrawlJobControlResponse{JobName: req.JobName, Success: err == nil}
+	if err != nil {
+		resp.ErrorMessage = err.Error()
+	}
+	return resp, nil
+}
+
+// GetStats 返回当前各传输（gRPC/QUIC等）各自的请求/成功/失败/流量/耗时/会话/活跃流统计快照，
+// 取自c.stats.Transports——顺序固定为transportGRPC、transportQUIC，与main.go打印日志时一致
+func (s *taskService) GetStats(ctx context.Context, req *taskapi.GetStatsRequest) (*taskapi.GetStatsResponse, error) {
+	kinds := []transportKind{transportGRPC, transportQUIC}
+	resp := &taskapi.GetStatsResponse{Transports: make([]*taskapi.TransportStatsEntry, 0, len(kinds))}
+	for _, kind := range kinds {
+		ts := s.crawler.statsFor(kind)
+		resp.Transports = append(resp.Transports, &taskapi.TransportStatsEntry{
+			Transport:      kind.label(),
+			Requests:       atomic.LoadInt64(&ts.Requests),
+			Success:        atomic.LoadInt64(&ts.Success),
+			Failed:         atomic.LoadInt64(&ts.Failed),
+			RequestBytes:   atomic.LoadInt64(&ts.RequestBytes),
+			ResponseBytes:  atomic.LoadInt64(&ts.ResponseBytes),
+			DurationMicros: atomic.LoadInt64(&ts.DurationMicros),
+			Sessions:       atomic.LoadInt64(&ts.Sessions),
+			ActiveStreams:  atomic.LoadInt64(&ts.ActiveStreams),
+		})
+	}
+	return resp, nil
+}
+
+// selectTargetIP 按既有的轮询顺序选下一个IP，但尽量跳过仍在429冷却期内的IP：
+// 最多尝试len(allowedIPs)次寻找一个未冷却的候选，全部都在冷却期时退化为按原有轮询顺序选取。
+func (c *Crawler) selectTargetIP(allowedIPs []string) string {
+	var candidate string
+	for i := 0; i < len(allowedIPs); i++ {
+		index := int(atomic.AddUint64(&c.ipSelector, 1) % uint64(len(allowedIPs)))
+		candidate = allowedIPs[index]
+		if !c.rateLimitTracker.InCooldown(candidate) {
+			return candidate
+		}
+	}
+	return candidate
+}
+
+func (c *Crawler) handleTaskRequest(ctx context.Context, transportLabel string, transportPrefix string, clientID, path string, opts taskRequestOptions) (int, []byte, error) {
+	ctx, span := c.startSpan(ctx, "handleTaskRequest")
+	span.SetAttr("transport", transportPrefix)
+	span.SetAttr("client_id", clientID)
+	span.SetAttr("path", path)
+	defer span.End()
+
 	allowedIPs := c.ipAccessControl.GetAllowedIPs()
 	if len(allowedIPs) == 0 {
 		return 0, nil, fmt.Errorf("白名单为空，无法调度任务")
@@ -91,12 +533,11 @@ func (c *Crawler) handleTaskRequest(ctx context.Context, transportLabel string,
 		default:
 		}
 
-		index := int(atomic.AddUint64(&c.ipSelector, 1) % uint64(len(allowedIPs)))
-		targetIP := allowedIPs[index]
+		targetIP := c.selectTargetIP(allowedIPs)
 		workID := fmt.Sprintf("%s-%s-%d", transportPrefix, clientID, attempt)
 
 		// 使用2秒超时，快速失败让客户端重试
-		resp, _, err, duration := c.performRequestAttempt(0, 0, attempt, targetIP, pathSuffix, workID, serverTimeout)
+		resp, _, err, duration := c.performRequestAttempt(0, 0, attempt, targetIP, pathSuffix, workID, serverTimeout, opts)
 
 		if err != nil {
 			// 立即清理resp对象
@@ -140,9 +581,26 @@ func (c *Crawler) handleTaskRequest(ctx context.Context, transportLabel string,
 		}
 
 		if statusCode == 200 {
+			c.rateLimitTracker.RecordSuccess(targetIP)
 			return statusCode, body, nil
 		}
 
+		if statusCode == http.StatusTooManyRequests {
+			body = nil
+			cooldown := c.rateLimitTracker.RecordTooManyRequests(targetIP, pathSuffix)
+			atomic.AddInt64(&c.stats.RateLimited, 1)
+			if attempt == maxAttempts {
+				log.Printf("%s 任务(%s) 第 %d 次命中429 [目标IP: %s]，冷却 %v 后跳过该IP", transportLabel, clientID, attempt, targetIP, cooldown)
+				return statusCode, nil, fmt.Errorf("远端返回状态码 %d（限流）", statusCode)
+			}
+			select {
+			case <-time.After(c.rateLimitTracker.RequeueDelay()):
+			case <-ctx.Done():
+				return 0, nil, ctx.Err()
+			}
+			continue
+		}
+
 		return statusCode, body, fmt.Errorf("远端返回状态码 %d", statusCode)
 	}
 
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"utlsProxy/internal/safego"
+)
+
+// health.go实现/healthz、/readyz两个只读HTTP探活端点，供k8s livenessProbe/readinessProbe
+// 或systemd这类外部编排系统判断本实例是否存活、是否应该接收流量。由ServerConfig.HealthPort
+// 控制开关和监听端口，是独立于gRPC/QUIC的一个net/http.Server，不经过taskapi的鉴权/限流——
+// 健康检查端点本身应当总是可达，不应该被业务层面的访问控制挡在外面。同一个HTTP服务上还
+// 挂了一个/stats端点，把c.stats.Transports转储成JSON，供不想走gRPC GetStats RPC的运维
+// 脚本（curl/监控采集器）直接拉取，内容与GetStats RPC返回的数据完全一致。
+
+// healthStatus是/healthz、/readyz返回的JSON状态，两个端点共用同一份状态计算，
+// 区别只在于/readyz按ready字段决定HTTP状态码是200还是503。
+type healthStatus struct {
+	Ready           bool           `json:"ready"`
+	UptimeSeconds   float64        `json:"uptime_seconds"`
+	WarmupComplete  bool           `json:"warmup_complete"`
+	WhitelistSize   int            `json:"whitelist_size"`
+	BlacklistSize   int            `json:"blacklist_size"`
+	GRPCServing     bool           `json:"grpc_serving"`
+	PoolHealthyConn map[string]int `json:"pool_healthy_conn"`
+	GoroutinePanics int64          `json:"goroutine_panics"`
+}
+
+// buildHealthStatus汇总判断存活/就绪所需的各项信号，ready等价于请求里要求的三个条件：
+// 预热跑完、白名单非空、gRPC在对外服务。
+func (c *Crawler) buildHealthStatus() healthStatus {
+	status := healthStatus{
+		UptimeSeconds:   time.Since(c.stats.StartTime).Seconds(),
+		WarmupComplete:  atomic.LoadInt32(&c.warmupComplete) == 1,
+		GRPCServing:     c.grpcServer != nil,
+		PoolHealthyConn: make(map[string]int),
+		GoroutinePanics: safego.CrashCount(),
+	}
+	if c.ipAccessControl != nil {
+		status.WhitelistSize = len(c.ipAccessControl.GetAllowedIPs())
+		status.BlacklistSize = len(c.ipAccessControl.GetBlockedIPs())
+	}
+	if c.poolManager != nil {
+		for _, domain := range c.poolManager.Domains() {
+			if pool, ok := c.poolManager.PoolForDomain(domain); ok {
+				status.PoolHealthyConn[domain] = pool.HealthyConnCount()
+			}
+		}
+	}
+	status.Ready = status.WarmupComplete && status.WhitelistSize > 0 && status.GRPCServing
+	return status
+}
+
+// statsDumpEntry与taskapi.TransportStatsEntry字段一一对应，单独定义是因为health.go不依赖
+// internal/taskapi包（避免健康检查服务被拖进gRPC编解码的依赖图），内容上两者保持一致。
+type statsDumpEntry struct {
+	Transport      string `json:"transport"`
+	Requests       int64  `json:"requests"`
+	Success        int64  `json:"success"`
+	Failed         int64  `json:"failed"`
+	RequestBytes   int64  `json:"request_bytes"`
+	ResponseBytes  int64  `json:"response_bytes"`
+	DurationMicros int64  `json:"duration_micros"`
+	Sessions       int64  `json:"sessions"`
+	ActiveStreams  int64  `json:"active_streams"`
+}
+
+// buildStatsDump把c.stats.Transports转成/stats端点返回的JSON，顺序固定为gRPC、QUIC，
+// 与GetStats RPC、main.go打印统计日志时的顺序保持一致。
+func (c *Crawler) buildStatsDump() []statsDumpEntry {
+	kinds := []transportKind{transportGRPC, transportQUIC}
+	entries := make([]statsDumpEntry, 0, len(kinds))
+	for _, kind := range kinds {
+		ts := c.statsFor(kind)
+		entries = append(entries, statsDumpEntry{
+			Transport:      kind.label(),
+			Requests:       atomic.LoadInt64(&ts.Requests),
+			Success:        atomic.LoadInt64(&ts.Success),
+			Failed:         atomic.LoadInt64(&ts.Failed),
+			RequestBytes:   atomic.LoadInt64(&ts.RequestBytes),
+			ResponseBytes:  atomic.LoadInt64(&ts.ResponseBytes),
+			DurationMicros: atomic.LoadInt64(&ts.DurationMicros),
+			Sessions:       atomic.LoadInt64(&ts.Sessions),
+			ActiveStreams:  atomic.LoadInt64(&ts.ActiveStreams),
+		})
+	}
+	return entries
+}
+
+func writeHealthJSON(w http.ResponseWriter, statusCode int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+// startHealthServer在ServerConfig.HealthPort非0时启动健康检查HTTP服务，为0时直接返回nil
+// （功能关闭），与startGRPCServer/startQUICServer的端口为0即跳过的约定保持一致。
+func (c *Crawler) startHealthServer() error {
+	if c.config == nil {
+		return fmt.Errorf("配置未初始化: config 为 nil")
+	}
+	if c.config.ServerConfig.HealthPort == 0 {
+		return nil
+	}
+
+	address := fmt.Sprintf(":%d", c.config.ServerConfig.HealthPort)
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("监听健康检查HTTP端口失败: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		// 存活探针：进程能响应HTTP请求就算存活，不看预热/白名单这类可自愈的业务状态——
+		// livenessProbe失败会直接重启容器，不应该被"暂时没有可用IP"这种情况触发
+		writeHealthJSON(w, http.StatusOK, c.buildHealthStatus())
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		status := c.buildHealthStatus()
+		code := http.StatusOK
+		if !status.Ready {
+			code = http.StatusServiceUnavailable
+		}
+		writeHealthJSON(w, code, status)
+	})
+	mux.HandleFunc("/stats", func(w http.ResponseWriter, r *http.Request) {
+		writeHealthJSON(w, http.StatusOK, c.buildStatsDump())
+	})
+
+	server := &http.Server{Handler: mux}
+	c.healthServer = server
+	log.Printf("健康检查HTTP服务启动，地址 %s（/healthz、/readyz、/stats）", address)
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("健康检查HTTP服务异常退出: %v", err)
+		}
+	}()
+
+	return nil
+}
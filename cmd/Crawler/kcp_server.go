@@ -0,0 +1,20 @@
+package main
+
+import "fmt"
+
+// startKCPServer在ServerConfig.EnableKCP启用时理应监听一个KCP会话并复用startGRPCServer/
+// startQUICServer共享的taskFrameHandler，对外提供第三种（gRPC、QUIC之外的）任务传输路径。
+// 目前go.mod尚未引入kcp-go依赖（见pkg/taskclient/transport.go顶部的文档注释），没有可用的
+// KCP会话实现可以监听，所以这里没有按enable直接no-op放过去——EnableKCP=true通常代表运维
+// 已经在config.toml里明确要求这条传输路径可用，静默不生效会让人以为KCP已经在工作，
+// 排查起来比直接启动失败更费时间。等kcp-go依赖可用、补上真正的KCP监听/处理逻辑后，
+// 这个函数直接替换成startGRPCServer/startQUICServer那样的"构建监听器->go c.acceptXXX"结构即可。
+func (c *Crawler) startKCPServer() error {
+	if c.config == nil {
+		return fmt.Errorf("配置未初始化: config 为 nil")
+	}
+	if !c.config.ServerConfig.EnableKCP {
+		return nil
+	}
+	return fmt.Errorf("KCP 传输尚未实现: go.mod 未引入 kcp-go 依赖，无法监听 KCPPort=%d（调参见config.KCPConfig），详见 pkg/taskclient/transport.go 的说明", c.config.ServerConfig.KCPPort)
+}
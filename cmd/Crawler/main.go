@@ -1,14 +1,18 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -24,30 +28,127 @@ import (
 
 // Crawler 爬虫结构体
 type Crawler struct {
-	pool            src.HotConnPool
-	client          *src.UTlsClient
-	config          *config.Config
-	domainMonitor   src.DomainMonitor
-	ipAccessControl src.IPAccessController
-	stats           *CrawlerStats
-	stopChan        chan struct{}
-	wg              sync.WaitGroup
-	concurrency     int
-	grpcSemaphore   chan struct{} // gRPC请求并发控制信号量
-	dataDir         string
-	stopped         int32
-	fingerprint     src.Profile
-	slowIPTracker   *SlowIPTracker
-	requestHeaders  map[string]string
-	ipSelector      uint64
-	grpcServer      *grpc.Server
-	grpcListener    net.Listener
-	quicListener    *quic.Listener
-	tempFileDir     string // 临时文件目录，用于存储大响应体
+	pool              src.HotConnPool
+	client            *src.UTlsClient
+	config            *config.Config
+	domainMonitor     src.DomainMonitor
+	ipAccessControl   src.IPAccessController
+	stats             *CrawlerStats
+	stopChan          chan struct{}
+	wg                sync.WaitGroup
+	concurrency       int
+	scheduler         *taskScheduler // 按TaskRequest.Priority分桶、桶内按ClientID轮询的gRPC请求执行槽位调度器
+	dataDir           string
+	stopped           int32
+	fingerprint       src.Profile
+	slowIPTracker     *SlowIPTracker
+	rateLimitTracker  *rateLimitTracker // 跟踪各IP的429冷却期和按IP/path统计的429命中次数
+	requestHeaders    atomic.Value      // 存放map[string]string，用atomic.Value是因为配置热重载（见config_reload.go）会在其他goroutine运行时替换整份请求头集合
+	ipSelector        uint64
+	grpcServer        *grpc.Server
+	grpcListener      net.Listener
+	quicListener      *quic.Listener
+	tempFileDir       string // 临时文件目录，用于存储大响应体
+	pacer             *ThroughputPacer
+	poolConfig        src.DomainConnPoolConfig // 保存创建连接池时使用的配置，供单独重建连接池时复用
+	poolManager       *src.PoolManager         // 管理RockTree、EarthImagery等各域名各自的连接池，pool也已注册其中
+	restartMu         sync.Mutex               // 串行化各子系统的重启操作，避免并发重启互相踩踏
+	quotaManager      *clientQuotaManager      // 按ClientID限制并发数和请求速率，见TaskAPIConfig
+	taskQueue         *persistentTaskQueue     // 持久化任务队列，EnqueueTask提交的任务在进程重启后仍能自动恢复执行
+	storage           Storage                  // 抓取结果的落盘/上传后端，见storage.go，由config.StorageConfig.Backend选择
+	grpcConcurrency   *aimdController          // 按executeTask的成功/失败信号自适应调整scheduler容量的AIMD控制器，见aimd.go
+	rateLimiter       *requestRateLimiter      // 全局/按目标IP的令牌桶限速器，见ratelimit.go，由config.RateLimit配置
+	jobScheduler      *jobScheduler            // 按config.CrawlJobs中各CronExpr定时触发的命名抓取任务，见cronjobs.go
+	configPath        string                   // LoadConfig时使用的配置文件路径，SIGHUP热重载（见config_reload.go）据此重新读取
+	warmupComplete    int32                    // 0/1，连接池预热goroutine是否已整体跑完，见health.go
+	healthServer      *http.Server             // /healthz、/readyz所在HTTP服务，由ServerConfig.HealthPort控制是否启动，见health.go
+	coordinatorServer *grpc.Server             // Coordinator.Role="coordinator"时的JSON-codec gRPC服务，见coordinator.go
+	ipsyncServer      *grpc.Server             // IPSync.Enabled时接收对等节点推送事件的JSON-codec gRPC服务，见ipsync.go
+	ipsyncQueue       *ipSyncQueue             // IPSync.Enabled时缓存待推送给对等节点的本地变更事件，见ipsync.go
+	certReloader      *quicCertReloader        // QUIC服务端证书/客户端CA的可原子替换状态，由startQUICServer构建，SIGHUP热重载（见cert_reload.go、config_reload.go）据此轮换证书
+	quicConnCount     int64                    // 当前存活的QUIC连接数，acceptQUICConnections据此对比ServerConfig.QUICMaxConnections，超出时拒绝新连接
+	tracingEnabled    atomic.Bool              // 是否为任务执行/上游请求记录链路追踪span，见tracing.go、internal/tracing；SIGHUP热重载可原子翻转（见config_reload.go）
+	tracingService    atomic.Value             // 存放string，记录span时使用的服务名，来自ServerConfig.TracingServiceName
+}
+
+// ThroughputPacer 根据配置的目标吞吐量（请求/秒、字节/秒）为任务派发设定节奏，
+// 使多个worker的实际吞吐量逼近目标值，而不需要手动调整并发数
+type ThroughputPacer struct {
+	targetInterval    time.Duration // 相邻两次任务派发的目标间隔，<=0表示不限速
+	targetBytesPerSec float64       // 目标字节速率，<=0表示不限速
+	startTime         time.Time
+	dispatched        int64
+	bytesSent         int64
+}
+
+// NewThroughputPacer 根据吞吐量配置创建调度器，两个目标均未设置时返回nil（不限速）
+func NewThroughputPacer(cfg config.ThroughputConfig) *ThroughputPacer {
+	interval := cfg.GetTargetInterval()
+	bytesPerSec := cfg.GetTargetBytesPerSecond()
+	if interval <= 0 && bytesPerSec <= 0 {
+		return nil
+	}
+	return &ThroughputPacer{
+		targetInterval:    interval,
+		targetBytesPerSec: bytesPerSec,
+		startTime:         time.Now(),
+	}
+}
+
+// Wait 阻塞直到轮到下一次任务派发，使实际请求速率/流量速率不超过目标值
+func (p *ThroughputPacer) Wait() {
+	if p == nil {
+		return
+	}
+	n := atomic.AddInt64(&p.dispatched, 1)
+	due := p.startTime
+	if p.targetInterval > 0 {
+		due = p.startTime.Add(time.Duration(n) * p.targetInterval)
+	}
+	if p.targetBytesPerSec > 0 {
+		bytesSoFar := atomic.LoadInt64(&p.bytesSent)
+		byteDue := p.startTime.Add(time.Duration(float64(bytesSoFar) / p.targetBytesPerSec * float64(time.Second)))
+		if byteDue.After(due) {
+			due = byteDue
+		}
+	}
+	if d := time.Until(due); d > 0 {
+		time.Sleep(d)
+	}
+}
+
+// RecordBytes 记录本次请求产生的响应字节数，供流量速率节奏使用
+func (p *ThroughputPacer) RecordBytes(n int64) {
+	if p == nil {
+		return
+	}
+	atomic.AddInt64(&p.bytesSent, n)
+}
+
+// ActualRequestsPerSecond 返回截至目前的实际请求速率，用于与目标速率对比
+func (p *ThroughputPacer) ActualRequestsPerSecond() float64 {
+	if p == nil {
+		return 0
+	}
+	elapsed := time.Since(p.startTime).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(atomic.LoadInt64(&p.dispatched)) / elapsed
 }
 
 const maxTaskDuration = 15 * time.Second // 增加超时时间到15秒，以应对慢速IP
 
+const (
+	// minReadyConnsToServe 是启动时预热阶段至少要就绪的连接数，达到后Start就立即对外提供
+	// 服务，不再等待全部目标IP都预热完毕
+	minReadyConnsToServe = 5
+
+	// warmupReadyTimeout 是等待minReadyConnsToServe就绪的上限，超时后即使就绪连接数不足
+	// 也会开始对外服务，避免域名解析异常等情况下启动被无限期卡住
+	warmupReadyTimeout = 10 * time.Second
+)
+
 // CrawlerStats 爬虫统计信息
 type CrawlerStats struct {
 	TotalRequests   int64
@@ -58,39 +159,65 @@ type CrawlerStats struct {
 	StartedTasks    int64
 	CompletedTasks  int64
 	CompletedMicros int64
-	// gRPC请求统计
-	GRPCRequests      int64 // gRPC请求总数
-	GRPCSuccess       int64 // gRPC成功请求数
-	GRPCFailed        int64 // gRPC失败请求数
-	GRPCRequestBytes  int64 // gRPC请求总字节数
-	GRPCResponseBytes int64 // gRPC响应总字节数
-	GRPCDuration      int64 // gRPC请求总耗时（微秒）
-	QUICRequests      int64 // QUIC请求总数
-	QUICSuccess       int64 // QUIC成功请求数
-	QUICFailed        int64 // QUIC失败请求数
-	QUICRequestBytes  int64 // QUIC请求总字节数
-	QUICResponseBytes int64 // QUIC响应总字节数
-	QUICDuration      int64 // QUIC请求总耗时（微秒）
-	QUICSessions      int64 // QUIC会话总数
-	QUICStreams       int64 // QUIC流总数
-	StartTime         time.Time
+	// Transports按transportKind收拢各传输各自的请求/成功/失败/流量/耗时统计，取代过去
+	// 分别铺开的GRPCRequests/QUICRequests...一整套同名字段——新增一种传输（如KCP，见
+	// transport.go文档注释）时只需要在newCrawlerStats里补一个map条目，不用再在这里
+	// 新增六七个字段。见transport.go的TransportStats定义和(*Crawler).statsFor。
+	Transports map[transportKind]*TransportStats
+	// FingerprintMismatches 统计实际连接使用的指纹与Crawler启动时选定的默认指纹不一致的请求数，
+	// 用于观测连接池按连接随机/粘滞选择指纹的比例是否符合预期，而不是真的出现了UA与TLS层不匹配的问题
+	// （该问题已经在UTlsClient.Do中通过使用连接实际的指纹重写请求头来规避）
+	FingerprintMismatches int64
+	// StatusCheck* 统计CheckStatusPath的元数据探测请求（runCrawler批量爬取前的前置校验），
+	// 这类请求不代表业务爬取结果，单独计数以免拉低/拉高TotalRequests体现的业务成功率
+	StatusCheckRequests int64
+	StatusCheckSuccess  int64
+	StatusCheckFailed   int64
+	// AuthRejected 统计因TaskAPIConfig.AuthToken校验失败被拒绝的RPC数
+	AuthRejected int64
+	// QuotaRejected 统计因超过PerClientMaxConcurrency或PerClientRequestsPerSecond被拒绝的任务数
+	QuotaRejected int64
+	// RateLimited 统计远端返回429的次数，详细的按IP/按path分布见rateLimitTracker.SnapshotByIP/SnapshotByPath
+	RateLimited int64
+	// QUICConnRejected 统计因超过ServerConfig.QUICMaxConnections被拒绝（CloseWithError关闭）的QUIC连接数
+	QUICConnRejected int64
+	StartTime        time.Time
+}
+
+// newCrawlerStats构造一个StartTime为当前时间、Transports已经按已知transportKind预先
+// 建好条目的CrawlerStats——Transports的键集合固定在这里一次性确定，运行期只原子修改
+// 各*TransportStats内部的字段，不再并发读写map本身，因此不需要额外加锁。
+func newCrawlerStats() *CrawlerStats {
+	return &CrawlerStats{
+		StartTime: time.Now(),
+		Transports: map[transportKind]*TransportStats{
+			transportGRPC: {},
+			transportQUIC: {},
+		},
+	}
 }
 
 // SlowIPTracker 用于跟踪响应缓慢的IP
 type SlowIPTracker struct {
-	threshold    time.Duration
-	counts       sync.Map
-	maxEntries   int // 最大条目数，超过后清理旧数据
-	lastCleanup  time.Time
-	cleanupMutex sync.Mutex
+	threshold        time.Duration
+	minThroughputBps float64 // 最低可接受吞吐量（字节/秒），配合threshold区分"响应慢"和"响应体大所以花得久"
+	counts           sync.Map
+	maxEntries       int // 最大条目数，超过后清理旧数据
+	lastCleanup      time.Time
+	cleanupMutex     sync.Mutex
 }
 
-// NewSlowIPTracker 创建慢速IP跟踪器
-func NewSlowIPTracker(threshold time.Duration) *SlowIPTracker {
+// NewSlowIPTracker 创建慢速IP跟踪器。threshold是耗时下限，minThroughputBps是吞吐量下限
+// （字节/秒，<=0表示不看吞吐量，退化为只按耗时判断）——同时满足"耗时超过threshold"和
+// "吞吐量低于minThroughputBps"才计入一次慢速记录，避免把"响应体本来就很大所以花得久"
+// 误判成"这个IP响应慢"，这两者仅凭耗时本身是分不清的，见ConnMetadata.ConsumeByteDelta/
+// RecordConnBytes提供的套接字层面字节数。
+func NewSlowIPTracker(threshold time.Duration, minThroughputBps float64) *SlowIPTracker {
 	tracker := &SlowIPTracker{
-		threshold:   threshold,
-		maxEntries:  1000, // 最多保存1000个慢IP记录
-		lastCleanup: time.Now(),
+		threshold:        threshold,
+		minThroughputBps: minThroughputBps,
+		maxEntries:       1000, // 最多保存1000个慢IP记录
+		lastCleanup:      time.Now(),
 	}
 	// 启动定期清理goroutine
 	go tracker.periodicCleanup()
@@ -136,11 +263,19 @@ func (t *SlowIPTracker) cleanupOldEntries() {
 	t.lastCleanup = time.Now()
 }
 
-// Record 如果响应耗时超过阈值则记录并返回累计次数
-func (t *SlowIPTracker) Record(ip string, duration time.Duration) int64 {
+// Record 如果响应耗时超过阈值则记录并返回累计次数；bytesTransferred>0且minThroughputBps>0时，
+// 额外要求吞吐量（bytesTransferred/duration）低于minThroughputBps才计入一次记录——耗时长但
+// 吞吐量正常（比如只是响应体本身很大）不应该被当成"这个IP响应慢"
+func (t *SlowIPTracker) Record(ip string, duration time.Duration, bytesTransferred int64) int64 {
 	if ip == "" || duration < t.threshold {
 		return 0
 	}
+	if bytesTransferred > 0 && t.minThroughputBps > 0 {
+		throughput := float64(bytesTransferred) / duration.Seconds()
+		if throughput >= t.minThroughputBps {
+			return 0
+		}
+	}
 	ptrIface, _ := t.counts.LoadOrStore(ip, new(int64))
 	counterPtr := ptrIface.(*int64)
 	return atomic.AddInt64(counterPtr, 1)
@@ -165,28 +300,61 @@ func NewCrawler(cfg *config.Config) (*Crawler, error) {
 		return nil, fmt.Errorf("创建数据目录失败: %w", err)
 	}
 
+	storage, err := newStorage(cfg.StorageConfig, dataDir)
+	if err != nil {
+		return nil, fmt.Errorf("初始化存储后端失败: %w", err)
+	}
+
+	if cfg.ServerConfig.RandomSeed != 0 {
+		log.Printf("[确定性随机] 已启用固定随机种子 %d，本次运行的指纹选择、IP选择与IPv6地址生成可复现", cfg.ServerConfig.RandomSeed)
+		src.SeedGlobalRandomness(cfg.ServerConfig.RandomSeed)
+	}
+
 	domainMonitor, err := createDomainMonitor(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("创建域名监控器失败: %w", err)
 	}
 	domainMonitor.Start()
 
+	startTime := time.Now()
+	for _, entry := range cfg.StaticIPs {
+		if err := domainMonitor.InjectStaticIPs(entry.Domain, entry.IPs, entry.GetExpiresAt(startTime)); err != nil {
+			log.Printf("警告: 注入域名 [%s] 的静态IP失败: %v", entry.Domain, err)
+		}
+	}
+
 	log.Printf("等待域名监控器为域名 [%s] 完成首次IP更新...", cfg.HotConnPool.Domain)
 	if !waitForIPs(domainMonitor, cfg.HotConnPool.Domain, 30*time.Second) {
 		return nil, fmt.Errorf("在30秒内未能从域名监控器获取到域名 [%s] 的任何IP地址", cfg.HotConnPool.Domain)
 	}
 
 	log.Println("正在初始化本地IP池（自动检测模式）...")
-	localIPv4Pool, err := src.NewLocalIPPool(cfg.HotConnPool.LocalIPv4Addresses, "")
-	if err != nil {
-		log.Printf("警告: 创建IPv4 IP池失败: %v", err)
-	}
-	localIPv6Pool, err := src.NewLocalIPPool([]string{}, cfg.HotConnPool.LocalIPv6SubnetCIDR)
-	if err != nil {
-		log.Printf("警告: 创建IPv6 IP池失败: %v", err)
+	var localIPv4Pool, localIPv6Pool src.IPPool
+	if cfg.ServerConfig.RandomSeed != 0 {
+		localIPv4Pool, err = src.NewLocalIPPoolSeeded(cfg.HotConnPool.LocalIPv4Addresses, "", cfg.ServerConfig.RandomSeed+1)
+		if err != nil {
+			log.Printf("警告: 创建IPv4 IP池失败: %v", err)
+		}
+		localIPv6Pool, err = src.NewLocalIPPoolSeeded([]string{}, cfg.HotConnPool.LocalIPv6SubnetCIDR, cfg.ServerConfig.RandomSeed+2)
+		if err != nil {
+			log.Printf("警告: 创建IPv6 IP池失败: %v", err)
+		}
+	} else {
+		localIPv4Pool, err = src.NewLocalIPPool(cfg.HotConnPool.LocalIPv4Addresses, "")
+		if err != nil {
+			log.Printf("警告: 创建IPv4 IP池失败: %v", err)
+		}
+		localIPv6Pool, err = src.NewLocalIPPool([]string{}, cfg.HotConnPool.LocalIPv6SubnetCIDR)
+		if err != nil {
+			log.Printf("警告: 创建IPv6 IP池失败: %v", err)
+		}
 	}
 	if localIPv4Pool == nil && localIPv6Pool == nil {
-		localIPv4Pool, _ = src.NewLocalIPPool([]string{}, "")
+		if cfg.ServerConfig.RandomSeed != 0 {
+			localIPv4Pool, _ = src.NewLocalIPPoolSeeded([]string{}, "", cfg.ServerConfig.RandomSeed+1)
+		} else {
+			localIPv4Pool, _ = src.NewLocalIPPool([]string{}, "")
+		}
 	}
 
 	fingerprint := getFingerprint(cfg)
@@ -196,22 +364,36 @@ func NewCrawler(cfg *config.Config) (*Crawler, error) {
 	ipAccessControl := src.NewWhiteBlackIPPool()
 
 	poolConfig := src.DomainConnPoolConfig{
-		DomainMonitor:         domainMonitor,
-		IPAccessControl:       ipAccessControl,
-		LocalIPv4Pool:         localIPv4Pool,
-		LocalIPv6Pool:         localIPv6Pool,
-		Fingerprint:           fingerprint,
-		Domain:                cfg.HotConnPool.Domain,
-		Port:                  cfg.HotConnPool.Port,
-		MaxConns:              cfg.HotConnPool.MaxConns,
-		IdleTimeout:           cfg.HotConnPool.GetIdleTimeout(),
-		WarmupPath:            warmupPath,
-		WarmupMethod:          cfg.HotConnPool.WarmupMethod,
-		WarmupHeaders:         warmupHeaders,
-		WarmupConcurrency:     cfg.HotConnPool.WarmupConcurrency,
-		BlacklistTestInterval: cfg.HotConnPool.GetBlacklistTestInterval(),
-		IPRefreshInterval:     cfg.HotConnPool.GetIPRefreshInterval(),
-		DialTimeout:           cfg.UTlsClient.GetDialTimeout(),
+		DomainMonitor:           domainMonitor,
+		IPAccessControl:         ipAccessControl,
+		LocalIPv4Pool:           localIPv4Pool,
+		LocalIPv6Pool:           localIPv6Pool,
+		Fingerprint:             fingerprint,
+		Domain:                  cfg.HotConnPool.Domain,
+		Port:                    cfg.HotConnPool.Port,
+		MaxConns:                cfg.HotConnPool.MaxConns,
+		IdleTimeout:             cfg.HotConnPool.GetIdleTimeout(),
+		WarmupPath:              warmupPath,
+		WarmupMethod:            cfg.HotConnPool.WarmupMethod,
+		WarmupHeaders:           warmupHeaders,
+		WarmupConcurrency:       cfg.HotConnPool.WarmupConcurrency,
+		BlacklistTestInterval:   cfg.HotConnPool.GetBlacklistTestInterval(),
+		IPRefreshInterval:       cfg.HotConnPool.GetIPRefreshInterval(),
+		DialTimeout:             cfg.UTlsClient.GetDialTimeout(),
+		FingerprintStickyIP:     cfg.HotConnPool.FingerprintStickyIP,
+		FingerprintSessionTTL:   cfg.HotConnPool.GetFingerprintSessionTTL(),
+		EmergencyResolveEnabled: cfg.HotConnPool.EmergencyResolveEnabled,
+		PlaintextHTTP:           cfg.HotConnPool.PlaintextHTTP,
+		SocketMark:              cfg.HotConnPool.SocketMark,
+		DSCP:                    cfg.HotConnPool.DSCP,
+		DailyByteCap:            cfg.HotConnPool.GetDailyByteCap(),
+		PreferredCountries:      cfg.HotConnPool.PreferredCountries,
+		PreferredASNs:           cfg.HotConnPool.PreferredASNs,
+		RecoveryWarmupCount:     cfg.HotConnPool.RecoveryWarmupCount,
+		StatusPolicy:            buildStatusPolicy(cfg.HotConnPool.StatusPolicy),
+	}
+	if cfg.ServerConfig.RandomSeed != 0 {
+		poolConfig.RandomSeed = cfg.ServerConfig.RandomSeed + 3
 	}
 
 	pool, err := src.NewDomainHotConnPool(poolConfig)
@@ -223,6 +405,41 @@ func NewCrawler(cfg *config.Config) (*Crawler, error) {
 	client.DialTimeout = cfg.UTlsClient.GetDialTimeout()
 	client.ReadTimeout = cfg.UTlsClient.GetReadTimeout()
 	client.HotConnPool = pool
+	client.MaxRedirects = cfg.UTlsClient.RedirectMaxHops
+	client.RedirectSameDomainOnly = cfg.UTlsClient.RedirectSameDomainOnly
+	client.MaxBodySize = cfg.UTlsClient.GetMaxBodySize()
+	client.MaxStatusLineBytes = cfg.UTlsClient.MaxStatusLineBytes
+	client.MaxHeaderBytes = cfg.UTlsClient.MaxHeaderBytes
+	client.MaxHeaderCount = cfg.UTlsClient.MaxHeaderCount
+
+	if cfg.UTlsClient.CookieJarEnabled {
+		cookieJar, err := src.NewPersistentCookieJar(cfg.UTlsClient.CookieJarPath)
+		if err != nil {
+			return nil, fmt.Errorf("创建Cookie jar失败: %w", err)
+		}
+		client.CookieJar = cookieJar
+	}
+
+	// 注册RockTree和EarthImagery两个业务域名的连接池，使client.Do能按UTlsRequest.Domain路由。
+	// 二者共享同一个domainMonitor、本地IP池和黑白名单，只有Domain、WarmupPath不同；
+	// 如果两者配置了相同的HostName（当前默认配置即如此），PoolManager会直接复用上面已创建的pool。
+	poolManager := src.NewPoolManager()
+	poolManager.Register(cfg.HotConnPool.Domain, pool)
+	if cfg.RockTreeDataConfig.HostName != "" {
+		rockTreeConfig := poolConfig
+		if err := poolManager.AddDomain(cfg.RockTreeDataConfig.HostName, rockTreeConfig); err != nil {
+			log.Printf("警告: 注册RockTree域名连接池失败: %v", err)
+		}
+	}
+	if cfg.EarthImageryDataConfig.HostName != "" {
+		earthImageryConfig := poolConfig
+		earthImageryConfig.WarmupPath = cfg.EarthImageryDataConfig.CheckStatusPath
+		earthImageryConfig.WarmupHeaders = parseHeaderList(cfg.EarthImageryDataConfig.RequestHeader)
+		if err := poolManager.AddDomain(cfg.EarthImageryDataConfig.HostName, earthImageryConfig); err != nil {
+			log.Printf("警告: 注册EarthImagery域名连接池失败: %v", err)
+		}
+	}
+	client.PoolManager = poolManager
 
 	// 初始化gRPC并发控制信号量，限制最大并发数为配置的并发数
 	grpcConcurrency := cfg.PoolConfig.Concurrency
@@ -236,25 +453,49 @@ func NewCrawler(cfg *config.Config) (*Crawler, error) {
 		return nil, fmt.Errorf("创建临时文件目录失败: %w", err)
 	}
 
+	grpcScheduler := newTaskScheduler(grpcConcurrency) // 限制gRPC并发数，并按优先级和ClientID公平调度
+	// grpcConcurrencyAIMD把静态的grpcConcurrency当作上限而不是固定值：成功率正常时逐步
+	// 向上探测到这个上限，出现超时/403一类的拥塞信号时乘性退让到最低1/8，退让后仍按同样
+	// 的节奏重新向上探测，不需要人工干预或重启。
+	grpcConcurrencyAIMD := newAIMDController(grpcConcurrency, grpcConcurrency/8, grpcConcurrency, 1, 0.5, grpcScheduler.SetCapacity)
+
 	crawler := &Crawler{
-		pool:            pool,
-		client:          client,
-		config:          cfg,
-		domainMonitor:   domainMonitor,
-		ipAccessControl: ipAccessControl,
-		stats: &CrawlerStats{
-			StartTime: time.Now(),
-		},
-		stopChan:       make(chan struct{}),
-		concurrency:    cfg.PoolConfig.Concurrency,
-		grpcSemaphore:  make(chan struct{}, grpcConcurrency), // 创建信号量，限制gRPC并发数
-		dataDir:        dataDir,
-		tempFileDir:    tempFileDir,
-		stopped:        0,
-		fingerprint:    fingerprint,
-		slowIPTracker:  NewSlowIPTracker(4 * time.Second),
-		requestHeaders: requestHeaders,
+		pool:             pool,
+		client:           client,
+		config:           cfg,
+		domainMonitor:    domainMonitor,
+		ipAccessControl:  ipAccessControl,
+		poolConfig:       poolConfig,
+		poolManager:      poolManager,
+		stats:            newCrawlerStats(),
+		stopChan:         make(chan struct{}),
+		concurrency:      cfg.PoolConfig.Concurrency,
+		scheduler:        grpcScheduler,
+		grpcConcurrency:  grpcConcurrencyAIMD,
+		dataDir:          dataDir,
+		tempFileDir:      tempFileDir,
+		stopped:          0,
+		fingerprint:      fingerprint,
+		slowIPTracker:    NewSlowIPTracker(4*time.Second, 2*1024), // 耗时超过4秒且吞吐量低于2KB/s才算响应慢
+		rateLimitTracker: newRateLimitTracker(),
+		pacer:            NewThroughputPacer(cfg.ThroughputConfig),
+		quotaManager:     newClientQuotaManager(cfg.TaskAPI.PerClientMaxConcurrency, cfg.TaskAPI.PerClientRequestsPerSecond),
+		storage:          storage,
+		rateLimiter:      newRequestRateLimiter(cfg.RateLimit),
+	}
+	crawler.requestHeaders.Store(requestHeaders)
+	crawler.tracingEnabled.Store(cfg.ServerConfig.TracingEnabled)
+	crawler.tracingService.Store(cfg.ServerConfig.TracingServiceName)
+	src.SetTracingConfig(cfg.ServerConfig.TracingEnabled, cfg.ServerConfig.TracingServiceName)
+	crawler.jobScheduler = newJobScheduler(crawler, cfg.CrawlJobs)
+
+	taskQueue, err := newPersistentTaskQueue(dataDir)
+	if err != nil {
+		return nil, fmt.Errorf("初始化持久化任务队列失败: %w", err)
 	}
+	taskQueue.crawler = crawler
+	crawler.taskQueue = taskQueue
+	taskQueue.ResumePending(context.Background())
 
 	log.Printf("[并发控制] gRPC服务器最大并发数设置为: %d", grpcConcurrency)
 	log.Printf("[内存优化] 临时文件目录: %s", tempFileDir)
@@ -317,6 +558,24 @@ func getFingerprint(cfg *config.Config) src.Profile {
 	return src.GetRandomFingerprint()
 }
 
+// buildStatusPolicy把配置文件里字符串形式的状态码->动作映射转换成src.StatusPolicy，
+// 键解析失败的条目直接跳过（不中断启动），与parseHeaderList对无效条目的处理方式一致。
+func buildStatusPolicy(raw map[string]string) src.StatusPolicy {
+	if len(raw) == 0 {
+		return nil
+	}
+	policy := make(src.StatusPolicy, len(raw))
+	for key, value := range raw {
+		statusCode, err := strconv.Atoi(strings.TrimSpace(key))
+		if err != nil {
+			log.Printf("警告: StatusPolicy配置中的状态码[%s]不是合法数字，已忽略", key)
+			continue
+		}
+		policy[statusCode] = src.StatusAction(strings.TrimSpace(value))
+	}
+	return policy
+}
+
 func parseHeaderList(list []string) map[string]string {
 	headers := make(map[string]string)
 	for _, header := range list {
@@ -332,6 +591,28 @@ func parseHeaderList(list []string) map[string]string {
 	return headers
 }
 
+// resolveRequestHeaders按path在config.HeaderProfiles中选用专用请求头（比如BulkMetadata、
+// NodeData、Imagery、dbroot、q2各自不同的头），找不到匹配的Profile时退回c.requestHeaders
+// 这份默认值；override非空时（来自TaskRequest.Headers）再逐项覆盖到结果上，
+// 供单次请求临时调整某个请求头而不必为此单独建一个HeaderProfile。
+func (c *Crawler) resolveRequestHeaders(path string, override map[string]string) map[string]string {
+	headers := c.config.GetHeadersForPath(path)
+	if headers == nil {
+		headers, _ = c.requestHeaders.Load().(map[string]string)
+	}
+	if len(override) == 0 {
+		return headers
+	}
+	merged := make(map[string]string, len(headers)+len(override))
+	for k, v := range headers {
+		merged[k] = v
+	}
+	for k, v := range override {
+		merged[k] = v
+	}
+	return merged
+}
+
 func (c *Crawler) Start() error {
 	log.Println("=========================================")
 	log.Println("启动高效爬虫系统")
@@ -339,15 +620,41 @@ func (c *Crawler) Start() error {
 
 	log.Println("开始预热连接池...")
 	warmupStart := time.Now()
-	if err := c.pool.Warmup(); err != nil {
-		log.Printf("预热连接池失败: %v", err)
-	} else {
+
+	// 预热达到minReadyConnsToServe个可用连接后就开始对外服务，不必等待全部目标IP都
+	// 预热完毕；尚未完成的IP继续在后台预热，由c.wg纳入Stop时的优雅关闭等待
+	readyCh := make(chan struct{})
+	var readyOnce sync.Once
+	var readyCount int32
+	c.poolManager.SetWarmupProgress(func(domain string, result src.WarmupResult) {
+		if !result.Success {
+			return
+		}
+		if atomic.AddInt32(&readyCount, 1) >= minReadyConnsToServe {
+			readyOnce.Do(func() { close(readyCh) })
+		}
+	})
+
+	warmupDone := make(chan struct{})
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer close(warmupDone)
+		defer atomic.StoreInt32(&c.warmupComplete, 1)
+		if _, err := c.poolManager.Warmup(); err != nil {
+			log.Printf("预热连接池失败: %v", err)
+		}
+	}()
+
+	select {
+	case <-readyCh:
+		log.Printf("已有至少 %d 个连接就绪，开始对外服务，剩余目标IP继续在后台预热（耗时: %v）", minReadyConnsToServe, time.Since(warmupStart))
+	case <-warmupDone:
 		log.Printf("连接池预热完成，耗时: %v", time.Since(warmupStart))
+	case <-time.After(warmupReadyTimeout):
+		log.Printf("等待预热连接就绪超时，开始对外服务（耗时: %v）", time.Since(warmupStart))
 	}
 
-	log.Println("等待预热连接稳定...")
-	time.Sleep(3 * time.Second)
-
 	if err := c.startGRPCServer(); err != nil {
 		return err
 	}
@@ -356,6 +663,26 @@ func (c *Crawler) Start() error {
 		return err
 	}
 
+	if err := c.startKCPServer(); err != nil {
+		return err
+	}
+
+	if err := c.startHealthServer(); err != nil {
+		return err
+	}
+
+	if err := c.startCoordinator(); err != nil {
+		return err
+	}
+
+	// 分布式协调模式下的worker角色：注册到协调节点、按分配到的分片起遍历、定期心跳同步IP名单
+	c.wg.Add(1)
+	go c.runAsWorker()
+
+	if err := c.startIPSyncServer(); err != nil {
+		return err
+	}
+
 	// 启动定期清理临时文件的goroutine
 	c.wg.Add(1)
 	go c.cleanupTempFiles()
@@ -368,6 +695,19 @@ func (c *Crawler) Start() error {
 	c.wg.Add(1)
 	go c.monitorGRPCSpeed()
 
+	// 启动定时抓取任务调度goroutine，按需cfg.CrawlJobs为空时runScheduledJobs会立即返回
+	c.wg.Add(1)
+	go c.runScheduledJobs()
+
+	// 启动任务队列定期重试扫描goroutine，使失败后退回pending的任务能自动重新执行，
+	// 不需要等到进程重启触发ResumePending
+	c.wg.Add(1)
+	go c.retrySweepLoop()
+
+	// 启动SIGHUP热重载监听goroutine，c.configPath为空（未设置，比如单元测试直接构造Crawler）时立即返回
+	c.wg.Add(1)
+	go c.watchConfigReload()
+
 	log.Println("爬虫系统已启动并等待任务")
 	return nil
 }
@@ -410,119 +750,25 @@ func (c *Crawler) fetchPlanetoidMetadata() (*PlanetoidMetadata, error) {
 		Fingerprint: c.fingerprint,
 		StartTime:   time.Now(),
 	}
+	atomic.AddInt64(&c.stats.StatusCheckRequests, 1)
+
 	resp, err := c.client.Do(req)
 	if err != nil {
+		atomic.AddInt64(&c.stats.StatusCheckFailed, 1)
 		return nil, err
 	}
+	c.recordFingerprintUsage(resp.Fingerprint)
 	if resp.StatusCode != 200 || len(resp.Body) != 13 {
+		atomic.AddInt64(&c.stats.StatusCheckFailed, 1)
 		return nil, fmt.Errorf("请求失败或响应体不正确")
 	}
+	atomic.AddInt64(&c.stats.StatusCheckSuccess, 1)
 	metadata := &PlanetoidMetadata{Version: fmt.Sprintf("%x", resp.Body)}
 	_ = c.saveData("PlanetoidMetadata.bin", resp.Body)
 	log.Printf("成功获取PlanetoidMetadata，响应体长度: %d字节，内容: %x", len(resp.Body), resp.Body)
 	return metadata, nil
 }
 
-func (c *Crawler) crawlBulkMetadataBatch(metadata *PlanetoidMetadata, semaphore chan struct{}) {
-	log.Println("开始批量爬取BulkMetadata（10000条任务，使用热连接池）...")
-
-	bulkPath := "/rt/earth/BulkMetadata/pb=!1m2!1s!2u1003"
-	totalTasks := 500
-	allowedIPs := c.ipAccessControl.GetAllowedIPs()
-	if len(allowedIPs) == 0 {
-		log.Println("警告: 白名单为空，无法执行爬取任务")
-		return
-	}
-	poolSize := len(allowedIPs)
-
-	log.Printf("批量爬取配置: 总任务数=%d, 白名单IP数量=%d, Worker数量=%d", totalTasks, len(allowedIPs), poolSize)
-
-	var wg sync.WaitGroup
-	taskChan := make(chan int, totalTasks)
-	for i := 0; i < totalTasks; i++ {
-		taskChan <- i
-	}
-	close(taskChan)
-
-	processTask := func(workerID int, taskID int, workerLocalIP *string) {
-		taskStart := time.Now()
-		c.recordTaskStart()
-		defer func() {
-			c.recordTaskCompletion(time.Since(taskStart))
-		}()
-
-		attempt := 0
-		for {
-			if atomic.LoadInt32(&c.stopped) == 1 {
-				return
-			}
-
-			attempt++
-			if c.executeBulkTask(workerID, taskID, attempt, workerLocalIP, bulkPath) {
-				if attempt > 1 {
-					log.Printf("[Worker %d] 任务 %d 在第 %d 次尝试后成功完成", workerID, taskID, attempt)
-				}
-				return
-			}
-
-			time.Sleep(c.backoffDuration(attempt))
-		}
-	}
-
-	for workerID := 0; workerID < poolSize; workerID++ {
-		wg.Add(1)
-		go func(id int) {
-			defer wg.Done()
-
-			var workerLocalIP string
-			taskCount := 0
-			for taskID := range taskChan {
-				taskCount++
-				processTask(id, taskID, &workerLocalIP)
-			}
-			if taskCount > 0 {
-				log.Printf("[Worker %d] [%s] 完成，共处理了 %d 个任务", id, workerLocalIP, taskCount)
-			}
-		}(workerID)
-	}
-
-	wg.Wait()
-	c.printStats()
-}
-
-func (c *Crawler) executeBulkTask(workerID, taskID, attempt int, workerLocalIP *string, bulkPath string) bool {
-	allowedIPs := c.ipAccessControl.GetAllowedIPs()
-	if len(allowedIPs) == 0 {
-		log.Printf("[Worker %d] 任务 %d 尝试 %d 次时白名单为空，等待可用IP...", workerID, taskID, attempt)
-		return false
-	}
-
-	targetIP := allowedIPs[(taskID+attempt-1)%len(allowedIPs)]
-	workID := fmt.Sprintf("bulk-%d-%d-%d", workerID, taskID, attempt)
-
-	resp, localIP, err, duration := c.performRequestAttempt(workerID, taskID, attempt, targetIP, bulkPath, workID, maxTaskDuration)
-	if err != nil {
-		log.Printf("[Worker %d] 任务 %d 请求失败（第 %d 次，目标IP: %s，耗时: %v）: %v", workerID, taskID, attempt, targetIP, duration, err)
-		return false
-	}
-
-	if duration > maxTaskDuration {
-		log.Printf("[Worker %d] 任务 %d 超时（第 %d 次，目标IP: %s，耗时: %v）", workerID, taskID, attempt, targetIP, duration)
-		return false
-	}
-
-	if resp.StatusCode == 200 {
-		if workerLocalIP != nil && *workerLocalIP == "" && localIP != "" {
-			*workerLocalIP = localIP
-		}
-		log.Printf("[Worker %d] 任务 %d 成功（第 %d 次，目标IP: %s，耗时: %v，长度: %d 字节）", workerID, taskID, attempt, targetIP, duration, len(resp.Body))
-		return true
-	}
-
-	log.Printf("[Worker %d] 任务 %d 返回状态码 %d（第 %d 次，目标IP: %s，耗时: %v）", workerID, taskID, resp.StatusCode, attempt, targetIP, duration)
-	return false
-}
-
 func (c *Crawler) backoffDuration(attempt int) time.Duration {
 	if attempt <= 0 {
 		return 200 * time.Millisecond
@@ -539,13 +785,13 @@ func (c *Crawler) backoffDuration(attempt int) time.Duration {
 	return backoff
 }
 
-func (c *Crawler) recordSlowIP(ip string, duration time.Duration) {
+func (c *Crawler) recordSlowIP(ip string, duration time.Duration, bytesTransferred int64) {
 	if c.slowIPTracker == nil || ip == "" {
 		return
 	}
-	count := c.slowIPTracker.Record(ip, duration)
+	count := c.slowIPTracker.Record(ip, duration, bytesTransferred)
 	if count > 0 && (count == 1 || count%5 == 0) {
-		log.Printf("[慢速IP] 目标IP: %s, 最近耗时: %v, 累计次数: %d", ip, duration, count)
+		log.Printf("[慢速IP] 目标IP: %s, 最近耗时: %v, 传输字节数: %d, 累计次数: %d", ip, duration, bytesTransferred, count)
 	}
 }
 
@@ -558,9 +804,33 @@ func (c *Crawler) recordTaskCompletion(duration time.Duration) {
 	atomic.AddInt64(&c.stats.CompletedMicros, duration.Microseconds())
 }
 
+// saveData 把抓取结果落盘，当Compression.Enabled为真时，会先以zstd压缩再写入
+// 文件名加.zst后缀的文件，未压缩时行为与之前完全一致。
 func (c *Crawler) saveData(filename string, data []byte) error {
-	filePath := filepath.Join(c.dataDir, filename)
-	return os.WriteFile(filePath, data, 0644)
+	if c.config != nil && c.config.Compression.Enabled {
+		compressed, err := compressZstd(data, c.config.Compression.Level)
+		if err != nil {
+			return fmt.Errorf("压缩数据失败: %w", err)
+		}
+		return c.storage.Save(filename+zstdSuffix, compressed)
+	}
+
+	return c.storage.Save(filename, data)
+}
+
+// loadData 读取saveData此前写入的数据：优先按未压缩文件名读取，
+// 找不到时回退到.zst压缩文件并透明解压，调用方拿到的始终是原始数据，
+// 不需要关心当前Compression.Enabled的配置值或写入时的配置值。
+func (c *Crawler) loadData(filename string) ([]byte, error) {
+	if data, err := c.storage.Load(filename); err == nil {
+		return data, nil
+	}
+
+	compressed, err := c.storage.Load(filename + zstdSuffix)
+	if err != nil {
+		return nil, err
+	}
+	return decompressZstd(compressed)
 }
 
 // monitorMemory 定期监控内存使用情况
@@ -612,7 +882,12 @@ func (c *Crawler) cleanupTempFiles() {
 	}
 }
 
-// cleanupOldTempFiles 清理超过30秒的临时文件（作为备用清理机制）
+// tempFileTTL 是落盘响应体文件在未被FetchFile完整取走的情况下最长保留的时间，
+// 需要给客户端留出足够时间通过FetchFile拉取（尤其是KCP/QUIC等慢速或有损传输路径），
+// 因此比早期"立即读回内存后删除"年代遗留下来的30秒判断宽松得多。
+const tempFileTTL = 5 * time.Minute
+
+// cleanupOldTempFiles 清理超过tempFileTTL仍未被取走的临时文件
 func (c *Crawler) cleanupOldTempFiles() {
 	if c.tempFileDir == "" {
 		return
@@ -641,8 +916,7 @@ func (c *Crawler) cleanupOldTempFiles() {
 			continue
 		}
 
-		// 清理超过30秒的临时文件（正常情况下文件应该立即删除，这里是备用清理）
-		if now.Sub(info.ModTime()) > 30*time.Second {
+		if now.Sub(info.ModTime()) > tempFileTTL {
 			filePath := filepath.Join(c.tempFileDir, entry.Name())
 			if err := os.Remove(filePath); err == nil {
 				cleanedCount++
@@ -708,9 +982,10 @@ func (c *Crawler) monitorGRPCSpeed() {
 		select {
 		case <-ticker.C:
 			now := time.Now()
-			currentGRPCRequests := atomic.LoadInt64(&c.stats.GRPCRequests)
-			currentGRPCRequestBytes := atomic.LoadInt64(&c.stats.GRPCRequestBytes)
-			currentGRPCResponseBytes := atomic.LoadInt64(&c.stats.GRPCResponseBytes)
+			grpcStats := c.statsFor(transportGRPC)
+			currentGRPCRequests := atomic.LoadInt64(&grpcStats.Requests)
+			currentGRPCRequestBytes := atomic.LoadInt64(&grpcStats.RequestBytes)
+			currentGRPCResponseBytes := atomic.LoadInt64(&grpcStats.ResponseBytes)
 
 			if lastTime.IsZero() {
 				// 第一次，只记录当前值
@@ -793,43 +1068,103 @@ func (c *Crawler) printStats() {
 	log.Printf("[统计] 运行时长=%v, 请求总数=%d (成功=%d, 失败=%d), 平均请求耗时=%v, 累计字节=%d",
 		elapsed, total, success, failed, avgReqDuration, bytes)
 
+	if mismatches := atomic.LoadInt64(&stats.FingerprintMismatches); mismatches > 0 {
+		log.Printf("[统计] 实际指纹与默认指纹不同的请求数=%d（连接池按连接随机/粘滞选择指纹的正常现象）", mismatches)
+	}
+
+	if statusChecks := atomic.LoadInt64(&stats.StatusCheckRequests); statusChecks > 0 {
+		log.Printf("[统计] 元数据探测请求=%d (成功=%d, 失败=%d)，独立于业务请求统计",
+			statusChecks, atomic.LoadInt64(&stats.StatusCheckSuccess), atomic.LoadInt64(&stats.StatusCheckFailed))
+	}
+
+	if authRejected := atomic.LoadInt64(&stats.AuthRejected); authRejected > 0 {
+		log.Printf("[统计] 鉴权失败被拒绝的请求数=%d", authRejected)
+	}
+	if quotaRejected := atomic.LoadInt64(&stats.QuotaRejected); quotaRejected > 0 {
+		log.Printf("[统计] 超过客户端配额被拒绝的任务数=%d", quotaRejected)
+	}
+	if quicConnRejected := atomic.LoadInt64(&stats.QUICConnRejected); quicConnRejected > 0 {
+		log.Printf("[统计] 超过QUICMaxConnections被拒绝的QUIC连接数=%d", quicConnRejected)
+	}
+	if rateLimited := atomic.LoadInt64(&stats.RateLimited); rateLimited > 0 {
+		log.Printf("[统计] 远端返回429的次数=%d，按IP分布=%v，按path分布=%v",
+			rateLimited, c.rateLimitTracker.SnapshotByIP(), c.rateLimitTracker.SnapshotByPath())
+	}
+
+	if c.poolManager != nil {
+		if warmupAttempts, warmupSuccess, warmupFailed := c.poolManager.WarmupStats(); warmupAttempts > 0 {
+			log.Printf("[统计] 连接池预热请求=%d (成功=%d, 失败=%d)，独立于业务请求统计",
+				warmupAttempts, warmupSuccess, warmupFailed)
+		}
+
+		for protocol, traffic := range c.poolManager.TrafficByProtocol() {
+			log.Printf("[统计] 套接字级流量[%s]: 读=%d字节, 写=%d字节（含TLS握手和帧层开销，按量计费口径）",
+				protocol, traffic.BytesRead, traffic.BytesWritten)
+		}
+
+		for fingerprint, hs := range c.poolManager.HandshakeStatsByFingerprint() {
+			if hs.Attempts == 0 {
+				continue
+			}
+			avgHandshake := time.Duration(0)
+			if completed := hs.FullHandshakes + hs.ResumedHandshakes; completed > 0 {
+				avgHandshake = hs.TotalDuration / time.Duration(completed)
+			}
+			log.Printf("[统计] 握手[指纹=%s]: 尝试=%d, 失败=%d, 完整=%d, 会话恢复=%d, 平均握手耗时=%v, ALPN分布=%v, 密码套件分布=%v",
+				fingerprint, hs.Attempts, hs.Failures, hs.FullHandshakes, hs.ResumedHandshakes, avgHandshake, hs.ALPNCounts, hs.CipherCounts)
+		}
+	}
+
+	if c.pacer != nil {
+		actualRPS := c.pacer.ActualRequestsPerSecond()
+		if targetRPS := c.config.ThroughputConfig.TargetRequestsPerSecond; targetRPS > 0 {
+			log.Printf("[吞吐量] 目标=%.2f req/s, 实际=%.2f req/s (%.1f%%)",
+				targetRPS, actualRPS, actualRPS/targetRPS*100)
+		}
+		if targetGBPerHour := c.config.ThroughputConfig.TargetGBPerHour; targetGBPerHour > 0 {
+			actualGBPerHour := float64(bytes) / (1024 * 1024 * 1024) / elapsed.Hours()
+			log.Printf("[吞吐量] 目标=%.3f GB/h, 实际=%.3f GB/h (%.1f%%)",
+				targetGBPerHour, actualGBPerHour, actualGBPerHour/targetGBPerHour*100)
+		}
+	}
+
 	if started > 0 {
 		log.Printf("[统计] 任务派发=%d, 已完成=%d, 平均任务耗时=%v, 未完成=%d",
 			started, completed, avgTaskDuration, started-completed)
 	}
 
-	// gRPC请求统计
-	grpcTotal := atomic.LoadInt64(&stats.GRPCRequests)
-	grpcSuccess := atomic.LoadInt64(&stats.GRPCSuccess)
-	grpcFailed := atomic.LoadInt64(&stats.GRPCFailed)
-	grpcReqBytes := atomic.LoadInt64(&stats.GRPCRequestBytes)
-	grpcRespBytes := atomic.LoadInt64(&stats.GRPCResponseBytes)
-	grpcTotalMicros := atomic.LoadInt64(&stats.GRPCDuration)
-
-	if grpcTotal > 0 {
-		avgGRPCDuration := time.Duration(0)
-		if grpcTotal > 0 {
-			avgGRPCDuration = time.Duration(grpcTotalMicros/grpcTotal) * time.Microsecond
+	// 按transportKind逐个打印请求/成功/失败/流量/耗时，取代过去分别手写的gRPC/QUIC两段
+	// 几乎一样的代码——新增一种传输只需要在newCrawlerStats里补一个map条目，这里自动跟着打印
+	for _, kind := range []transportKind{transportGRPC, transportQUIC} {
+		ts := c.statsFor(kind)
+		total := atomic.LoadInt64(&ts.Requests)
+		if total == 0 {
+			continue
+		}
+		success := atomic.LoadInt64(&ts.Success)
+		failed := atomic.LoadInt64(&ts.Failed)
+		reqBytes := atomic.LoadInt64(&ts.RequestBytes)
+		respBytes := atomic.LoadInt64(&ts.ResponseBytes)
+		totalMicros := atomic.LoadInt64(&ts.DurationMicros)
+		avgDuration := time.Duration(totalMicros/total) * time.Microsecond
+
+		sessions := atomic.LoadInt64(&ts.Sessions)
+		activeStreams := atomic.LoadInt64(&ts.ActiveStreams)
+		if sessions > 0 || activeStreams > 0 {
+			log.Printf("[统计] %s请求总数=%d (成功=%d, 失败=%d), 平均耗时=%v, 请求流量=%d字节, 响应流量=%d字节, 总流量=%d字节, 会话=%d, 活跃流=%d",
+				kind.label(), total, success, failed, avgDuration, reqBytes, respBytes, reqBytes+respBytes, sessions, activeStreams)
+		} else {
+			log.Printf("[统计] %s请求总数=%d (成功=%d, 失败=%d), 平均耗时=%v, 请求流量=%d字节, 响应流量=%d字节, 总流量=%d字节",
+				kind.label(), total, success, failed, avgDuration, reqBytes, respBytes, reqBytes+respBytes)
 		}
-		log.Printf("[统计] gRPC请求总数=%d (成功=%d, 失败=%d), 平均耗时=%v, 请求流量=%d字节, 响应流量=%d字节, 总流量=%d字节",
-			grpcTotal, grpcSuccess, grpcFailed, avgGRPCDuration, grpcReqBytes, grpcRespBytes, grpcReqBytes+grpcRespBytes)
 	}
 
-	quicTotal := atomic.LoadInt64(&stats.QUICRequests)
-	if quicTotal > 0 {
-		quicSuccess := atomic.LoadInt64(&stats.QUICSuccess)
-		quicFailed := atomic.LoadInt64(&stats.QUICFailed)
-		quicReqBytes := atomic.LoadInt64(&stats.QUICRequestBytes)
-		quicRespBytes := atomic.LoadInt64(&stats.QUICResponseBytes)
-		quicTotalMicros := atomic.LoadInt64(&stats.QUICDuration)
-		quicSessions := atomic.LoadInt64(&stats.QUICSessions)
-		quicStreams := atomic.LoadInt64(&stats.QUICStreams)
-		avgQUICDuration := time.Duration(0)
-		if quicTotal > 0 {
-			avgQUICDuration = time.Duration(quicTotalMicros/quicTotal) * time.Microsecond
-		}
-		log.Printf("[统计] QUIC请求总数=%d (成功=%d, 失败=%d), 平均耗时=%v, 请求流量=%d字节, 响应流量=%d字节, 总流量=%d字节, 会话=%d, 流=%d",
-			quicTotal, quicSuccess, quicFailed, avgQUICDuration, quicReqBytes, quicRespBytes, quicReqBytes+quicRespBytes, quicSessions, quicStreams)
+	if c.grpcConcurrency != nil {
+		log.Printf("[自适应并发] gRPC调度器当前上限=%d（AIMD按成功率/403自动收放）", c.grpcConcurrency.Limit())
+	}
+
+	if globalChecked, perIPChecked := c.rateLimiter.Stats(); globalChecked > 0 || perIPChecked > 0 {
+		log.Printf("[限速] 经过全局令牌桶检查的请求数=%d, 经过按IP令牌桶检查的请求数=%d", globalChecked, perIPChecked)
 	}
 }
 
@@ -845,6 +1180,21 @@ func (c *Crawler) Stop() {
 		c.grpcServer = nil
 	}
 
+	if c.healthServer != nil {
+		_ = c.healthServer.Close()
+		c.healthServer = nil
+	}
+
+	if c.coordinatorServer != nil {
+		c.coordinatorServer.GracefulStop()
+		c.coordinatorServer = nil
+	}
+
+	if c.ipsyncServer != nil {
+		c.ipsyncServer.GracefulStop()
+		c.ipsyncServer = nil
+	}
+
 	if c.quicListener != nil {
 		_ = c.quicListener.Close()
 		c.quicListener = nil
@@ -857,9 +1207,19 @@ func (c *Crawler) Stop() {
 		c.grpcListener = nil
 	}
 
-	c.pool.Close()
+	closeCtx, cancelClose := context.WithTimeout(context.Background(), 10*time.Second)
+	if err := c.poolManager.CloseGracefully(closeCtx); err != nil {
+		log.Printf("关闭连接池失败: %v", err)
+	}
+	cancelClose()
 	c.domainMonitor.Stop()
 
+	if c.client.CookieJar != nil {
+		if err := c.client.CookieJar.Save(); err != nil {
+			log.Printf("保存Cookie jar失败: %v", err)
+		}
+	}
+
 	// 清理所有临时文件
 	c.cleanupAllTempFiles()
 
@@ -868,14 +1228,24 @@ func (c *Crawler) Stop() {
 }
 
 func main() {
-	cfg, err := config.LoadConfig("./config/config.toml")
+	configPath := flag.String("config", "./config/config.toml", "配置文件路径")
+	var overrides config.KeyValueFlags
+	flag.Var(&overrides, "set", "覆盖单个配置项，格式为Section.Field=value，可重复指定；"+
+		"生效优先级为 配置文件 < UTLSPROXY_前缀环境变量 < -set标志，详见config/env_override.go")
+	flag.Parse()
+
+	cfg, err := config.LoadConfig(*configPath)
 	if err != nil {
 		log.Fatalf("加载配置失败: %v", err)
 	}
+	if err := config.ApplyOverrides(cfg, overrides); err != nil {
+		log.Fatalf("应用配置覆盖失败: %v", err)
+	}
 	crawler, err := NewCrawler(cfg)
 	if err != nil {
 		log.Fatalf("创建爬虫失败: %v", err)
 	}
+	crawler.configPath = *configPath
 	defer crawler.Stop()
 
 	if err := crawler.Start(); err != nil {
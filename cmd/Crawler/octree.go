@@ -0,0 +1,426 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"utlsProxy/src"
+)
+
+// octree.go 是crawlBulkMetadataBatch背后真正的八叉树遍历实现：解析BulkMetadata响应，
+// 按解析出的子节点调度后续的BulkMetadata（子树边界节点）、NodeData、Imagery请求，
+// 用一个去重集合保证同一个八叉树路径不会被重复调度，取代此前固定请求500次同一个path的占位实现。
+//
+// Google未正式公开RockTree的protobuf schema，下面的bulkField*/nodeField*字段编号取自公开的
+// 逆向工程资料，不保证与kh.google.com当前返回的版本完全吻合。parseBulkMetadata对识别不出的
+// 字段直接跳过而不是中断整个批次，如果实测发现解析不出任何子节点，应抓包核对这里的编号。
+
+const (
+	// octreeLevelsPerBulkPacket是单份BulkMetadata响应覆盖的八叉树层数：服务端一次性把
+	// 请求路径往下这么多层的全部节点元数据都打包返回，只有到达这个边界的节点才需要
+	// 再发一次BulkMetadata请求去取更深层的元数据。
+	octreeLevelsPerBulkPacket = 4
+
+	// maxOctreeFetchAttempts是单个八叉树节点请求失败时的最大重试次数
+	maxOctreeFetchAttempts = 5
+
+	bulkFieldNodeMetadata protowire.Number = 2 // BulkMetadataResponse.node_metadata
+
+	nodeFieldPath         protowire.Number = 1  // NodeMetadata.path：相对于请求路径的子路径，每字节取值0-7
+	nodeFieldEpoch        protowire.Number = 9  // NodeMetadata.epoch
+	nodeFieldImageryEpoch protowire.Number = 10 // NodeMetadata.imagery_epoch，<=0表示该节点没有影像数据
+)
+
+// octreeNode是从一份BulkMetadata响应中解析出的单个节点，Path是相对于发起请求的path拼接出的完整路径
+type octreeNode struct {
+	Path         string
+	Epoch        int32
+	ImageryEpoch int32
+}
+
+func (n *octreeNode) hasImagery() bool { return n.ImageryEpoch > 0 }
+
+// decodeOctreeFields依次消费data中的每个字段（tag+value），交给set处理，
+// 与internal/taskapi/codec_proto.go的decodeFields是同一种手写protobuf解析惯用法的独立实现——
+// 两边各自服务于不同的消息集合，没有共用的必要。
+func decodeOctreeFields(data []byte, set func(num protowire.Number, typ protowire.Type, rest []byte) (n int, err error)) error {
+	for len(data) > 0 {
+		num, typ, tagLen := protowire.ConsumeTag(data)
+		if tagLen < 0 {
+			return protowire.ParseError(tagLen)
+		}
+		rest := data[tagLen:]
+		n, err := set(num, typ, rest)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			n = protowire.ConsumeFieldValue(num, typ, rest)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+		}
+		data = rest[n:]
+	}
+	return nil
+}
+
+// parseBulkMetadata解析一份BulkMetadataResponse，basePath是发起这次请求时使用的八叉树路径，
+// 返回的每个octreeNode.Path都已经是basePath拼接相对路径后的完整路径。
+func parseBulkMetadata(data []byte, basePath string) ([]*octreeNode, error) {
+	var nodes []*octreeNode
+	err := decodeOctreeFields(data, func(num protowire.Number, typ protowire.Type, rest []byte) (int, error) {
+		if num != bulkFieldNodeMetadata || typ != protowire.BytesType {
+			return 0, nil
+		}
+		msg, n := protowire.ConsumeBytes(rest)
+		if n < 0 {
+			return 0, protowire.ParseError(n)
+		}
+		node, err := parseNodeMetadata(msg, basePath)
+		if err != nil {
+			log.Printf("[octree] 警告: 解析NodeMetadata失败，已跳过该节点: %v", err)
+			return n, nil
+		}
+		nodes = append(nodes, node)
+		return n, nil
+	})
+	return nodes, err
+}
+
+func parseNodeMetadata(data []byte, basePath string) (*octreeNode, error) {
+	node := &octreeNode{Path: basePath}
+	err := decodeOctreeFields(data, func(num protowire.Number, typ protowire.Type, rest []byte) (int, error) {
+		switch num {
+		case nodeFieldPath:
+			v, n := protowire.ConsumeBytes(rest)
+			if n < 0 {
+				return 0, protowire.ParseError(n)
+			}
+			node.Path = basePath + octreePathToString(v)
+			return n, nil
+		case nodeFieldEpoch:
+			v, n := protowire.ConsumeVarint(rest)
+			if n < 0 {
+				return 0, protowire.ParseError(n)
+			}
+			node.Epoch = int32(v)
+			return n, nil
+		case nodeFieldImageryEpoch:
+			v, n := protowire.ConsumeVarint(rest)
+			if n < 0 {
+				return 0, protowire.ParseError(n)
+			}
+			node.ImageryEpoch = int32(v)
+			return n, nil
+		}
+		return 0, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// octreePathToString把NodeMetadata.path这种每字节取值0-7的相对路径转换成BulkMetadataPath/NodeDataPath
+// 等请求模板里!1s参数使用的ASCII数字字符串
+func octreePathToString(raw []byte) string {
+	b := make([]byte, len(raw))
+	for i, d := range raw {
+		b[i] = '0' + (d & 0x7)
+	}
+	return string(b)
+}
+
+// octreeCrawler持有一次crawlBulkMetadataBatch遍历过程中的去重状态和统计计数，
+// seen以路径为key记录已经调度过的BulkMetadata/NodeData/Imagery请求，避免八叉树里
+// 通过不同父节点到达同一路径时被重复请求。
+type octreeCrawler struct {
+	c           *Crawler
+	maxDepth    int
+	allowedIPs  func() []string
+	seen        sync.Map
+	dedup       *pathDedupIndex
+	nextTaskSeq int64
+
+	// fetchLimiter/fetchAIMD让实际并发的fetch数量独立于worker goroutine数量（后者固定等于
+	// 白名单IP数），按成功率/403信号自适应收放，取代过去"worker数量=并发上限"的固定假设。
+	fetchLimiter *taskScheduler
+	fetchAIMD    *aimdController
+
+	// storageKeyPrefix非空时，scheduleNodeData额外把fetch到的NodeData/Imagery原始字节
+	// 通过Crawler.saveData落盘（键为prefix拼接节点路径），供CrawlJobConfig.StorageTarget使用；
+	// 为空（默认，含crawlBulkMetadataBatch的常规路径）时完全不持久化，行为与此前一致。
+	storageKeyPrefix string
+
+	bulkFetched   int64
+	nodesFetched  int64
+	imageFetched  int64
+	fetchFailures int64
+	dedupSkipped  int64
+}
+
+func (c *Crawler) crawlBulkMetadataBatch(metadata *PlanetoidMetadata, semaphore chan struct{}) {
+	log.Println("开始遍历RockTree八叉树（BulkMetadata -> NodeData/Imagery）...")
+
+	allowedIPs := c.ipAccessControl.GetAllowedIPs()
+	if len(allowedIPs) == 0 {
+		log.Println("警告: 白名单为空，无法执行爬取任务")
+		return
+	}
+
+	maxDepth := c.config.RockTreeDataConfig.MaxTraversalDepth
+	if maxDepth <= 0 {
+		maxDepth = octreeLevelsPerBulkPacket
+	}
+
+	dedupIndexPath := c.config.RockTreeDataConfig.DedupIndexPath
+	if dedupIndexPath == "" {
+		dedupIndexPath = filepath.Join(c.dataDir, "dedup_index.log")
+	}
+	dedup, err := newPathDedupIndex(c.config.RockTreeDataConfig.DedupEnabled, dedupIndexPath)
+	if err != nil {
+		log.Printf("警告: 初始化去重索引失败，本轮遍历将不做持久化去重: %v", err)
+		dedup, _ = newPathDedupIndex(false, "")
+	}
+	defer dedup.Close()
+
+	fetchLimiter := newTaskScheduler(len(allowedIPs))
+	fetchAIMD := newAIMDController(len(allowedIPs), 1, len(allowedIPs)*4, 1, 0.5, fetchLimiter.SetCapacity)
+
+	oc := &octreeCrawler{
+		c:            c,
+		maxDepth:     maxDepth,
+		allowedIPs:   c.ipAccessControl.GetAllowedIPs,
+		dedup:        dedup,
+		fetchLimiter: fetchLimiter,
+		fetchAIMD:    fetchAIMD,
+	}
+
+	log.Printf("八叉树遍历配置: 最大深度=%d, 白名单IP数量=%d, Worker数量=%d, 持久化去重=%v", maxDepth, len(allowedIPs), len(allowedIPs), c.config.RockTreeDataConfig.DedupEnabled)
+
+	oc.run("")
+
+	checked, hits := oc.dedup.Stats()
+	var hitRate float64
+	if checked > 0 {
+		hitRate = float64(hits) / float64(checked) * 100
+	}
+	log.Printf("八叉树遍历完成: BulkMetadata=%d, NodeData=%d, Imagery=%d, 失败=%d, 去重跳过=%d（命中率 %.1f%%）, 自适应并发上限=%d",
+		atomic.LoadInt64(&oc.bulkFetched), atomic.LoadInt64(&oc.nodesFetched),
+		atomic.LoadInt64(&oc.imageFetched), atomic.LoadInt64(&oc.fetchFailures),
+		atomic.LoadInt64(&oc.dedupSkipped), hitRate, oc.fetchAIMD.Limit())
+	c.printStats()
+}
+
+// run以seedPath为起点做广度优先遍历（crawlBulkMetadataBatch的常规路径传入""，即从根节点开始；
+// jobScheduler按CrawlJobConfig.PathTemplate发起的定时任务可以传入子路径，只遍历该子树）：
+// pending统计尚未处理完的队列项（当前项本身加上它可能还会派生出的子项），归零时关闭queue结束
+// 所有worker，workerCount与白名单IP数量对齐，沿用executeBulkTask时代"每个白名单IP配一个worker"的调度方式。
+func (oc *octreeCrawler) run(seedPath string) {
+	allowedIPs := oc.allowedIPs()
+	workerCount := len(allowedIPs)
+	if workerCount == 0 {
+		return
+	}
+
+	queue := make(chan string, 8192)
+	var pending sync.WaitGroup
+
+	enqueue := func(path string) {
+		if _, loaded := oc.seen.LoadOrStore(path, struct{}{}); loaded {
+			return
+		}
+		pending.Add(1)
+		select {
+		case queue <- path:
+		default:
+			go func() { queue <- path }()
+		}
+	}
+
+	enqueue(seedPath)
+
+	var workers sync.WaitGroup
+	for i := 0; i < workerCount; i++ {
+		workers.Add(1)
+		go func(workerID int) {
+			defer workers.Done()
+			for path := range queue {
+				if atomic.LoadInt32(&oc.c.stopped) == 1 {
+					pending.Done()
+					continue
+				}
+				oc.processBulkMetadata(workerID, path, enqueue)
+				pending.Done()
+			}
+		}(i)
+	}
+
+	go func() {
+		pending.Wait()
+		close(queue)
+	}()
+
+	workers.Wait()
+}
+
+// processBulkMetadata为path取一次BulkMetadata，为解析出的每个子节点调度NodeData/Imagery请求，
+// 到达octreeLevelsPerBulkPacket层边界且未超过maxDepth的子节点再通过enqueue投递下一份BulkMetadata请求。
+func (oc *octreeCrawler) processBulkMetadata(workerID int, path string, enqueue func(string)) {
+	c := oc.c
+	dedupKey := "bulk:" + path
+	if oc.dedup.Seen(dedupKey) {
+		atomic.AddInt64(&oc.dedupSkipped, 1)
+		return
+	}
+
+	bulkPath := fmt.Sprintf(c.config.RockTreeDataConfig.BulkMetadataPath, path, 1)
+
+	resp, ok := oc.fetch(workerID, bulkPath)
+	if !ok {
+		atomic.AddInt64(&oc.fetchFailures, 1)
+		return
+	}
+	atomic.AddInt64(&oc.bulkFetched, 1)
+	oc.dedup.Mark(dedupKey)
+
+	nodes, err := parseBulkMetadata(resp.Body, path)
+	if err != nil {
+		log.Printf("[Worker %d] 解析BulkMetadata失败（path=%q）: %v", workerID, path, err)
+		return
+	}
+
+	for _, node := range nodes {
+		if len(node.Path)-len(path) > octreeLevelsPerBulkPacket {
+			continue // 超出单份BulkMetadata应当覆盖的层数，说明解析跑偏了，丢弃这条防止路径爆炸
+		}
+
+		oc.scheduleNodeData(workerID, node)
+
+		atBoundary := len(node.Path) > 0 && len(node.Path)%octreeLevelsPerBulkPacket == 0
+		if atBoundary && len(node.Path) < oc.maxDepth {
+			enqueue(node.Path)
+		}
+	}
+}
+
+// scheduleNodeData为单个节点取NodeData，存在影像数据时再额外取一次Imagery，
+// 两者各自独立失败不影响对方，也不影响兄弟节点的调度。
+func (oc *octreeCrawler) scheduleNodeData(workerID int, node *octreeNode) {
+	c := oc.c
+	nodeDedupKey := fmt.Sprintf("node:%s:%d", node.Path, node.Epoch)
+	if oc.dedup.Seen(nodeDedupKey) {
+		atomic.AddInt64(&oc.dedupSkipped, 1)
+	} else {
+		nodePath := fmt.Sprintf(c.config.RockTreeDataConfig.NodeDataPath, node.Path, node.Epoch)
+		if resp, ok := oc.fetch(workerID, nodePath); ok {
+			atomic.AddInt64(&oc.nodesFetched, 1)
+			oc.dedup.Mark(nodeDedupKey)
+			oc.persistFetched(fmt.Sprintf("node/%s/%d", node.Path, node.Epoch), resp.Body)
+		} else {
+			atomic.AddInt64(&oc.fetchFailures, 1)
+		}
+	}
+
+	if !node.hasImagery() {
+		return
+	}
+
+	imageryDedupKey := fmt.Sprintf("img:%s:%d:%d", node.Path, node.Epoch, node.ImageryEpoch)
+	if oc.dedup.Seen(imageryDedupKey) {
+		atomic.AddInt64(&oc.dedupSkipped, 1)
+		return
+	}
+	imageryPath := fmt.Sprintf(c.config.RockTreeDataConfig.ImageryDataPath, node.Path, node.Epoch, node.ImageryEpoch)
+	if resp, ok := oc.fetch(workerID, imageryPath); ok {
+		atomic.AddInt64(&oc.imageFetched, 1)
+		oc.dedup.Mark(imageryDedupKey)
+		oc.persistFetched(fmt.Sprintf("img/%s/%d/%d", node.Path, node.Epoch, node.ImageryEpoch), resp.Body)
+	} else {
+		atomic.AddInt64(&oc.fetchFailures, 1)
+	}
+}
+
+// persistFetched在storageKeyPrefix非空时把data落盘到storageKeyPrefix/key，复用Crawler.saveData
+// 既有的压缩/存储后端逻辑；storageKeyPrefix为空（默认）时是no-op，不影响任何现有调用方。
+func (oc *octreeCrawler) persistFetched(key string, data []byte) {
+	if oc.storageKeyPrefix == "" {
+		return
+	}
+	fullKey := filepath.Join(oc.storageKeyPrefix, key)
+	if err := oc.c.saveData(fullKey, data); err != nil {
+		log.Printf("[octree] 持久化 %s 失败: %v", fullKey, err)
+	}
+}
+
+// fetch按pathSuffix发起请求，失败时按指数退避重试至多maxOctreeFetchAttempts次，
+// 与原executeBulkTask的重试策略一致，只是path不再是写死的常量。
+func (oc *octreeCrawler) fetch(workerID int, pathSuffix string) (*src.UTlsResponse, bool) {
+	c := oc.c
+	taskSeq := int(atomic.AddInt64(&oc.nextTaskSeq, 1))
+
+	taskStart := time.Now()
+	c.recordTaskStart()
+	defer func() {
+		c.recordTaskCompletion(time.Since(taskStart))
+	}()
+
+	// 实际发起请求前先排进fetchLimiter，把并发请求数限制在fetchAIMD当前探测到的上限内，
+	// 与worker goroutine数量（=白名单IP数）解耦。
+	release, acquireErr := oc.fetchLimiter.Acquire(context.Background(), "octree", 0)
+	if acquireErr != nil {
+		return nil, false
+	}
+	defer release()
+
+	for attempt := 1; attempt <= maxOctreeFetchAttempts; attempt++ {
+		if atomic.LoadInt32(&c.stopped) == 1 {
+			return nil, false
+		}
+
+		allowedIPs := oc.allowedIPs()
+		if len(allowedIPs) == 0 {
+			log.Printf("[Worker %d] 路径 %s 尝试 %d 次时白名单为空，等待可用IP...", workerID, pathSuffix, attempt)
+			time.Sleep(c.backoffDuration(attempt))
+			continue
+		}
+
+		targetIP := allowedIPs[(taskSeq+attempt-1)%len(allowedIPs)]
+		workID := fmt.Sprintf("octree-%d-%d-%d", workerID, taskSeq, attempt)
+
+		resp, _, err, duration := c.performRequestAttempt(workerID, taskSeq, attempt, targetIP, pathSuffix, workID, maxTaskDuration, taskRequestOptions{})
+		if err != nil {
+			log.Printf("[Worker %d] 路径 %s 请求失败（第 %d 次，目标IP: %s，耗时: %v）: %v", workerID, pathSuffix, attempt, targetIP, duration, err)
+			oc.fetchAIMD.OnFailure()
+			time.Sleep(c.backoffDuration(attempt))
+			continue
+		}
+		if duration > maxTaskDuration {
+			log.Printf("[Worker %d] 路径 %s 超时（第 %d 次，目标IP: %s，耗时: %v）", workerID, pathSuffix, attempt, targetIP, duration)
+			oc.fetchAIMD.OnFailure()
+			time.Sleep(c.backoffDuration(attempt))
+			continue
+		}
+		if resp.StatusCode != 200 {
+			log.Printf("[Worker %d] 路径 %s 返回状态码 %d（第 %d 次，目标IP: %s，耗时: %v）", workerID, pathSuffix, resp.StatusCode, attempt, targetIP, duration)
+			if resp.StatusCode == 403 {
+				oc.fetchAIMD.OnFailure()
+			}
+			time.Sleep(c.backoffDuration(attempt))
+			continue
+		}
+		oc.fetchAIMD.OnSuccess()
+		return resp, true
+	}
+
+	log.Printf("[Worker %d] 路径 %s 在 %d 次尝试后放弃", workerID, pathSuffix, maxOctreeFetchAttempts)
+	return nil, false
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"os"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// buildACMETLSConfig用golang.org/x/crypto/acme/autocert为serverCfg.ACMEHostname自动申请/续期
+// 一张受信任CA（默认Let's Encrypt）签发的证书，换掉原来每次启动都生成一份自签名证书的做法——
+// TaskClient端因此可以用系统自带的根证书池正常校验服务端身份，不再需要单独分发QUICCAFile
+// 给每个客户端。golang.org/x/crypto已经是go.mod里quic-go/grpc间接引入的依赖，这里只是
+// 新增对其acme/autocert子包的直接引用，没有引入新的第三方模块，符合本仓库不随意添加
+// 新依赖的约定。
+//
+// 已知限制：autocert默认的TLS-ALPN-01质询要求质询方通过TCP连到本机443端口完成一次TLS
+// 握手，而本项目的QUIC服务端只监听UDP，没有配套的TCP监听器，因此线上环境要让证书申请
+// 真正跑通，还需要额外跑一个HTTP-01质询用的80端口HTTP服务（可以用autocert.Manager的
+// HTTPHandler包出来），或者在443上叠加一个仅用于ALPN质询的TCP监听器；这里先把QUIC
+// 服务端这一侧的证书加载/自动续期接上，质询响应方式留给部署方按实际网络环境选择。
+func (c *Crawler) buildACMETLSConfig() (*tls.Config, error) {
+	serverCfg := c.config.ServerConfig
+	if serverCfg.ACMEHostname == "" {
+		return nil, fmt.Errorf("启用了ACMEEnabled但未配置 ACMEHostname")
+	}
+
+	cacheDir := serverCfg.ACMECacheDir
+	if cacheDir == "" {
+		cacheDir = "acme-cache"
+	}
+	if err := os.MkdirAll(cacheDir, 0700); err != nil {
+		return nil, fmt.Errorf("创建ACME证书缓存目录失败: %w", err)
+	}
+
+	manager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(serverCfg.ACMEHostname),
+		Cache:      autocert.DirCache(cacheDir),
+	}
+	if serverCfg.ACMEEmail != "" {
+		manager.Email = serverCfg.ACMEEmail
+	}
+
+	tlsConfig := manager.TLSConfig()
+	tlsConfig.NextProtos = append(tlsConfig.NextProtos, serverCfg.QUICALPN)
+	tlsConfig.MinVersion = tls.VersionTLS13
+
+	log.Printf("[QUIC] 已启用ACME证书自动申请/续期，hostname=%s，缓存目录=%s", serverCfg.ACMEHostname, cacheDir)
+	return tlsConfig, nil
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"bufio"
 	"context"
 	"crypto/ecdsa"
 	"crypto/elliptic"
@@ -9,12 +8,10 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
-	"encoding/binary"
 	"encoding/json"
 	"encoding/pem"
 	"errors"
 	"fmt"
-	"io"
 	"log"
 	"math/big"
 	"net"
@@ -24,11 +21,23 @@ import (
 
 	"github.com/quic-go/quic-go"
 
+	"utlsProxy/config"
+	"utlsProxy/internal/safego"
 	"utlsProxy/internal/taskapi"
 )
 
 const (
 	maxQUICRequestSize = 16 * 1024 * 1024 // 16MB
+
+	// maxQUICDatagramResponseSize 是数据报快速路径下响应序列化后允许的最大字节数，取值
+	// 比常见路径MTU（以太网1500字节减去IP/UDP/QUIC头部开销）略保守，留出安全余量；
+	// 超出该大小的响应直接丢弃数据报，客户端的stream回退路径会在等待超时后接管。
+	maxQUICDatagramResponseSize = 1200
+
+	// quicErrorCodeTooManyConnections是超过ServerConfig.QUICMaxConnections时，用来关闭多余连接的
+	// QUIC应用层错误码，通过CONNECTION_CLOSE帧显式告知客户端拒绝原因，而不是直接丢弃连接让它超时，
+	// 取值是utlsProxy自定义的应用错误码，不与QUIC传输层错误码空间冲突。
+	quicErrorCodeTooManyConnections quic.ApplicationErrorCode = 1
 )
 
 func (c *Crawler) startQUICServer() error {
@@ -49,9 +58,10 @@ func (c *Crawler) startQUICServer() error {
 
 	address := fmt.Sprintf(":%d", c.config.ServerConfig.QUICPort)
 	quicConfig := &quic.Config{
-		KeepAlivePeriod: 5 * time.Second,
-		MaxIdleTimeout:  c.config.ServerConfig.GetQUICMaxIdleTimeout(),
-		EnableDatagrams: false,
+		KeepAlivePeriod:    5 * time.Second,
+		MaxIdleTimeout:     c.config.ServerConfig.GetQUICMaxIdleTimeout(),
+		EnableDatagrams:    true,
+		MaxIncomingStreams: c.config.ServerConfig.GetQUICMaxStreamsPerConn(),
 	}
 
 	listener, err := quic.ListenAddr(address, tlsConfig, quicConfig)
@@ -69,42 +79,90 @@ func (c *Crawler) startQUICServer() error {
 }
 
 func (c *Crawler) buildQUICServerTLSConfig() (*tls.Config, error) {
-	var certificate tls.Certificate
-	var err error
-
 	serverCfg := c.config.ServerConfig
 
-	if serverCfg.QUICCertFile != "" && serverCfg.QUICKeyFile != "" {
-		certificate, err = tls.LoadX509KeyPair(serverCfg.QUICCertFile, serverCfg.QUICKeyFile)
+	var tlsConfig *tls.Config
+	var certFile, keyFile string
+	var leafCert tls.Certificate
+	if serverCfg.ACMEEnabled {
+		// ACME模式下证书由autocert.Manager自行申请、缓存和续期，没有对应的本地证书文件，
+		// certFile/keyFile留空传给下面的certReloader——它在这条路径上只负责ClientCAs的轮换
+		cfg, err := c.buildACMETLSConfig()
 		if err != nil {
-			return nil, fmt.Errorf("加载 QUIC TLS 证书失败: %w", err)
+			return nil, err
 		}
+		tlsConfig = cfg
 	} else {
-		certificate, err = generateSelfSignedCertificate()
+		cfg, cert, cf, kf, err := c.buildStaticQUICTLSConfig(serverCfg)
 		if err != nil {
-			return nil, fmt.Errorf("生成自签名证书失败: %w", err)
+			return nil, err
 		}
-		log.Printf("[QUIC] 未配置证书，已生成临时自签名证书供测试使用")
-	}
-
-	tlsConfig := &tls.Config{
-		Certificates: []tls.Certificate{certificate},
-		NextProtos:   []string{serverCfg.QUICALPN},
-		MinVersion:   tls.VersionTLS13,
+		tlsConfig, leafCert, certFile, keyFile = cfg, cert, cf, kf
 	}
 
+	var caPool *x509.CertPool
 	if serverCfg.QUICCAFile != "" {
-		certPool, err := loadCertPool(serverCfg.QUICCAFile)
+		var err error
+		caPool, err = loadCertPool(serverCfg.QUICCAFile)
 		if err != nil {
 			return nil, err
 		}
-		tlsConfig.ClientCAs = certPool
-		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	c.certReloader = newQUICCertReloader(certFile, keyFile, serverCfg.QUICCAFile, leafCert, caPool)
+	if !serverCfg.ACMEEnabled {
+		tlsConfig.GetCertificate = c.certReloader.GetCertificate
+	}
+
+	if serverCfg.QUICCAFile != "" {
+		if serverCfg.QUICRequireClientCert {
+			tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		} else {
+			tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		}
+		// GetConfigForClient按连接各自取一份当前ClientCAs快照，SIGHUP触发c.certReloader.Reload()
+		// 替换CA池后，新连接握手会立刻用上新池，不要求重建quic.Listener
+		tlsConfig.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cfg := tlsConfig.Clone()
+			cfg.ClientCAs = c.certReloader.ClientCAs()
+			cfg.GetConfigForClient = nil // 避免Clone出的配置递归引用自己
+			return cfg, nil
+		}
 	}
 
 	return tlsConfig, nil
 }
 
+// buildStaticQUICTLSConfig构建非ACME模式下的QUIC服务端TLS配置：优先加载配置文件指定的
+// 证书，否则生成一份仅进程生命周期内有效的自签名证书；返回值里的cert/certFile/keyFile
+// 供调用方组装证书热重载所需的quicCertReloader。
+func (c *Crawler) buildStaticQUICTLSConfig(serverCfg config.ServerConfig) (*tls.Config, tls.Certificate, string, string, error) {
+	var certificate tls.Certificate
+	var err error
+
+	certFile, keyFile := serverCfg.QUICCertFile, serverCfg.QUICKeyFile
+	if certFile != "" && keyFile != "" {
+		certificate, err = tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, tls.Certificate{}, "", "", fmt.Errorf("加载 QUIC TLS 证书失败: %w", err)
+		}
+	} else {
+		certificate, err = generateSelfSignedCertificate()
+		if err != nil {
+			return nil, tls.Certificate{}, "", "", fmt.Errorf("生成自签名证书失败: %w", err)
+		}
+		log.Printf("[QUIC] 未配置证书，已生成临时自签名证书供测试使用")
+		// 自签名证书没有对应的文件路径，certFile/keyFile留空传给reloader，
+		// 后续Reload()会直接跳过——自签名证书的生命周期和进程一致，不支持轮换
+		certFile, keyFile = "", ""
+	}
+
+	tlsConfig := &tls.Config{
+		NextProtos: []string{serverCfg.QUICALPN},
+		MinVersion: tls.VersionTLS13,
+	}
+	return tlsConfig, certificate, certFile, keyFile, nil
+}
+
 func generateSelfSignedCertificate() (tls.Certificate, error) {
 	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 	if err != nil {
@@ -171,7 +229,15 @@ func (c *Crawler) acceptQUICConnections(listener *quic.Listener) {
 			continue
 		}
 
-		atomic.AddInt64(&c.stats.QUICSessions, 1)
+		count := atomic.AddInt64(&c.quicConnCount, 1)
+		if max := int64(c.config.ServerConfig.QUICMaxConnections); max > 0 && count > max {
+			atomic.AddInt64(&c.quicConnCount, -1)
+			atomic.AddInt64(&c.stats.QUICConnRejected, 1)
+			_ = conn.CloseWithError(quicErrorCodeTooManyConnections, "too many concurrent connections")
+			continue
+		}
+
+		atomic.AddInt64(&c.statsFor(transportQUIC).Sessions, 1)
 		c.wg.Add(1)
 		go c.handleQUICConnection(conn)
 	}
@@ -179,8 +245,12 @@ func (c *Crawler) acceptQUICConnections(listener *quic.Listener) {
 
 func (c *Crawler) handleQUICConnection(conn *quic.Conn) {
 	defer c.wg.Done()
+	defer atomic.AddInt64(&c.quicConnCount, -1)
 	label := "[QUIC]"
 
+	c.wg.Add(1)
+	go c.handleQUICDatagrams(conn)
+
 	for {
 		stream, err := conn.AcceptStream(conn.Context())
 		if err != nil {
@@ -191,119 +261,84 @@ func (c *Crawler) handleQUICConnection(conn *quic.Conn) {
 			return
 		}
 
-		atomic.AddInt64(&c.stats.QUICStreams, 1)
 		c.wg.Add(1)
 		go c.handleQUICStream(conn, stream)
 	}
 }
 
+// handleQUICStream 把*quic.Stream适配成frameConn，一问一答的处理逻辑都委托给共享的
+// taskFrameHandler（见frame_transport.go），这里只负责QUIC特有的流/连接层面的事情。
 func (c *Crawler) handleQUICStream(conn *quic.Conn, stream *quic.Stream) {
 	defer c.wg.Done()
 	defer func() { _ = stream.Close() }()
 
-	label := "[QUIC]"
-	start := time.Now()
-	reader := bufio.NewReader(stream)
+	activeStreams := &c.statsFor(transportQUIC).ActiveStreams
+	atomic.AddInt64(activeStreams, 1)
+	defer atomic.AddInt64(activeStreams, -1)
 
-	var lengthBuf [4]byte
-	if _, err := io.ReadFull(reader, lengthBuf[:]); err != nil {
-		c.writeQUICError(stream, label, "读取请求长度失败", err, start, 0)
-		return
-	}
+	// 单个流的处理逻辑panic时只应该丢掉这一个流，不该带着整条QUIC连接乃至整个进程一起崩掉
+	safego.Protect("quic.handleStream", func() {
+		frame := newLengthPrefixedConn(stream, stream, conn.RemoteAddr().String(), maxQUICRequestSize)
+		newTaskFrameHandler(c, transportQUIC).Handle(conn.Context(), frame)
+	})
+}
 
-	payloadLen := binary.BigEndian.Uint32(lengthBuf[:])
-	if payloadLen == 0 {
-		c.writeQUICError(stream, label, "请求负载为空", nil, start, 0)
-		return
-	}
-	if payloadLen > maxQUICRequestSize {
-		c.writeQUICError(stream, label, fmt.Sprintf("请求体过大（%d 字节）", payloadLen), nil, start, int64(payloadLen))
-		return
-	}
+// handleQUICDatagrams 为小请求/小响应提供不可靠数据报快速路径：每条连接一个接收循环，
+// 收到的每个数据报都被当作一次独立的TaskRequest交给单独的goroutine处理，
+// 这样一个请求的执行耗时不会挡住同一连接上后续数据报的接收。
+func (c *Crawler) handleQUICDatagrams(conn *quic.Conn) {
+	defer c.wg.Done()
+	label := "[QUIC-datagram]"
 
-	payload := make([]byte, payloadLen)
-	if _, err := io.ReadFull(reader, payload); err != nil {
-		c.writeQUICError(stream, label, "读取请求体失败", err, start, int64(payloadLen))
-		return
+	for {
+		payload, err := conn.ReceiveDatagram(conn.Context())
+		if err != nil {
+			return
+		}
+		c.wg.Add(1)
+		go c.handleQUICDatagramPayload(conn, payload, label)
 	}
+}
+
+// handleQUICDatagramPayload 解码、执行一条数据报请求并把响应以数据报形式发回；
+// 响应太大放不进一个数据报时直接丢弃，不尝试降级成stream——由客户端自己的
+// stream回退路径在等待超时后接管，服务端这里不需要感知客户端的回退策略。
+func (c *Crawler) handleQUICDatagramPayload(conn *quic.Conn, payload []byte, label string) {
+	defer c.wg.Done()
 
 	var req taskapi.TaskRequest
 	if err := json.Unmarshal(payload, &req); err != nil {
-		c.writeQUICError(stream, label, "请求体解码失败", err, start, int64(payloadLen))
+		log.Printf("%s 请求解码失败: %v (remote=%s)", label, err, conn.RemoteAddr())
+		return
+	}
+	if req.CorrelationID == "" {
+		log.Printf("%s 数据报请求缺少CorrelationID，已丢弃 (remote=%s)", label, conn.RemoteAddr())
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(conn.Context(), maxTaskDuration)
 	defer cancel()
 
-	resp, err := c.executeTask(ctx, transportQUIC, &req, int64(payloadLen))
-	if err != nil && (resp == nil || resp.ErrorMessage == "") {
+	resp, err := c.executeTask(ctx, transportQUIC, &req, int64(len(payload)))
+	if err != nil {
 		if resp == nil {
 			resp = &taskapi.TaskResponse{ClientID: req.ClientID}
 		}
 		resp.ErrorMessage = err.Error()
 	}
+	resp.CorrelationID = req.CorrelationID
 
-	responsePayload, err := json.Marshal(resp)
+	respData, err := json.Marshal(resp)
 	if err != nil {
-		c.writeQUICError(stream, label, "响应编码失败", err, start, int64(payloadLen))
+		log.Printf("%s 响应编码失败: %v (remote=%s)", label, err, conn.RemoteAddr())
 		return
 	}
-
-	if err := c.writeQUICPayload(stream, responsePayload); err != nil {
-		log.Printf("%s 发送响应失败: %v", label, err)
+	if len(respData) > maxQUICDatagramResponseSize {
 		return
 	}
-}
-
-func (c *Crawler) writeQUICPayload(stream *quic.Stream, payload []byte) error {
-	writer := bufio.NewWriter(stream)
-	var header [4]byte
-	binary.BigEndian.PutUint32(header[:], uint32(len(payload)))
-	if _, err := writer.Write(header[:]); err != nil {
-		return err
-	}
-	if _, err := writer.Write(payload); err != nil {
-		return err
-	}
-	return writer.Flush()
-}
-
-func (c *Crawler) writeQUICError(stream *quic.Stream, label, message string, err error, start time.Time, requestBytes int64) {
-	metrics := c.metricsForTransport(transportQUIC)
-	fullMsg := message
-	if err != nil {
-		fullMsg = fmt.Sprintf("%s: %v", message, err)
-	}
-	log.Printf("%s %s", label, fullMsg)
-
-	if metrics.requests != nil {
-		atomic.AddInt64(metrics.requests, 1)
-	}
-	if metrics.failed != nil {
-		atomic.AddInt64(metrics.failed, 1)
-	}
-	if metrics.requestBytes != nil && requestBytes > 0 {
-		atomic.AddInt64(metrics.requestBytes, requestBytes)
-	}
-
-	resp := &taskapi.TaskResponse{
-		ErrorMessage: fullMsg,
-	}
-
-	responsePayload, marshalErr := json.Marshal(resp)
-	if marshalErr == nil {
-		if metrics.responseBytes != nil {
-			atomic.AddInt64(metrics.responseBytes, int64(len(responsePayload)))
-		}
-		if err := c.writeQUICPayload(stream, responsePayload); err != nil {
-			log.Printf("%s 发送错误响应失败: %v", label, err)
-		}
-	} else {
-		log.Printf("%s 编码错误响应失败: %v", label, marshalErr)
+	if err := conn.SendDatagram(respData); err != nil {
+		log.Printf("%s 发送数据报响应失败: %v (remote=%s)", label, err, conn.RemoteAddr())
 	}
-
-	c.addTransportDuration(metrics.duration, start)
 }
 
 func isListenerClosedErr(err error) bool {
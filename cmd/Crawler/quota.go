@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// clientQuota 记录单个ClientID当前占用的并发槽位数和令牌桶状态
+type clientQuota struct {
+	mu         sync.Mutex
+	inFlight   int
+	tokens     float64
+	lastRefill time.Time
+}
+
+// clientQuotaManager 按ClientID独立限制并发数和请求速率，避免单个客户端
+// （无论是恶意的还是失控的）占满整个白名单IP池的执行能力。
+// maxConcurrency、requestsPerSecond任一项为0表示对应维度不限制。
+type clientQuotaManager struct {
+	maxConcurrency    int
+	requestsPerSecond float64
+	mu                sync.Mutex
+	clients           map[string]*clientQuota
+}
+
+// newClientQuotaManager 根据TaskAPIConfig里的配额创建管理器
+func newClientQuotaManager(maxConcurrency int, requestsPerSecond float64) *clientQuotaManager {
+	return &clientQuotaManager{
+		maxConcurrency:    maxConcurrency,
+		requestsPerSecond: requestsPerSecond,
+		clients:           make(map[string]*clientQuota),
+	}
+}
+
+func (m *clientQuotaManager) quotaFor(clientID string) *clientQuota {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	q, ok := m.clients[clientID]
+	if !ok {
+		q = &clientQuota{tokens: m.requestsPerSecond, lastRefill: time.Now()}
+		m.clients[clientID] = q
+	}
+	return q
+}
+
+// Acquire 在允许clientID发起一次新任务时返回一个release函数（调用方必须在任务结束后调用），
+// 超过并发上限或速率上限时返回错误，两个维度均未配置时永远放行。clientID为空字符串时
+// 和其他任何取值一样当成一个独立的桶处理，不能通过不填ClientID绕过配额。
+func (m *clientQuotaManager) Acquire(clientID string) (func(), error) {
+	if m == nil || (m.maxConcurrency <= 0 && m.requestsPerSecond <= 0) {
+		return func() {}, nil
+	}
+
+	q := m.quotaFor(clientID)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if m.requestsPerSecond > 0 {
+		now := time.Now()
+		q.tokens += now.Sub(q.lastRefill).Seconds() * m.requestsPerSecond
+		if q.tokens > m.requestsPerSecond {
+			q.tokens = m.requestsPerSecond
+		}
+		q.lastRefill = now
+		if q.tokens < 1 {
+			return nil, fmt.Errorf("客户端 %s 超过速率配额 (%.1f 请求/秒)", clientID, m.requestsPerSecond)
+		}
+	}
+
+	if m.maxConcurrency > 0 && q.inFlight >= m.maxConcurrency {
+		return nil, fmt.Errorf("客户端 %s 超过并发配额 (%d)", clientID, m.maxConcurrency)
+	}
+
+	if m.requestsPerSecond > 0 {
+		q.tokens--
+	}
+	q.inFlight++
+
+	return func() {
+		q.mu.Lock()
+		q.inFlight--
+		q.mu.Unlock()
+	}, nil
+}
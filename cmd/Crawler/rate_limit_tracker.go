@@ -0,0 +1,109 @@
+package main
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// rateLimitBaseCooldown和rateLimitMaxCooldown界定单个IP收到429后的冷却时长范围：
+// 实际冷却时长在[base, base+jitter]间取随机值（jitter同样不超过base），并按命中429的次数
+// 指数放大，直到封顶maxCooldown，避免持续对同一个已经限流的IP发起请求。
+const (
+	rateLimitBaseCooldown = 2 * time.Second
+	rateLimitMaxCooldown  = 2 * time.Minute
+)
+
+// rateLimitTracker 记录每个IP因429被限流后的冷却截止时间，以及按IP、按path统计的429命中次数，
+// 用于handleTaskRequest在选择目标IP时跳过仍在冷却期的IP，并让调用方能观测限流的分布。
+type rateLimitTracker struct {
+	cooldownUntil sync.Map // ip -> time.Time
+	hitStreak     sync.Map // ip -> *int64，连续命中429的次数，用于指数退避，一次成功请求后清零
+	ipHits        sync.Map // ip -> *int64，累计429命中次数
+	pathHits      sync.Map // path -> *int64，累计429命中次数
+
+	randMu sync.Mutex
+	rand   *rand.Rand
+}
+
+func newRateLimitTracker() *rateLimitTracker {
+	return &rateLimitTracker{
+		rand: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// InCooldown 返回该IP当前是否仍处于429冷却期内
+func (t *rateLimitTracker) InCooldown(ip string) bool {
+	v, ok := t.cooldownUntil.Load(ip)
+	if !ok {
+		return false
+	}
+	return time.Now().Before(v.(time.Time))
+}
+
+// RecordTooManyRequests 记录一次该IP、该path的429命中，按连续命中次数指数放大冷却时长并叠加抖动，
+// 返回本次设置的冷却时长供调用方记录日志
+func (t *rateLimitTracker) RecordTooManyRequests(ip, path string) time.Duration {
+	incrCounter(&t.ipHits, ip)
+	incrCounter(&t.pathHits, path)
+	streak := incrCounter(&t.hitStreak, ip)
+
+	cooldown := rateLimitBaseCooldown << uint(streak-1)
+	if cooldown > rateLimitMaxCooldown || cooldown <= 0 {
+		cooldown = rateLimitMaxCooldown
+	}
+
+	t.randMu.Lock()
+	jitter := time.Duration(t.rand.Int63n(int64(cooldown) + 1))
+	t.randMu.Unlock()
+	cooldown += jitter
+
+	t.cooldownUntil.Store(ip, time.Now().Add(cooldown))
+	return cooldown
+}
+
+// RecordSuccess 清除该IP的连续429命中计数，使下一次429重新从基础冷却时长开始退避
+func (t *rateLimitTracker) RecordSuccess(ip string) {
+	t.hitStreak.Delete(ip)
+}
+
+// rateLimitRequeueBase和rateLimitRequeueJitter界定命中429后在本次调用内重新入队重试前的等待时长，
+// 这个等待独立于、且远小于IP的冷却期（后者影响的是未来请求对该IP的选取），
+// 目的只是避免在同一次请求处理内对刚刚429的目标IP（或新选中的IP）发起突发重试。
+const (
+	rateLimitRequeueBase   = 50 * time.Millisecond
+	rateLimitRequeueJitter = 150 * time.Millisecond
+)
+
+// RequeueDelay 返回命中429后、在同一次请求处理内重试前应等待的抖动延迟
+func (t *rateLimitTracker) RequeueDelay() time.Duration {
+	t.randMu.Lock()
+	jitter := time.Duration(t.rand.Int63n(int64(rateLimitRequeueJitter)))
+	t.randMu.Unlock()
+	return rateLimitRequeueBase + jitter
+}
+
+// SnapshotByIP 返回各IP累计429命中次数
+func (t *rateLimitTracker) SnapshotByIP() map[string]int64 {
+	return snapshotCounters(&t.ipHits)
+}
+
+// SnapshotByPath 返回各path累计429命中次数
+func (t *rateLimitTracker) SnapshotByPath() map[string]int64 {
+	return snapshotCounters(&t.pathHits)
+}
+
+func incrCounter(m *sync.Map, key string) int64 {
+	ptrIface, _ := m.LoadOrStore(key, new(int64))
+	return atomic.AddInt64(ptrIface.(*int64), 1)
+}
+
+func snapshotCounters(m *sync.Map) map[string]int64 {
+	result := make(map[string]int64)
+	m.Range(func(key, value any) bool {
+		result[key.(string)] = atomic.LoadInt64(value.(*int64))
+		return true
+	})
+	return result
+}
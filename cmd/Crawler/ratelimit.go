@@ -0,0 +1,142 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"utlsProxy/config"
+)
+
+// ratelimit.go 在performRequestAttempt发起实际请求前按令牌桶阻塞节流，和clientQuotaManager
+// 的令牌桶记账方式一致（tokens float64按流逝时间线性续满，借1个token就发1次请求），
+// 区别是这里命中限速时选择阻塞等到有令牌，而不是像clientQuotaManager那样直接拒绝——
+// 全局/按IP限速的目标是把长期平均速率压低，而不是保护某个瞬时并发上限，阻塞等待更合适。
+
+// tokenBucket是一个按固定速率续满、容量等于速率的令牌桶，rate<=0表示不限制（Wait立即返回）。
+type tokenBucket struct {
+	rate float64
+
+	mu         sync.Mutex
+	tokens     float64
+	lastRefill time.Time
+}
+
+func newTokenBucket(rate float64) *tokenBucket {
+	return &tokenBucket{rate: rate, tokens: rate, lastRefill: time.Now()}
+}
+
+// Wait阻塞直到从桶中取得一个令牌
+func (b *tokenBucket) Wait() {
+	if b == nil || b.rate <= 0 {
+		return
+	}
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		b.tokens += now.Sub(b.lastRefill).Seconds() * b.rate
+		if b.tokens > b.rate {
+			b.tokens = b.rate
+		}
+		b.lastRefill = now
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return
+		}
+		wait := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		b.mu.Unlock()
+		if wait <= 0 {
+			wait = time.Millisecond
+		}
+		time.Sleep(wait)
+	}
+}
+
+// requestRateLimiter持有全局令牌桶和按目标IP独立的令牌桶集合，Wait按两个维度依次节流，
+// 任一维度未配置速率时该维度不拦截。enabled为false时Wait完全不介入，行为与限速功能
+// 加入之前完全一致。enabled/global/perIPRate可能被Reconfigure并发改写（配置热重载），
+// 统一用perIPMu保护，不单独为三个字段各开一把锁。
+type requestRateLimiter struct {
+	perIPMu sync.Mutex
+	enabled bool
+	global  *tokenBucket
+
+	perIPRate float64
+	perIP     map[string]*tokenBucket
+
+	globalChecked int64
+	perIPChecked  int64
+}
+
+// newRequestRateLimiter按cfg构造限速器；cfg.Enabled为false时返回的限速器Wait是no-op。
+func newRequestRateLimiter(cfg config.RateLimitConfig) *requestRateLimiter {
+	rl := &requestRateLimiter{
+		enabled:   cfg.Enabled,
+		perIPRate: cfg.PerIPRequestsPerSecond,
+		perIP:     make(map[string]*tokenBucket),
+	}
+	if cfg.GlobalRequestsPerSecond > 0 {
+		rl.global = newTokenBucket(cfg.GlobalRequestsPerSecond)
+	}
+	return rl
+}
+
+func (rl *requestRateLimiter) bucketForIP(ip string) *tokenBucket {
+	rl.perIPMu.Lock()
+	defer rl.perIPMu.Unlock()
+	b, ok := rl.perIP[ip]
+	if !ok {
+		b = newTokenBucket(rl.perIPRate)
+		rl.perIP[ip] = b
+	}
+	return b
+}
+
+// Wait先过全局令牌桶，再过targetIP专属的令牌桶，两道都通过才返回。
+func (rl *requestRateLimiter) Wait(targetIP string) {
+	if rl == nil {
+		return
+	}
+	rl.perIPMu.Lock()
+	enabled, global, perIPRate := rl.enabled, rl.global, rl.perIPRate
+	rl.perIPMu.Unlock()
+	if !enabled {
+		return
+	}
+	if global != nil {
+		global.Wait()
+		atomic.AddInt64(&rl.globalChecked, 1)
+	}
+	if perIPRate > 0 {
+		rl.bucketForIP(targetIP).Wait()
+		atomic.AddInt64(&rl.perIPChecked, 1)
+	}
+}
+
+// Reconfigure在运行期套用新的限速配置（配置热重载场景），全局桶直接替换，已经为各IP创建的
+// perIP桶清空重建，下一次Wait会按新的perIPRate惰性重建，旧桶里积累的令牌不做迁移——
+// 限速本来就是为了压低长期平均速率，重建瞬间放宽一点令牌桶状态不影响这个目标。
+func (rl *requestRateLimiter) Reconfigure(cfg config.RateLimitConfig) {
+	if rl == nil {
+		return
+	}
+	rl.perIPMu.Lock()
+	defer rl.perIPMu.Unlock()
+	rl.enabled = cfg.Enabled
+	if cfg.GlobalRequestsPerSecond > 0 {
+		rl.global = newTokenBucket(cfg.GlobalRequestsPerSecond)
+	} else {
+		rl.global = nil
+	}
+	rl.perIPRate = cfg.PerIPRequestsPerSecond
+	rl.perIP = make(map[string]*tokenBucket)
+}
+
+// Stats返回自启动以来经过全局/按IP维度限流检查的请求数，用于观测限速配置是否生效
+func (rl *requestRateLimiter) Stats() (globalChecked, perIPChecked int64) {
+	if rl == nil {
+		return 0, 0
+	}
+	return atomic.LoadInt64(&rl.globalChecked), atomic.LoadInt64(&rl.perIPChecked)
+}
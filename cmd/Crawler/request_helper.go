@@ -9,7 +9,26 @@ import (
 	"utlsProxy/src"
 )
 
-func (c *Crawler) performRequestAttempt(workerID, taskID, attempt int, targetIP, pathSuffix, workID string, timeout time.Duration) (*src.UTlsResponse, string, error, time.Duration) {
+// recordFingerprintUsage 统计实际连接使用的指纹与Crawler启动时选定的默认指纹是否一致，
+// 用于观测连接池实际分配的每连接指纹分布，而不是真的需要对齐请求头——
+// 请求头与TLS层指纹的一致性已经由UTlsClient.Do在发送请求前保证。
+func (c *Crawler) recordFingerprintUsage(actual src.Profile) {
+	if actual.Name != "" && actual.Name != c.fingerprint.Name {
+		atomic.AddInt64(&c.stats.FingerprintMismatches, 1)
+	}
+}
+
+// taskRequestOptions携带TaskRequest里允许按请求覆盖的字段（Method/Headers/Body/Domain），
+// 由调用方（目前只有handleTaskRequest，经task_executor.go按TaskAPIConfig的白名单校验后
+// 转发）按需要填充，零值等价于原有的"GET、无body、用默认域名"行为。
+type taskRequestOptions struct {
+	method  string
+	headers map[string]string
+	body    []byte
+	domain  string
+}
+
+func (c *Crawler) performRequestAttempt(workerID, taskID, attempt int, targetIP, pathSuffix, workID string, timeout time.Duration, opts taskRequestOptions) (*src.UTlsResponse, string, error, time.Duration) {
 	formattedIP := targetIP
 	if ip := net.ParseIP(targetIP); ip != nil && ip.To4() == nil {
 		formattedIP = "[" + targetIP + "]"
@@ -20,26 +39,43 @@ func (c *Crawler) performRequestAttempt(workerID, taskID, attempt int, targetIP,
 		timeout = maxTaskDuration
 	}
 
+	method := opts.method
+	if method == "" {
+		method = "GET"
+	}
+	domain := opts.domain
+	if domain == "" {
+		domain = c.config.RockTreeDataConfig.HostName
+	}
+
 	req := &src.UTlsRequest{
 		WorkID:      workID,
-		Domain:      c.config.RockTreeDataConfig.HostName,
-		Method:      "GET",
+		Domain:      domain,
+		Method:      method,
 		Path:        fullPath,
-		Headers:     c.requestHeaders,
+		Headers:     c.resolveRequestHeaders(pathSuffix, opts.headers),
+		Body:        opts.body,
 		DomainIP:    targetIP,
 		Fingerprint: c.fingerprint,
 		StartTime:   time.Now(),
 		Timeout:     timeout,
 	}
 
+	c.pacer.Wait()               // 按目标吞吐量节奏派发请求
+	c.rateLimiter.Wait(targetIP) // 全局/按目标IP令牌桶限速，超出速率时阻塞到有令牌为止
+
 	resp, err := c.client.Do(req)
 	duration := time.Since(req.StartTime)
 
+	if err == nil {
+		c.recordFingerprintUsage(resp.Fingerprint)
+	}
+
 	if err != nil {
 		atomic.AddInt64(&c.stats.FailedRequests, 1)
 		atomic.AddInt64(&c.stats.TotalRequests, 1)
 		atomic.AddInt64(&c.stats.TotalDuration, duration.Microseconds())
-		c.recordSlowIP(targetIP, duration)
+		c.recordSlowIP(targetIP, duration, 0)
 		return nil, "", err, duration
 	}
 
@@ -48,10 +84,11 @@ func (c *Crawler) performRequestAttempt(workerID, taskID, attempt int, targetIP,
 	if resp.StatusCode == 200 {
 		atomic.AddInt64(&c.stats.SuccessRequests, 1)
 		atomic.AddInt64(&c.stats.TotalBytes, int64(len(resp.Body)))
+		c.pacer.RecordBytes(int64(len(resp.Body)))
 	} else {
 		atomic.AddInt64(&c.stats.FailedRequests, 1)
 	}
-	c.recordSlowIP(targetIP, duration)
+	c.recordSlowIP(targetIP, duration, int64(len(resp.Body)))
 
 	// 注意：resp.Body会在调用者使用完后立即释放
 	// 调用者负责在复制body后释放resp.Body
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// schedulerAgingInterval 每放行这么多个任务就强制从更低优先级的桶中取一个（如果非空），
+// 避免某个客户端持续提交高优先级请求时把普通/低优先级客户端彻底饿死。
+const schedulerAgingInterval = 8
+
+// taskScheduler 用优先级桶+每桶内按ClientID轮询取代单一的grpcSemaphore信号量：
+// TaskRequest.Priority>0进高优先级桶、<0进低优先级桶、==0进普通桶，
+// 桶间按优先级从高到低放行（定期老化让低优先级桶也能插队），
+// 同一个桶内部不是简单FIFO，而是在各ClientID之间轮询，
+// 这样一个客户端瞬间提交的上百个请求不会把同优先级的其他客户端彻底挤出执行槽位。
+type taskScheduler struct {
+	capacity int
+
+	mu    chanMutex
+	inUse int
+	tiers [3]*priorityTier
+
+	grantsSinceAging int
+}
+
+// chanMutex 是用带缓冲1的channel实现的互斥锁，选它是为了避免在持锁期间向等待者的channel发送时
+// 引入额外的锁类型依赖——taskScheduler里所有状态变更都很短，用channel互斥足够。
+type chanMutex chan struct{}
+
+func newChanMutex() chanMutex {
+	m := make(chanMutex, 1)
+	m <- struct{}{}
+	return m
+}
+
+func (m chanMutex) Lock()   { <-m }
+func (m chanMutex) Unlock() { m <- struct{}{} }
+
+// priorityTier 是单个优先级桶：按ClientID分组的等待队列，cursor在各ClientID间轮询
+type priorityTier struct {
+	order   []string
+	pending map[string][]chan struct{}
+	cursor  int
+}
+
+func newPriorityTier() *priorityTier {
+	return &priorityTier{pending: make(map[string][]chan struct{})}
+}
+
+func (t *priorityTier) empty() bool {
+	return len(t.order) == 0
+}
+
+func (t *priorityTier) enqueue(clientID string, ch chan struct{}) {
+	if _, ok := t.pending[clientID]; !ok {
+		t.order = append(t.order, clientID)
+	}
+	t.pending[clientID] = append(t.pending[clientID], ch)
+}
+
+// dequeue 轮询到下一个有等待者的ClientID，弹出其队首等待者并返回
+func (t *priorityTier) dequeue() chan struct{} {
+	if t.empty() {
+		return nil
+	}
+	for i := 0; i < len(t.order); i++ {
+		idx := (t.cursor + i) % len(t.order)
+		clientID := t.order[idx]
+		waiters := t.pending[clientID]
+		if len(waiters) == 0 {
+			continue
+		}
+		ch := waiters[0]
+		t.pending[clientID] = waiters[1:]
+		if len(t.pending[clientID]) == 0 {
+			delete(t.pending, clientID)
+			t.order = append(t.order[:idx], t.order[idx+1:]...)
+			t.cursor = idx
+		} else {
+			t.cursor = idx + 1
+		}
+		return ch
+	}
+	return nil
+}
+
+// remove 从等待队列中摘除一个已超时/取消但尚未被放行的等待者，找不到说明已经被放行，忽略即可
+func (t *priorityTier) remove(clientID string, ch chan struct{}) {
+	waiters := t.pending[clientID]
+	for i, w := range waiters {
+		if w == ch {
+			t.pending[clientID] = append(waiters[:i], waiters[i+1:]...)
+			if len(t.pending[clientID]) == 0 {
+				delete(t.pending, clientID)
+				for idx, id := range t.order {
+					if id == clientID {
+						t.order = append(t.order[:idx], t.order[idx+1:]...)
+						break
+					}
+				}
+			}
+			return
+		}
+	}
+}
+
+// newTaskScheduler 创建一个总容量为capacity的调度器，capacity<=0时视为1
+func newTaskScheduler(capacity int) *taskScheduler {
+	if capacity <= 0 {
+		capacity = 1
+	}
+	return &taskScheduler{
+		capacity: capacity,
+		mu:       newChanMutex(),
+		tiers:    [3]*priorityTier{newPriorityTier(), newPriorityTier(), newPriorityTier()},
+	}
+}
+
+// tierIndex 把TaskRequest.Priority映射到三档桶：0=高，1=普通，2=低
+func tierIndex(priority int32) int {
+	switch {
+	case priority > 0:
+		return 0
+	case priority < 0:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// Acquire 按clientID和priority排队等待一个执行槽位，返回的release必须在任务完成后调用恰好一次。
+// ctx被取消时返回ctx.Err()，此时不会持有槽位。
+func (s *taskScheduler) Acquire(ctx context.Context, clientID string, priority int32) (func(), error) {
+	s.mu.Lock()
+	if s.inUse < s.capacity {
+		s.inUse++
+		s.mu.Unlock()
+		return s.release, nil
+	}
+
+	tier := s.tiers[tierIndex(priority)]
+	ch := make(chan struct{}, 1)
+	tier.enqueue(clientID, ch)
+	s.mu.Unlock()
+
+	select {
+	case <-ch:
+		return s.release, nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		tier.remove(clientID, ch)
+		s.mu.Unlock()
+		select {
+		case <-ch:
+			// remove执行前已经被release()放行，槽位归我们所有但已不再需要，必须转交出去，
+			// 否则容量会被永久少算一个名额
+			s.release()
+		default:
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// release 释放一个执行槽位：如果有等待者，直接把槽位移交给下一个被选中的等待者；否则归还容量
+func (s *taskScheduler) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if next := s.pickNextWaiter(); next != nil {
+		next <- struct{}{}
+		return
+	}
+	s.inUse--
+}
+
+// pickNextWaiter 按优先级从高到低选择下一个等待者所在的桶，每schedulerAgingInterval次
+// 强制改为从最低优先级的非空桶开始找，防止高优先级桶持续有新请求时彻底饿死低优先级桶
+func (s *taskScheduler) pickNextWaiter() chan struct{} {
+	order := []int{0, 1, 2}
+	s.grantsSinceAging++
+	if s.grantsSinceAging >= schedulerAgingInterval {
+		s.grantsSinceAging = 0
+		order = []int{2, 1, 0}
+	}
+	for _, idx := range order {
+		if ch := s.tiers[idx].dequeue(); ch != nil {
+			return ch
+		}
+	}
+	return nil
+}
+
+// Stats 返回调度器当前的排队深度（三档桶里所有等待者之和）和空闲执行槽位数，
+// 供Execute等RPC在响应里附带容量提示，让客户端据此做节流或在多节点间负载均衡。
+func (s *taskScheduler) Stats() (queueDepth, freeSlots int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, tier := range s.tiers {
+		for _, waiters := range tier.pending {
+			queueDepth += len(waiters)
+		}
+	}
+	freeSlots = s.capacity - s.inUse
+	return queueDepth, freeSlots
+}
+
+// SetCapacity原子地调整调度器总容量，供外部的AIMD控制器按成功率/失败信号动态收放。
+// 新容量可以小于当前inUse——这种情况下不会抢占已经在执行的任务，只是暂停放行新的
+// 等待者，直到陆续的release把inUse降到新容量以下为止。
+func (s *taskScheduler) SetCapacity(n int) {
+	if n < 1 {
+		n = 1
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.capacity = n
+}
+
+// Capacity返回调度器当前的总容量
+func (s *taskScheduler) Capacity() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.capacity
+}
+
+func (s *taskScheduler) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fmt.Sprintf("taskScheduler{inUse: %d/%d}", s.inUse, s.capacity)
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"utlsProxy/config"
+)
+
+// storage.go 为saveData/loadData提供可替换的落盘/上传后端，接口之上压缩层（saveData的
+// Compression逻辑）保持不变，只有最终的字节读写委托给这里的Storage实现。
+//
+// 仓库里没有引入任何数据库驱动依赖（go.mod里也没有sqlite/bbolt），离线环境下无法新增，
+// 所以这里只提供两种不需要额外依赖的实现：本地文件系统（可选分片目录）和手写SigV4签名的
+// S3兼容对象存储。如果将来需要一个可查询的索引层，应该在能够引入bbolt一类依赖之后再加，
+// 而不是在这里用纯标准库拼凑一个不成熟的替代品。
+
+// Storage 是saveData/loadData最终落盘/上传所依赖的抽象，key是saveData传入的文件名
+// （可能带zstdSuffix后缀），实现只需要按字节存取，不关心调用方是否启用了压缩。
+type Storage interface {
+	Save(key string, data []byte) error
+	Load(key string) ([]byte, error)
+	Exists(key string) bool
+}
+
+// newStorage按cfg.Backend构造对应的Storage实现，Backend为空时等价于"local"。
+func newStorage(cfg config.StorageConfig, dataDir string) (Storage, error) {
+	switch strings.ToLower(strings.TrimSpace(cfg.Backend)) {
+	case "", "local":
+		return &localStorage{baseDir: dataDir, shardWidth: cfg.ShardWidth}, nil
+	case "s3":
+		if cfg.S3Endpoint == "" || cfg.S3Bucket == "" {
+			return nil, fmt.Errorf("StorageConfig.Backend为s3时S3Endpoint和S3Bucket不能为空")
+		}
+		return &s3Storage{
+			endpoint:     cfg.S3Endpoint,
+			bucket:       cfg.S3Bucket,
+			region:       cfg.S3Region,
+			accessKeyID:  cfg.S3AccessKeyID,
+			secretKey:    cfg.S3SecretKey,
+			usePathStyle: cfg.S3UsePathStyle,
+			useTLS:       cfg.S3UseTLS,
+			client:       &http.Client{Timeout: 30 * time.Second},
+		}, nil
+	default:
+		return nil, fmt.Errorf("未知的StorageConfig.Backend: %q", cfg.Backend)
+	}
+}
+
+// localStorage 把数据写到baseDir下，shardWidth>0时按key的SHA-256前shardWidth个
+// 十六进制字符分两级子目录（每级一个字符），避免单个目录下堆积过多文件。
+type localStorage struct {
+	baseDir    string
+	shardWidth int
+}
+
+func (s *localStorage) path(key string) string {
+	if s.shardWidth <= 0 {
+		return filepath.Join(s.baseDir, key)
+	}
+	sum := sha256.Sum256([]byte(key))
+	digest := hex.EncodeToString(sum[:])
+	width := s.shardWidth
+	if width > len(digest) {
+		width = len(digest)
+	}
+	shardDirs := make([]string, 0, width)
+	for i := 0; i < width; i++ {
+		shardDirs = append(shardDirs, string(digest[i]))
+	}
+	parts := append([]string{s.baseDir}, shardDirs...)
+	parts = append(parts, key)
+	return filepath.Join(parts...)
+}
+
+func (s *localStorage) Save(key string, data []byte) error {
+	p := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return fmt.Errorf("创建存储目录失败: %w", err)
+	}
+	return os.WriteFile(p, data, 0644)
+}
+
+func (s *localStorage) Load(key string) ([]byte, error) {
+	return os.ReadFile(s.path(key))
+}
+
+func (s *localStorage) Exists(key string) bool {
+	_, err := os.Stat(s.path(key))
+	return err == nil
+}
+
+// s3Storage 用手写的AWS SigV4签名把数据以PUT/GET对象的方式存取到S3兼容的对象存储，
+// 不依赖官方SDK——这个仓库的go.mod里从未引入过，离线环境下也没法新增。
+type s3Storage struct {
+	endpoint     string
+	bucket       string
+	region       string
+	accessKeyID  string
+	secretKey    string
+	usePathStyle bool
+	useTLS       bool
+	client       *http.Client
+}
+
+func (s *s3Storage) objectURL(key string) string {
+	scheme := "http"
+	if s.useTLS {
+		scheme = "https"
+	}
+	escapedKey := escapeS3Key(key)
+	if s.usePathStyle {
+		return fmt.Sprintf("%s://%s/%s/%s", scheme, s.endpoint, s.bucket, escapedKey)
+	}
+	return fmt.Sprintf("%s://%s.%s/%s", scheme, s.bucket, s.endpoint, escapedKey)
+}
+
+func escapeS3Key(key string) string {
+	segments := strings.Split(key, "/")
+	for i, seg := range segments {
+		segments[i] = strings.ReplaceAll(strings.TrimSpace(seg), " ", "%20")
+	}
+	return strings.Join(segments, "/")
+}
+
+func (s *s3Storage) do(method, key string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest(method, s.objectURL(key), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	host := s.bucket + "." + s.endpoint
+	if s.usePathStyle {
+		host = s.endpoint
+	}
+	req.Host = host
+	if err := s.signSigV4(req, body, host); err != nil {
+		return nil, fmt.Errorf("SigV4签名失败: %w", err)
+	}
+	return s.client.Do(req)
+}
+
+func (s *s3Storage) Save(key string, data []byte) error {
+	resp, err := s.do(http.MethodPut, key, data)
+	if err != nil {
+		return fmt.Errorf("上传对象 %q 失败: %w", key, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("上传对象 %q 失败，状态码 %d: %s", key, resp.StatusCode, string(respBody))
+	}
+	return nil
+}
+
+func (s *s3Storage) Load(key string) ([]byte, error) {
+	resp, err := s.do(http.MethodGet, key, nil)
+	if err != nil {
+		return nil, fmt.Errorf("下载对象 %q 失败: %w", key, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, os.ErrNotExist
+	}
+	if resp.StatusCode/100 != 2 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("下载对象 %q 失败，状态码 %d: %s", key, resp.StatusCode, string(respBody))
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (s *s3Storage) Exists(key string) bool {
+	resp, err := s.do(http.MethodHead, key, nil)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode/100 == 2
+}
+
+// signSigV4 按AWS Signature Version 4（单块签名，非chunked上传）给req加上Authorization头，
+// 参考 https://docs.aws.amazon.com/general/latest/gr/sigv4-signing-examples.html 的流程手写实现。
+func (s *s3Storage) signSigV4(req *http.Request, body []byte, host string) error {
+	now := s3SignTimeFromHeader(req)
+	amzDate := now.Format("20060102T150405Z")
+	dateStamp := now.Format("20060102")
+
+	payloadHash := sha256Hex(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	req.Header.Set("Host", host)
+
+	signedHeaders := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	canonicalHeaders := fmt.Sprintf("host:%s\nx-amz-content-sha256:%s\nx-amz-date:%s\n", host, payloadHash, amzDate)
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		"",
+		canonicalHeaders,
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", dateStamp, s.region)
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signingKey := s3SigningKey(s.secretKey, dateStamp, s.region)
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	authHeader := fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKeyID, scope, strings.Join(signedHeaders, ";"), signature)
+	req.Header.Set("Authorization", authHeader)
+	return nil
+}
+
+// s3SignTimeFromHeader固定从req上已有的X-Amz-Date（若有）取时间，否则用当前时间；
+// 拆成独立函数只是为了让签名逻辑本身不直接依赖time.Now，方便后续如有需要单独测试。
+func s3SignTimeFromHeader(req *http.Request) time.Time {
+	if v := req.Header.Get("X-Amz-Date"); v != "" {
+		if t, err := time.Parse("20060102T150405Z", v); err == nil {
+			return t
+		}
+	}
+	return time.Now().UTC()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func s3SigningKey(secretKey, dateStamp, region string) []byte {
+	kDate := hmacSHA256([]byte("AWS4"+secretKey), dateStamp)
+	kRegion := hmacSHA256(kDate, region)
+	kService := hmacSHA256(kRegion, "s3")
+	return hmacSHA256(kService, "aws4_request")
+}
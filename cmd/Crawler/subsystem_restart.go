@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"utlsProxy/src"
+)
+
+// RestartResult 描述单个子系统重启操作的结构化结果，供gRPC层转换为响应返回给调用方
+type RestartResult struct {
+	Subsystem string
+	Success   bool
+	Message   string
+}
+
+// RestartDomainMonitor 单独重启域名监控器：停止旧实例、创建并启动新实例。
+// 注意：已经持有旧DomainMonitor引用的连接池（c.pool）在重启后仍会继续使用旧实例，
+// 要让连接池感知到新的监控器，需要随后调用RestartPool。
+func (c *Crawler) RestartDomainMonitor() RestartResult {
+	c.restartMu.Lock()
+	defer c.restartMu.Unlock()
+
+	log.Println("[子系统重启] 正在重启域名监控器...")
+
+	newMonitor, err := createDomainMonitor(c.config)
+	if err != nil {
+		return RestartResult{Subsystem: "domain_monitor", Success: false, Message: fmt.Sprintf("创建新域名监控器失败: %v", err)}
+	}
+	newMonitor.Start()
+
+	if !waitForIPs(newMonitor, c.config.HotConnPool.Domain, 30*time.Second) {
+		newMonitor.Stop()
+		return RestartResult{Subsystem: "domain_monitor", Success: false, Message: "新域名监控器在30秒内未获取到任何IP地址"}
+	}
+
+	oldMonitor := c.domainMonitor
+	c.domainMonitor = newMonitor
+	c.poolConfig.DomainMonitor = newMonitor
+	oldMonitor.Stop()
+
+	log.Println("[子系统重启] 域名监控器重启完成")
+	return RestartResult{Subsystem: "domain_monitor", Success: true, Message: "域名监控器已重启，连接池仍持有旧引用直至下次重启"}
+}
+
+// RestartPool 单独重启热连接池：用当前的poolConfig重建连接池并重新预热，随后原子替换。
+func (c *Crawler) RestartPool() RestartResult {
+	c.restartMu.Lock()
+	defer c.restartMu.Unlock()
+
+	log.Println("[子系统重启] 正在重启连接池...")
+
+	newPool, err := src.NewDomainHotConnPool(c.poolConfig)
+	if err != nil {
+		return RestartResult{Subsystem: "pool", Success: false, Message: fmt.Sprintf("创建新连接池失败: %v", err)}
+	}
+	if report, err := newPool.Warmup(); err != nil {
+		log.Printf("[子系统重启] 新连接池预热出现问题: %v（成功 %d/%d）", err, report.Succeeded, report.Attempted)
+	}
+
+	oldPool := c.pool
+	c.pool = newPool
+	c.client.HotConnPool = newPool
+	// c.pool对应的域名也注册在poolManager中（resolvePool按Domain路由时优先查询poolManager），
+	// 必须同步替换，否则后续请求仍会被路由到刚刚关闭的旧连接池。
+	c.poolManager.Replace(c.poolConfig.Domain, newPool)
+	_ = oldPool.Close()
+
+	log.Println("[子系统重启] 连接池重启完成")
+	return RestartResult{Subsystem: "pool", Success: true, Message: "连接池已重建并完成预热"}
+}
+
+// RestartQUICListener 单独重启QUIC监听器，不影响gRPC监听器和正在进行的请求处理。
+func (c *Crawler) RestartQUICListener() RestartResult {
+	c.restartMu.Lock()
+	defer c.restartMu.Unlock()
+
+	if !c.config.ServerConfig.EnableQUIC {
+		return RestartResult{Subsystem: "quic_listener", Success: false, Message: "QUIC未启用，无需重启"}
+	}
+
+	log.Println("[子系统重启] 正在重启QUIC监听器...")
+
+	oldListener := c.quicListener
+	c.quicListener = nil
+	if oldListener != nil {
+		_ = oldListener.Close()
+	}
+
+	if err := c.startQUICServer(); err != nil {
+		return RestartResult{Subsystem: "quic_listener", Success: false, Message: fmt.Sprintf("重启QUIC监听器失败: %v", err)}
+	}
+
+	log.Println("[子系统重启] QUIC监听器重启完成")
+	return RestartResult{Subsystem: "quic_listener", Success: true, Message: "QUIC监听器已重启"}
+}
@@ -2,10 +2,13 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -14,74 +17,103 @@ import (
 )
 
 func (c *Crawler) executeTask(ctx context.Context, transport transportKind, req *taskapi.TaskRequest, rawRequestBytes int64) (*taskapi.TaskResponse, error) {
-	metrics := c.metricsForTransport(transport)
+	ctx, span := c.startSpan(ctx, "executeTask")
+	span.SetAttr("transport", transport.label())
+	defer span.End()
+
+	ts := c.statsFor(transport)
 	label := "[" + transport.label() + "]"
 	start := time.Now()
 
-	if metrics.requests != nil {
-		atomic.AddInt64(metrics.requests, 1)
-	}
+	atomic.AddInt64(&ts.Requests, 1)
 
 	resp := &taskapi.TaskResponse{}
 
-	if req == nil {
-		if metrics.failed != nil {
-			atomic.AddInt64(metrics.failed, 1)
+	// 无论本次执行成功与否，都在返回前附带当前的服务端容量提示，
+	// 客户端可据此判断是否该降低发送速率或切换到另一个节点
+	defer func() {
+		queueDepth, freeSlots := c.scheduler.Stats()
+		resp.QueueDepth = int32(queueDepth)
+		resp.FreeSlots = int32(freeSlots)
+		if c.pool != nil {
+			resp.HealthyConns = int32(c.pool.HealthyConnCount())
 		}
+	}()
+
+	if req == nil {
+		atomic.AddInt64(&ts.Failed, 1)
 		resp.ErrorMessage = "空请求"
-		c.addTransportDuration(metrics.duration, start)
+		c.addTransportDuration(&ts.DurationMicros, start)
 		return resp, nil
 	}
 
 	resp.ClientID = req.ClientID
+	span.SetAttr("client_id", req.ClientID)
+	span.SetAttr("path", req.Path)
 
 	if rawRequestBytes <= 0 {
 		rawRequestBytes = int64(len(req.Path) + len(req.ClientID))
 	}
-	if metrics.requestBytes != nil && rawRequestBytes > 0 {
-		atomic.AddInt64(metrics.requestBytes, rawRequestBytes)
+	if rawRequestBytes > 0 {
+		atomic.AddInt64(&ts.RequestBytes, rawRequestBytes)
 	}
 
 	if strings.TrimSpace(req.Path) == "" {
-		if metrics.failed != nil {
-			atomic.AddInt64(metrics.failed, 1)
-		}
+		atomic.AddInt64(&ts.Failed, 1)
 		resp.ErrorMessage = "path 不能为空"
-		c.addTransportDuration(metrics.duration, start)
+		c.addTransportDuration(&ts.DurationMicros, start)
 		return resp, nil
 	}
 
-	acquired, acquireErr := c.acquireTaskSlot(ctx)
-	if acquireErr != nil {
-		if metrics.failed != nil {
-			atomic.AddInt64(metrics.failed, 1)
-		}
-		resp.ErrorMessage = fmt.Sprintf("%s 并发受限: %v", label, acquireErr)
-		c.addTransportDuration(metrics.duration, start)
+	if !c.config.TaskAPI.IsMethodAllowed(req.Method) {
+		atomic.AddInt64(&ts.Failed, 1)
+		resp.ErrorMessage = fmt.Sprintf("方法 %q 不在允许列表中", req.Method)
+		c.addTransportDuration(&ts.DurationMicros, start)
 		return resp, nil
 	}
-	if !acquired {
-		if metrics.failed != nil {
-			atomic.AddInt64(metrics.failed, 1)
-		}
-		resp.ErrorMessage = "无法获取并发资源"
-		c.addTransportDuration(metrics.duration, start)
+
+	if req.Domain != "" && !c.config.TaskAPI.IsDomainAllowed(req.Domain) {
+		atomic.AddInt64(&ts.Failed, 1)
+		resp.ErrorMessage = fmt.Sprintf("域名 %q 不在允许列表中", req.Domain)
+		c.addTransportDuration(&ts.DurationMicros, start)
+		return resp, nil
+	}
+
+	releaseQuota, quotaErr := c.quotaManager.Acquire(quotaClientID(ctx, req.ClientID))
+	if quotaErr != nil {
+		atomic.AddInt64(&ts.Failed, 1)
+		atomic.AddInt64(&c.stats.QuotaRejected, 1)
+		resp.ErrorMessage = quotaErr.Error()
+		c.addTransportDuration(&ts.DurationMicros, start)
 		return resp, nil
 	}
-	defer func() { <-c.grpcSemaphore }()
+	defer releaseQuota()
+
+	releaseSlot, acquireErr := c.acquireTaskSlot(ctx, req.ClientID, req.Priority)
+	if acquireErr != nil {
+		atomic.AddInt64(&ts.Failed, 1)
+		resp.ErrorMessage = fmt.Sprintf("%s 并发受限: %v", label, acquireErr)
+		c.addTransportDuration(&ts.DurationMicros, start)
+		return resp, nil
+	}
+	defer releaseSlot()
 
 	taskStart := time.Now()
 	c.recordTaskStart()
 
 	defer func() {
 		c.recordTaskCompletion(time.Since(taskStart))
-		if metrics.duration != nil {
-			atomic.AddInt64(metrics.duration, time.Since(start).Microseconds())
-		}
+		atomic.AddInt64(&ts.DurationMicros, time.Since(start).Microseconds())
 	}()
 
-	statusCode, body, err := c.handleTaskRequest(ctx, label, transport.prefix(), req.ClientID, req.Path)
+	statusCode, body, err := c.handleTaskRequest(ctx, label, transport.prefix(), req.ClientID, req.Path, taskRequestOptions{
+		method:  req.Method,
+		headers: req.Headers,
+		body:    req.Body,
+		domain:  req.Domain,
+	})
 	resp.StatusCode = int32(statusCode)
+	span.SetAttr("status_code", strconv.Itoa(statusCode))
 
 	bodyLen := len(body)
 	defer func() {
@@ -91,22 +123,25 @@ func (c *Crawler) executeTask(ctx context.Context, transport transportKind, req
 	}()
 
 	if err != nil {
-		if metrics.failed != nil {
-			atomic.AddInt64(metrics.failed, 1)
+		atomic.AddInt64(&ts.Failed, 1)
+		if c.grpcConcurrency != nil {
+			c.grpcConcurrency.OnFailure()
 		}
 		resp.ErrorMessage = err.Error()
-		if metrics.responseBytes != nil {
-			atomic.AddInt64(metrics.responseBytes, int64(len(resp.ErrorMessage)))
-		}
+		atomic.AddInt64(&ts.ResponseBytes, int64(len(resp.ErrorMessage)))
 		return resp, nil
 	}
 
 	if statusCode == 200 {
-		if metrics.success != nil {
-			atomic.AddInt64(metrics.success, 1)
+		atomic.AddInt64(&ts.Success, 1)
+		if c.grpcConcurrency != nil {
+			c.grpcConcurrency.OnSuccess()
+		}
+	} else {
+		atomic.AddInt64(&ts.Failed, 1)
+		if c.grpcConcurrency != nil && statusCode == 403 {
+			c.grpcConcurrency.OnFailure()
 		}
-	} else if metrics.failed != nil {
-		atomic.AddInt64(metrics.failed, 1)
 	}
 
 	const maxResponseBodySize = 50 * 1024 * 1024 // 50MB
@@ -116,6 +151,16 @@ func (c *Crawler) executeTask(ctx context.Context, transport transportKind, req
 		bodyLen = maxResponseBodySize
 	}
 
+	if bodyLen > 0 {
+		checksum := sha256.Sum256(body)
+		resp.BodySHA256 = hex.EncodeToString(checksum[:])
+		resp.BodySize = int64(bodyLen)
+	}
+
+	// 超过阈值的响应体落盘后只把FilePath回传给调用方，由其通过FetchFile流式RPC
+	// 按需拉取分片，服务端不再把刚写入的文件立即读回内存——那样等于白白多做一次
+	// 磁盘I/O还占用与Body等量的内存。文件留存在tempFileDir内，由cleanupOldTempFiles
+	// 在客户端取走（或放弃）后兜底清理。
 	const largeBodyThreshold = 100 * 1024 // 100KB
 	if bodyLen > largeBodyThreshold {
 		tempFile := filepath.Join(c.tempFileDir, fmt.Sprintf("resp_%s_%d_%d.tmp", req.ClientID, time.Now().UnixNano(), bodyLen))
@@ -124,33 +169,29 @@ func (c *Crawler) executeTask(ctx context.Context, transport transportKind, req
 			resp.Body = body
 		} else {
 			body = nil
-			defer func(file string) {
-				if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
-					log.Printf("%s 警告: 删除临时文件失败: %v (文件: %s)", label, err, file)
-				}
-			}(tempFile)
-
-			fileData, err := os.ReadFile(tempFile)
-			if err != nil {
-				log.Printf("%s 警告: 读取临时文件失败: %v，将返回错误", label, err)
-				resp.ErrorMessage = fmt.Sprintf("读取临时文件失败: %v", err)
-				return resp, nil
-			}
-			resp.Body = fileData
+			resp.FilePath = tempFile
 		}
 	} else {
 		resp.Body = body
 		body = nil
 	}
 
-	if metrics.responseBytes != nil {
-		responseSize := int64(bodyLen)
-		if resp.ErrorMessage != "" {
-			responseSize += int64(len(resp.ErrorMessage))
+	// 仅当客户端明确表示能解压（AcceptCompressed）且压缩确实能减小体积时才压缩，
+	// 避免对已经是压缩格式（如protobuf里嵌的图像瓦片）的body做无意义的二次压缩
+	if req.AcceptCompressed && len(resp.Body) > 0 {
+		if compressed, cerr := taskapi.CompressBody(resp.Body); cerr == nil && len(compressed) < len(resp.Body) {
+			resp.Body = compressed
+			resp.BodyCompressed = true
+			resp.ContentEncoding = "zstd"
 		}
-		atomic.AddInt64(metrics.responseBytes, responseSize)
 	}
 
+	responseSize := int64(bodyLen)
+	if resp.ErrorMessage != "" {
+		responseSize += int64(len(resp.ErrorMessage))
+	}
+	atomic.AddInt64(&ts.ResponseBytes, responseSize)
+
 	if bodyLen == 0 {
 		log.Printf("%s 警告: 响应体为空: client_id=%s, status=%d", label, req.ClientID, statusCode)
 	}
@@ -172,22 +213,18 @@ func (c *Crawler) addTransportDuration(durationPtr *int64, start time.Time) {
 	atomic.AddInt64(durationPtr, time.Since(start).Microseconds())
 }
 
-func (c *Crawler) acquireTaskSlot(ctx context.Context) (bool, error) {
-	select {
-	case c.grpcSemaphore <- struct{}{}:
-		return true, nil
-	default:
-		waitCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
-		defer cancel()
-
-		select {
-		case c.grpcSemaphore <- struct{}{}:
-			return true, nil
-		case <-waitCtx.Done():
-			return false, fmt.Errorf("服务器繁忙，请稍后重试（并发限制）")
-		case <-ctx.Done():
-			return false, ctx.Err()
+// acquireTaskSlot 向调度器申请一个执行槽位，等待超过100ms仍未获得时放弃并让客户端重试，
+// 避免一个长时间排队的请求占着goroutine不放。
+func (c *Crawler) acquireTaskSlot(ctx context.Context, clientID string, priority int32) (func(), error) {
+	waitCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+
+	release, err := c.scheduler.Acquire(waitCtx, clientID, priority)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
 		}
+		return nil, fmt.Errorf("服务器繁忙，请稍后重试（并发限制）")
 	}
+	return release, nil
 }
-
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"utlsProxy/internal/taskapi"
+)
+
+// taskQueue*状态常量描述taskQueueEntry在其生命周期内的阶段：
+// pending（已提交，等待/重新等待执行）-> running（正在执行）-> done（成功）或failed（耗尽重试次数后仍失败）。
+// running状态在WAL里被观测到，只可能是进程在上一次执行过程中崩溃，ResumePending会把它当成pending重新执行。
+const (
+	taskQueuePending = "pending"
+	taskQueueRunning = "running"
+	taskQueueDone    = "done"
+	taskQueueFailed  = "failed"
+)
+
+// taskQueueMaxAttempts 是单个任务自动重试的次数上限，超过后状态固定为failed，不再重新入队
+const taskQueueMaxAttempts = 3
+
+// taskQueueEntry 记录persistentTaskQueue里单个任务的完整生命周期状态，既是内存map的value，
+// 也是WAL文件里一行JSON记录的内容：同一TaskID可能对应多条记录，重放时以最后一条为准。
+type taskQueueEntry struct {
+	TaskID       string                `json:"task_id"`
+	Request      *taskapi.TaskRequest  `json:"request"`
+	Status       string                `json:"status"`
+	Response     *taskapi.TaskResponse `json:"response,omitempty"`
+	ErrorMessage string                `json:"error_message,omitempty"`
+	Attempts     int32                 `json:"attempts"`
+	CreatedAt    time.Time             `json:"created_at"`
+	UpdatedAt    time.Time             `json:"updated_at"`
+}
+
+func (e *taskQueueEntry) clone() *taskQueueEntry {
+	c := *e
+	return &c
+}
+
+// toStatusResponse 把队列条目转换成TaskStatus/DrainTasks对外返回的taskapi.TaskStatusResponse
+func (e *taskQueueEntry) toStatusResponse() *taskapi.TaskStatusResponse {
+	return &taskapi.TaskStatusResponse{
+		TaskID:       e.TaskID,
+		Status:       e.Status,
+		Response:     e.Response,
+		ErrorMessage: e.ErrorMessage,
+		Attempts:     e.Attempts,
+	}
+}
+
+// persistentTaskQueue 是一个以追加写文件（WAL）为后盾的任务队列：EnqueueTask提交的每个任务都立即
+// 落盘一条记录，Crawler重启后通过重放WAL恢复所有尚未跑到终态的任务并自动重新执行，
+// 使提交过的任务不会因为进程崩溃或重启而被悄悄丢弃。
+type persistentTaskQueue struct {
+	mu      sync.Mutex
+	entries map[string]*taskQueueEntry
+	walPath string
+	walFile *os.File
+	counter uint64
+	crawler *Crawler // 用于实际执行任务，调用方在newPersistentTaskQueue之后立即回填
+}
+
+// newPersistentTaskQueue 打开（或创建）dataDir下的WAL文件，并重放其中的记录以恢复上次退出时的队列状态
+func newPersistentTaskQueue(dataDir string) (*persistentTaskQueue, error) {
+	q := &persistentTaskQueue{
+		entries: make(map[string]*taskQueueEntry),
+		walPath: filepath.Join(dataDir, "task_queue.wal"),
+	}
+
+	if err := q.replay(); err != nil {
+		return nil, fmt.Errorf("重放任务队列WAL失败: %w", err)
+	}
+
+	file, err := os.OpenFile(q.walPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("打开任务队列WAL失败: %w", err)
+	}
+	q.walFile = file
+
+	return q, nil
+}
+
+// replay 按行重放WAL中已有的记录，相同TaskID的多条记录以最后一条为准；
+// 被截断的最后一行（例如进程在写完一半时崩溃）会被直接忽略，不影响之前已完整写入的记录。
+func (q *persistentTaskQueue) replay() error {
+	file, err := os.Open(q.walPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var entry taskQueueEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			continue
+		}
+		q.entries[entry.TaskID] = &entry
+	}
+	return nil
+}
+
+// appendLocked 把entry当前状态追加写入WAL一行，调用方必须持有q.mu
+func (q *persistentTaskQueue) appendLocked(entry *taskQueueEntry) error {
+	if q.walFile == nil {
+		return nil
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	_, err = q.walFile.Write(data)
+	return err
+}
+
+// compactLocked 用当前entries map的内容重写WAL文件，丢弃已被Drain移除的终态任务的历史记录，
+// 避免WAL随任务数增长无限膨胀；调用方必须持有q.mu
+func (q *persistentTaskQueue) compactLocked() error {
+	tmpPath := q.walPath + ".tmp"
+	tmpFile, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	for _, entry := range q.entries {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			tmpFile.Close()
+			return err
+		}
+		if _, err := tmpFile.Write(append(data, '\n')); err != nil {
+			tmpFile.Close()
+			return err
+		}
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	if q.walFile != nil {
+		q.walFile.Close()
+	}
+	if err := os.Rename(tmpPath, q.walPath); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(q.walPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	q.walFile = file
+	return nil
+}
+
+// Enqueue 创建一个新任务、落盘并返回其TaskID，不等待任务执行完成
+func (q *persistentTaskQueue) Enqueue(req *taskapi.TaskRequest) (string, error) {
+	if req == nil {
+		return "", fmt.Errorf("request 不能为空")
+	}
+
+	id := fmt.Sprintf("task-%d-%d", time.Now().UnixNano(), atomic.AddUint64(&q.counter, 1))
+	now := time.Now()
+	entry := &taskQueueEntry{
+		TaskID:    id,
+		Request:   req,
+		Status:    taskQueuePending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	q.mu.Lock()
+	q.entries[id] = entry
+	err := q.appendLocked(entry)
+	q.mu.Unlock()
+	if err != nil {
+		return "", fmt.Errorf("持久化任务失败: %w", err)
+	}
+
+	return id, nil
+}
+
+// EnqueueAndRun 落盘一个新任务并立即异步开始执行，返回TaskID供调用方之后用Status/Drain查询结果，
+// 执行本身用独立的context，不会因为本次RPC调用返回而被取消
+func (q *persistentTaskQueue) EnqueueAndRun(req *taskapi.TaskRequest) (string, error) {
+	id, err := q.Enqueue(req)
+	if err != nil {
+		return "", err
+	}
+	entry, _ := q.Status(id)
+	go q.runEntry(context.Background(), entry)
+	return id, nil
+}
+
+// Status 返回指定TaskID当前状态的快照，第二个返回值为false表示该TaskID不存在
+// （从未提交过，或已经被Drain收割掉）
+func (q *persistentTaskQueue) Status(id string) (*taskQueueEntry, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	entry, ok := q.entries[id]
+	if !ok {
+		return nil, false
+	}
+	return entry.clone(), true
+}
+
+// Drain 取走并从队列里移除所有已到终态（done/failed）的任务
+func (q *persistentTaskQueue) Drain() []*taskQueueEntry {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var drained []*taskQueueEntry
+	for id, entry := range q.entries {
+		if entry.Status == taskQueueDone || entry.Status == taskQueueFailed {
+			drained = append(drained, entry.clone())
+			delete(q.entries, id)
+		}
+	}
+	if len(drained) > 0 {
+		if err := q.compactLocked(); err != nil {
+			log.Printf("[任务队列] 压缩WAL失败: %v", err)
+		}
+	}
+	return drained
+}
+
+// markRunning 把任务标记为正在执行并记录一次尝试，执行结果未知前先落盘这条记录，
+// 这样即使本次执行过程中进程崩溃，ResumePending在下次启动时也能发现并重新执行它
+func (q *persistentTaskQueue) markRunning(id string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	entry, ok := q.entries[id]
+	if !ok {
+		return
+	}
+	entry.Status = taskQueueRunning
+	entry.Attempts++
+	entry.UpdatedAt = time.Now()
+	if err := q.appendLocked(entry); err != nil {
+		log.Printf("[任务队列] 记录任务 %s 状态失败: %v", id, err)
+	}
+}
+
+// markFinished 根据一次执行的结果推进任务状态：成功则done；失败且还有重试次数则退回pending
+// 等待下次ResumePending或新一轮调用重新执行；重试次数耗尽则固定为failed
+func (q *persistentTaskQueue) markFinished(id string, resp *taskapi.TaskResponse, execErr error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	entry, ok := q.entries[id]
+	if !ok {
+		return
+	}
+	entry.UpdatedAt = time.Now()
+
+	if execErr == nil && resp != nil && resp.ErrorMessage == "" {
+		entry.Status = taskQueueDone
+		entry.Response = resp
+		entry.ErrorMessage = ""
+	} else {
+		if execErr == nil && resp != nil {
+			execErr = fmt.Errorf("%s", resp.ErrorMessage)
+		}
+		entry.ErrorMessage = execErr.Error()
+		if entry.Attempts < taskQueueMaxAttempts {
+			entry.Status = taskQueuePending
+		} else {
+			entry.Status = taskQueueFailed
+		}
+	}
+
+	if err := q.appendLocked(entry); err != nil {
+		log.Printf("[任务队列] 记录任务 %s 状态失败: %v", id, err)
+	}
+}
+
+// runEntry 实际执行一个任务条目：标记running、复用Crawler.executeTask完成请求
+// （沿用与Execute相同的配额、调度和统计逻辑），再把结果写回队列
+func (q *persistentTaskQueue) runEntry(ctx context.Context, entry *taskQueueEntry) {
+	q.markRunning(entry.TaskID)
+	resp, err := q.crawler.executeTask(ctx, transportGRPC, entry.Request, 0)
+	q.markFinished(entry.TaskID, resp, err)
+}
+
+// ResumePending 在Crawler启动时调用一次，为所有上次退出时仍处于pending或running状态的任务
+// 重新发起执行，使提交过的任务在进程重启后也能自动跑完，而不需要调用方重新提交
+func (q *persistentTaskQueue) ResumePending(ctx context.Context) {
+	q.mu.Lock()
+	var pending []*taskQueueEntry
+	for _, entry := range q.entries {
+		if entry.Status == taskQueuePending || entry.Status == taskQueueRunning {
+			pending = append(pending, entry.clone())
+		}
+	}
+	q.mu.Unlock()
+
+	for _, entry := range pending {
+		log.Printf("[任务队列] 恢复未完成任务 %s（上次状态: %s, 已尝试 %d 次）", entry.TaskID, entry.Status, entry.Attempts)
+		go q.runEntry(ctx, entry)
+	}
+}
+
+// retrySweep 重新发起所有当前处于pending状态的任务，供retrySweepLoop定期调用：markFinished
+// 在还有重试次数时只是把任务退回pending，不会自己重新入队执行，需要这个扫描才能让失败任务
+// 在进程不重启的情况下也被自动重试，而不是像之前那样只在ResumePending跑过一次的下次启动前
+// 一直卡在pending。只扫描pending、不碰running——running的任务已经有一个runEntry goroutine在
+// 跑，扫描到它再起一个会导致同一个任务被并发执行两次。
+func (q *persistentTaskQueue) retrySweep(ctx context.Context) {
+	q.mu.Lock()
+	var pending []*taskQueueEntry
+	for _, entry := range q.entries {
+		if entry.Status == taskQueuePending {
+			pending = append(pending, entry.clone())
+		}
+	}
+	q.mu.Unlock()
+
+	for _, entry := range pending {
+		log.Printf("[任务队列] 定期重试扫描重新发起任务 %s（已尝试 %d 次）", entry.TaskID, entry.Attempts)
+		go q.runEntry(ctx, entry)
+	}
+}
+
+// retrySweepLoop按TaskAPIConfig.RetrySweepIntervalSeconds周期调用retrySweep，
+// c.stopChan关闭时退出，与reapExpiredIPs等其余后台goroutine同样的起法。
+func (c *Crawler) retrySweepLoop() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(c.config.TaskAPI.GetRetrySweepInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.taskQueue.retrySweep(context.Background())
+		case <-c.stopChan:
+			return
+		}
+	}
+}
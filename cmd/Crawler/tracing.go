@@ -0,0 +1,17 @@
+package main
+
+import (
+	"context"
+
+	"utlsProxy/internal/tracing"
+)
+
+// startSpan是internal/tracing.StartSpan的瘦封装，从c.tracingEnabled/c.tracingService
+// 原子读取当前开关状态和服务名并注入ctx——调用方（executeTask、handleTaskRequest等）
+// 不需要关心tracer是怎么配置进ctx的，直接c.startSpan(ctx, "name")即可，未启用追踪时
+// 返回的span是禁用状态，span.End()直接跳过。
+func (c *Crawler) startSpan(ctx context.Context, name string) (context.Context, *tracing.Span) {
+	serviceName, _ := c.tracingService.Load().(string)
+	ctx = tracing.WithTracer(ctx, c.tracingEnabled.Load(), serviceName)
+	return tracing.StartSpan(ctx, name)
+}
@@ -9,13 +9,19 @@ const (
 	transportQUIC
 )
 
-type transportMetrics struct {
-	requests      *int64
-	success       *int64
-	failed        *int64
-	requestBytes  *int64
-	responseBytes *int64
-	duration      *int64
+// TransportStats是单个传输（gRPC/QUIC/未来的KCP等）各自的请求/成功/失败/流量/耗时/会话/
+// 活跃流计数，字段只用atomic包原子读写，不加锁——CrawlerStats.Transports的键集合在
+// newCrawlerStats里一次性建好，运行期只会并发修改某个*TransportStats内部的字段，不会
+// 并发读写map本身。JSON标签供GetStats RPC/健康检查HTTP端点直接序列化返回。
+type TransportStats struct {
+	Requests       int64 `json:"requests"`
+	Success        int64 `json:"success"`
+	Failed         int64 `json:"failed"`
+	RequestBytes   int64 `json:"requestBytes"`
+	ResponseBytes  int64 `json:"responseBytes"`
+	DurationMicros int64 `json:"durationMicros"`
+	Sessions       int64 `json:"sessions"`
+	ActiveStreams  int64 `json:"activeStreams"`
 }
 
 func (t transportKind) label() string {
@@ -33,28 +39,16 @@ func (t transportKind) prefix() string {
 	return strings.ToLower(t.label())
 }
 
-func (c *Crawler) metricsForTransport(t transportKind) transportMetrics {
-	switch t {
-	case transportGRPC:
-		return transportMetrics{
-			requests:      &c.stats.GRPCRequests,
-			success:       &c.stats.GRPCSuccess,
-			failed:        &c.stats.GRPCFailed,
-			requestBytes:  &c.stats.GRPCRequestBytes,
-			responseBytes: &c.stats.GRPCResponseBytes,
-			duration:      &c.stats.GRPCDuration,
-		}
-	case transportQUIC:
-		return transportMetrics{
-			requests:      &c.stats.QUICRequests,
-			success:       &c.stats.QUICSuccess,
-			failed:        &c.stats.QUICFailed,
-			requestBytes:  &c.stats.QUICRequestBytes,
-			responseBytes: &c.stats.QUICResponseBytes,
-			duration:      &c.stats.QUICDuration,
-		}
-	default:
-		return transportMetrics{}
+// unknownTransportStats是statsFor在遇到未知transportKind时返回的兜底实例，保证调用方
+// 永远拿到非nil指针、不用额外判空；这些计数不属于任何已知传输，只会原地被丢弃。
+var unknownTransportStats TransportStats
+
+// statsFor返回t在c.stats.Transports里对应的*TransportStats，取代过去按字段名散落的
+// metricsForTransport/transportMetrics组合——调用方直接原子读写返回值的字段即可，
+// 新增一种传输只需要在newCrawlerStats里补一个map条目。
+func (c *Crawler) statsFor(t transportKind) *TransportStats {
+	if ts, ok := c.stats.Transports[t]; ok {
+		return ts
 	}
+	return &unknownTransportStats
 }
-
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+
+	"utlsProxy/internal/coordapi"
+	"utlsProxy/internal/taskapi"
+)
+
+// worker_client.go实现Coordinator.Role="worker"一侧的逻辑：向CoordinatorAddress注册并拿到
+// 一组八叉树根节点前缀，给每个前缀各起一轮独立的octreeCrawler遍历（与runJob为定时任务起
+// 遍历是同一种用法），再定期心跳并把本地ipAccessControl自上次上报以来新增的白/黑名单IP
+// 上报给coordinator、把coordinator合并后的全量结果拉回本地——本地与coordinator两份名单
+// 因此逐步趋同，但worker即使暂时连不上coordinator也能继续用已有名单正常抓取。
+
+// workerIPSnapshot记录上一次上报时的本地名单，供下一轮上报算出"自上次以来新增的IP"这个差集
+type workerIPSnapshot struct {
+	whitelisted map[string]bool
+	blacklisted map[string]bool
+}
+
+func newWorkerIPSnapshot() *workerIPSnapshot {
+	return &workerIPSnapshot{whitelisted: make(map[string]bool), blacklisted: make(map[string]bool)}
+}
+
+// diff返回当前名单相对snapshot新增的部分，并把snapshot更新为当前名单
+func (snap *workerIPSnapshot) diff(whitelist, blacklist []string) (newWhite, newBlack []string) {
+	for _, ip := range whitelist {
+		if !snap.whitelisted[ip] {
+			newWhite = append(newWhite, ip)
+		}
+	}
+	for _, ip := range blacklist {
+		if !snap.blacklisted[ip] {
+			newBlack = append(newBlack, ip)
+		}
+	}
+	snap.whitelisted = toIPSet(whitelist)
+	snap.blacklisted = toIPSet(blacklist)
+	return newWhite, newBlack
+}
+
+func toIPSet(ips []string) map[string]bool {
+	set := make(map[string]bool, len(ips))
+	for _, ip := range ips {
+		set[ip] = true
+	}
+	return set
+}
+
+// workerID返回Coordinator.WorkerID，留空时用主机名+PID兜底，保证重启后仍能拿到比较稳定
+// 的身份（同一台机器、同一个配置重启后主机名不变，只有PID会变，分片不会因此漂移太远）。
+func (c *Crawler) workerID() string {
+	if c.config.Coordinator.WorkerID != "" {
+		return c.config.Coordinator.WorkerID
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown-host"
+	}
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
+
+// runAsWorker在Coordinator.Enabled且Role="worker"时向CoordinatorAddress注册、起per-前缀
+// 遍历并循环心跳/同步IP名单，Role不是"worker"时直接返回，作为c.wg纳入Stop优雅关闭的
+// 后台goroutine，与runScheduledJobs等其余后台goroutine的启动方式一致。
+func (c *Crawler) runAsWorker() {
+	defer c.wg.Done()
+	if !c.config.Coordinator.Enabled || c.config.Coordinator.Role != "worker" {
+		return
+	}
+	if c.config.Coordinator.CoordinatorAddress == "" {
+		log.Printf("[协调worker] CoordinatorAddress 未配置，worker 模式无法启动")
+		return
+	}
+
+	conn, err := taskapi.DialJSON(c.config.Coordinator.CoordinatorAddress)
+	if err != nil {
+		log.Printf("[协调worker] 连接协调节点 %s 失败: %v", c.config.Coordinator.CoordinatorAddress, err)
+		return
+	}
+	defer conn.Close()
+
+	client := coordapi.NewCoordinatorServiceClient(conn)
+	workerID := c.workerID()
+
+	regCtx, cancel := context.WithTimeout(c.coordinatorAuthContext(context.Background()), 10*time.Second)
+	resp, err := client.RegisterWorker(regCtx, &coordapi.RegisterWorkerRequest{WorkerID: workerID})
+	cancel()
+	if err != nil {
+		log.Printf("[协调worker] 向协调节点注册失败: %v", err)
+		return
+	}
+	log.Printf("[协调worker] 已向协调节点注册为 %q，分配分片 %d/%d，前缀 %v", workerID, resp.ShardIndex, resp.ShardCount, resp.PathPrefixes)
+
+	for _, prefix := range resp.PathPrefixes {
+		c.wg.Add(1)
+		go func(prefix string) {
+			defer c.wg.Done()
+			c.runWorkerShard(prefix)
+		}(prefix)
+	}
+
+	c.workerHeartbeatLoop(client, workerID)
+}
+
+// runWorkerShard用c.config.RockTreeDataConfig.MaxTraversalDepth为本地分配到的单个根前缀
+// 起一轮octreeCrawler遍历，与runJob对定时任务起遍历是同一种用法，只是种子路径是分片前缀
+// 而不是某个CrawlJobConfig.PathTemplate。
+func (c *Crawler) runWorkerShard(prefix string) {
+	allowedIPs := c.ipAccessControl.GetAllowedIPs()
+	if len(allowedIPs) == 0 {
+		log.Printf("[协调worker] 白名单为空，分片 %q 暂不执行", prefix)
+		return
+	}
+
+	maxDepth := c.config.RockTreeDataConfig.MaxTraversalDepth
+	if maxDepth <= 0 {
+		maxDepth = octreeLevelsPerBulkPacket
+	}
+
+	dedupIndexPath := c.config.RockTreeDataConfig.DedupIndexPath
+	if dedupIndexPath == "" {
+		dedupIndexPath = fmt.Sprintf("%s/dedup_index_shard_%s.log", c.dataDir, prefix)
+	}
+	dedup, err := newPathDedupIndex(c.config.RockTreeDataConfig.DedupEnabled, dedupIndexPath)
+	if err != nil {
+		log.Printf("[协调worker] 警告: 初始化分片 %q 去重索引失败，本轮遍历将不做持久化去重: %v", prefix, err)
+		dedup, _ = newPathDedupIndex(false, "")
+	}
+	defer dedup.Close()
+
+	fetchLimiter := newTaskScheduler(len(allowedIPs))
+	fetchAIMD := newAIMDController(len(allowedIPs), 1, len(allowedIPs)*4, 1, 0.5, fetchLimiter.SetCapacity)
+
+	oc := &octreeCrawler{
+		c:                c,
+		maxDepth:         maxDepth,
+		allowedIPs:       c.ipAccessControl.GetAllowedIPs,
+		dedup:            dedup,
+		fetchLimiter:     fetchLimiter,
+		fetchAIMD:        fetchAIMD,
+		storageKeyPrefix: fmt.Sprintf("worker-shard-%s", prefix),
+	}
+	oc.run(prefix)
+
+	log.Printf("[协调worker] 分片 %q 遍历完成: BulkMetadata=%d, NodeData=%d, Imagery=%d, 失败=%d",
+		prefix, oc.bulkFetched, oc.nodesFetched, oc.imageFetched, oc.fetchFailures)
+}
+
+// workerHeartbeatLoop按HeartbeatIntervalSeconds周期性心跳，并在每次心跳时顺带上报本地
+// 新发现的白/黑名单IP、拉取coordinator合并后的全量名单写回本地，c.stopChan关闭时退出。
+func (c *Crawler) workerHeartbeatLoop(client coordapi.CoordinatorServiceClient, workerID string) {
+	interval := c.config.Coordinator.GetHeartbeatInterval()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	snapshot := newWorkerIPSnapshot()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.workerSyncOnce(client, workerID, snapshot)
+		case <-c.stopChan:
+			return
+		}
+	}
+}
+
+// coordinatorAuthContext在CoordinatorConfig.AuthToken非空时把它附加到ctx的outgoing metadata，
+// 供coordinatorAuthInterceptor校验，token为空时原样返回ctx（未启用鉴权）。
+func (c *Crawler) coordinatorAuthContext(ctx context.Context) context.Context {
+	if token := c.config.Coordinator.AuthToken; token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, authMetadataKey, token)
+	}
+	return ctx
+}
+
+func (c *Crawler) workerSyncOnce(client coordapi.CoordinatorServiceClient, workerID string, snapshot *workerIPSnapshot) {
+	ctx, cancel := context.WithTimeout(c.coordinatorAuthContext(context.Background()), 10*time.Second)
+	defer cancel()
+
+	if _, err := client.Heartbeat(ctx, &coordapi.HeartbeatRequest{WorkerID: workerID}); err != nil {
+		log.Printf("[协调worker] 心跳失败: %v", err)
+		return
+	}
+
+	newWhite, newBlack := snapshot.diff(c.ipAccessControl.GetAllowedIPs(), c.ipAccessControl.GetBlockedIPs())
+	if len(newWhite) > 0 || len(newBlack) > 0 {
+		if _, err := client.ReportIPFindings(ctx, &coordapi.ReportIPFindingsRequest{
+			WorkerID:         workerID,
+			NewlyWhitelisted: newWhite,
+			NewlyBlacklisted: newBlack,
+		}); err != nil {
+			log.Printf("[协调worker] 上报IP发现失败: %v", err)
+		}
+	}
+
+	known, err := client.GetKnownIPs(ctx, &coordapi.GetKnownIPsRequest{})
+	if err != nil {
+		log.Printf("[协调worker] 拉取协调节点合并名单失败: %v", err)
+		return
+	}
+	for _, ip := range known.WhitelistedIPs {
+		c.ipAccessControl.AddIP(ip, true)
+	}
+	for _, ip := range known.BlacklistedIPs {
+		c.ipAccessControl.AddIP(ip, false)
+	}
+}
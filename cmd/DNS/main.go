@@ -2,6 +2,7 @@ package main // 主程序包
 
 import ( // 导入所需包
 	"encoding/json"    // 用于JSON编码解码
+	"flag"             // 用于解析命令行标志（-config、-set）
 	"log"              // 用于日志记录
 	"os"               // 用于操作系统功能
 	"os/signal"        // 用于处理系统信号
@@ -18,11 +19,20 @@ type DNSDatabaseConfig struct {
 
 // main 函数是程序的入口点
 func main() {
+	// 0. 解析命令行标志：-config指定配置文件路径，-set覆盖单个配置项（容器化部署场景）
+	configPath := flag.String("config", "./config/config.toml", "配置文件路径") // 配置文件路径标志
+	var overrides config.KeyValueFlags                                    // 收集-set标志，可重复指定
+	flag.Var(&overrides, "set", "覆盖单个配置项，格式为Section.Field=value，可重复指定")   // 注册-set标志
+	flag.Parse()                                                          // 解析命令行参数
+
 	// 1. 加载统一配置文件
-	cfg, err := config.LoadConfig("./config/config.toml") // 加载配置文件
-	if err != nil {                                       // 如果加载失败
+	cfg, err := config.LoadConfig(*configPath) // 加载配置文件
+	if err != nil {                            // 如果加载失败
 		log.Fatalf("加载配置文件失败: %v", err) // 记录致命错误并退出
 	}
+	if err := config.ApplyOverrides(cfg, overrides); err != nil { // 套用UTLSPROXY_环境变量和-set标志覆盖
+		log.Fatalf("应用配置覆盖失败: %v", err) // 记录致命错误并退出
+	}
 	log.Println("成功加载配置文件") // 记录日志
 
 	// 2. 从配置文件指定的路径加载DNS服务器数据库
@@ -58,6 +68,10 @@ func main() {
 		UpdateInterval: cfg.DNSDomain.GetUpdateInterval(), // 从配置文件读取更新间隔
 		StorageDir:     cfg.DNSDomain.StorageDir,          // 从配置文件读取存储目录
 		StorageFormat:  cfg.DNSDomain.StorageFormat,       // 从配置文件读取存储格式
+
+		EnrichConcurrency:  cfg.DNSDomain.EnrichConcurrency,       // 从配置文件读取IP信息查询并发度上限
+		EnrichMaxRetries:   cfg.DNSDomain.EnrichMaxRetries,        // 从配置文件读取IP信息查询最大重试次数
+		EnrichRetryBackoff: cfg.DNSDomain.GetEnrichRetryBackoff(), // 从配置文件读取IP信息查询重试退避时间
 	}
 
 	// 4. 初始化并启动监视器
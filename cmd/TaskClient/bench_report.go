@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ThroughputBucket 是吞吐量随时间变化序列里的一个采样点：Run开始后第Second秒内
+// 完成了Count个请求（不区分成功或失败，统计的是完成速率而不是成功速率）。
+type ThroughputBucket struct {
+	Second int `json:"second"`
+	Count  int `json:"count"`
+}
+
+// BenchReport 是bench子命令一次运行的完整报告，在SLOResult的分位数/错误率之上
+// 补充了吞吐量随时间的变化和按错误类型/状态码的分布，通过writeBenchReport落盘成
+// JSON和CSV两份文件——JSON保留聚合后的统计量方便人/脚本直接读取，CSV保留每个
+// 时间桶的吞吐量方便导入表格工具画图。
+type BenchReport struct {
+	SampleCount    int                `json:"sample_count"`
+	ErrorCount     int                `json:"error_count"`
+	ErrorRate      float64            `json:"error_rate"`
+	Duration       time.Duration      `json:"duration_ns"`
+	ThroughputRPS  float64            `json:"throughput_rps"`
+	P50            time.Duration      `json:"p50_ns"`
+	P90            time.Duration      `json:"p90_ns"`
+	P95            time.Duration      `json:"p95_ns"`
+	P99            time.Duration      `json:"p99_ns"`
+	Passed         bool               `json:"passed"`
+	Violations     []string           `json:"violations,omitempty"`
+	ErrorBreakdown map[string]int     `json:"error_breakdown"`
+	Throughput     []ThroughputBucket `json:"throughput_per_second"`
+}
+
+// buildBenchReport 把SLOMonitor.Run的聚合结果(result)和逐样本明细(samples)汇总成
+// 一份BenchReport：error_breakdown按BenchSample.Category计数，throughput_per_second
+// 按BenchSample.OffsetSeconds分桶计数，桶之间如果有空档（某秒没有请求完成）不补0，
+// 只输出实际发生过请求的秒数，避免稀疏压测场景下报告里全是无意义的0。
+func buildBenchReport(result *SLOResult, samples []BenchSample, totalDuration time.Duration) *BenchReport {
+	report := &BenchReport{
+		SampleCount:    result.SampleCount,
+		ErrorCount:     result.ErrorCount,
+		ErrorRate:      result.ErrorRate,
+		Duration:       totalDuration,
+		P50:            result.P50,
+		P90:            result.P90,
+		P95:            result.P95,
+		P99:            result.P99,
+		Passed:         result.Passed(),
+		Violations:     result.Violations,
+		ErrorBreakdown: make(map[string]int),
+	}
+
+	if totalDuration > 0 {
+		report.ThroughputRPS = float64(result.SampleCount) / totalDuration.Seconds()
+	}
+
+	buckets := make(map[int]int)
+	for _, s := range samples {
+		report.ErrorBreakdown[s.Category]++
+		buckets[s.OffsetSeconds]++
+	}
+
+	seconds := make([]int, 0, len(buckets))
+	for second := range buckets {
+		seconds = append(seconds, second)
+	}
+	sort.Ints(seconds)
+	for _, second := range seconds {
+		report.Throughput = append(report.Throughput, ThroughputBucket{Second: second, Count: buckets[second]})
+	}
+
+	return report
+}
+
+// writeBenchReport 把report写入basePath+".json"和basePath+".csv"两个文件，
+// JSON放完整的聚合报告，CSV放吞吐量时间序列（单独一份，方便直接拖进表格软件画图，
+// 不用先从JSON里把throughput_per_second数组挑出来）。
+func writeBenchReport(basePath string, report *BenchReport) error {
+	if err := writeBenchReportJSON(basePath+".json", report); err != nil {
+		return err
+	}
+	return writeBenchReportCSV(basePath+".csv", report.Throughput)
+}
+
+func writeBenchReportJSON(path string, report *BenchReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化报告失败: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("写入JSON报告失败: %w", err)
+	}
+	return nil
+}
+
+func writeBenchReportCSV(path string, throughput []ThroughputBucket) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("创建CSV报告失败: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	if err := writer.Write([]string{"second", "requests_completed"}); err != nil {
+		return fmt.Errorf("写入CSV表头失败: %w", err)
+	}
+	for _, bucket := range throughput {
+		row := []string{strconv.Itoa(bucket.Second), strconv.Itoa(bucket.Count)}
+		if err := writer.Write(row); err != nil {
+			return fmt.Errorf("写入CSV数据行失败: %w", err)
+		}
+	}
+	return nil
+}
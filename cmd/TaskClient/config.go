@@ -0,0 +1,86 @@
+package main
+
+import (
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// fileConfig是-config指向的TOML文件的结构，字段均为可选——零值表示"文件里没写这一项，
+// 不覆盖对应的命令行标志/环境变量/默认值"，和cliFlags里各字段一一对应，但都用基础类型
+// （RequestTimeoutSeconds/RetryDelayMS而不是time.Duration）以匹配TOML只支持的字面量形态，
+// 与config.CoordinatorConfig.HeartbeatIntervalSeconds这类字段的写法保持一致。
+type fileConfig struct {
+	ServerAddress         string `toml:"ServerAddress"`
+	AuthToken             string `toml:"AuthToken"`
+	RequestPath           string `toml:"RequestPath"`
+	ClientID              string `toml:"ClientID"`
+	RepeatCount           int    `toml:"RepeatCount"`
+	Concurrency           int    `toml:"Concurrency"`
+	RequestTimeoutSeconds int    `toml:"RequestTimeoutSeconds"`
+	MaxAttempts           int    `toml:"MaxAttempts"`
+	RetryDelayMS          int    `toml:"RetryDelayMS"`
+	OutputDir             string `toml:"OutputDir"`
+	WriterWorkers         int    `toml:"WriterWorkers"`
+	WriterQueueSize       int    `toml:"WriterQueueSize"`
+	WriterFsyncBatch      int    `toml:"WriterFsyncBatch"`
+	ReportPath            string `toml:"ReportPath"`
+}
+
+// loadFileConfig解析path指向的TOML文件；文件不存在或格式错误都直接返回error，
+// 交由调用方（resolveFlags）Fatal退出——配置文件路径是用户显式通过-config传入的，
+// 指向一个读不出来的文件通常意味着拼错了路径，静默忽略比报错更容易让人没注意到配置没生效。
+func loadFileConfig(path string) (*fileConfig, error) {
+	var cfg fileConfig
+	if _, err := toml.DecodeFile(path, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// applyFileConfig把file中的非零字段覆盖进flags，但只覆盖explicit中不存在的标志名——
+// 命令行上显式传入的标志永远优先于配置文件，见resolveFlags的优先级说明。
+func applyFileConfig(flags *cliFlags, file *fileConfig, explicit map[string]bool) {
+	if file.ServerAddress != "" && !explicit["server"] {
+		flags.serverAddress = file.ServerAddress
+	}
+	if file.AuthToken != "" && !explicit["token"] {
+		flags.authToken = file.AuthToken
+	}
+	if file.RequestPath != "" && !explicit["path"] {
+		flags.requestPath = file.RequestPath
+	}
+	if file.ClientID != "" && !explicit["client-id"] {
+		flags.defaultClientID = file.ClientID
+	}
+	if file.RepeatCount > 0 && !explicit["repeat"] {
+		flags.repeatCount = file.RepeatCount
+	}
+	if file.Concurrency > 0 && !explicit["concurrency"] {
+		flags.concurrency = file.Concurrency
+	}
+	if file.RequestTimeoutSeconds > 0 && !explicit["timeout"] {
+		flags.requestTimeout = time.Duration(file.RequestTimeoutSeconds) * time.Second
+	}
+	if file.MaxAttempts > 0 && !explicit["max-attempts"] {
+		flags.rpcMaxAttempts = file.MaxAttempts
+	}
+	if file.RetryDelayMS > 0 && !explicit["retry-delay"] {
+		flags.rpcRetryDelay = time.Duration(file.RetryDelayMS) * time.Millisecond
+	}
+	if file.OutputDir != "" && !explicit["output"] {
+		flags.outputDir = file.OutputDir
+	}
+	if file.WriterWorkers > 0 && !explicit["writer-workers"] {
+		flags.writerWorkers = file.WriterWorkers
+	}
+	if file.WriterQueueSize > 0 && !explicit["writer-queue"] {
+		flags.writerQueueSize = file.WriterQueueSize
+	}
+	if file.WriterFsyncBatch > 0 && !explicit["writer-fsync-batch"] {
+		flags.writerFsyncBatch = file.WriterFsyncBatch
+	}
+	if file.ReportPath != "" && !explicit["report"] {
+		flags.reportPath = file.ReportPath
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"time"
+)
+
+// cliFlags收拢run/single/bench三个子命令共用的连接和请求参数。
+type cliFlags struct {
+	serverAddress    string
+	authToken        string
+	requestPath      string
+	defaultClientID  string
+	repeatCount      int
+	concurrency      int
+	requestTimeout   time.Duration
+	rpcMaxAttempts   int
+	rpcRetryDelay    time.Duration
+	outputDir        string
+	writerWorkers    int
+	writerQueueSize  int
+	writerFsyncBatch int
+	writerPackTar    bool
+	contentHashNames bool
+	configPath       string
+	reportPath       string
+}
+
+// cliFlagPtrs持有flag.FlagSet.String/Int/Duration返回的指针，供flag.Parse之后读出最终值、
+// 以及fs.Visit判断某个标志是否被显式传在命令行上（区别于只是取了默认值）。
+type cliFlagPtrs struct {
+	serverAddress    *string
+	authToken        *string
+	requestPath      *string
+	defaultClientID  *string
+	repeatCount      *int
+	concurrency      *int
+	requestTimeout   *time.Duration
+	rpcMaxAttempts   *int
+	rpcRetryDelay    *time.Duration
+	outputDir        *string
+	writerWorkers    *int
+	writerQueueSize  *int
+	writerFsyncBatch *int
+	writerPackTar    *bool
+	contentHashNames *bool
+	configPath       *string
+	reportPath       *string
+}
+
+// registerCommonFlags把run/single/bench三个子命令共用的标志注册到fs上，未指定标志时
+// 回退到UTLSPROXY_TASKCLIENT_前缀环境变量，环境变量也未设置时才使用硬编码默认值——
+// 这一层的优先级（命令行标志 > 环境变量 > 硬编码默认值）在resolveFlags里还会被
+// -config指定的TOML文件再插入一层，见resolveFlags的文档注释。
+func registerCommonFlags(fs *flag.FlagSet) *cliFlagPtrs {
+	return &cliFlagPtrs{
+		serverAddress: fs.String("server", envOr("UTLSPROXY_TASKCLIENT_SERVERADDRESS", "2607:8700:5500:2943::2:9091"),
+			"任务服务地址，支持IPv4（\"172.93.47.57:9091\"）和IPv6（\"[2607:8700:5500:2943::cc67]:9091\"）；"+
+				"逗号分隔多个地址（如\"crawler-a:9091,crawler-b:9091\"）时taskapi.Dial会带健康检查地把任务轮询分给多个Crawler节点"),
+		authToken: fs.String("token", envOr("UTLSPROXY_TASKCLIENT_AUTHTOKEN", ""),
+			"与Crawler端TaskAPIConfig.AuthToken一致，留空表示服务端未启用鉴权"),
+		requestPath: fs.String("path", envOr("UTLSPROXY_TASKCLIENT_REQUESTPATH", "/rt/earth/BulkMetadata/pb=!1m2!1s3142!2u1003"),
+			"抓取请求路径"),
+		defaultClientID: fs.String("client-id", envOr("UTLSPROXY_TASKCLIENT_CLIENTID", "1"), "ClientID"),
+		repeatCount:     fs.Int("repeat", mustAtoi(envOr("UTLSPROXY_TASKCLIENT_REPEATCOUNT", "50000")), "总请求次数（仅run/bench子命令使用）"),
+		concurrency:     fs.Int("concurrency", mustAtoi(envOr("UTLSPROXY_TASKCLIENT_CONCURRENCY", "500")), "并发worker数"),
+		requestTimeout: fs.Duration("timeout", mustDuration(envOr("UTLSPROXY_TASKCLIENT_REQUESTTIMEOUT", "20s")),
+			"单次请求超时时间（增大以应对慢速IP）"),
+		rpcMaxAttempts: fs.Int("max-attempts", mustAtoi(envOr("UTLSPROXY_TASKCLIENT_RPCMAXATTEMPTS", "5")), "单个任务最大重试次数"),
+		rpcRetryDelay:  fs.Duration("retry-delay", mustDuration(envOr("UTLSPROXY_TASKCLIENT_RPCRETRYDELAY", "50ms")), "重试前的等待时间"),
+		outputDir:      fs.String("output", envOr("UTLSPROXY_TASKCLIENT_OUTPUTDIR", "/Volumes/SSD/taskclient_data"), "响应体保存目录（仅run子命令使用）"),
+		writerWorkers: fs.Int("writer-workers", mustAtoi(envOr("UTLSPROXY_TASKCLIENT_WRITERWORKERS", "0")),
+			"异步落盘worker数，<=0使用taskclient.DefaultWriterWorkers"),
+		writerQueueSize: fs.Int("writer-queue", mustAtoi(envOr("UTLSPROXY_TASKCLIENT_WRITERQUEUE", "0")),
+			"落盘队列容量，<=0使用taskclient.DefaultWriterQueueSize；队列写满时请求worker会被阻塞（背压）"),
+		writerFsyncBatch: fs.Int("writer-fsync-batch", mustAtoi(envOr("UTLSPROXY_TASKCLIENT_WRITERFSYNCBATCH", "0")),
+			"每攒够多少次写入才显式fsync一次，<=0使用taskclient.DefaultFsyncBatchSize"),
+		writerPackTar: fs.Bool("writer-pack-tar", envOr("UTLSPROXY_TASKCLIENT_WRITERPACKTAR", "false") == "true",
+			"落盘时把响应体打包进tar文件而不是各自独立的.bin文件"),
+		contentHashNames: fs.Bool("content-hash", envOr("UTLSPROXY_TASKCLIENT_CONTENTHASH", "false") == "true",
+			"按响应体sha256命名输出文件并跳过重复内容的写入，同时在output目录下维护一份"+
+				"content_index.json记录path到内容哈希的映射（仅run子命令使用）"),
+		configPath: fs.String("config", envOr("UTLSPROXY_TASKCLIENT_CONFIG", ""), "TOML配置文件路径，留空表示不使用；字段见fileConfig，未在命令行上显式指定的标志会被文件中对应的非零值覆盖"),
+		reportPath: fs.String("report", envOr("UTLSPROXY_TASKCLIENT_REPORTPATH", ""),
+			"bench子命令的报告文件路径前缀（留空表示不落盘），实际写出<report>.json和<report>.csv两个文件"),
+	}
+}
+
+// resolveFlags解析fs（args通常是flag.Args()去掉子命令名之后剩下的部分），并在-config
+// 指定了文件时用fileConfig覆盖所有"未被命令行显式指定"的字段，得到的最终优先级是
+// 命令行标志 > TOML配置文件 > UTLSPROXY_TASKCLIENT_环境变量 > 硬编码默认值——
+// 这样运维可以把一套常用参数固化进配置文件，偶尔用命令行标志临时覆盖其中一两项，
+// 不需要为了改一个并发数就重新拼一整条命令行。
+func resolveFlags(fs *flag.FlagSet, args []string) cliFlags {
+	ptrs := registerCommonFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		log.Fatalf("解析命令行参数失败: %v", err)
+	}
+
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	result := cliFlags{
+		serverAddress:    *ptrs.serverAddress,
+		authToken:        *ptrs.authToken,
+		requestPath:      *ptrs.requestPath,
+		defaultClientID:  *ptrs.defaultClientID,
+		repeatCount:      *ptrs.repeatCount,
+		concurrency:      *ptrs.concurrency,
+		requestTimeout:   *ptrs.requestTimeout,
+		rpcMaxAttempts:   *ptrs.rpcMaxAttempts,
+		rpcRetryDelay:    *ptrs.rpcRetryDelay,
+		outputDir:        *ptrs.outputDir,
+		writerWorkers:    *ptrs.writerWorkers,
+		writerQueueSize:  *ptrs.writerQueueSize,
+		writerFsyncBatch: *ptrs.writerFsyncBatch,
+		writerPackTar:    *ptrs.writerPackTar,
+		contentHashNames: *ptrs.contentHashNames,
+		configPath:       *ptrs.configPath,
+		reportPath:       *ptrs.reportPath,
+	}
+
+	if result.configPath != "" {
+		fileCfg, err := loadFileConfig(result.configPath)
+		if err != nil {
+			log.Fatalf("加载TOML配置文件失败: %v", err)
+		}
+		applyFileConfig(&result, fileCfg, explicit)
+	}
+
+	return result
+}
@@ -2,424 +2,178 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"os"
-	"path/filepath"
-	"strings"
-	"sync"
-	"sync/atomic"
+	"strconv"
 	"time"
 
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/connectivity"
-
-	"utlsProxy/internal/taskapi"
+	"utlsProxy/pkg/taskclient"
 )
 
-func main() {
-	const (
-		// serverAddress 支持IPv4和IPv6地址
-		// IPv4格式: "172.93.47.57:9091"
-		// IPv6格式: "[2607:8700:5500:2943::cc67]:9091" 或 "2607:8700:5500:2943::cc67:9091"（会自动格式化）
-		serverAddress   = "2607:8700:5500:2943::2:9091"
-		requestPath     = "/rt/earth/BulkMetadata/pb=!1m2!1s3142!2u1003"
-		defaultClientID = "1"
-		repeatCount     = 50000
-		concurrency     = 500
-		requestTimeout  = 20 * time.Second // 增加超时时间以应对慢速IP
-		rpcMaxAttempts  = 5
-		rpcRetryDelay   = 50 * time.Millisecond
-		outputDir       = "/Volumes/SSD/taskclient_data" // 响应体保存目录
-	)
-
-	if repeatCount <= 0 {
-		log.Fatal("repeatCount 必须大于 0")
-	}
-	if concurrency <= 0 {
-		log.Fatal("concurrency 必须大于 0")
+// envOr在环境变量key存在时返回其值，否则返回def，供registerCommonFlags里各标志的默认值使用——
+// TaskClient是独立的压测/基准CLI，不经过config.LoadConfig，因此沿用和Crawler/DNS同样的
+// UTLSPROXY_前缀命名习惯（此处固定用UTLSPROXY_TASKCLIENT_前缀），但按标志逐个声明，
+// 而不是像config.ApplyEnvOverrides那样反射遍历一个配置结构体——这里压根没有配置结构体。
+func envOr(key, def string) string {
+	if v, ok := os.LookupEnv(key); ok {
+		return v
 	}
+	return def
+}
 
-	// 创建输出目录
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		log.Fatalf("创建输出目录失败: %v", err)
+// mustAtoi/mustDuration解析envOr返回的默认值字符串，这些值要么来自下面写死的合法字面量、
+// 要么来自运维设置的环境变量；后者一旦写错格式，让程序在启动时就Fatal退出，比静默吞掉
+// 错误配置继续用一个意料之外的默认值跑压测更安全。
+func mustAtoi(s string) int {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		log.Fatalf("环境变量取值 %q 不是合法整数: %v", s, err)
 	}
-	log.Printf("响应体将保存到目录: %s", outputDir)
-
-	var conn *grpc.ClientConn
-	var err error
-	var client taskapi.TaskServiceClient
-	var connMutex sync.Mutex
+	return n
+}
 
-	// 建立TCP连接
-	conn, err = taskapi.Dial(serverAddress)
+func mustDuration(s string) time.Duration {
+	d, err := time.ParseDuration(s)
 	if err != nil {
-		log.Fatalf("连接任务服务失败: %v", err)
+		log.Fatalf("环境变量取值 %q 不是合法时间段: %v", s, err)
 	}
-	log.Printf("已连接到服务器（TCP传输）: %s", serverAddress)
+	return d
+}
 
-	// 等待连接就绪
-	waitForReady := func(c *grpc.ClientConn, timeout time.Duration) error {
-		ctx, cancel := context.WithTimeout(context.Background(), timeout)
-		defer cancel()
-		for {
-			state := c.GetState()
-			if state == connectivity.Ready {
-				return nil
-			}
-			if state == connectivity.Shutdown {
-				return fmt.Errorf("连接已关闭")
-			}
-			if !c.WaitForStateChange(ctx, state) {
-				return ctx.Err()
-			}
-		}
+// main按第一个非标志参数分发到run/single/bench三个子命令，每个子命令各自拥有一套
+// 独立的flag.FlagSet（共用registerCommonFlags注册的标志），不再像过去那样用一个
+// 全局的-slo布尔标志在"批量压测"和"SLO验收"两种模式之间切换——新增模式只需要
+// 新增一个子命令文件，不用在main()里堆更多if分支。
+func main() {
+	if len(os.Args) < 2 {
+		printUsage()
+		os.Exit(2)
 	}
 
-	// 等待连接就绪（最多等待5秒）
-	if err := waitForReady(conn, 5*time.Second); err != nil {
-		log.Fatalf("等待连接就绪失败: %v", err)
+	subcommand := os.Args[1]
+	args := os.Args[2:]
+
+	switch subcommand {
+	case "run":
+		runCommand(args)
+	case "single":
+		singleCommand(args)
+	case "bench":
+		benchCommand(args)
+	case "-h", "--help", "help":
+		printUsage()
+	default:
+		log.Printf("未知子命令 %q", subcommand)
+		printUsage()
+		os.Exit(2)
 	}
-	log.Printf("连接已就绪，状态: %v", conn.GetState())
-
-	defer func() { _ = conn.Close() }()
-
-	client = taskapi.NewTaskServiceClient(conn)
-
-	// 重连函数（带重连限制和互斥锁）
-	var reconnectCount int64
-	var lastReconnectTime time.Time
-	var isReconnecting int32 // 使用原子操作
-	reconnectMutex := sync.Mutex{}
-
-	reconnect := func() error {
-		// 使用原子操作检查是否正在重连
-		if !atomic.CompareAndSwapInt32(&isReconnecting, 0, 1) {
-			// 如果正在重连，等待一小段时间后返回错误，让调用者重试
-			time.Sleep(100 * time.Millisecond)
-			return fmt.Errorf("正在重连中，请稍候")
-		}
-		defer atomic.StoreInt32(&isReconnecting, 0)
-
-		reconnectMutex.Lock()
-
-		// 限制重连频率：每2秒最多重连1次
-		now := time.Now()
-		if !lastReconnectTime.IsZero() && now.Sub(lastReconnectTime) < 2*time.Second {
-			reconnectMutex.Unlock()
-			return fmt.Errorf("重连过于频繁，请稍后再试")
-		}
-		lastReconnectTime = now
-		reconnectMutex.Unlock()
-
-		connMutex.Lock()
-
-		// 关闭旧连接
-		if conn != nil {
-			_ = conn.Close()
-		}
-
-		// 建立新连接
-		newConn, err := taskapi.Dial(serverAddress)
-		if err != nil {
-			connMutex.Unlock()
-			return fmt.Errorf("重连失败: %w", err)
-		}
-
-		conn = newConn
-		client = taskapi.NewTaskServiceClient(conn)
-		reconnectCount++
-
-		connMutex.Unlock()
+}
 
-		// 等待连接就绪（最多等待3秒）
-		waitForReady := func(c *grpc.ClientConn, timeout time.Duration) error {
-			ctx, cancel := context.WithTimeout(context.Background(), timeout)
-			defer cancel()
-			for {
-				state := c.GetState()
-				if state == connectivity.Ready {
-					return nil
-				}
-				if state == connectivity.Shutdown {
-					return fmt.Errorf("连接已关闭")
-				}
-				if !c.WaitForStateChange(ctx, state) {
-					return ctx.Err()
-				}
-			}
-		}
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "用法: TaskClient <run|single|bench> [标志...]")
+	fmt.Fprintln(os.Stderr, "  run    - 按repeat/concurrency批量提交任务并把响应体落盘到output目录")
+	fmt.Fprintln(os.Stderr, "  single - 提交一次请求，打印结果后退出，用于调试单个path")
+	fmt.Fprintln(os.Stderr, "  bench  - SLO验收模式：测量延迟分位数、吞吐量和错误分布并与内置objectives比较，"+
+		"达标与否决定退出码；-report非空时把完整结果写入<report>.json/.csv")
+	fmt.Fprintln(os.Stderr, "各子命令共用的标志见 TaskClient <子命令> -h")
+}
 
-		if err := waitForReady(conn, 3*time.Second); err != nil {
-			return fmt.Errorf("等待连接就绪失败: %w", err)
-		}
+// runCommand是过去main()里的批量压测逻辑：按repeatCount生成等量的requestPath任务，
+// 用concurrency个worker通过taskclient.Client并发提交，成功的响应体落盘到outputDir。
+func runCommand(args []string) {
+	flags := resolveFlags(flag.NewFlagSet("run", flag.ExitOnError), args)
 
-		if reconnectCount <= 3 || reconnectCount%10 == 0 {
-			log.Printf("已重新连接到服务器（TCP传输）: %s (重连次数: %d, 连接状态: %v)", serverAddress, reconnectCount, conn.GetState())
-		}
+	if flags.repeatCount <= 0 {
+		log.Fatal("repeatCount 必须大于 0")
+	}
+	if flags.concurrency <= 0 {
+		log.Fatal("concurrency 必须大于 0")
+	}
 
-		return nil
+	client, err := taskclient.New(taskclient.Options{
+		ServerAddress:      flags.serverAddress,
+		AuthToken:          flags.authToken,
+		ClientID:           flags.defaultClientID,
+		RequestTimeout:     flags.requestTimeout,
+		MaxAttempts:        flags.rpcMaxAttempts,
+		RetryDelay:         flags.rpcRetryDelay,
+		Concurrency:        flags.concurrency,
+		OutputDir:          flags.outputDir,
+		WriterWorkers:      flags.writerWorkers,
+		WriterQueueSize:    flags.writerQueueSize,
+		WriterFsyncBatch:   flags.writerFsyncBatch,
+		WriterPackTar:      flags.writerPackTar,
+		ContentAddressable: flags.contentHashNames,
+	})
+	if err != nil {
+		log.Fatalf("%v", err)
 	}
+	defer func() { _ = client.Close() }()
+	log.Printf("已连接到服务器: %s", flags.serverAddress)
+	log.Printf("响应体将保存到目录: %s", flags.outputDir)
 
-	jobCount := repeatCount
-	workerCount := concurrency
-	if workerCount > jobCount {
-		workerCount = jobCount
+	paths := make([]string, flags.repeatCount)
+	for i := range paths {
+		paths[i] = flags.requestPath
 	}
 
-	var counter uint64
+	log.Printf("启动并发=%d，准备处理 %d 个任务", flags.concurrency, flags.repeatCount)
 	start := time.Now()
+	results := client.ExecuteBatch(context.Background(), paths)
+	elapsed := time.Since(start)
 
-	jobs := make(chan int, jobCount)
-	for i := 0; i < jobCount; i++ {
-		jobs <- i
+	var successCount, failCount int
+	for _, result := range results {
+		if result.Err != nil {
+			failCount++
+		} else {
+			successCount++
+		}
 	}
-	close(jobs)
-
-	var wg sync.WaitGroup
-	var successCount uint64
-	var failCount uint64
-
-	log.Printf("启动 %d 个worker goroutine，准备处理 %d 个任务", workerCount, jobCount)
-	wg.Add(workerCount)
-	for i := 0; i < workerCount; i++ {
-		go func(workerID int) {
-			defer wg.Done()
-			if workerID < 3 {
-				log.Printf("[Worker %d] 已启动", workerID)
-			}
-			for idx := range jobs {
-				if workerID < 3 && idx < 3 {
-					log.Printf("[Worker %d] 开始处理任务 %d", workerID, idx)
-				}
-				id := defaultClientID
-				if id == "" {
-					current := atomic.AddUint64(&counter, 1)
-					id = fmt.Sprintf("client-%d-%d", time.Now().UnixNano(), current)
-				}
-
-				var success bool
-				for attempt := 1; attempt <= rpcMaxAttempts; attempt++ {
-					ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
-
-					// 获取当前客户端连接（可能需要加锁）
-					connMutex.Lock()
-					currentClient := client
-					currentConn := conn
-					connMutex.Unlock()
-
-					// 检查连接状态，如果不是 READY 则等待或重连
-					if currentConn != nil {
-						state := currentConn.GetState()
-						if state != connectivity.Ready {
-							if idx < 5 {
-								log.Printf("[任务 %d] 连接状态不是 READY: %v，等待或重连", idx, state)
-							}
-							// 如果连接正在连接中，等待一小段时间
-							if state == connectivity.Connecting {
-								cancel() // 取消当前上下文
-								time.Sleep(500 * time.Millisecond)
-								// 再次检查状态
-								connMutex.Lock()
-								if conn != nil && conn.GetState() == connectivity.Ready {
-									currentClient = client
-									currentConn = conn
-								}
-								connMutex.Unlock()
-								// 重新创建上下文
-								ctx, cancel = context.WithTimeout(context.Background(), requestTimeout)
-							} else if state == connectivity.TransientFailure || state == connectivity.Shutdown {
-								// 连接失败，尝试重连
-								cancel() // 取消当前上下文
-								if reconnectErr := reconnect(); reconnectErr == nil {
-									connMutex.Lock()
-									currentClient = client
-									currentConn = conn
-									connMutex.Unlock()
-									// 重新创建上下文后继续循环
-									ctx, cancel = context.WithTimeout(context.Background(), requestTimeout)
-									// 继续循环，重新检查连接状态
-									continue
-								} else {
-									// 重连失败，等待后继续
-									time.Sleep(rpcRetryDelay)
-									// 重新创建上下文
-									ctx, cancel = context.WithTimeout(context.Background(), requestTimeout)
-									continue
-								}
-							}
-						}
-					}
-
-					// 调试日志：记录请求发送
-					if idx < 5 || idx%1000 == 0 {
-						log.Printf("[任务 %d] 准备发送请求（第 %d/%d 次）", idx, attempt, rpcMaxAttempts)
-					}
-
-					// 使用 goroutine 监控请求是否超时
-					type result struct {
-						resp *taskapi.TaskResponse
-						err  error
-					}
-					done := make(chan result, 1)
-
-					go func() {
-						// 在goroutine中执行请求，避免变量共享问题
-						// 检查连接状态
-						connMutex.Lock()
-						connState := conn.GetState()
-						connMutex.Unlock()
-						if idx < 5 {
-							log.Printf("[任务 %d] 开始执行请求，连接状态: %v", idx, connState)
-						}
-
-						r, e := currentClient.Execute(ctx, &taskapi.TaskRequest{
-							ClientID: id,
-							Path:     requestPath,
-						})
-
-						// 请求完成后检查连接状态
-						connMutex.Lock()
-						afterState := conn.GetState()
-						connMutex.Unlock()
-						if idx < 5 && e != nil {
-							log.Printf("[任务 %d] 请求执行完成，错误: %v，连接状态: %v", idx, e, afterState)
-						}
-
-						done <- result{resp: r, err: e}
-					}()
-
-					// 等待请求完成或超时
-					var resp *taskapi.TaskResponse
-					var err error
-					select {
-					case res := <-done:
-						// 请求完成
-						resp = res.resp
-						err = res.err
-						cancel()
-					case <-ctx.Done():
-						// 请求超时
-						err = ctx.Err()
-						cancel()
-						if idx < 5 {
-							log.Printf("[任务 %d] 请求超时（第 %d/%d 次）: %v", idx, attempt, rpcMaxAttempts, err)
-						}
-					}
-
-					// 调试日志：记录请求结果
-					if idx < 5 || idx%1000 == 0 {
-						if err != nil {
-							log.Printf("[任务 %d] 请求失败（第 %d/%d 次）: %v", idx, attempt, rpcMaxAttempts, err)
-						} else if resp != nil {
-							log.Printf("[任务 %d] 请求成功（第 %d/%d 次）: status=%d", idx, attempt, rpcMaxAttempts, resp.StatusCode)
-						}
-					}
-
-					if err != nil {
-						// 检查是否是连接错误，如果是则尝试重连
-						errStr := err.Error()
-						isConnectionError := strings.Contains(errStr, "closed pipe") ||
-							strings.Contains(errStr, "connection error") ||
-							strings.Contains(errStr, "transport is closing") ||
-							strings.Contains(errStr, "connection refused") ||
-							strings.Contains(errStr, "Unavailable") ||
-							strings.Contains(errStr, "the client connection is closing")
-
-						// 检查连接状态，只有在连接确实失败时才重连
-						connMutex.Lock()
-						connState := conn.GetState()
-						connMutex.Unlock()
-
-						// 只有在连接状态是TransientFailure或Shutdown时才重连
-						shouldReconnect := isConnectionError &&
-							(connState == connectivity.TransientFailure || connState == connectivity.Shutdown) &&
-							attempt < rpcMaxAttempts
-
-						if shouldReconnect {
-							// 尝试重连（只在不是最后一次尝试时重连）
-							cancel() // 取消当前上下文
-							if reconnectErr := reconnect(); reconnectErr == nil {
-								// 重连成功，重新创建上下文并重试请求
-								ctx, cancel = context.WithTimeout(context.Background(), requestTimeout)
-								continue // 重试请求
-							} else {
-								// 重连失败或被限制，等待后继续
-								if attempt == rpcMaxAttempts-1 {
-									log.Printf("[任务 %d] 重连失败或被限制（第 %d/%d 次）: %v", idx, attempt, rpcMaxAttempts, reconnectErr)
-								}
-								// 重新创建上下文
-								ctx, cancel = context.WithTimeout(context.Background(), requestTimeout)
-							}
-						} else if isConnectionError && connState == connectivity.Connecting {
-							// 连接正在连接中，等待一段时间后重试
-							cancel()
-							time.Sleep(500 * time.Millisecond)
-							// 继续循环，下次循环会重新创建上下文
-							continue
-						}
-
-						if attempt == rpcMaxAttempts {
-							atomic.AddUint64(&failCount, 1)
-							log.Printf("[任务 %d] gRPC 调用失败（第 %d/%d 次）: %v", idx, attempt, rpcMaxAttempts, err)
-						}
-						// 只在最后一次尝试失败时记录日志，减少日志输出
-						cancel() // 确保取消上下文
-						time.Sleep(rpcRetryDelay)
-						// 继续循环，下次循环会重新创建上下文
-						continue
-					}
-
-					if resp.ErrorMessage != "" {
-						if attempt == rpcMaxAttempts {
-							atomic.AddUint64(&failCount, 1)
-							log.Printf("[任务 %d] 服务器返回错误（第 %d/%d 次）: %s (status=%d)", idx, attempt, rpcMaxAttempts, resp.ErrorMessage, resp.StatusCode)
-						}
-						// 只在最后一次尝试失败时记录日志，减少日志输出
-						cancel() // 确保取消上下文
-						time.Sleep(rpcRetryDelay)
-						continue
-					}
-
-					// 请求成功，取消上下文
-					cancel()
-
-					atomic.AddUint64(&successCount, 1)
-
-					// 所有响应体都通过resp.Body传输，立即写入文件并释放内存
-					var bodyLen int
-					if len(resp.Body) > 0 {
-						bodyLen = len(resp.Body)
-						// 保存响应体到文件（gzip格式）
-						filename := fmt.Sprintf("task_%d_%d_%d.gz", idx, attempt, time.Now().UnixNano())
-						filePath := filepath.Join(outputDir, filename)
-						if err := os.WriteFile(filePath, resp.Body, 0644); err != nil {
-							// 只在保存失败时记录日志
-							log.Printf("[任务 %d] 警告: 保存响应体到文件失败: %v", idx, err)
-						}
-						// 立即释放响应体内存，避免内存累积
-						resp.Body = nil
-					}
-
-					// 采样日志：每1000次成功记录一次，减少日志输出和内存占用
-					successCountValue := atomic.LoadUint64(&successCount)
-					if successCountValue%1000 == 0 {
-						log.Printf("[任务 %d] 成功（第 %d/%d 次）: client_id=%s status=%d body_len=%d", idx, attempt, rpcMaxAttempts, resp.ClientID, resp.StatusCode, bodyLen)
-					}
+	log.Printf("任务发送完成，耗时 %v，成功 %d，失败 %d", elapsed, successCount, failCount)
+}
 
-					success = true
-					break
-				}
+// singleCommand提交一次请求并打印结果，不落盘，用于确认某个path本身能不能跑通，
+// 不需要为了调试一个请求就跑一遍repeat=50000的run子命令。
+func singleCommand(args []string) {
+	flags := resolveFlags(flag.NewFlagSet("single", flag.ExitOnError), args)
+
+	client, err := taskclient.New(taskclient.Options{
+		ServerAddress:  flags.serverAddress,
+		AuthToken:      flags.authToken,
+		ClientID:       flags.defaultClientID,
+		RequestTimeout: flags.requestTimeout,
+		MaxAttempts:    flags.rpcMaxAttempts,
+		RetryDelay:     flags.rpcRetryDelay,
+	})
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	defer func() { _ = client.Close() }()
 
-				if !success {
-					log.Printf("[任务 %d] 所有尝试均失败", idx)
-				}
-			}
-		}(i)
+	result := client.Execute(context.Background(), flags.requestPath)
+	if result.Err != nil {
+		log.Fatalf("请求失败（尝试 %d 次）: %v", result.Attempts, result.Err)
 	}
 
-	wg.Wait()
+	log.Printf("请求成功（尝试 %d 次）: status=%d body_len=%d", result.Attempts, result.Response.StatusCode, len(result.Response.Body))
+}
 
-	elapsed := time.Since(start)
-	log.Printf("任务发送完成，耗时 %v，成功 %d，失败 %d", elapsed, successCount, failCount)
+// benchCommand是过去-slo标志开启的SLO验收模式：以run_slo_acceptance同样的内置objectives
+// 衡量一段时间内的延迟分位数（含p50/p90/p95/p99）、吞吐量随时间的变化和按错误类型/状态码
+// 的分布，未达标时以非零状态码退出，适合接入CI流水线；-report非空时额外把完整结果写入
+// <report>.json和<report>.csv，不再只靠一行汇总日志。
+func benchCommand(args []string) {
+	flags := resolveFlags(flag.NewFlagSet("bench", flag.ExitOnError), args)
+
+	objectives := SLOObjectives{
+		P50Max:       500 * time.Millisecond,
+		P95Max:       2 * time.Second,
+		P99Max:       5 * time.Second,
+		MaxErrorRate: 0.01,
+	}
+	runSLOAcceptance(flags.serverAddress, flags.authToken, flags.defaultClientID, flags.requestPath,
+		flags.repeatCount, flags.concurrency, flags.requestTimeout, objectives, flags.reportPath)
 }
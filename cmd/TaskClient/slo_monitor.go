@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/status"
+
+	"utlsProxy/internal/taskapi"
+	"utlsProxy/pkg/taskclient"
+)
+
+// SLOObjectives 定义一次验收测试需要满足的延迟和错误率目标，<=0表示不检查该项
+type SLOObjectives struct {
+	P50Max       time.Duration // P50延迟上限
+	P95Max       time.Duration // P95延迟上限
+	P99Max       time.Duration // P99延迟上限
+	MaxErrorRate float64       // 允许的最大错误率（0~1），例如0.01表示最多1%
+}
+
+// SLOResult 描述一次SLO监控实测到的延迟分位数、错误率，以及与SLOObjectives比较后的违规项
+type SLOResult struct {
+	SampleCount int
+	ErrorCount  int
+	ErrorRate   float64
+	P50         time.Duration
+	P90         time.Duration
+	P95         time.Duration
+	P99         time.Duration
+	Violations  []string // 未达标的具体指标描述，空表示全部达标
+}
+
+// BenchSample 记录单次采样的延迟、响应状态和耗时发生在本次Run开始后的第几秒，
+// 供bench_report.go计算吞吐量随时间的变化和按错误类型/状态码的错误分布，
+// 而不是像SLOResult那样只保留聚合后的分位数和错误率。
+type BenchSample struct {
+	OffsetSeconds int // 相对Run开始时间的偏移，向下取整到秒，用于吞吐量分桶
+	Latency       time.Duration
+	StatusCode    int32  // 请求成功（服务端未返回ErrorMessage）时的HTTP状态码，其余情况为0
+	Category      string // "ok"、"server:<status>"，或grpc状态码名称（如"DeadlineExceeded"、"Unavailable"）
+}
+
+// categorizeSample把一次采样的err/resp归类成一个简短的错误类型标签，用于错误分布统计：
+// 请求层面没出错（err为nil且resp.ErrorMessage为空）归为"ok"；服务端显式返回了错误
+// 归为"server:<status>"；其余情况（连接失败、超时、被取消等）用grpc状态码名称区分，
+// 因为transport.Execute的错误大多来自底层的gRPC调用，status.Code能统一识别这些错误，
+// 非gRPC错误（如QUICTransport）则归为status.Code默认返回的"Unknown"。
+func categorizeSample(err error, resp *taskapi.TaskResponse) string {
+	if err == nil && resp != nil && resp.ErrorMessage == "" {
+		return "ok"
+	}
+	if err == nil && resp != nil {
+		return fmt.Sprintf("server:%d", resp.StatusCode)
+	}
+	return status.Code(err).String()
+}
+
+// Passed 返回本次SLO监控是否全部达标
+func (r *SLOResult) Passed() bool {
+	return len(r.Violations) == 0
+}
+
+// SLOMonitor 通过Transport持续发起请求，统计实际达到的p50/p95/p99延迟和错误率，
+// 并与配置的SLOObjectives比较，用于对一次Crawler部署做CI风格的验收测试：
+// 达标与否由Run返回的SLOResult.Passed()决定，调用方据此决定进程退出码。
+type SLOMonitor struct {
+	transport   taskclient.Transport
+	objectives  SLOObjectives
+	clientID    string
+	requestPath string
+
+	startTime time.Time
+
+	mu        sync.Mutex
+	latencies []time.Duration
+	errors    int
+	samples   []BenchSample
+}
+
+// NewSLOMonitor 创建一个SLO监控器，transport通常是taskclient.GRPCTransport或taskclient.QUICTransport
+func NewSLOMonitor(transport taskclient.Transport, clientID, requestPath string, objectives SLOObjectives) *SLOMonitor {
+	return &SLOMonitor{
+		transport:   transport,
+		objectives:  objectives,
+		clientID:    clientID,
+		requestPath: requestPath,
+	}
+}
+
+// Run 以指定并发持续发起sampleCount个请求直至全部完成或ctx被取消，返回聚合后的SLOResult
+func (m *SLOMonitor) Run(ctx context.Context, sampleCount, concurrency int, requestTimeout time.Duration) *SLOResult {
+	workerCount := concurrency
+	if workerCount > sampleCount {
+		workerCount = sampleCount
+	}
+	if workerCount <= 0 {
+		workerCount = 1
+	}
+
+	jobs := make(chan struct{}, sampleCount)
+	for i := 0; i < sampleCount; i++ {
+		jobs <- struct{}{}
+	}
+	close(jobs)
+
+	m.startTime = time.Now()
+
+	var wg sync.WaitGroup
+	wg.Add(workerCount)
+	for w := 0; w < workerCount; w++ {
+		go func() {
+			defer wg.Done()
+			for range jobs {
+				select {
+				case <-ctx.Done():
+					return
+				default:
+				}
+				m.sample(ctx, requestTimeout)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return m.evaluate()
+}
+
+// sample 发起一次请求，记录其延迟、错误分类，并判断是否应计入错误
+func (m *SLOMonitor) sample(ctx context.Context, requestTimeout time.Duration) {
+	reqCtx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	reqStart := time.Now()
+	resp, err := m.transport.Execute(reqCtx, &taskapi.TaskRequest{
+		ClientID: m.clientID,
+		Path:     m.requestPath,
+	})
+	latency := time.Since(reqStart)
+
+	isError := err != nil || resp == nil || resp.ErrorMessage != ""
+	category := categorizeSample(err, resp)
+	var statusCode int32
+	if resp != nil {
+		statusCode = resp.StatusCode
+	}
+
+	m.mu.Lock()
+	m.latencies = append(m.latencies, latency)
+	if isError {
+		m.errors++
+	}
+	m.samples = append(m.samples, BenchSample{
+		OffsetSeconds: int(reqStart.Sub(m.startTime) / time.Second),
+		Latency:       latency,
+		StatusCode:    statusCode,
+		Category:      category,
+	})
+	m.mu.Unlock()
+}
+
+// Samples 返回本次Run采集到的全部BenchSample副本，供bench_report.go构建吞吐量和
+// 错误分布统计；必须在Run返回之后调用，否则可能拿到尚未完成的中间状态。
+func (m *SLOMonitor) Samples() []BenchSample {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	samples := make([]BenchSample, len(m.samples))
+	copy(samples, m.samples)
+	return samples
+}
+
+// evaluate 根据已采集的样本计算延迟分位数和错误率，并与objectives比较得出违规项
+func (m *SLOMonitor) evaluate() *SLOResult {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := &SLOResult{SampleCount: len(m.latencies), ErrorCount: m.errors}
+	if result.SampleCount > 0 {
+		result.ErrorRate = float64(m.errors) / float64(result.SampleCount)
+	}
+
+	sorted := make([]time.Duration, len(m.latencies))
+	copy(sorted, m.latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	result.P50 = latencyPercentile(sorted, 0.50)
+	result.P90 = latencyPercentile(sorted, 0.90)
+	result.P95 = latencyPercentile(sorted, 0.95)
+	result.P99 = latencyPercentile(sorted, 0.99)
+
+	if m.objectives.P50Max > 0 && result.P50 > m.objectives.P50Max {
+		result.Violations = append(result.Violations, fmt.Sprintf("P50延迟 %v 超过目标 %v", result.P50, m.objectives.P50Max))
+	}
+	if m.objectives.P95Max > 0 && result.P95 > m.objectives.P95Max {
+		result.Violations = append(result.Violations, fmt.Sprintf("P95延迟 %v 超过目标 %v", result.P95, m.objectives.P95Max))
+	}
+	if m.objectives.P99Max > 0 && result.P99 > m.objectives.P99Max {
+		result.Violations = append(result.Violations, fmt.Sprintf("P99延迟 %v 超过目标 %v", result.P99, m.objectives.P99Max))
+	}
+	if m.objectives.MaxErrorRate > 0 && result.ErrorRate > m.objectives.MaxErrorRate {
+		result.Violations = append(result.Violations, fmt.Sprintf("错误率 %.2f%% 超过目标 %.2f%%", result.ErrorRate*100, m.objectives.MaxErrorRate*100))
+	}
+
+	return result
+}
+
+// runSLOAcceptance 建立gRPC传输、运行一轮SLO监控，把完整结果写入reportPath指定的
+// JSON/CSV报告（reportPath为空时跳过落盘），并在未达标时以非零状态码退出进程，
+// 用于CI流水线中对一次Crawler部署做验收测试
+func runSLOAcceptance(serverAddress, authToken, clientID, requestPath string, sampleCount, concurrency int, requestTimeout time.Duration, objectives SLOObjectives, reportPath string) {
+	transport, err := taskclient.NewGRPCTransport(serverAddress, authToken)
+	if err != nil {
+		log.Fatalf("连接任务服务失败: %v", err)
+	}
+	defer func() { _ = transport.Close() }()
+
+	log.Printf("[SLO验收] 已连接到服务器: %s，样本数=%d，并发=%d", serverAddress, sampleCount, concurrency)
+
+	monitor := NewSLOMonitor(transport, clientID, requestPath, objectives)
+	runStart := time.Now()
+	result := monitor.Run(context.Background(), sampleCount, concurrency, requestTimeout)
+	totalDuration := time.Since(runStart)
+
+	report := buildBenchReport(result, monitor.Samples(), totalDuration)
+
+	log.Printf("[SLO验收] 样本数=%d 错误数=%d 错误率=%.2f%% 吞吐=%.1f req/s P50=%v P90=%v P95=%v P99=%v",
+		result.SampleCount, result.ErrorCount, result.ErrorRate*100, report.ThroughputRPS,
+		result.P50, result.P90, result.P95, result.P99)
+	for category, count := range report.ErrorBreakdown {
+		log.Printf("[SLO验收] 错误分类 %s: %d", category, count)
+	}
+
+	if reportPath != "" {
+		if err := writeBenchReport(reportPath, report); err != nil {
+			log.Printf("[SLO验收] 写入报告失败: %v", err)
+		} else {
+			log.Printf("[SLO验收] 报告已写入 %s.json / %s.csv", reportPath, reportPath)
+		}
+	}
+
+	if result.Passed() {
+		log.Println("[SLO验收] 全部指标达标")
+		return
+	}
+
+	for _, violation := range result.Violations {
+		log.Printf("[SLO验收] 警告: %s", violation)
+	}
+	os.Exit(1)
+}
+
+// latencyPercentile 返回已排序的延迟切片中指定分位数（0~1）对应的值，切片为空时返回0
+func latencyPercentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(float64(len(sorted)-1) * p)
+	return sorted[idx]
+}
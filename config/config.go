@@ -19,6 +19,212 @@ type Config struct {
 	HotConnPool            HotConnPoolConfig      `toml:"HotConnPool"`            // 热连接池配置
 	RockTreeDataConfig     RockTreeDataConfig     `toml:"RockTreeDataConfig"`     // RockTree数据配置
 	EarthImageryDataConfig EarthImageryDataConfig `toml:"EarthImageryDataConfig"` // Earth影像数据配置
+	ThroughputConfig       ThroughputConfig       `toml:"ThroughputConfig"`       // 吞吐量目标配置
+	Compression            CompressionConfig      `toml:"Compression"`            // 落盘数据压缩配置
+	StorageConfig          StorageConfig          `toml:"StorageConfig"`          // 抓取结果的落盘/上传后端配置
+	RateLimit              RateLimitConfig        `toml:"RateLimit"`              // 全局/按IP令牌桶限速配置
+	TaskAPI                TaskAPIConfig          `toml:"TaskAPI"`                // TaskService鉴权与按客户端配额配置
+	StaticIPs              []StaticIPEntry        `toml:"StaticIPs"`              // 启动时手工注入的静态IP列表
+	// HeaderProfiles 按path前缀匹配选用专用请求头，匹配不到任何Profile时退回
+	// GetWarmupHeaders()计算出的默认请求头。按声明顺序匹配，取第一个PathPrefix是
+	// 请求path前缀的Profile。
+	HeaderProfiles []HeaderProfile `toml:"HeaderProfiles"`
+	// CrawlJobs 定义按cron表达式定时触发的命名抓取任务，见cmd/Crawler/cronjobs.go的jobScheduler，
+	// 可通过TaskService的ListCrawlJobs/ControlCrawlJob在运行期查询状态或暂停/恢复。
+	CrawlJobs []CrawlJobConfig `toml:"CrawlJobs"`
+	// Coordinator 配置分布式协调模式：多个Crawler实例里选一个当coordinator，其余当worker，
+	// 见cmd/Crawler/coordinator.go、cmd/Crawler/worker_client.go。
+	Coordinator CoordinatorConfig `toml:"Coordinator"`
+	// IPSync 配置多个Crawler实例之间的白/黑名单gossip复制，见cmd/Crawler/ipsync.go，
+	// 与Coordinator相互独立，可以单独开启（也可以同时开启：Coordinator负责任务分片，
+	// IPSync负责让各实例间的IP名单尽快收敛）。
+	IPSync IPSyncConfig `toml:"IPSync"`
+	// KCP 配置KCP传输的协议调参（NoDelay/Interval/窗口大小/MTU），服务端（ServerConfig.EnableKCP）
+	// 和客户端后续各自的KCP会话都应该从这一份配置取值，而不是各自硬编码一套。当前go.mod尚未
+	// 引入kcp-go依赖（见pkg/taskclient/transport.go文档注释），这份配置会被正常解析、校验、
+	// 填充默认值，但在真正的KCP会话实现接入之前不会被任何地方读取。
+	KCP KCPConfig `toml:"KCP"`
+}
+
+// KCPConfig KCP传输的协议调参，字段命名直接对应kcp-go的同名会话参数（NoDelay/(*UDPSession).SetNoDelay
+// 的nodelay/interval/resend/nc四个参数里的前两个单独暴露，resend/nc由NoDelay是否开启隐含，不单独开放；
+// SetWindowSize对应SndWnd/RcvWnd；SetMtu对应MTU），方便以后接入kcp-go时直接按字段名传参，
+// 不需要再做一次映射。
+type KCPConfig struct {
+	// NoDelay 是否开启快速模式（对应kcp-go nodelay=1）：开启后ACK不等待延迟确认、RTO增长更保守、
+	// 用更激进的快速重传换取更低延迟，代价是更高的带宽占用；关闭则是TCP-like的保守模式
+	NoDelay bool `toml:"NoDelay"`
+	// IntervalMs 内部协议时钟粒度（毫秒），越小重传/拥塞探测越及时但CPU开销越高
+	IntervalMs int `toml:"IntervalMs"`
+	// SendWindow/ReceiveWindow 发送/接收滑动窗口大小，单位是包（不是字节），过小会在高延迟或
+	// 高丢包链路上限制吞吐量
+	SendWindow    int `toml:"SendWindow"`
+	ReceiveWindow int `toml:"ReceiveWindow"`
+	// MTU 单个KCP包的最大负载字节数，需要小于链路实际MTU减去UDP/IP头部开销（通常1400左右比较
+	// 安全），设得过大会在有损网络上被分片，反而更容易丢包
+	MTU int `toml:"MTU"`
+}
+
+// GetInterval 获取IntervalMs对应的time.Duration，<=0时返回kcp-go自身默认的40ms
+func (c *KCPConfig) GetInterval() time.Duration {
+	if c.IntervalMs <= 0 {
+		return 40 * time.Millisecond
+	}
+	return time.Duration(c.IntervalMs) * time.Millisecond
+}
+
+// CoordinatorConfig 描述分布式协调模式下本实例扮演的角色：Role为空或Enabled为false时
+// 完全不启用分布式模式，行为与单机运行完全一致。
+type CoordinatorConfig struct {
+	Enabled                  bool   `toml:"Enabled"`                  // 是否启用分布式协调模式
+	Role                     string `toml:"Role"`                     // "coordinator" 或 "worker"
+	ListenPort               int    `toml:"ListenPort"`               // Role=coordinator时协调服务的gRPC监听端口
+	ShardCount               int    `toml:"ShardCount"`               // Role=coordinator时把8个八叉树根节点前缀切分成的分片数，<=0或>8按8处理
+	CoordinatorAddress       string `toml:"CoordinatorAddress"`       // Role=worker时要连接的协调节点地址
+	WorkerID                 string `toml:"WorkerID"`                 // Role=worker时上报给协调节点的身份标识，留空则用主机名+进程PID
+	HeartbeatIntervalSeconds int    `toml:"HeartbeatIntervalSeconds"` // Role=worker时心跳/IP发现上报的间隔，<=0时使用默认值30
+	// AuthToken 要求worker在metadata的authorization字段携带的共享密钥，与TaskAPI.AuthToken/
+	// IPSync.AuthToken同样的校验方式（见checkSharedToken），留空表示不启用鉴权——但
+	// ReportIPFindings/GetKnownIPs能直接改写并分发fleet范围内的白/黑名单，生产环境强烈建议配置。
+	AuthToken string `toml:"AuthToken"`
+}
+
+// IPSyncConfig 描述白/黑名单gossip复制：全部PeerAddresses互相推送各自新观测到的IP事件，
+// 不需要选主，任一实例可随时加入或离开（离开只影响该实例自己不再收到新鲜事件，不影响
+// 其余实例之间继续复制）。
+type IPSyncConfig struct {
+	Enabled               bool     `toml:"Enabled"`               // 是否启用gossip复制
+	PeerID                string   `toml:"PeerID"`                // 本实例的身份标识，仅用于事件的OriginPeerID和日志，留空则用主机名+进程PID
+	ListenPort            int      `toml:"ListenPort"`            // 接收其他实例推送事件的gRPC监听端口
+	PeerAddresses         []string `toml:"PeerAddresses"`         // 要推送事件给哪些对等节点，地址形如"host:port"
+	GossipIntervalSeconds int      `toml:"GossipIntervalSeconds"` // 批量推送新事件的间隔，<=0时使用默认值5
+	BlacklistTTLMinutes   int      `toml:"BlacklistTTLMinutes"`   // 收到远端黑名单事件后在本地的有效期，<=0表示永不过期
+	WhitelistTTLMinutes   int      `toml:"WhitelistTTLMinutes"`   // 收到远端白名单事件后在本地的有效期，<=0表示永不过期
+	// AuthToken 要求推送方在metadata的authorization字段携带的共享密钥，与TaskAPI.AuthToken同样的
+	// 校验方式（见checkSharedToken），留空表示不启用鉴权——但PushEvents能直接改写fleet范围内的
+	// 白/黑名单，生产环境强烈建议配置，不应该比TaskService更宽松。
+	AuthToken string `toml:"AuthToken"`
+}
+
+// CrawlJobConfig 描述一个定时抓取任务：CronExpr按标准5字段cron语法（分 时 日 月 周）
+// 决定触发时机，PathTemplate是本次遍历的起始八叉树路径（空字符串表示从根节点开始，
+// 与crawlBulkMetadataBatch的默认行为一致），Depth覆盖本次遍历的RockTreeDataConfig.MaxTraversalDepth，
+// Concurrency覆盖本次遍历的初始自适应并发上限，StorageTarget非空时NodeData/Imagery的响应体会
+// 以StorageTarget为前缀落盘（经由Storage接口），空值表示和原来一样不做持久化，只探测服务端。
+type CrawlJobConfig struct {
+	Name          string `toml:"Name"`          // 任务名，ControlCrawlJob按这个名字定位任务
+	CronExpr      string `toml:"CronExpr"`      // 标准5字段cron表达式，如 "0 */6 * * *" 表示每6小时整点触发
+	PathTemplate  string `toml:"PathTemplate"`  // 起始八叉树路径，空表示从根节点开始
+	Depth         int    `toml:"Depth"`         // 本次遍历的最大展开层数，<=0时沿用RockTreeDataConfig.MaxTraversalDepth
+	Concurrency   int    `toml:"Concurrency"`   // 本次遍历的初始并发上限，<=0时沿用白名单IP数量
+	StorageTarget string `toml:"StorageTarget"` // 落盘键前缀，空表示不持久化抓取到的NodeData/Imagery
+}
+
+// StaticIPEntry 描述一组要在启动时手工注入某个域名IP池的静态IP，
+// 注入后这些IP会和DNS发现的IP一样进入白名单/预热流程，
+// 典型用途是临时追加运维自行确认可用、但DNS尚未收录或被污染的IP。
+type StaticIPEntry struct {
+	Domain              string   `toml:"Domain"`              // 要注入的目标域名，须与DNSDomain.HostName中的某一项一致
+	IPs                 []string `toml:"IPs"`                 // 要注入的IP列表，IPv4/IPv6均可，由InjectStaticIPs自动分类
+	ExpiresAfterMinutes int      `toml:"ExpiresAfterMinutes"` // 注入的IP在多少分钟后过期失效，0表示永不过期
+}
+
+// GetExpiresAt 以startTime为基准换算出该条目的绝对过期时间，ExpiresAfterMinutes<=0时返回零值表示永不过期
+func (e *StaticIPEntry) GetExpiresAt(startTime time.Time) time.Time {
+	if e.ExpiresAfterMinutes <= 0 {
+		return time.Time{}
+	}
+	return startTime.Add(time.Duration(e.ExpiresAfterMinutes) * time.Minute)
+}
+
+// TaskAPIConfig 控制TaskService对外暴露的gRPC端口的鉴权和按ClientID的配额限制，
+// 避免任何能连到该端口的客户端无限制占用白名单IP池的执行能力
+type TaskAPIConfig struct {
+	AuthToken string `toml:"AuthToken"` // 要求客户端在metadata的authorization字段携带的共享密钥，留空表示不启用鉴权
+	// ClientTokens 按客户端分别签发的鉴权token（token -> ClientID），配置后每个客户端必须携带
+	// 自己provisioning时领到的token，PerClientMaxConcurrency/PerClientRequestsPerSecond配额按
+	// token对应的ClientID计算，而不是按TaskRequest里客户端自报的ClientID——避免客户端通过更换
+	// ClientID字段绕过配额。留空时退回AuthToken的单一共享密钥模式，配额仍按TaskRequest.ClientID
+	// 计算（旧行为，要求运维自行保证客户端不会恶意轮换ClientID）。配置了ClientTokens后AuthToken
+	// 不再生效。
+	ClientTokens map[string]string `toml:"ClientTokens"`
+	// PerClientMaxConcurrency 单个ClientID同时在执行中的任务数上限，0表示不限制
+	PerClientMaxConcurrency int `toml:"PerClientMaxConcurrency"`
+	// PerClientRequestsPerSecond 单个ClientID的请求速率上限（令牌桶，桶容量等于该值），0表示不限制
+	PerClientRequestsPerSecond float64 `toml:"PerClientRequestsPerSecond"`
+	// AllowedDomains 限制TaskRequest.Domain可以指定的目标域名，为空表示不限制（包括
+	// TaskRequest未设置Domain、退回默认域名的情况）。生产部署建议显式列出允许的域名，
+	// 防止TaskService的Domain覆盖被用来把白名单IP池当作任意域名的开放代理。
+	AllowedDomains []string `toml:"AllowedDomains"`
+	// AllowedMethods 限制TaskRequest.Method可以使用的HTTP方法，为空时默认只允许GET；
+	// 需要POST等写方法时必须显式把它加入这个列表。
+	AllowedMethods []string `toml:"AllowedMethods"`
+	// RetrySweepIntervalSeconds 持久化任务队列定期重新扫描pending任务并自动重试的间隔，
+	// <=0时使用默认值30；见cmd/Crawler/task_queue.go的retrySweepLoop。
+	RetrySweepIntervalSeconds int `toml:"RetrySweepIntervalSeconds"`
+}
+
+// GetRetrySweepInterval 返回持久化任务队列重新扫描pending任务的间隔，<=0时回退到默认值30秒
+func (c *TaskAPIConfig) GetRetrySweepInterval() time.Duration {
+	if c.RetrySweepIntervalSeconds <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(c.RetrySweepIntervalSeconds) * time.Second
+}
+
+// IsDomainAllowed 判断domain是否在AllowedDomains白名单内；AllowedDomains为空表示不限制。
+func (c *TaskAPIConfig) IsDomainAllowed(domain string) bool {
+	if len(c.AllowedDomains) == 0 {
+		return true
+	}
+	for _, d := range c.AllowedDomains {
+		if strings.EqualFold(d, domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsMethodAllowed 判断method是否在AllowedMethods白名单内；method为空时按GET处理，
+// AllowedMethods为空时默认只放行GET。
+func (c *TaskAPIConfig) IsMethodAllowed(method string) bool {
+	if method == "" {
+		method = "GET"
+	}
+	if len(c.AllowedMethods) == 0 {
+		return strings.EqualFold(method, "GET")
+	}
+	for _, m := range c.AllowedMethods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// CompressionConfig 控制爬取结果落盘时是否以及如何用zstd压缩，
+// 抓取到的很多body本身就是未压缩的protobuf，压缩后通常能缩小5-10倍。
+type CompressionConfig struct {
+	Enabled bool `toml:"Enabled"` // 是否启用zstd压缩，默认关闭以保持与旧数据目录的兼容
+	Level   int  `toml:"Level"`   // 压缩档位：1=最快 2=默认 3=更高压缩率 4=最高压缩率，其余值按默认处理
+}
+
+// StorageConfig 控制saveData/loadData最终把数据写到哪：Backend为空或"local"时落到
+// DataDir所在的本地文件系统（ShardWidth>0时按文件名哈希的前几位分目录，避免单目录文件数过多）；
+// Backend为"s3"时改为上传到S3兼容的对象存储，凭证和连接参数见下面几个字段。
+type StorageConfig struct {
+	Backend string `toml:"Backend"` // 存储后端："local"（默认）或"s3"
+	// ShardWidth 本地存储按文件名SHA-256前ShardWidth个十六进制字符分出两级子目录，
+	// 0表示不分片，直接平铺在DataDir下（兼容旧数据目录）
+	ShardWidth int `toml:"ShardWidth"`
+
+	S3Endpoint     string `toml:"S3Endpoint"`     // S3兼容服务的endpoint，如 "s3.us-east-1.amazonaws.com"
+	S3Bucket       string `toml:"S3Bucket"`       // 目标bucket
+	S3Region       string `toml:"S3Region"`       // SigV4签名用的region，如 "us-east-1"
+	S3AccessKeyID  string `toml:"S3AccessKeyID"`  // Access Key ID
+	S3SecretKey    string `toml:"S3SecretKey"`    // Secret Access Key
+	S3UsePathStyle bool   `toml:"S3UsePathStyle"` // true时使用path-style endpoint/bucket/key，false使用virtual-hosted-style bucket.endpoint/key（MinIO等自建服务通常需要true）
+	S3UseTLS       bool   `toml:"S3UseTLS"`       // 是否通过https访问S3Endpoint
 }
 
 // ServerConfig 服务器配置
@@ -30,8 +236,22 @@ type ServerConfig struct {
 	QUICCertFile             string `toml:"QUICCertFile"`             // QUIC TLS 证书路径
 	QUICKeyFile              string `toml:"QUICKeyFile"`              // QUIC TLS 私钥路径
 	QUICCAFile               string `toml:"QUICCAFile"`               // QUIC 根证书（可选，用于客户端校验）
+	QUICRequireClientCert    bool   `toml:"QUICRequireClientCert"`    // 是否强制双向TLS：true时未出示QUICCAFile签发证书的客户端连接直接被拒绝，false（默认）时仅在出示证书时才校验，即QUICCAFile配置但未启用强制时的行为
 	QUICALPN                 string `toml:"QUICALPN"`                 // ALPN 标识符
 	QUICMaxIdleTimeoutSecond int    `toml:"QUICMaxIdleTimeoutSecond"` // 会话最大空闲超时（秒）
+	QUICMaxConnections       int    `toml:"QUICMaxConnections"`       // 最大并发QUIC连接数，<=0（默认）表示不限制；超出的新连接会被立即以错误码关闭，见acceptQUICConnections
+	QUICMaxStreamsPerConn    int    `toml:"QUICMaxStreamsPerConn"`    // 单个QUIC连接允许的最大并发双向流数，<=0（默认）时交给quic-go套用内置默认值
+	ACMEEnabled              bool   `toml:"ACMEEnabled"`              // 是否用ACME（Let's Encrypt等）自动申请/续期QUIC证书，开启时忽略QUICCertFile/QUICKeyFile和自签名回退
+	ACMEHostname             string `toml:"ACMEHostname"`             // 申请证书所绑定的域名，ACMEEnabled为true时必填
+	ACMEEmail                string `toml:"ACMEEmail"`                // 注册ACME账号用的联系邮箱，留空表示不提供
+	ACMECacheDir             string `toml:"ACMECacheDir"`             // 证书和账号密钥的本地缓存目录，留空默认为"acme-cache"，跨重启复用已申请到的证书
+	RandomSeed               int64  `toml:"RandomSeed"`               // 随机数种子，0表示使用基于时间的种子；用于调试时复现某次运行的指纹选择、IP选择和IPv6地址生成序列
+	HealthPort               int    `toml:"HealthPort"`               // /healthz、/readyz所在HTTP端口，0表示不启动健康检查服务
+	TracingEnabled           bool   `toml:"TracingEnabled"`           // 是否为任务执行/上游请求记录链路追踪span，见internal/tracing文档注释（当前导出到日志，尚未接入真正的OTLP collector）
+	TracingServiceName       string `toml:"TracingServiceName"`       // 上报span时使用的服务名，留空默认为"utlsProxy-crawler"
+	TracingOTLPEndpoint      string `toml:"TracingOTLPEndpoint"`      // OTLP collector地址（如"localhost:4317"），预留给接入真正的OpenTelemetry SDK后使用，当前导出器不会连接它
+	EnableKCP                bool   `toml:"EnableKCP"`                // 是否启用 KCP 传输，见pkg/taskclient/transport.go文档注释——go.mod尚未引入kcp-go依赖，启用后startKCPServer会直接返回明确的错误而不是静默不生效
+	KCPPort                  int    `toml:"KCPPort"`                  // KCP 监听端口（UDP）
 }
 
 // DNSDomainConfig DNS域名配置
@@ -48,6 +268,17 @@ type DNSDomainConfig struct {
 	HTTPMaxIdleConns           int      `toml:"HTTPMaxIdleConns"`           // HTTP最大空闲连接数
 	HTTPMaxIdleConnsPerHost    int      `toml:"HTTPMaxIdleConnsPerHost"`    // 每个主机最大空闲连接数
 	HTTPIdleConnTimeoutSeconds int      `toml:"HTTPIdleConnTimeoutSeconds"` // HTTP空闲连接超时时间（秒）
+	EnrichConcurrency          int      `toml:"EnrichConcurrency"`          // 新发现IP信息查询的最大并发数，<=0时使用默认值20，见src.MonitorConfig.EnrichConcurrency
+	EnrichMaxRetries           int      `toml:"EnrichMaxRetries"`           // 单个IP查询失败后的最大重试次数，<=0时使用默认值2，见src.MonitorConfig.EnrichMaxRetries
+	EnrichRetryBackoffSeconds  int      `toml:"EnrichRetryBackoffSeconds"`  // 查询失败后的重试退避基准时间（秒），<=0时使用默认值1，见src.MonitorConfig.EnrichRetryBackoff
+}
+
+// GetEnrichRetryBackoff 获取IP信息查询失败后的重试退避基准时间，<=0时返回0，由NewRemoteIPMonitor应用默认值
+func (c *DNSDomainConfig) GetEnrichRetryBackoff() time.Duration {
+	if c.EnrichRetryBackoffSeconds <= 0 {
+		return 0
+	}
+	return time.Duration(c.EnrichRetryBackoffSeconds) * time.Second
 }
 
 // PoolConfig 连接池配置
@@ -68,6 +299,29 @@ type UTlsClientConfig struct {
 	ReadTimeoutSeconds int `toml:"ReadTimeoutSeconds"` // 读取超时时间（秒）
 	DialTimeoutSeconds int `toml:"DialTimeoutSeconds"` // 连接超时时间（秒）
 	MaxRetries         int `toml:"MaxRetries"`         // 最大重试次数
+	// CookieJarEnabled 是否为每个域名维护一个Cookie jar，自动保存响应中的Set-Cookie并在
+	// 同一域名后续请求中回放，部分反爬系统要求会话期间Cookie保持一致，见src/cookie_jar.go
+	CookieJarEnabled bool `toml:"CookieJarEnabled"`
+	// CookieJarPath Cookie jar的持久化文件路径，跨进程重启复用已有Cookie；留空则仅保存在
+	// 内存中，进程重启后清空，仅在CookieJarEnabled为true时生效
+	CookieJarPath string `toml:"CookieJarPath"`
+	// RedirectMaxHops Do()自动跟随3xx响应Location的最大跳转次数，<=0（默认）表示不跟随，
+	// 3xx原样当作最终响应返回，与升级前的行为一致
+	RedirectMaxHops int `toml:"RedirectMaxHops"`
+	// RedirectSameDomainOnly 为true时只跟随Location指向同一域名的跳转，跨域名跳转按原样
+	// 返回3xx响应，不继续跟随
+	RedirectSameDomainOnly bool `toml:"RedirectSameDomainOnly"`
+	// MaxBodySizeMB 单次响应体最多允许读取的大小（MB），<=0（默认）表示不设上限，与升级前
+	// 的行为一致；超过限制时请求会返回显式错误，而不是先把超大body读入内存再截断，见
+	// src/UTlsClient.go的MaxBodySize/readLimitedBody
+	MaxBodySizeMB int `toml:"MaxBodySizeMB"`
+	// MaxStatusLineBytes/MaxHeaderBytes/MaxHeaderCount 限制HTTP/1.1响应头解析阶段允许读取的
+	// 状态行长度（字节）、响应头总字节数（含状态行）、响应头字段行数，均<=0（默认）表示使用
+	// src/HTTPHeaderLimits.go里的内置默认值，而不是像MaxBodySizeMB那样表示不设上限——响应头
+	// 解析是防御畸形/恶意上游的第一道关口，默认就应该带着防护
+	MaxStatusLineBytes int `toml:"MaxStatusLineBytes"`
+	MaxHeaderBytes     int `toml:"MaxHeaderBytes"`
+	MaxHeaderCount     int `toml:"MaxHeaderCount"`
 }
 
 // HotConnPoolConfig 热连接池配置
@@ -93,7 +347,61 @@ type HotConnPoolConfig struct {
 	IPRefreshIntervalMinutes     int `toml:"IPRefreshIntervalMinutes"`     // IP列表刷新间隔（分钟）
 
 	// TLS指纹配置
-	FingerprintName string `toml:"FingerprintName"` // TLS指纹名称
+	FingerprintName              string `toml:"FingerprintName"`              // TLS指纹名称
+	FingerprintStickyIP          bool   `toml:"FingerprintStickyIP"`          // 是否按目标IP粘滞指纹，而不是整个连接池共用同一个指纹
+	FingerprintSessionTTLMinutes int    `toml:"FingerprintSessionTTLMinutes"` // 粘滞指纹的会话有效期（分钟），0表示在进程生命周期内永不过期
+
+	// EmergencyResolveEnabled 为true时，如果DomainMonitor依赖的存储暂不可达、尚未产出过
+	// 任何IP数据，连接池会用系统默认解析器现场直接解析一次域名来自举，而不是一直报错等待，
+	// 见src/UtlsClientHotConnPool.go的DomainConnPoolConfig.EmergencyResolveEnabled
+	EmergencyResolveEnabled bool `toml:"EmergencyResolveEnabled"`
+
+	// PlaintextHTTP 为true时，该连接池维护的是裸TCP连接而不是uTLS连接，用于内部镜像、
+	// 测试环境等只提供HTTP而没有TLS的场景；为false（默认）时保持原有的强制TLS行为。
+	// 明文场景需要配合Port显式指定实际端口（如"80"），见
+	// src/UtlsClientHotConnPool.go的DomainConnPoolConfig.PlaintextHTTP
+	PlaintextHTTP bool `toml:"PlaintextHTTP"`
+
+	// SocketMark 大于0时，出站套接字会被打上这个fwmark（仅Linux），供多上联服务器用
+	// ip rule/ip route按mark把爬虫流量引到指定上联；<=0（默认）时不设置，见
+	// src/UtlsClientHotConnPool.go的DomainConnPoolConfig.SocketMark
+	SocketMark int `toml:"SocketMark"`
+	// DSCP 大于0时，出站套接字的IP_TOS/IPV6_TCLASS会被设成这个DSCP值（0-63，仅Linux），
+	// 供中间路由设备按DSCP做QoS分级；<=0（默认）时不设置，见
+	// src/UtlsClientHotConnPool.go的DomainConnPoolConfig.DSCP
+	DSCP int `toml:"DSCP"`
+
+	// DailyByteCapMB 大于0时，该域名的连接池在当天套接字级读写字节总数达到这个值（单位MB）后
+	// 暂停签出新连接，次日自然日切换时自动重置；用于限制metered egress服务器的单域名日流量。
+	// <=0（默认）时不设上限，见src/UtlsClientHotConnPool.go的DomainConnPoolConfig.DailyByteCap
+	DailyByteCapMB int `toml:"DailyByteCapMB"`
+
+	// PreferredCountries/PreferredASNs 非空时，拨号优先从DomainMonitor已采集的IPInfo落在这些
+	// 国家代码/ASN里的候选IP中选择，降低RTT；候选里一个匹配都没有时退回不做偏好的原有行为，
+	// 见src/UtlsClientHotConnPool.go的DomainConnPoolConfig.PreferredCountries/PreferredASNs
+	PreferredCountries []string `toml:"PreferredCountries"`
+	PreferredASNs      []string `toml:"PreferredASNs"`
+
+	// RecoveryWarmupCount 大于0时，黑名单IP探测恢复后额外并发预热这么多条连接；<=0（默认）时
+	// 只保留探测本身用掉的那一条连接，见src/UtlsClientHotConnPool.go的
+	// DomainConnPoolConfig.RecoveryWarmupCount
+	RecoveryWarmupCount int `toml:"RecoveryWarmupCount"`
+
+	// StatusPolicy 把HTTP状态码（字符串形式的键，如"429"）映射到动作名（"whitelist"/
+	// "blacklist"/"retry"/"ignore"），未配置的状态码使用内置默认规则（200=whitelist，
+	// 403=blacklist，其余=retry）。TOML不支持整数键，所以这里用字符串键，由调用方
+	// （cmd/Crawler/main.go）转换成src.StatusPolicy（map[int]src.StatusAction）喂给连接池，
+	// 见src/StatusPolicy.go。
+	StatusPolicy map[string]string `toml:"StatusPolicy"`
+}
+
+// HeaderProfile 把一个path前缀绑定到一组专用请求头，配置为TOML数组表
+// （如[[HeaderProfiles]]），Config.GetHeadersForPath按PathPrefix匹配选用对应Profile，
+// 取代过去"不管请求的是BulkMetadata、NodeData、Imagery还是dbroot、q2，都套用同一份
+// RocktreeRquestHeader/RequestHeader"的做法。
+type HeaderProfile struct {
+	PathPrefix string   `toml:"PathPrefix"` // 要匹配的path前缀，如"/rt/earth/BulkMetadata"
+	Headers    []string `toml:"Headers"`    // 该前缀专用的请求头列表，格式与RocktreeRquestHeader一致（"Key: Value"）
 }
 
 // RockTreeDataConfig RockTree数据配置
@@ -104,6 +412,14 @@ type RockTreeDataConfig struct {
 	NodeDataPath         string   `toml:"NodeDataPath"`         // 节点数据路径
 	ImageryDataPath      string   `toml:"ImageryDataPath"`      // 影像数据路径
 	RocktreeRquestHeader []string `toml:"RocktreeRquestHeader"` // 请求头列表
+	// MaxTraversalDepth 限制crawlBulkMetadataBatch对八叉树做广度优先遍历时展开的最大层数，
+	// <=0时退回octreeLevelsPerBulkPacket（单份BulkMetadata覆盖的层数，即只展开根节点那一份）。
+	MaxTraversalDepth int `toml:"MaxTraversalDepth"`
+	// DedupEnabled 启用后，已经成功抓取过的BulkMetadata/NodeData/Imagery路径会被记入
+	// DedupIndexPath指向的持久化索引，重启进程或重新跑一轮遍历时不会重复抓取同一路径。
+	DedupEnabled bool `toml:"DedupEnabled"`
+	// DedupIndexPath 持久化去重索引文件路径，留空时默认落在数据目录下的dedup_index.log
+	DedupIndexPath string `toml:"DedupIndexPath"`
 }
 
 // EarthImageryDataConfig Earth影像数据配置
@@ -116,6 +432,38 @@ type EarthImageryDataConfig struct {
 	RequestHeader   []string `toml:"requestHeader"`   // 请求头列表
 }
 
+// ThroughputConfig 吞吐量目标配置，用于把目标速率转换为worker间的调度节奏
+type ThroughputConfig struct {
+	TargetRequestsPerSecond float64 `toml:"TargetRequestsPerSecond"` // 目标请求速率（请求/秒），0表示不限速
+	TargetGBPerHour         float64 `toml:"TargetGBPerHour"`         // 目标流量速率（GB/小时），0表示不限速
+}
+
+// RateLimitConfig 控制按目标令牌桶限速的总请求速率和按目标IP的请求速率，与ThroughputConfig
+// 的区别是：ThroughputConfig按派发节奏把吞吐量拉平到目标值附近（可超可不足），这里是
+// 刚性上限——超过桶容量的请求会被阻塞到有令牌为止，用于把请求速率压在目标站点的
+// 封禁阈值以下，不依赖调整并发数去间接控制速率。
+type RateLimitConfig struct {
+	Enabled                 bool    `toml:"Enabled"`                 // 是否启用限速，默认关闭
+	GlobalRequestsPerSecond float64 `toml:"GlobalRequestsPerSecond"` // 全局总请求速率上限（请求/秒），<=0表示不限制
+	PerIPRequestsPerSecond  float64 `toml:"PerIPRequestsPerSecond"`  // 单个目标IP的请求速率上限（请求/秒），<=0表示不限制
+}
+
+// GetTargetInterval 将目标请求速率换算为相邻两次任务派发之间的目标间隔，<=0表示不限速
+func (c *ThroughputConfig) GetTargetInterval() time.Duration {
+	if c.TargetRequestsPerSecond <= 0 {
+		return 0
+	}
+	return time.Duration(float64(time.Second) / c.TargetRequestsPerSecond)
+}
+
+// GetTargetBytesPerSecond 将目标流量速率从GB/小时换算为字节/秒，<=0表示不限速
+func (c *ThroughputConfig) GetTargetBytesPerSecond() float64 {
+	if c.TargetGBPerHour <= 0 {
+		return 0
+	}
+	return c.TargetGBPerHour * 1024 * 1024 * 1024 / 3600
+}
+
 // LoadConfig 从指定路径加载配置文件
 // 参数：configPath - 配置文件路径
 // 返回值：配置结构体指针和错误信息
@@ -225,9 +573,52 @@ func (c *Config) validateAndSetDefaults() error {
 	if c.ServerConfig.QUICMaxIdleTimeoutSecond == 0 {
 		c.ServerConfig.QUICMaxIdleTimeoutSecond = 30
 	}
+	if c.ServerConfig.ACMEEnabled && c.ServerConfig.ACMECacheDir == "" {
+		c.ServerConfig.ACMECacheDir = "acme-cache"
+	}
 	if c.ServerConfig.EnableQUIC && c.ServerConfig.QUICPort == 0 {
 		c.ServerConfig.QUICPort = 9092
 	}
+	if c.ServerConfig.EnableKCP && c.ServerConfig.KCPPort == 0 {
+		c.ServerConfig.KCPPort = 9093
+	}
+	if c.ServerConfig.TracingEnabled && c.ServerConfig.TracingServiceName == "" {
+		c.ServerConfig.TracingServiceName = "utlsProxy-crawler"
+	}
+
+	// KCP 配置默认值：对应kcp-go官方推荐的"快速模式"参数组合
+	if c.KCP.IntervalMs == 0 {
+		c.KCP.IntervalMs = 40
+	}
+	if c.KCP.SendWindow == 0 {
+		c.KCP.SendWindow = 128
+	}
+	if c.KCP.ReceiveWindow == 0 {
+		c.KCP.ReceiveWindow = 128
+	}
+	if c.KCP.MTU == 0 {
+		c.KCP.MTU = 1400
+	}
+	if c.KCP.IntervalMs < 0 {
+		return fmt.Errorf("KCP.IntervalMs 不能为负数，实际为 %d", c.KCP.IntervalMs)
+	}
+	if c.KCP.SendWindow < 0 || c.KCP.ReceiveWindow < 0 {
+		return fmt.Errorf("KCP.SendWindow/ReceiveWindow 不能为负数，实际为 %d/%d", c.KCP.SendWindow, c.KCP.ReceiveWindow)
+	}
+	if c.KCP.MTU <= 0 || c.KCP.MTU > 65535 {
+		return fmt.Errorf("KCP.MTU 必须在 (0, 65535] 范围内，实际为 %d", c.KCP.MTU)
+	}
+
+	// 压缩配置默认值
+	if c.Compression.Level == 0 {
+		c.Compression.Level = 2
+	}
+
+	// 分布式协调模式默认值；WorkerID留空交给worker_client.go在运行期用主机名+PID兜底，
+	// 这里不处理（获取主机名是运行期行为，不适合放在纯配置校验函数里）
+	if c.Coordinator.Enabled && c.Coordinator.Role != "coordinator" && c.Coordinator.Role != "worker" {
+		return fmt.Errorf("Coordinator.Role 必须是 \"coordinator\" 或 \"worker\"，实际为 %q", c.Coordinator.Role)
+	}
 
 	return nil
 }
@@ -247,6 +638,19 @@ func (c *HotConnPoolConfig) GetIPRefreshInterval() time.Duration {
 	return time.Duration(c.IPRefreshIntervalMinutes) * time.Minute
 }
 
+// GetFingerprintSessionTTL 获取粘滞指纹的会话有效期，<=0表示永不过期
+func (c *HotConnPoolConfig) GetFingerprintSessionTTL() time.Duration {
+	return time.Duration(c.FingerprintSessionTTLMinutes) * time.Minute
+}
+
+// GetDailyByteCap 获取当天允许的最大字节数，DailyByteCapMB<=0时返回0，表示不设上限
+func (c *HotConnPoolConfig) GetDailyByteCap() int64 {
+	if c.DailyByteCapMB <= 0 {
+		return 0
+	}
+	return int64(c.DailyByteCapMB) * 1024 * 1024
+}
+
 // GetWarmupPath 获取预热路径
 // 如果 HotConnPool.WarmupPath 为空，则使用 RockTreeDataConfig.CheckStatusPath
 func (c *Config) GetWarmupPath() string {
@@ -285,10 +689,14 @@ func (c *Config) GetWarmupHeaders() map[string]string {
 		}
 	}
 
-	// 解析请求头字符串数组为 map
+	return parseHeaderStrings(headerList)
+}
+
+// parseHeaderStrings 把"Key: Value"或"Key:Value"格式的请求头字符串列表解析为map，
+// 供GetWarmupHeaders和GetHeadersForPath共用。
+func parseHeaderStrings(headerList []string) map[string]string {
 	headers := make(map[string]string)
 	for _, headerStr := range headerList {
-		// 解析格式："Key: Value" 或 "Key:Value"
 		parts := strings.SplitN(headerStr, ":", 2)
 		if len(parts) == 2 {
 			key := strings.TrimSpace(parts[0])
@@ -298,10 +706,20 @@ func (c *Config) GetWarmupHeaders() map[string]string {
 			}
 		}
 	}
-
 	return headers
 }
 
+// GetHeadersForPath 按path前缀在HeaderProfiles中查找第一个匹配的Profile并返回其请求头，
+// 没有任何Profile匹配时返回nil，调用方应退回GetWarmupHeaders()计算出的默认请求头。
+func (c *Config) GetHeadersForPath(path string) map[string]string {
+	for _, profile := range c.HeaderProfiles {
+		if profile.PathPrefix != "" && strings.HasPrefix(path, profile.PathPrefix) {
+			return parseHeaderStrings(profile.Headers)
+		}
+	}
+	return nil
+}
+
 // GetRehabilitationInterval 获取恢复间隔
 func (c *PoolConfig) GetRehabilitationInterval() time.Duration {
 	return time.Duration(c.RehabilitationIntervalMinutes) * time.Minute
@@ -342,6 +760,14 @@ func (c *UTlsClientConfig) GetDialTimeout() time.Duration {
 	return time.Duration(c.DialTimeoutSeconds) * time.Second
 }
 
+// GetMaxBodySize 获取单次响应体最大允许字节数，MaxBodySizeMB<=0时返回0，表示不设上限
+func (c *UTlsClientConfig) GetMaxBodySize() int64 {
+	if c.MaxBodySizeMB <= 0 {
+		return 0
+	}
+	return int64(c.MaxBodySizeMB) * 1024 * 1024
+}
+
 // GetQUICMaxIdleTimeout 获取 QUIC 会话最大空闲超时时间
 func (c *ServerConfig) GetQUICMaxIdleTimeout() time.Duration {
 	if c.QUICMaxIdleTimeoutSecond <= 0 {
@@ -349,3 +775,53 @@ func (c *ServerConfig) GetQUICMaxIdleTimeout() time.Duration {
 	}
 	return time.Duration(c.QUICMaxIdleTimeoutSecond) * time.Second
 }
+
+// GetQUICMaxStreamsPerConn 获取单个QUIC连接允许的最大并发双向流数，供quic.Config.MaxIncomingStreams
+// 使用；<=0时返回0，表示不覆盖，交由quic-go自己套用内置默认值
+func (c *ServerConfig) GetQUICMaxStreamsPerConn() int64 {
+	if c.QUICMaxStreamsPerConn <= 0 {
+		return 0
+	}
+	return int64(c.QUICMaxStreamsPerConn)
+}
+
+// GetHeartbeatInterval 返回Role=worker时的心跳/IP发现上报间隔，<=0时回退到默认值30秒
+func (c *CoordinatorConfig) GetHeartbeatInterval() time.Duration {
+	if c.HeartbeatIntervalSeconds <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(c.HeartbeatIntervalSeconds) * time.Second
+}
+
+// GetShardCount 返回Role=coordinator时把8个八叉树根节点前缀切分成的分片数，
+// <=0或>8时按8处理（每个分片恰好一个根前缀，不能比8更细）
+func (c *CoordinatorConfig) GetShardCount() int {
+	if c.ShardCount <= 0 || c.ShardCount > 8 {
+		return 8
+	}
+	return c.ShardCount
+}
+
+// GetGossipInterval 返回批量推送新IP事件的间隔，<=0时回退到默认值5秒
+func (c *IPSyncConfig) GetGossipInterval() time.Duration {
+	if c.GossipIntervalSeconds <= 0 {
+		return 5 * time.Second
+	}
+	return time.Duration(c.GossipIntervalSeconds) * time.Second
+}
+
+// GetBlacklistTTL 返回收到远端黑名单事件后在本地的有效期，<=0表示永不过期
+func (c *IPSyncConfig) GetBlacklistTTL() time.Duration {
+	if c.BlacklistTTLMinutes <= 0 {
+		return 0
+	}
+	return time.Duration(c.BlacklistTTLMinutes) * time.Minute
+}
+
+// GetWhitelistTTL 返回收到远端白名单事件后在本地的有效期，<=0表示永不过期
+func (c *IPSyncConfig) GetWhitelistTTL() time.Duration {
+	if c.WhitelistTTLMinutes <= 0 {
+		return 0
+	}
+	return time.Duration(c.WhitelistTTLMinutes) * time.Minute
+}
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// env_override.go支持容器化部署时不把config.toml打进镜像，只通过环境变量/命令行标志下发
+// 差异化配置：LoadConfig解析文件得到基线值，ApplyEnvOverrides按UTLSPROXY_<段名>_<字段名>
+// 命名规则覆盖环境变量中存在的项，ApplyKeyValueOverride供各cmd的-set标志按"Section.Field=value"
+// 覆盖单个配置项，两者都在之后调用，因此生效优先级是：配置文件 < 环境变量 < 命令行标志。
+
+// envOverridePrefix是环境变量覆盖配置项时使用的统一前缀
+const envOverridePrefix = "UTLSPROXY_"
+
+// ApplyEnvOverrides递归遍历cfg的全部字段，对嵌套结构体按"上一级前缀_字段名"拼出下一级前缀，
+// 标量字段存在同名环境变量时覆盖原值；slice/map字段没有能唯一表达"覆盖第几项"的env命名方式，
+// 直接跳过，只能整份写在配置文件里。
+func ApplyEnvOverrides(cfg *Config) error {
+	return applyEnvOverrides(reflect.ValueOf(cfg).Elem(), envOverridePrefix)
+}
+
+func applyEnvOverrides(v reflect.Value, prefix string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" { // 未导出字段
+			continue
+		}
+		fv := v.Field(i)
+		key := prefix + strings.ToUpper(field.Name)
+
+		switch fv.Kind() {
+		case reflect.Struct:
+			if err := applyEnvOverrides(fv, key+"_"); err != nil {
+				return err
+			}
+			continue
+		case reflect.Slice, reflect.Map:
+			continue
+		}
+
+		raw, ok := os.LookupEnv(key)
+		if !ok {
+			continue
+		}
+		if err := setScalarFromString(fv, raw); err != nil {
+			return fmt.Errorf("环境变量 %s 的值 %q 无法应用到配置项: %w", key, raw, err)
+		}
+	}
+	return nil
+}
+
+// ApplyKeyValueOverride把形如"HotConnPool.MaxConns"的点分路径定位到cfg中的对应标量字段并赋值raw，
+// 路径各段按字段名不区分大小写匹配，供-set命令行标志使用。
+func ApplyKeyValueOverride(cfg *Config, path, raw string) error {
+	segments := strings.Split(path, ".")
+	v := reflect.ValueOf(cfg).Elem()
+	for i, seg := range segments {
+		if v.Kind() != reflect.Struct {
+			return fmt.Errorf("配置项路径 %q 在 %q 之后已不是结构体，无法继续定位", path, strings.Join(segments[:i], "."))
+		}
+		fv := v.FieldByNameFunc(func(name string) bool { return strings.EqualFold(name, seg) })
+		if !fv.IsValid() {
+			return fmt.Errorf("配置项 %q 不存在", path)
+		}
+		if i == len(segments)-1 {
+			return setScalarFromString(fv, raw)
+		}
+		v = fv
+	}
+	return fmt.Errorf("配置项路径 %q 为空", path)
+}
+
+// KeyValueFlags实现flag.Value，供命令行-set标志重复出现时收集"Section.Field=value"字符串，
+// 用法示例：-set HotConnPool.MaxConns=2000 -set RateLimit.Enabled=true。
+type KeyValueFlags []string
+
+func (f *KeyValueFlags) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *KeyValueFlags) Set(v string) error {
+	*f = append(*f, v)
+	return nil
+}
+
+// ApplyOverrides依次套用环境变量覆盖和sets里的-set覆盖，供cmd各二进制在LoadConfig之后、
+// 构造业务对象之前调用一次；生效优先级是配置文件 < 环境变量 < -set标志，这与
+// config_reload.go注释里记录的"安全子集"原则一致：宁可少覆盖，不盲目支持slice/map这类
+// 没有清晰命令行表达方式的字段。
+func ApplyOverrides(cfg *Config, sets KeyValueFlags) error {
+	if err := ApplyEnvOverrides(cfg); err != nil {
+		return err
+	}
+	for _, kv := range sets {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok {
+			return fmt.Errorf("-set参数 %q 格式应为Key=Value", kv)
+		}
+		if err := ApplyKeyValueOverride(cfg, key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setScalarFromString把raw按fv的实际类型解析后赋值，slice/map/struct等复合类型不支持，
+// 返回错误交由调用方决定是否中止。
+func setScalarFromString(fv reflect.Value, raw string) error {
+	if !fv.CanSet() {
+		return fmt.Errorf("字段不可写")
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(n)
+	default:
+		return fmt.Errorf("不支持覆盖类型为%s的字段", fv.Kind())
+	}
+	return nil
+}
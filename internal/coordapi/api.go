@@ -0,0 +1,238 @@
+// Package coordapi定义分布式协调模式下coordinator/worker之间的gRPC控制面协议：
+// RegisterWorker分配八叉树根前缀分片，Heartbeat/ReportIPFindings/GetKnownIPs让各worker
+// 发现的白/黑名单IP汇总到coordinator再分发回各worker，见cmd/Crawler/coordinator.go、
+// cmd/Crawler/worker_client.go。
+//
+// 这是控制面协议，调用频率远低于taskapi.TaskService（量级是每个worker每隔数十秒一次心跳，
+// 而不是每秒数百次抓取请求），因此沿用taskapi已经注册好的JSON编解码器（taskapi.NewJSONServer /
+// taskapi.DialJSON），不像taskapi那样为每个消息类型手写protowire编解码——JSON方案省掉的工作量
+// 在这个量级上明显划算，且JSON报文本身也更方便直接用日志或grpcurl排查协调状态。
+package coordapi
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RegisterWorkerRequest是worker启动时向coordinator发起的注册请求
+type RegisterWorkerRequest struct {
+	WorkerID string `json:"worker_id"`
+	Address  string `json:"address"` // worker自身信息，供coordinator记录观测，当前未被反向调用
+}
+
+// RegisterWorkerResponse携带coordinator分配给该worker的八叉树根前缀分片
+type RegisterWorkerResponse struct {
+	ShardIndex   int32    `json:"shard_index"`
+	ShardCount   int32    `json:"shard_count"`
+	PathPrefixes []string `json:"path_prefixes"` // 分配给该worker的起始路径前缀集合，每个元素是"0"~"7"之一
+}
+
+// HeartbeatRequest是worker定期发送的存活信号
+type HeartbeatRequest struct {
+	WorkerID string `json:"worker_id"`
+}
+
+// HeartbeatResponse确认coordinator已收到心跳
+type HeartbeatResponse struct {
+	Acknowledged bool `json:"acknowledged"`
+}
+
+// ReportIPFindingsRequest携带worker自上次上报以来新发现的白/黑名单IP
+type ReportIPFindingsRequest struct {
+	WorkerID         string   `json:"worker_id"`
+	NewlyWhitelisted []string `json:"newly_whitelisted"`
+	NewlyBlacklisted []string `json:"newly_blacklisted"`
+}
+
+// ReportIPFindingsResponse确认coordinator已合并这批发现
+type ReportIPFindingsResponse struct {
+	Accepted bool `json:"accepted"`
+}
+
+// GetKnownIPsRequest请求coordinator当前汇总的全量白/黑名单
+type GetKnownIPsRequest struct{}
+
+// GetKnownIPsResponse是coordinator当前汇总的全量白/黑名单快照
+type GetKnownIPsResponse struct {
+	WhitelistedIPs []string `json:"whitelisted_ips"`
+	BlacklistedIPs []string `json:"blacklisted_ips"`
+}
+
+// CoordinatorServiceServer是coordinator侧需要实现的RPC集合
+type CoordinatorServiceServer interface {
+	RegisterWorker(context.Context, *RegisterWorkerRequest) (*RegisterWorkerResponse, error)
+	Heartbeat(context.Context, *HeartbeatRequest) (*HeartbeatResponse, error)
+	ReportIPFindings(context.Context, *ReportIPFindingsRequest) (*ReportIPFindingsResponse, error)
+	GetKnownIPs(context.Context, *GetKnownIPsRequest) (*GetKnownIPsResponse, error)
+}
+
+// UnimplementedCoordinatorServiceServer提供默认的"未实现"实现，内嵌它可以只覆盖关心的方法，
+// 与taskapi.UnimplementedTaskServiceServer的用法一致。
+type UnimplementedCoordinatorServiceServer struct{}
+
+func (UnimplementedCoordinatorServiceServer) RegisterWorker(context.Context, *RegisterWorkerRequest) (*RegisterWorkerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RegisterWorker not implemented")
+}
+
+func (UnimplementedCoordinatorServiceServer) Heartbeat(context.Context, *HeartbeatRequest) (*HeartbeatResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Heartbeat not implemented")
+}
+
+func (UnimplementedCoordinatorServiceServer) ReportIPFindings(context.Context, *ReportIPFindingsRequest) (*ReportIPFindingsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReportIPFindings not implemented")
+}
+
+func (UnimplementedCoordinatorServiceServer) GetKnownIPs(context.Context, *GetKnownIPsRequest) (*GetKnownIPsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetKnownIPs not implemented")
+}
+
+func RegisterCoordinatorServiceServer(s *grpc.Server, srv CoordinatorServiceServer) {
+	s.RegisterService(&CoordinatorService_ServiceDesc, srv)
+}
+
+func _CoordinatorService_RegisterWorker_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterWorkerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CoordinatorServiceServer).RegisterWorker(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/coordpb.CoordinatorService/RegisterWorker",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CoordinatorServiceServer).RegisterWorker(ctx, req.(*RegisterWorkerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CoordinatorService_Heartbeat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HeartbeatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CoordinatorServiceServer).Heartbeat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/coordpb.CoordinatorService/Heartbeat",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CoordinatorServiceServer).Heartbeat(ctx, req.(*HeartbeatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CoordinatorService_ReportIPFindings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReportIPFindingsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CoordinatorServiceServer).ReportIPFindings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/coordpb.CoordinatorService/ReportIPFindings",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CoordinatorServiceServer).ReportIPFindings(ctx, req.(*ReportIPFindingsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CoordinatorService_GetKnownIPs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetKnownIPsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CoordinatorServiceServer).GetKnownIPs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/coordpb.CoordinatorService/GetKnownIPs",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CoordinatorServiceServer).GetKnownIPs(ctx, req.(*GetKnownIPsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var CoordinatorService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "coordpb.CoordinatorService",
+	HandlerType: (*CoordinatorServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "RegisterWorker",
+			Handler:    _CoordinatorService_RegisterWorker_Handler,
+		},
+		{
+			MethodName: "Heartbeat",
+			Handler:    _CoordinatorService_Heartbeat_Handler,
+		},
+		{
+			MethodName: "ReportIPFindings",
+			Handler:    _CoordinatorService_ReportIPFindings_Handler,
+		},
+		{
+			MethodName: "GetKnownIPs",
+			Handler:    _CoordinatorService_GetKnownIPs_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proto/coordinator.proto",
+}
+
+type CoordinatorServiceClient interface {
+	RegisterWorker(ctx context.Context, in *RegisterWorkerRequest, opts ...grpc.CallOption) (*RegisterWorkerResponse, error)
+	Heartbeat(ctx context.Context, in *HeartbeatRequest, opts ...grpc.CallOption) (*HeartbeatResponse, error)
+	ReportIPFindings(ctx context.Context, in *ReportIPFindingsRequest, opts ...grpc.CallOption) (*ReportIPFindingsResponse, error)
+	GetKnownIPs(ctx context.Context, in *GetKnownIPsRequest, opts ...grpc.CallOption) (*GetKnownIPsResponse, error)
+}
+
+type coordinatorServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCoordinatorServiceClient(cc grpc.ClientConnInterface) CoordinatorServiceClient {
+	return &coordinatorServiceClient{cc}
+}
+
+func (c *coordinatorServiceClient) RegisterWorker(ctx context.Context, in *RegisterWorkerRequest, opts ...grpc.CallOption) (*RegisterWorkerResponse, error) {
+	out := new(RegisterWorkerResponse)
+	if err := c.cc.Invoke(ctx, "/coordpb.CoordinatorService/RegisterWorker", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *coordinatorServiceClient) Heartbeat(ctx context.Context, in *HeartbeatRequest, opts ...grpc.CallOption) (*HeartbeatResponse, error) {
+	out := new(HeartbeatResponse)
+	if err := c.cc.Invoke(ctx, "/coordpb.CoordinatorService/Heartbeat", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *coordinatorServiceClient) ReportIPFindings(ctx context.Context, in *ReportIPFindingsRequest, opts ...grpc.CallOption) (*ReportIPFindingsResponse, error) {
+	out := new(ReportIPFindingsResponse)
+	if err := c.cc.Invoke(ctx, "/coordpb.CoordinatorService/ReportIPFindings", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *coordinatorServiceClient) GetKnownIPs(ctx context.Context, in *GetKnownIPsRequest, opts ...grpc.CallOption) (*GetKnownIPsResponse, error) {
+	out := new(GetKnownIPsResponse)
+	if err := c.cc.Invoke(ctx, "/coordpb.CoordinatorService/GetKnownIPs", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,110 @@
+// Package ipsync定义多个Crawler实例之间复制白/黑名单IP变更的简单gossip协议：
+// 任一实例本地观测到一次AddIP（典型场景是请求返回403被加入黑名单）就把这条事件推送给
+// 配置的全部对等节点，对等节点各自把事件应用到本地WhiteBlackIPPool，从而让"一个实例看到
+// 的403几乎立刻反映到全部实例的黑名单里"，不依赖任何集中式存储（包括Redis——环境里
+// 没有对应的客户端库可用，见cmd/Crawler/ipsync.go的选型说明）。
+//
+// 事件按(IP, IsWhite, ExpiresAtUnix)三元组整体复制，冲突解决策略是"更晚过期的事件胜出"：
+// 同一个IP在同一份名单里先后收到两条TTL不同的事件时，保留ExpiresAtUnix更大（或为0，即永不
+// 过期）的那条，见cmd/Crawler/ipsync.go的applyEvent。这与黑名单场景下"宁可误判为仍被封，
+// 不要提前解封"的保守取向一致。
+//
+// 和internal/coordapi一样，这是低频控制面流量（每次AddIP触发一次，不是每个请求），复用
+// taskapi已经注册好的JSON编解码器，不单独手写protowire编解码。
+package ipsync
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// IPEvent是一次白/黑名单变更的复制事件
+type IPEvent struct {
+	IP            string `json:"ip"`
+	IsWhite       bool   `json:"is_white"`
+	ExpiresAtUnix int64  `json:"expires_at_unix"` // 0表示永不过期
+	OriginPeerID  string `json:"origin_peer_id"`  // 最初观测到这次变更的节点标识，用于日志排查，不参与冲突解决
+}
+
+// PushEventsRequest携带发送方自上次推送以来新产生的事件
+type PushEventsRequest struct {
+	SenderPeerID string    `json:"sender_peer_id"`
+	Events       []IPEvent `json:"events"`
+}
+
+// PushEventsResponse确认接收方已应用这批事件
+type PushEventsResponse struct {
+	Applied int32 `json:"applied"`
+}
+
+// GossipServiceServer是每个参与gossip的节点都要实现的RPC集合，目前只有一个推送方法——
+// gossip是全对等广播，不需要额外的拉取/注册RPC。
+type GossipServiceServer interface {
+	PushEvents(context.Context, *PushEventsRequest) (*PushEventsResponse, error)
+}
+
+// UnimplementedGossipServiceServer提供默认的"未实现"实现，用法与
+// taskapi.UnimplementedTaskServiceServer一致。
+type UnimplementedGossipServiceServer struct{}
+
+func (UnimplementedGossipServiceServer) PushEvents(context.Context, *PushEventsRequest) (*PushEventsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PushEvents not implemented")
+}
+
+func RegisterGossipServiceServer(s *grpc.Server, srv GossipServiceServer) {
+	s.RegisterService(&GossipService_ServiceDesc, srv)
+}
+
+func _GossipService_PushEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PushEventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GossipServiceServer).PushEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ipsyncpb.GossipService/PushEvents",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GossipServiceServer).PushEvents(ctx, req.(*PushEventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var GossipService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "ipsyncpb.GossipService",
+	HandlerType: (*GossipServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "PushEvents",
+			Handler:    _GossipService_PushEvents_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proto/ipsync.proto",
+}
+
+type GossipServiceClient interface {
+	PushEvents(ctx context.Context, in *PushEventsRequest, opts ...grpc.CallOption) (*PushEventsResponse, error)
+}
+
+type gossipServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewGossipServiceClient(cc grpc.ClientConnInterface) GossipServiceClient {
+	return &gossipServiceClient{cc}
+}
+
+func (c *gossipServiceClient) PushEvents(ctx context.Context, in *PushEventsRequest, opts ...grpc.CallOption) (*PushEventsResponse, error) {
+	out := new(PushEventsResponse)
+	if err := c.cc.Invoke(ctx, "/ipsyncpb.GossipService/PushEvents", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
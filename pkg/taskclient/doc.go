@@ -0,0 +1,9 @@
+// Package taskclient是internal/taskapi.TaskService的可复用客户端：连接管理、断线重连、
+// 按配置重试、响应体解压和（可选）落盘都封装在Client内部，调用方只需要New一个Client，
+// 然后反复调用Execute/ExecuteBatch提交抓取路径，不需要像cmd/TaskClient过去那样手写一遍
+// 连接状态机和重试循环。
+//
+// 底层可插拔的传输见Transport接口，当前提供GRPCTransport（默认）和QUICTransport两种实现，
+// 两者都可以再包一层ReconnectingTransport获得统一的"未就绪时自动重连"语义。cmd/TaskClient
+// 只保留命令行参数解析、任务计数/并发调度、进度日志这些CLI本身关心的部分，其余都委托给这个包。
+package taskclient
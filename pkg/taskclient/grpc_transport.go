@@ -0,0 +1,331 @@
+package taskclient
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/metadata"
+
+	"utlsProxy/internal/taskapi"
+)
+
+// authMetadataKey 是携带共享密钥的gRPC metadata字段名，须与Crawler端的authMetadataKey一致
+const authMetadataKey = "authorization"
+
+// withAuthToken 把共享密钥token附加到outgoing metadata的authorization字段，
+// 与TaskAPIConfig.AuthToken非空时服务端的鉴权拦截器配对使用；token为空时原样返回ctx
+func withAuthToken(ctx context.Context, token string) context.Context {
+	if token == "" {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, authMetadataKey, token)
+}
+
+// authDialOptions 返回携带共享密钥的客户端拦截器DialOption，token为空时返回空切片（不改变行为）
+func authDialOptions(token string) []grpc.DialOption {
+	if token == "" {
+		return nil
+	}
+	return []grpc.DialOption{
+		grpc.WithUnaryInterceptor(func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			return invoker(withAuthToken(ctx, token), method, req, reply, cc, opts...)
+		}),
+		grpc.WithStreamInterceptor(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			return streamer(withAuthToken(ctx, token), desc, cc, method, opts...)
+		}),
+	}
+}
+
+// GRPCTransport gRPC传输实现
+type GRPCTransport struct {
+	address   string
+	authToken string
+
+	mu     sync.RWMutex
+	conn   *grpc.ClientConn
+	client taskapi.TaskServiceClient
+
+	closed int32
+}
+
+// NewGRPCTransport 创建新的gRPC传输实例，authToken非空时会在每次RPC调用上附加鉴权metadata，
+// 需与Crawler端TaskAPIConfig.AuthToken配置的值一致
+func NewGRPCTransport(address, authToken string) (*GRPCTransport, error) {
+	conn, err := taskapi.Dial(address, authDialOptions(authToken)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GRPCTransport{
+		address:   address,
+		authToken: authToken,
+		conn:      conn,
+		client:    taskapi.NewTaskServiceClient(conn),
+	}, nil
+}
+
+func (t *GRPCTransport) current() (*grpc.ClientConn, taskapi.TaskServiceClient) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.conn, t.client
+}
+
+// Execute 执行任务请求
+func (t *GRPCTransport) Execute(ctx context.Context, req *taskapi.TaskRequest) (*taskapi.TaskResponse, error) {
+	if atomic.LoadInt32(&t.closed) == 1 {
+		return nil, fmt.Errorf("传输已关闭")
+	}
+	_, client := t.current()
+	resp, err := client.Execute(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return decompressTaskResponseBody(resp), nil
+}
+
+// decompressTaskResponseBody 在服务端按BodyCompressed标记压缩了响应体时就地解压，
+// 对应task_executor.go里仅在AcceptCompressed且压缩确实省字节时才置位的约定
+func decompressTaskResponseBody(resp *taskapi.TaskResponse) *taskapi.TaskResponse {
+	if resp == nil || !resp.BodyCompressed {
+		return resp
+	}
+	body, err := taskapi.DecompressBody(resp.Body)
+	if err != nil {
+		log.Printf("[GRPCTransport] 解压响应体失败: %v", err)
+		return resp
+	}
+	resp.Body = body
+	resp.BodyCompressed = false
+	return resp
+}
+
+// ExecuteStreamed 与Execute等价，但通过ExecuteStream分片接收响应体并在本地重新拼接，
+// 用于主动规避大响应体带来的单消息体积上限和内存峰值（50MB响应经常撞上该上限）。
+func (t *GRPCTransport) ExecuteStreamed(ctx context.Context, req *taskapi.TaskRequest) (*taskapi.TaskResponse, error) {
+	if atomic.LoadInt32(&t.closed) == 1 {
+		return nil, fmt.Errorf("传输已关闭")
+	}
+	_, client := t.current()
+
+	stream, err := client.ExecuteStream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &taskapi.TaskResponse{ClientID: req.ClientID}
+	var body bytes.Buffer
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if chunk.ChunkIndex == 0 {
+			resp.StatusCode = chunk.StatusCode
+			resp.ErrorMessage = chunk.ErrorMessage
+		}
+		body.Write(chunk.Data)
+		if chunk.IsLast {
+			break
+		}
+	}
+	resp.Body = body.Bytes()
+
+	return resp, nil
+}
+
+// ExecuteBatch 在一次RPC里提交一批path，收集服务器fan-out执行后流式返回的每个path各自的结果，
+// 用于替代对每个path各发一次Execute调用，把N次RPC往返合并为1次。
+func (t *GRPCTransport) ExecuteBatch(ctx context.Context, clientID string, paths []string) ([]*taskapi.TaskBatchResult, error) {
+	if atomic.LoadInt32(&t.closed) == 1 {
+		return nil, fmt.Errorf("传输已关闭")
+	}
+	_, client := t.current()
+
+	stream, err := client.ExecuteBatch(ctx, &taskapi.TaskBatchRequest{ClientID: clientID, Paths: paths})
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*taskapi.TaskBatchResult, 0, len(paths))
+	for {
+		result, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// TaskStreamSession 在单条TaskStream连接上提交请求并按CorrelationID异步接收对应响应，
+// 调用方不再需要像Execute那样为每个请求单独检查连接状态或另开连接，
+// 只要这条流本身保持打开，就可以持续推送任意数量的并发请求。
+type TaskStreamSession struct {
+	stream taskapi.TaskService_TaskStreamClient
+
+	mu      sync.Mutex
+	waiters map[string]chan *taskapi.TaskResponse
+
+	recvErr error
+	done    chan struct{}
+}
+
+// NewTaskStream 打开一条新的TaskStream连接，并启动后台goroutine持续接收响应、
+// 按CorrelationID分发给对应的Submit调用者。
+func (t *GRPCTransport) NewTaskStream(ctx context.Context) (*TaskStreamSession, error) {
+	if atomic.LoadInt32(&t.closed) == 1 {
+		return nil, fmt.Errorf("传输已关闭")
+	}
+	_, client := t.current()
+
+	stream, err := client.TaskStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &TaskStreamSession{
+		stream:  stream,
+		waiters: make(map[string]chan *taskapi.TaskResponse),
+		done:    make(chan struct{}),
+	}
+	go s.recvLoop()
+	return s, nil
+}
+
+func (s *TaskStreamSession) recvLoop() {
+	defer close(s.done)
+	for {
+		resp, err := s.stream.Recv()
+		if err != nil {
+			s.mu.Lock()
+			s.recvErr = err
+			waiters := s.waiters
+			s.waiters = nil
+			s.mu.Unlock()
+			for _, ch := range waiters {
+				close(ch)
+			}
+			return
+		}
+
+		s.mu.Lock()
+		ch, ok := s.waiters[resp.CorrelationID]
+		if ok {
+			delete(s.waiters, resp.CorrelationID)
+		}
+		s.mu.Unlock()
+		if ok {
+			ch <- decompressTaskResponseBody(resp)
+		}
+	}
+}
+
+// Submit 推送一个请求并阻塞等待与之CorrelationID匹配的响应，可以在多个goroutine中并发调用，
+// 各自提交的请求会通过服务端的公平调度各自独立地被处理和应答。
+func (s *TaskStreamSession) Submit(ctx context.Context, req *taskapi.TaskRequest) (*taskapi.TaskResponse, error) {
+	if req.CorrelationID == "" {
+		return nil, fmt.Errorf("CorrelationID 不能为空")
+	}
+
+	ch := make(chan *taskapi.TaskResponse, 1)
+	s.mu.Lock()
+	if s.waiters == nil {
+		err := s.recvErr
+		s.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("TaskStream 已关闭")
+	}
+	s.waiters[req.CorrelationID] = ch
+	s.mu.Unlock()
+
+	if err := s.stream.Send(req); err != nil {
+		s.mu.Lock()
+		delete(s.waiters, req.CorrelationID)
+		s.mu.Unlock()
+		return nil, err
+	}
+
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			return nil, s.recvErr
+		}
+		return resp, nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		delete(s.waiters, req.CorrelationID)
+		s.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// Close 结束发送方向，并等待接收goroutine退出
+func (s *TaskStreamSession) Close() error {
+	err := s.stream.CloseSend()
+	<-s.done
+	return err
+}
+
+// IsReady 检查传输是否就绪
+func (t *GRPCTransport) IsReady() bool {
+	if atomic.LoadInt32(&t.closed) == 1 {
+		return false
+	}
+	conn, _ := t.current()
+	return conn.GetState() == connectivity.Ready
+}
+
+// Reconnect 丢弃当前的gRPC连接并用相同的地址和鉴权token重新Dial一个新连接，
+// 供reconnectingTransport在IsReady持续为false时调用
+func (t *GRPCTransport) Reconnect() error {
+	if atomic.LoadInt32(&t.closed) == 1 {
+		return fmt.Errorf("传输已关闭")
+	}
+
+	newConn, err := taskapi.Dial(t.address, authDialOptions(t.authToken)...)
+	if err != nil {
+		return fmt.Errorf("重新连接失败: %w", err)
+	}
+
+	t.mu.Lock()
+	oldConn := t.conn
+	t.conn = newConn
+	t.client = taskapi.NewTaskServiceClient(newConn)
+	t.mu.Unlock()
+
+	if oldConn != nil {
+		_ = oldConn.Close()
+	}
+	return nil
+}
+
+// Close 关闭传输连接
+func (t *GRPCTransport) Close() error {
+	if !atomic.CompareAndSwapInt32(&t.closed, 0, 1) {
+		return nil // 已经关闭
+	}
+	conn, _ := t.current()
+	return conn.Close()
+}
+
+// GetConn 获取底层连接（用于等待就绪等操作）
+func (t *GRPCTransport) GetConn() *grpc.ClientConn {
+	conn, _ := t.current()
+	return conn
+}
@@ -1,4 +1,4 @@
-package main
+package taskclient
 
 import (
 	"bufio"
@@ -13,21 +13,81 @@ import (
 	"os"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/quic-go/quic-go"
 
 	"utlsProxy/internal/taskapi"
 )
 
+// quicDatagramFastPathMaxSize 是走数据报快速路径的请求体上限，超出则直接用stream发送；
+// 取值与服务端cmd/Crawler/quic_server.go的maxQUICDatagramResponseSize保持一致的保守量级。
+const quicDatagramFastPathMaxSize = 1200
+
+// quicDatagramFastPathTimeout 是等待数据报响应的最长时间，超时或SendDatagram本身失败
+// 都会落回到按stream重新发送一次，因此这个值应该明显小于调用方的ctx超时。
+const quicDatagramFastPathTimeout = 300 * time.Millisecond
+
+// quicDatagramRouter 按CorrelationID把一条QUIC连接上收到的数据报响应分发给等待它的调用者，
+// 使同一个会话可以并发承载多个未完成的数据报快速路径请求而不互相串话。
+type quicDatagramRouter struct {
+	mu      sync.Mutex
+	waiters map[string]chan *taskapi.TaskResponse
+}
+
+func newQUICDatagramRouter() *quicDatagramRouter {
+	return &quicDatagramRouter{waiters: make(map[string]chan *taskapi.TaskResponse)}
+}
+
+func (r *quicDatagramRouter) register(correlationID string) chan *taskapi.TaskResponse {
+	ch := make(chan *taskapi.TaskResponse, 1)
+	r.mu.Lock()
+	r.waiters[correlationID] = ch
+	r.mu.Unlock()
+	return ch
+}
+
+func (r *quicDatagramRouter) unregister(correlationID string) {
+	r.mu.Lock()
+	delete(r.waiters, correlationID)
+	r.mu.Unlock()
+}
+
+// run 持续从conn读取数据报并按CorrelationID分发，conn关闭或不支持数据报时退出
+func (r *quicDatagramRouter) run(conn *quic.Conn) {
+	for {
+		payload, err := conn.ReceiveDatagram(conn.Context())
+		if err != nil {
+			return
+		}
+		var resp taskapi.TaskResponse
+		if err := json.Unmarshal(payload, &resp); err != nil {
+			continue
+		}
+
+		r.mu.Lock()
+		ch, ok := r.waiters[resp.CorrelationID]
+		if ok {
+			delete(r.waiters, resp.CorrelationID)
+		}
+		r.mu.Unlock()
+		if ok {
+			ch <- &resp
+		}
+	}
+}
+
 // QUICTransport QUIC传输实现
 type QUICTransport struct {
 	address      string
 	tlsConfig    *tls.Config
 	quicConfig   *quic.Config
 	sessionPool  []*quic.Conn
+	routers      map[*quic.Conn]*quicDatagramRouter
 	poolMutex    sync.RWMutex
 	maxSessions  int
 	currentIndex int64
+	datagramSeq  int64
 	closed       int32
 }
 
@@ -42,6 +102,7 @@ func NewQUICTransport(address string, tlsConfig *tls.Config, quicConfig *quic.Co
 		tlsConfig:   tlsConfig,
 		quicConfig:  quicConfig,
 		sessionPool: make([]*quic.Conn, 0, maxSessions),
+		routers:     make(map[*quic.Conn]*quicDatagramRouter),
 		maxSessions: maxSessions,
 	}
 
@@ -73,6 +134,9 @@ func (t *QUICTransport) ensureSession() error {
 	}
 
 	t.sessionPool = append(t.sessionPool, conn)
+	router := newQUICDatagramRouter()
+	t.routers[conn] = router
+	go router.run(conn)
 	log.Printf("[QUIC] 创建新会话，当前会话数: %d", len(t.sessionPool))
 	return nil
 }
@@ -83,6 +147,8 @@ func (t *QUICTransport) cleanupClosedSessions() {
 	for _, conn := range t.sessionPool {
 		if conn.Context().Err() == nil {
 			validSessions = append(validSessions, conn)
+		} else {
+			delete(t.routers, conn)
 		}
 	}
 	t.sessionPool = validSessions
@@ -142,18 +208,68 @@ func (t *QUICTransport) getSession() (*quic.Conn, error) {
 	return nil, fmt.Errorf("无法获取可用会话")
 }
 
-// Execute 执行任务请求
+// Execute 执行任务请求：请求体足够小时先尝试数据报快速路径，数据报被拒绝、发送失败
+// 或等待响应超时都会自动退回到按stream发送，对调用方完全透明。
 func (t *QUICTransport) Execute(ctx context.Context, req *taskapi.TaskRequest) (*taskapi.TaskResponse, error) {
 	if atomic.LoadInt32(&t.closed) == 1 {
 		return nil, fmt.Errorf("传输已关闭")
 	}
 
-	// 获取会话
 	conn, err := t.getSession()
 	if err != nil {
 		return nil, fmt.Errorf("获取会话失败: %w", err)
 	}
 
+	if resp, ok := t.tryExecuteDatagram(ctx, conn, req); ok {
+		return resp, nil
+	}
+
+	return t.executeViaStream(ctx, conn, req)
+}
+
+// tryExecuteDatagram 尝试走数据报快速路径，第二个返回值为false表示快速路径不可用
+// （请求过大、数据报被对端禁用、发送失败或等待响应超时），调用方应退回到executeViaStream。
+func (t *QUICTransport) tryExecuteDatagram(ctx context.Context, conn *quic.Conn, req *taskapi.TaskRequest) (*taskapi.TaskResponse, bool) {
+	if req.CorrelationID == "" {
+		req.CorrelationID = fmt.Sprintf("dgram-%d", atomic.AddInt64(&t.datagramSeq, 1))
+	}
+
+	reqData, err := json.Marshal(req)
+	if err != nil || len(reqData) > quicDatagramFastPathMaxSize {
+		return nil, false
+	}
+
+	t.poolMutex.RLock()
+	router := t.routers[conn]
+	t.poolMutex.RUnlock()
+	if router == nil {
+		return nil, false
+	}
+
+	ch := router.register(req.CorrelationID)
+	if err := conn.SendDatagram(reqData); err != nil {
+		router.unregister(req.CorrelationID)
+		return nil, false
+	}
+
+	timer := time.NewTimer(quicDatagramFastPathTimeout)
+	defer timer.Stop()
+
+	select {
+	case resp := <-ch:
+		return resp, true
+	case <-timer.C:
+		router.unregister(req.CorrelationID)
+		return nil, false
+	case <-ctx.Done():
+		router.unregister(req.CorrelationID)
+		return nil, false
+	}
+}
+
+// executeViaStream 是数据报快速路径不可用时的可靠回退：每次调用打开一条新的QUIC stream，
+// 原来Execute的全部逻辑都原样保留在这里。
+func (t *QUICTransport) executeViaStream(ctx context.Context, conn *quic.Conn, req *taskapi.TaskRequest) (*taskapi.TaskResponse, error) {
 	// 打开新的stream
 	stream, err := conn.OpenStreamSync(ctx)
 	if err != nil {
@@ -262,6 +378,29 @@ func (t *QUICTransport) IsReady() bool {
 	return false
 }
 
+// Reconnect 丢弃会话池中的全部会话并重新建立一个新会话，供reconnectingTransport在
+// IsReady持续为false时调用；池中仍然有效的会话也会被直接丢弃，以确保重连后不再复用
+// 可能处于半开状态的旧会话
+func (t *QUICTransport) Reconnect() error {
+	if atomic.LoadInt32(&t.closed) == 1 {
+		return fmt.Errorf("传输已关闭")
+	}
+
+	t.poolMutex.Lock()
+	oldSessions := t.sessionPool
+	t.sessionPool = nil
+	for _, conn := range oldSessions {
+		delete(t.routers, conn)
+	}
+	t.poolMutex.Unlock()
+
+	for _, conn := range oldSessions {
+		_ = conn.CloseWithError(0, "reconnecting")
+	}
+
+	return t.ensureSession()
+}
+
 // Close 关闭传输连接
 func (t *QUICTransport) Close() error {
 	if !atomic.CompareAndSwapInt32(&t.closed, 0, 1) {
@@ -278,12 +417,16 @@ func (t *QUICTransport) Close() error {
 		}
 	}
 	t.sessionPool = nil
+	t.routers = make(map[*quic.Conn]*quicDatagramRouter)
 
 	return lastErr
 }
 
-// buildQUICClientTLSConfig 构建QUIC客户端TLS配置
-func buildQUICClientTLSConfig(serverName string, caFile string, insecureSkipVerify bool) (*tls.Config, error) {
+// buildQUICClientTLSConfig 构建QUIC客户端TLS配置；clientCertFile/clientKeyFile均非空时
+// 加载客户端证书供服务端（cmd/Crawler/quic_server.go在QUICRequireClientCert=true时）
+// 做双向校验，留空则不出示客户端证书，行为与改动前一致——服务端ClientAuth为
+// VerifyClientCertIfGiven时这类客户端仍然可以连上，只是不会被记为已认证的身份。
+func buildQUICClientTLSConfig(serverName, caFile, clientCertFile, clientKeyFile string, insecureSkipVerify bool) (*tls.Config, error) {
 	tlsConfig := &tls.Config{
 		ServerName:         serverName,
 		InsecureSkipVerify: insecureSkipVerify,
@@ -298,6 +441,14 @@ func buildQUICClientTLSConfig(serverName string, caFile string, insecureSkipVeri
 		tlsConfig.RootCAs = certPool
 	}
 
+	if clientCertFile != "" && clientKeyFile != "" {
+		clientCert, err := tls.LoadX509KeyPair(clientCertFile, clientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("加载客户端证书失败: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{clientCert}
+	}
+
 	return tlsConfig, nil
 }
 
@@ -313,4 +464,3 @@ func loadCertPool(path string) (*x509.CertPool, error) {
 	}
 	return pool, nil
 }
-
@@ -0,0 +1,87 @@
+package taskclient
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"utlsProxy/internal/taskapi"
+)
+
+// reconnectMinInterval 限制两次真正重连尝试之间的最小间隔，避免在连接持续异常时
+// 对下游地址（DNS、负载均衡器等）发起重连风暴
+const reconnectMinInterval = 2 * time.Second
+
+// ReconnectingTransport 包一层在任意Transport实现之上：Execute前如果IsReady为false就
+// 先尝试Reconnect，Execute过程中如果返回的错误像是连接失败也会在下次调用前触发重连。
+// 上层Client据此不需要再区分底层究竟是gRPC、QUIC还是其他实现各自的连通性状态机，
+// 也不需要像cmd/TaskClient/main.go过去那样为每种传输各自手写一遍"检查状态->必要时
+// 重连->重试"的状态机。
+type ReconnectingTransport struct {
+	inner Transport
+
+	mu              sync.Mutex
+	lastReconnectAt time.Time
+	reconnecting    bool
+}
+
+// NewReconnectingTransport 用inner包出一个具备统一重连逻辑的Transport
+func NewReconnectingTransport(inner Transport) *ReconnectingTransport {
+	return &ReconnectingTransport{inner: inner}
+}
+
+// ensureReady 在inner未就绪时尝试重连一次；重连被频率限制跳过或重连失败都不是致命错误，
+// 调用方仍会继续尝试Execute，让底层传输自己的错误信息向上传递
+func (t *ReconnectingTransport) ensureReady() {
+	if t.inner.IsReady() {
+		return
+	}
+
+	t.mu.Lock()
+	if t.reconnecting || time.Since(t.lastReconnectAt) < reconnectMinInterval {
+		t.mu.Unlock()
+		return
+	}
+	t.reconnecting = true
+	t.mu.Unlock()
+
+	err := t.inner.Reconnect()
+
+	t.mu.Lock()
+	t.reconnecting = false
+	t.lastReconnectAt = time.Now()
+	t.mu.Unlock()
+
+	_ = err // 重连失败时交由后续Execute的错误返回值反映，这里不中断调用方
+}
+
+// Execute 在必要时先重连，再委托给inner执行请求
+func (t *ReconnectingTransport) Execute(ctx context.Context, req *taskapi.TaskRequest) (*taskapi.TaskResponse, error) {
+	t.ensureReady()
+	resp, err := t.inner.Execute(ctx, req)
+	if err != nil {
+		t.mu.Lock()
+		ready := t.inner.IsReady()
+		t.mu.Unlock()
+		if !ready {
+			return nil, fmt.Errorf("%w（传输未就绪）", err)
+		}
+	}
+	return resp, err
+}
+
+// IsReady 透传给inner
+func (t *ReconnectingTransport) IsReady() bool {
+	return t.inner.IsReady()
+}
+
+// Reconnect 透传给inner，供调用方需要强制重连时直接调用
+func (t *ReconnectingTransport) Reconnect() error {
+	return t.inner.Reconnect()
+}
+
+// Close 透传给inner
+func (t *ReconnectingTransport) Close() error {
+	return t.inner.Close()
+}
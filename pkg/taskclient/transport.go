@@ -0,0 +1,28 @@
+package taskclient
+
+import (
+	"context"
+
+	"utlsProxy/internal/taskapi"
+)
+
+// Transport 定义传输层接口，支持不同的底层协议（gRPC、QUIC等）；实现见GRPCTransport、
+// QUICTransport，以及在两者之上提供统一重连逻辑的ReconnectingTransport。本仓库go.mod
+// 尚未引入kcp-go依赖，暂不提供KCP实现，待依赖可用时补一个同样满足这个接口的KCPTransport即可；
+// 届时KCP会话必须在裸UDP之上先过一层加密再进行业务通信——kcp-go内置的block crypto
+// （AES/salsa20，密钥来自与gRPC鉴权同源的共享密钥配置）或者直接用crypto/tls包一层均可，
+// 不能像现在的KCP相关代码注释里设想的那样以明文传输TaskRequest/TaskResponse。
+// 同样，kcp-go的DataShard/ParityShard前向纠错参数和smux多路复用（让多个并发请求共享一个
+// KCP会话而不是互相排队）也都要在那时候的KCPTransport里一并配起来，这个接口本身不关心
+// 具体传输内部是否多路复用，只要求Execute在并发调用下是安全的。
+type Transport interface {
+	// Execute 执行任务请求
+	Execute(ctx context.Context, req *taskapi.TaskRequest) (*taskapi.TaskResponse, error)
+	// Close 关闭传输连接
+	Close() error
+	// IsReady 检查传输是否就绪
+	IsReady() bool
+	// Reconnect 丢弃当前的底层连接/会话并重新建立一个新的，IsReady此前返回false
+	// 是调用它的典型时机；各实现自行决定重连的具体方式（gRPC重新Dial，QUIC重建会话池等）
+	Reconnect() error
+}
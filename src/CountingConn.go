@@ -0,0 +1,42 @@
+package src
+
+import (
+	"net"
+	"sync/atomic"
+)
+
+// countingConn 包装原始TCP连接，在TLS握手之下的套接字层面统计读写字节数，
+// 因此TLS握手、记录层封装和HTTP/2帧头都会被计入，而不只是应用层body大小。
+type countingConn struct {
+	net.Conn
+	bytesRead    int64
+	bytesWritten int64
+}
+
+func newCountingConn(conn net.Conn) *countingConn {
+	return &countingConn{Conn: conn}
+}
+
+func (c *countingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		atomic.AddInt64(&c.bytesRead, int64(n))
+	}
+	return n, err
+}
+
+func (c *countingConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	if n > 0 {
+		atomic.AddInt64(&c.bytesWritten, int64(n))
+	}
+	return n, err
+}
+
+func (c *countingConn) BytesRead() int64 {
+	return atomic.LoadInt64(&c.bytesRead)
+}
+
+func (c *countingConn) BytesWritten() int64 {
+	return atomic.LoadInt64(&c.bytesWritten)
+}
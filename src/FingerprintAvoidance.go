@@ -0,0 +1,133 @@
+package src
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// fingerprintFailureThreshold 是(指纹, 目标IP)这一对连续握手失败达到多少次后，
+	// 进入冷却期、暂停在该IP上选用这个指纹的阈值。只看连续失败是因为偶发的单次握手失败
+	// 很常见（网络抖动、目标瞬时过载），不应该仅凭一次失败就放弃一个指纹；连续失败更可能
+	// 意味着这个指纹（比如纯PQ的ClientHello）被这个目标/CDN边缘明确拒绝。
+	fingerprintFailureThreshold = 3
+	// fingerprintCooldownDuration 是冷却期时长：期间fingerprintForIP会跳过这个(指纹, IP)组合，
+	// 改选其他指纹；到期后自动恢复尝试，因为目标站点的拒绝策略本身也可能随时间变化
+	// （比如临时的中间人/WAF规则，而不是永久的协议不支持）。
+	fingerprintCooldownDuration = 10 * time.Minute
+	// fingerprintAvoidanceMaxEntries 是avoidanceMap允许保存的(指纹, IP)组合上限，
+	// 超过后清理已过期或最旧的条目，避免长时间运行、IP/指纹组合数量很大时无限增长
+	fingerprintAvoidanceMaxEntries = 5000
+	// fingerprintRerollAttempts 是选到处于冷却期的指纹时，重新按权重选择的最多尝试次数；
+	// 用尽仍然只能选出被冷却的指纹时就接受这个结果，避免指纹库规模较小、大部分指纹都在
+	// 冷却中时无限重试
+	fingerprintRerollAttempts = 5
+)
+
+// fingerprintFailureState 记录一个(指纹, 目标IP)组合最近的连续握手失败次数，以及如果已经
+// 触发冷却，冷却到期的时间点（零值表示尚未进入冷却）
+type fingerprintFailureState struct {
+	consecutiveFailures int
+	cooldownUntil       time.Time
+}
+
+// fingerprintAvoidanceTracker 按(指纹名, 目标IP)组合跟踪握手失败，触发冷却后由
+// fingerprintForIP在选择指纹时跳过，改选其他指纹，而不是对一个明显被目标拒绝的指纹
+// 盲目重试。见createConnection握手成功/失败后的RecordSuccess/RecordFailure调用。
+type fingerprintAvoidanceTracker struct {
+	mu    sync.Mutex
+	state map[fingerprintIPKey]*fingerprintFailureState
+}
+
+type fingerprintIPKey struct {
+	fingerprint string
+	targetIP    string
+}
+
+func newFingerprintAvoidanceTracker() *fingerprintAvoidanceTracker {
+	return &fingerprintAvoidanceTracker{
+		state: make(map[fingerprintIPKey]*fingerprintFailureState),
+	}
+}
+
+// RecordFailure记录一次握手失败，连续失败达到fingerprintFailureThreshold时触发冷却，
+// 返回是否刚好在这次调用触发了冷却（仅用于日志提示，不影响后续行为）
+func (t *fingerprintAvoidanceTracker) RecordFailure(fingerprint, targetIP string) bool {
+	if fingerprint == "" || targetIP == "" {
+		return false
+	}
+	key := fingerprintIPKey{fingerprint: fingerprint, targetIP: targetIP}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	entry, ok := t.state[key]
+	if !ok {
+		entry = &fingerprintFailureState{}
+		t.state[key] = entry
+	}
+	entry.consecutiveFailures++
+	if entry.consecutiveFailures >= fingerprintFailureThreshold && entry.cooldownUntil.IsZero() {
+		entry.cooldownUntil = time.Now().Add(fingerprintCooldownDuration)
+		return true
+	}
+	return false
+}
+
+// RecordSuccess 清除一次握手成功对应的(指纹, IP)失败记录：握手既然已经成功，
+// 之前的失败计数和冷却状态都不应该继续影响后续选择
+func (t *fingerprintAvoidanceTracker) RecordSuccess(fingerprint, targetIP string) {
+	if fingerprint == "" || targetIP == "" {
+		return
+	}
+	key := fingerprintIPKey{fingerprint: fingerprint, targetIP: targetIP}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.state, key)
+}
+
+// IsAvoided 返回(指纹, IP)组合当前是否处于冷却期，冷却已过期时顺带清掉这条记录
+func (t *fingerprintAvoidanceTracker) IsAvoided(fingerprint, targetIP string) bool {
+	if fingerprint == "" || targetIP == "" {
+		return false
+	}
+	key := fingerprintIPKey{fingerprint: fingerprint, targetIP: targetIP}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	entry, ok := t.state[key]
+	if !ok || entry.cooldownUntil.IsZero() {
+		return false
+	}
+	if time.Now().After(entry.cooldownUntil) {
+		delete(t.state, key)
+		return false
+	}
+	return true
+}
+
+// cleanup 清理已过期的冷却记录；超过fingerprintAvoidanceMaxEntries时额外清理一半最旧的条目，
+// 由domainConnPool的IP统计清理后台任务定期调用
+func (t *fingerprintAvoidanceTracker) cleanup() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	for key, entry := range t.state {
+		if !entry.cooldownUntil.IsZero() && now.After(entry.cooldownUntil) {
+			delete(t.state, key)
+		}
+	}
+
+	if len(t.state) > fingerprintAvoidanceMaxEntries {
+		toDelete := len(t.state) - fingerprintAvoidanceMaxEntries/2
+		deleted := 0
+		for key := range t.state {
+			if deleted >= toDelete {
+				break
+			}
+			delete(t.state, key)
+			deleted++
+		}
+	}
+}
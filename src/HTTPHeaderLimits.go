@@ -0,0 +1,115 @@
+package src
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// 默认的HTTP/1.1响应头解析限制，UTlsClient.MaxStatusLineBytes/MaxHeaderBytes/MaxHeaderCount
+// 为<=0（零值）时生效。这几个限制的目的是防御畸形或恶意的上游响应（fuzz输入、故意发送
+// 超长状态行/海量头部字段的中间人或钓鱼上游）把bufio.Reader/textproto一直喂下去，
+// 与MaxBodySize默认不设上限（保持旧行为）不同，响应头解析默认就带着这组限制生效——
+// 读响应头是连接复用前必经的路径，不应该默认不设防。
+const (
+	defaultMaxStatusLineBytes = 8 * 1024 // 状态行最大8KB，远超任何合理HTTP状态行
+	defaultMaxHeaderBytes     = 1 << 20  // 响应头（含状态行）总字节数上限1MB
+	defaultMaxHeaderCount     = 200      // 响应头字段行数上限
+)
+
+// HTTPLimitExceededError在HTTP/1.1响应的状态行或响应头超过配置的限制时返回，调用方可以用
+// errors.As把它从其他读取失败（超时、连接断开等传输层错误）中区分出来，按需要把发送畸形
+// 响应的目标IP当成异常处理（比如加入黑名单），而不是像普通连接失败一样走StatusPolicy重试。
+type HTTPLimitExceededError struct {
+	Limit string // 触发的限制名称："status_line"/"header_bytes"/"header_count"
+	Max   int    // 对应限制配置的最大值
+}
+
+func (e *HTTPLimitExceededError) Error() string {
+	return fmt.Sprintf("HTTP响应超过%s限制（最大%d）", e.Limit, e.Max)
+}
+
+// headerSizeLimitingReader包裹一个原始连接读取器，在HTTP响应头解析阶段（从第一个字节到
+// 空行结束）强制执行状态行长度、响应头总字节数、响应头字段行数三项限制，一旦超限立即
+// 返回*HTTPLimitExceededError，不再继续读取；头部结束（遇到空行）之后转入透传模式，
+// 不对响应体的读取施加任何限制（响应体大小由UTlsClient.MaxBodySize单独控制）。
+type headerSizeLimitingReader struct {
+	r io.Reader
+
+	maxStatusLine int
+	maxHeaderSize int
+	maxHeaderLine int
+
+	totalRead    int
+	curLineBytes int
+	sawFirstLine bool
+	lineCount    int
+	headerDone   bool
+	pending      []byte // 一次底层Read里，头部结束符之后多读到的字节（即响应体的开头），留给下一次Read返回
+}
+
+func newHeaderSizeLimitingReader(r io.Reader, maxStatusLine, maxHeaderSize, maxHeaderLine int) *headerSizeLimitingReader {
+	return &headerSizeLimitingReader{
+		r:             r,
+		maxStatusLine: maxStatusLine,
+		maxHeaderSize: maxHeaderSize,
+		maxHeaderLine: maxHeaderLine,
+	}
+}
+
+func (l *headerSizeLimitingReader) Read(p []byte) (int, error) {
+	if len(l.pending) > 0 {
+		n := copy(p, l.pending)
+		l.pending = l.pending[n:]
+		return n, nil
+	}
+	if l.headerDone {
+		return l.r.Read(p)
+	}
+
+	n, err := l.r.Read(p)
+	for i := 0; i < n; i++ {
+		l.totalRead++
+		if l.totalRead > l.maxHeaderSize {
+			return i, &HTTPLimitExceededError{Limit: "header_bytes", Max: l.maxHeaderSize}
+		}
+		if !l.sawFirstLine && l.totalRead > l.maxStatusLine {
+			return i, &HTTPLimitExceededError{Limit: "status_line", Max: l.maxStatusLine}
+		}
+
+		if p[i] != '\n' {
+			l.curLineBytes++
+			continue
+		}
+
+		// 走到这里说明这一行（状态行或某个头部字段行）结束了
+		isBlankLine := l.curLineBytes <= 1 // 只有裸\n或\r\n，没有其余内容，即头部结束的空行
+		l.curLineBytes = 0
+		if !l.sawFirstLine {
+			l.sawFirstLine = true
+		} else if !isBlankLine {
+			l.lineCount++
+			if l.lineCount > l.maxHeaderLine {
+				return i, &HTTPLimitExceededError{Limit: "header_count", Max: l.maxHeaderLine}
+			}
+		}
+		if isBlankLine && l.sawFirstLine {
+			l.headerDone = true
+			if rest := p[i+1 : n]; len(rest) > 0 {
+				l.pending = append([]byte(nil), rest...)
+			}
+			return i + 1, nil
+		}
+	}
+	return n, err
+}
+
+// ReadHTTPHeaderLimited用给定的三项限制从r中解析一个HTTP/1.1响应，只是对
+// headerSizeLimitingReader+http.ReadResponse这条解析路径的直接暴露，不经过
+// UTlsClient.readHTTPResponse的连接管理、超时、CookieJar、Keep-Alive记录等逻辑，专供测试
+// （尤其是模糊测试）独立驱动响应头解析路径使用。生产请求路径见UTlsClient.readHTTPResponse。
+func ReadHTTPHeaderLimited(r io.Reader, maxStatusLine, maxHeaderBytes, maxHeaderCount int) (*http.Response, error) {
+	limited := newHeaderSizeLimitingReader(r, maxStatusLine, maxHeaderBytes, maxHeaderCount)
+	return http.ReadResponse(bufio.NewReader(limited), nil)
+}
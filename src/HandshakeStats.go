@@ -0,0 +1,148 @@
+package src
+
+import (
+	"crypto/tls"
+	"sync"
+	"time"
+)
+
+// HandshakeStats 是某个维度（目标IP或指纹名）下聚合的TLS握手统计快照。TotalDuration只累加
+// 握手成功的耗时，用于计算平均握手耗时（TotalDuration/(FullHandshakes+ResumedHandshakes)）；
+// ALPNCounts/CipherCounts只在握手成功时才有值，按实际协商结果计数，用来发现某个指纹在
+// 某些CDN边缘节点总是协商不到期望的ALPN/密码套件。
+type HandshakeStats struct {
+	Attempts          int64
+	Failures          int64
+	FullHandshakes    int64
+	ResumedHandshakes int64
+	TotalDuration     time.Duration
+	ALPNCounts        map[string]int64
+	CipherCounts      map[string]int64
+}
+
+func newHandshakeStats() *HandshakeStats {
+	return &HandshakeStats{
+		ALPNCounts:   make(map[string]int64),
+		CipherCounts: make(map[string]int64),
+	}
+}
+
+func (s *HandshakeStats) clone() HandshakeStats {
+	out := HandshakeStats{
+		Attempts:          s.Attempts,
+		Failures:          s.Failures,
+		FullHandshakes:    s.FullHandshakes,
+		ResumedHandshakes: s.ResumedHandshakes,
+		TotalDuration:     s.TotalDuration,
+		ALPNCounts:        make(map[string]int64, len(s.ALPNCounts)),
+		CipherCounts:      make(map[string]int64, len(s.CipherCounts)),
+	}
+	for k, v := range s.ALPNCounts {
+		out.ALPNCounts[k] = v
+	}
+	for k, v := range s.CipherCounts {
+		out.CipherCounts[k] = v
+	}
+	return out
+}
+
+// mergeHandshakeStats把src累加进dst，供PoolManager跨多个域名连接池汇总同一目标IP/指纹的统计。
+func mergeHandshakeStats(dst *HandshakeStats, src HandshakeStats) {
+	dst.Attempts += src.Attempts
+	dst.Failures += src.Failures
+	dst.FullHandshakes += src.FullHandshakes
+	dst.ResumedHandshakes += src.ResumedHandshakes
+	dst.TotalDuration += src.TotalDuration
+	if dst.ALPNCounts == nil {
+		dst.ALPNCounts = make(map[string]int64)
+	}
+	if dst.CipherCounts == nil {
+		dst.CipherCounts = make(map[string]int64)
+	}
+	for k, v := range src.ALPNCounts {
+		dst.ALPNCounts[k] += v
+	}
+	for k, v := range src.CipherCounts {
+		dst.CipherCounts[k] += v
+	}
+}
+
+// handshakeRecord描述一次TLS握手尝试的结果，由domainConnPool.createConnection在Handshake()
+// 返回后产生，经handshakeStatsTracker.record聚合进按目标IP、按指纹两个维度的统计里。
+type handshakeRecord struct {
+	targetIP    string
+	fingerprint string // Profile.Name
+	success     bool
+	resumed     bool          // 对应uTLS ConnectionState.DidResume，握手失败时无意义
+	duration    time.Duration // 从调用Handshake()到返回为止的耗时
+	cipherSuite uint16        // 握手失败时为0
+	alpn        string        // state.NegotiatedProtocol，握手失败时为空
+}
+
+// handshakeStatsTracker 线程安全地按目标IP、按指纹名两个维度聚合handshakeRecord，
+// 供Warmup/业务请求路径共用同一份统计，不区分触发来源。
+type handshakeStatsTracker struct {
+	mu            sync.Mutex
+	byIP          map[string]*HandshakeStats
+	byFingerprint map[string]*HandshakeStats
+}
+
+func newHandshakeStatsTracker() *handshakeStatsTracker {
+	return &handshakeStatsTracker{
+		byIP:          make(map[string]*HandshakeStats),
+		byFingerprint: make(map[string]*HandshakeStats),
+	}
+}
+
+func (t *handshakeStatsTracker) record(rec handshakeRecord) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	applyHandshakeRecord(t.byIP, rec.targetIP, rec)
+	applyHandshakeRecord(t.byFingerprint, rec.fingerprint, rec)
+}
+
+func applyHandshakeRecord(m map[string]*HandshakeStats, key string, rec handshakeRecord) {
+	if key == "" {
+		return
+	}
+	stats, ok := m[key]
+	if !ok {
+		stats = newHandshakeStats()
+		m[key] = stats
+	}
+	stats.Attempts++
+	if !rec.success {
+		stats.Failures++
+		return
+	}
+	stats.TotalDuration += rec.duration
+	if rec.resumed {
+		stats.ResumedHandshakes++
+	} else {
+		stats.FullHandshakes++
+	}
+	if rec.alpn != "" {
+		stats.ALPNCounts[rec.alpn]++
+	}
+	if rec.cipherSuite != 0 {
+		stats.CipherCounts[tls.CipherSuiteName(rec.cipherSuite)]++
+	}
+}
+
+func (t *handshakeStatsTracker) snapshot(m map[string]*HandshakeStats) map[string]HandshakeStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make(map[string]HandshakeStats, len(m))
+	for k, v := range m {
+		out[k] = v.clone()
+	}
+	return out
+}
+
+func (t *handshakeStatsTracker) byIPSnapshot() map[string]HandshakeStats {
+	return t.snapshot(t.byIP)
+}
+
+func (t *handshakeStatsTracker) byFingerprintSnapshot() map[string]HandshakeStats {
+	return t.snapshot(t.byFingerprint)
+}
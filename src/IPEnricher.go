@@ -0,0 +1,325 @@
+package src // Package src 定义src包
+
+import ( // 导入所需的标准库
+	"bytes"         // 用于构造批量查询请求体
+	"encoding/json" // 用于JSON编码解码
+	"fmt"           // 用于格式化输入输出
+	"io"            // 用于基础IO操作
+	"net/http"      // 用于HTTP客户端功能
+	"strings"       // 用于字符串操作
+	"sync"          // 用于同步原语如互斥锁
+	"time"          // 用于时间处理
+)
+
+// IPEnricher 定义了"给一个IP查询归属地/ASN等附加信息"这一能力的契约，
+// processSingleDomain不再直接依赖具体的ipinfo.io实现，而是面向这个接口编程，
+// 由MonitorConfig.Enricher决定实际用哪家数据源，便于按配额、价格或可用性切换供应商。
+type IPEnricher interface {
+	// Enrich 查询单个IP的信息；没有查到或查询失败时返回error，调用方应跳过该IP而不是中断整批处理
+	Enrich(ip string) (*IPInfoResponse, error)
+}
+
+// BatchIPEnricher是IPEnricher的可选扩展，由支持批量查询接口的数据源实现（目前只有ipinfo.io）。
+// processSingleDomain优先对支持这个接口的Enricher走批量路径，一次HTTP往返查多个IP，
+// 而不是给每个IP各开一个goroutine打一次请求，从根源上减少触发对方API速率限制的概率。
+type BatchIPEnricher interface {
+	IPEnricher
+	// EnrichBatch 一次性查询ips的信息，返回值按IP建索引；某个IP查询失败时，结果里既不包含
+	// 该IP对应的条目，也不会让整批查询因此失败（除非请求本身发送失败）
+	EnrichBatch(ips []string) (map[string]*IPInfoResponse, error)
+}
+
+// --- ipinfo.io ---
+
+// ipInfoEnricher 是IPEnricher/BatchIPEnricher基于ipinfo.io的实现，沿用了重构前
+// remoteIPMonitor.fetchIPInfo的请求逻辑，只是不再和remoteIPMonitor的其余状态耦合在一起。
+type ipInfoEnricher struct {
+	httpClient *http.Client
+	token      string
+}
+
+// NewIPInfoEnricher 创建一个基于ipinfo.io的IPEnricher，token为空时仍可查询，
+// 只是会受到ipinfo.io对匿名请求更低的速率限制
+func NewIPInfoEnricher(httpClient *http.Client, token string) IPEnricher {
+	return &ipInfoEnricher{httpClient: httpClient, token: token}
+}
+
+func (e *ipInfoEnricher) Enrich(ip string) (*IPInfoResponse, error) {
+	url := fmt.Sprintf("https://ipinfo.io/%s/json?token=%s", ip, e.token) // 构造URL
+	resp, err := e.httpClient.Get(url)                                    // 发起HTTP GET请求
+	if err != nil {                                                       // 如果请求失败
+		return nil, err // 返回错误
+	}
+	defer func() { _ = resp.Body.Close() }() // 延迟关闭响应体
+	body, err := io.ReadAll(resp.Body)       // 读取响应体
+	if err != nil {                          // 如果读取失败
+		return nil, err // 返回错误
+	}
+	var info IPInfoResponse                             // 声明IP信息响应变量
+	if err := json.Unmarshal(body, &info); err != nil { // 解析JSON数据
+		return nil, err // 返回错误
+	}
+	return &info, nil // 返回IP信息响应
+}
+
+// ipInfoBatchMaxSize是ipinfo.io /batch接口单次请求允许携带的最大IP数量（官方文档规定的上限）
+const ipInfoBatchMaxSize = 1000
+
+// EnrichBatch 通过ipinfo.io的POST /batch接口一次性查询多个IP，超过ipInfoBatchMaxSize时
+// 按批次拆分成多次请求后再合并结果。
+func (e *ipInfoEnricher) EnrichBatch(ips []string) (map[string]*IPInfoResponse, error) {
+	result := make(map[string]*IPInfoResponse, len(ips))
+	for start := 0; start < len(ips); start += ipInfoBatchMaxSize {
+		end := start + ipInfoBatchMaxSize
+		if end > len(ips) {
+			end = len(ips)
+		}
+		chunk, err := e.enrichBatchChunk(ips[start:end])
+		if err != nil {
+			return result, err
+		}
+		for ip, info := range chunk {
+			result[ip] = info
+		}
+	}
+	return result, nil
+}
+
+func (e *ipInfoEnricher) enrichBatchChunk(ips []string) (map[string]*IPInfoResponse, error) {
+	payload, err := json.Marshal(ips)
+	if err != nil {
+		return nil, fmt.Errorf("序列化批量查询IP列表失败: %w", err)
+	}
+
+	url := fmt.Sprintf("https://ipinfo.io/batch?token=%s", e.token)
+	resp, err := e.httpClient.Post(url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// /batch的响应是"IP -> 单个IP的/json响应"这样一份映射，与单个IP查询的JSON结构完全一致，
+	// 查询失败的单个IP在ipinfo.io侧会返回一条error字符串而不是对象，反序列化到*IPInfoResponse
+	// 会失败，这里按条跳过而不让整批结果作废
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("解析批量查询响应失败: %w", err)
+	}
+
+	result := make(map[string]*IPInfoResponse, len(raw))
+	for ip, msg := range raw {
+		var info IPInfoResponse
+		if err := json.Unmarshal(msg, &info); err != nil {
+			continue
+		}
+		result[ip] = &info
+	}
+	return result, nil
+}
+
+// --- ip-api.com ---
+
+// ipAPIEnricher 是IPEnricher基于ip-api.com免费接口的实现，字段命名和ipinfo.io不同，
+// Enrich内部负责把ip-api.com的响应映射成统一的IPInfoResponse，调用方不需要关心具体来源。
+type ipAPIEnricher struct {
+	httpClient *http.Client
+}
+
+// ipAPIResponse 映射了ip-api.com /json/{ip}接口的响应字段
+type ipAPIResponse struct {
+	Status      string  `json:"status"`
+	Message     string  `json:"message"`
+	Country     string  `json:"country"`
+	CountryCode string  `json:"countryCode"`
+	Region      string  `json:"region"`
+	RegionName  string  `json:"regionName"`
+	City        string  `json:"city"`
+	Zip         string  `json:"zip"`
+	Lat         float64 `json:"lat"`
+	Lon         float64 `json:"lon"`
+	Timezone    string  `json:"timezone"`
+	ISP         string  `json:"isp"`
+	Org         string  `json:"org"`
+	AS          string  `json:"as"`
+	Query       string  `json:"query"`
+}
+
+// NewIPAPIEnricher 创建一个基于ip-api.com的IPEnricher，免费额度不需要token，
+// 但有更严格的每分钟请求数限制，适合作为ipinfo.io配额用尽时的备选数据源
+func NewIPAPIEnricher(httpClient *http.Client) IPEnricher {
+	return &ipAPIEnricher{httpClient: httpClient}
+}
+
+func (e *ipAPIEnricher) Enrich(ip string) (*IPInfoResponse, error) {
+	url := fmt.Sprintf("http://ip-api.com/json/%s", ip)
+	resp, err := e.httpClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw ipAPIResponse
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, err
+	}
+	if raw.Status != "success" {
+		return nil, fmt.Errorf("ip-api.com查询IP [%s] 失败: %s", ip, raw.Message)
+	}
+
+	return &IPInfoResponse{
+		IP:          raw.Query,
+		City:        raw.City,
+		Region:      raw.RegionName,
+		Country:     raw.Country,
+		CountryCode: raw.CountryCode,
+		Loc:         fmt.Sprintf("%g,%g", raw.Lat, raw.Lon),
+		Org:         strings.TrimSpace(raw.AS + " " + raw.Org),
+		Postal:      raw.Zip,
+		Timezone:    raw.Timezone,
+	}, nil
+}
+
+// --- MaxMind GeoLite2本地库 ---
+
+// maxmindEnricher预留给基于本地GeoLite2 mmdb文件的离线查询实现，不像ipinfo.io/ip-api.com
+// 那样依赖外部API配额。当前go.mod未引入解析mmdb格式所需的第三方库（如geoip2-golang），
+// 这里先给出一个诚实的占位实现：Enrich直接返回明确的"未实现"错误，而不是悄悄退化成
+// no-op或返回假数据，等到引入对应依赖时只需要替换这个类型的内部实现。
+type maxmindEnricher struct {
+	dbPath string
+}
+
+// NewMaxMindEnricher 创建一个基于本地GeoLite2 mmdb文件的IPEnricher。dbPath是mmdb文件路径，
+// 但解析逻辑尚未实现（见maxmindEnricher的类型注释），Enrich会直接返回错误。
+func NewMaxMindEnricher(dbPath string) IPEnricher {
+	return &maxmindEnricher{dbPath: dbPath}
+}
+
+func (e *maxmindEnricher) Enrich(ip string) (*IPInfoResponse, error) {
+	return nil, fmt.Errorf("MaxMind GeoLite2本地查询尚未实现（go.mod未引入mmdb解析依赖），dbPath=%s", e.dbPath)
+}
+
+// --- no-op ---
+
+// noopEnricher是IPEnricher的空实现，Enrich直接返回nil、nil，用于完全关闭IP信息富化
+// （比如内网环境地理位置信息没有意义，或者单纯不想为此消耗任何额外的网络请求）。
+type noopEnricher struct{}
+
+// NewNoopEnricher 创建一个不做任何查询的IPEnricher，IPRecord.IPInfo会始终为nil
+func NewNoopEnricher() IPEnricher {
+	return noopEnricher{}
+}
+
+func (noopEnricher) Enrich(ip string) (*IPInfoResponse, error) {
+	return nil, nil
+}
+
+// --- 缓存装饰器 ---
+
+// cachedIPInfo是cachingEnricher内部缓存的一条记录，连同查询时间一起保存，用于判断是否过期
+type cachedIPInfo struct {
+	info      *IPInfoResponse
+	fetchedAt time.Time
+}
+
+// cachingEnricher用一份带TTL的内存缓存包装任意IPEnricher，避免同一个IP在短时间内
+// 被反复查询、白白消耗API配额——processSingleDomain本身已经只对"新发现的IP"发起查询，
+// 但同一个IP仍然可能在不同域名的解析结果里重复出现，缓存能在这种跨域名场景下命中。
+type cachingEnricher struct {
+	inner IPEnricher
+	ttl   time.Duration
+	mu    sync.RWMutex
+	cache map[string]cachedIPInfo
+}
+
+// NewCachingEnricher 用ttl包装inner；ttl<=0时不做任何缓存，直接透传到inner，
+// 保持"默认不缓存"的行为不变。
+func NewCachingEnricher(inner IPEnricher, ttl time.Duration) IPEnricher {
+	if ttl <= 0 {
+		return inner
+	}
+	return &cachingEnricher{
+		inner: inner,
+		ttl:   ttl,
+		cache: make(map[string]cachedIPInfo),
+	}
+}
+
+func (e *cachingEnricher) Enrich(ip string) (*IPInfoResponse, error) {
+	if cached, ok := e.lookup(ip); ok {
+		return cached, nil
+	}
+	info, err := e.inner.Enrich(ip)
+	if err != nil {
+		return nil, err
+	}
+	e.store(ip, info)
+	return info, nil
+}
+
+// EnrichBatch让cachingEnricher在inner支持BatchIPEnricher时也具备批量能力：先用缓存命中
+// 掉已经查过的IP，只把缓存未命中的IP交给inner批量查询，查询结果再写回缓存。
+// inner不支持BatchIPEnricher时，cachingEnricher本身也不对外暴露这个方法
+// （见下面的类型断言helper asBatchEnricher），调用方会自动退回到逐个Enrich的路径。
+func (e *cachingEnricher) EnrichBatch(ips []string) (map[string]*IPInfoResponse, error) {
+	batchInner, ok := e.inner.(BatchIPEnricher)
+	if !ok {
+		return nil, fmt.Errorf("底层Enricher不支持批量查询")
+	}
+
+	result := make(map[string]*IPInfoResponse, len(ips))
+	var misses []string
+	for _, ip := range ips {
+		if cached, ok := e.lookup(ip); ok {
+			result[ip] = cached
+			continue
+		}
+		misses = append(misses, ip)
+	}
+	if len(misses) == 0 {
+		return result, nil
+	}
+
+	fetched, err := batchInner.EnrichBatch(misses)
+	if err != nil {
+		return result, err
+	}
+	for ip, info := range fetched {
+		e.store(ip, info)
+		result[ip] = info
+	}
+	return result, nil
+}
+
+func (e *cachingEnricher) lookup(ip string) (*IPInfoResponse, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	entry, ok := e.cache[ip]
+	if !ok || time.Since(entry.fetchedAt) > e.ttl {
+		return nil, false
+	}
+	return entry.info, true
+}
+
+func (e *cachingEnricher) store(ip string, info *IPInfoResponse) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.cache[ip] = cachedIPInfo{info: info, fetchedAt: time.Now()}
+}
+
+// asBatchEnricher返回enricher的BatchIPEnricher视图（支持批量查询时），否则ok为false，
+// 供processSingleDomain决定走批量路径还是逐IP的Enrich路径
+func asBatchEnricher(enricher IPEnricher) (BatchIPEnricher, bool) {
+	batch, ok := enricher.(BatchIPEnricher)
+	return batch, ok
+}
@@ -0,0 +1,103 @@
+package src // Package src 定义src包
+
+import ( // 导入所需的标准库
+	"encoding/csv"  // 用于CSV编码
+	"encoding/json" // 用于JSON编码
+	"fmt"           // 用于格式化输入输出
+	"io"            // 用于基础IO操作
+	"strconv"       // 用于布尔值到字符串的转换
+	"strings"       // 用于字符串操作
+)
+
+// IPPoolFilter 描述对IPRecord集合的过滤条件，各字段均为"零值=不过滤"语义，可以任意组合使用，
+// 供QueryDomainPool按国家/ASN/IP版本/Anycast标记筛选出运维真正想要的IP子集
+// （例如"只要ASN 15169在欧洲的IPv4地址"），而不是无条件接受DNS解析到的全部IP。
+type IPPoolFilter struct {
+	Country     string // 按国家代码过滤（IPInfoResponse.CountryCode），不区分大小写；空值不过滤
+	ASN         string // 按ASN过滤（IPInfoResponse.AS.ASN，形如"AS15169"），不区分大小写精确匹配；空值不过滤
+	IPVersion   string // "ipv4"或"ipv6"，对应GetDomainPool返回map的key；空值表示两者都要
+	AnycastOnly bool   // true时只保留IPInfo.Anycast或IPInfo.IsAnycast为true的记录
+}
+
+// matches 判断record是否满足过滤条件；record.IPInfo为nil时，只有Country/ASN/AnycastOnly
+// 均为零值（即不做任何富化信息相关的过滤）才算匹配。
+func (f IPPoolFilter) matches(record IPRecord) bool {
+	if f.Country == "" && f.ASN == "" && !f.AnycastOnly {
+		return true
+	}
+	if record.IPInfo == nil {
+		return false
+	}
+	if f.Country != "" && !strings.EqualFold(record.IPInfo.CountryCode, f.Country) {
+		return false
+	}
+	if f.ASN != "" {
+		if record.IPInfo.AS == nil || !strings.EqualFold(record.IPInfo.AS.ASN, f.ASN) {
+			return false
+		}
+	}
+	if f.AnycastOnly && !(record.IPInfo.Anycast || record.IPInfo.IsAnycast) {
+		return false
+	}
+	return true
+}
+
+// QueryDomainPool 实现了IPPoolQuerier接口。它在GetDomainPool已有的深拷贝和过期过滤基础上，
+// 再按filter筛选出匹配的记录，合并ipv4/ipv6两个分组后以单个切片返回；domain不存在时ok返回false。
+func (m *remoteIPMonitor) QueryDomainPool(domain string, filter IPPoolFilter) (records []IPRecord, ok bool) {
+	pool, found := m.GetDomainPool(domain)
+	if !found {
+		return nil, false
+	}
+	for version, list := range pool {
+		if filter.IPVersion != "" && !strings.EqualFold(filter.IPVersion, version) {
+			continue
+		}
+		for _, record := range list {
+			if filter.matches(record) {
+				records = append(records, record)
+			}
+		}
+	}
+	return records, true
+}
+
+// ExportIPRecords 把records按format（"csv"或"json"）写入w，供运维把QueryDomainPool筛选出的
+// IP子集导入热连接池配置或其它系统。CSV列固定为ip,country,asn,org,anycast，IPInfo为nil时相应列留空。
+func ExportIPRecords(w io.Writer, records []IPRecord, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(records)
+	case "csv":
+		return exportIPRecordsCSV(w, records)
+	default:
+		return fmt.Errorf("不支持的导出格式: %s", format)
+	}
+}
+
+// exportIPRecordsCSV 是ExportIPRecords的CSV分支实现
+func exportIPRecordsCSV(w io.Writer, records []IPRecord) error {
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+
+	if err := writer.Write([]string{"ip", "country", "asn", "org", "anycast"}); err != nil {
+		return err
+	}
+	for _, record := range records {
+		var country, asn, org, anycast string
+		if record.IPInfo != nil {
+			country = record.IPInfo.CountryCode
+			org = record.IPInfo.Org
+			anycast = strconv.FormatBool(record.IPInfo.Anycast || record.IPInfo.IsAnycast)
+			if record.IPInfo.AS != nil {
+				asn = record.IPInfo.AS.ASN
+			}
+		}
+		if err := writer.Write([]string{record.IP, country, asn, org, anycast}); err != nil {
+			return err
+		}
+	}
+	return writer.Error()
+}
@@ -7,9 +7,9 @@ import ( // 导入所需的标准库
 	"math/big"        // 用于大整数运算
 	mrand "math/rand" // 用于伪随机数生成
 	"net"             // 用于网络相关功能
-	"os/exec"         // 用于执行系统命令
 	"strings"         // 用于字符串操作
 	"sync"            // 用于同步原语如互斥锁
+	"sync/atomic"     // 用于原子计数
 	"time"            // 用于时间处理
 )
 
@@ -25,10 +25,22 @@ type IPPool interface { // 定义IPPool接口
 	MarkIPUnused(ip net.IP) // 标记IP地址为未使用方法
 	// SetTargetIPCount 设置目标IP数量（用于IPv6地址池动态调整）
 	SetTargetIPCount(count int) // 设置目标IP数量方法
+	// Stats 返回地址池当前的统计快照，供metrics端点或运维排查展示
+	Stats() IPPoolStats // 获取地址池统计信息的方法
 	// Closer io.Closer 接口的实现，允许使用 defer pool.Close() 的方式优雅关闭。
 	io.Closer // 嵌入Closer接口，用于资源清理
 }
 
+// IPPoolStats 是IPPool某一时刻的统计快照，仅IPv6动态地址相关的计数在仅IPv4模式下恒为0。
+type IPPoolStats struct {
+	CreatedCount     int                  // 已在系统上创建过的IPv6地址数（含已标记未使用但尚未删除的）
+	ActiveCount      int                  // 当前活跃（已创建且仍在网卡上）的IPv6地址数
+	UsedCount        int                  // 当前正被某个连接占用的IPv6地址数
+	QueuedCount      int                  // 预生成队列中等待被取用的IPv6地址数
+	CreationFailures int64                // 累计的地址创建失败次数（如exec("ip addr add")返回非零）
+	LastUsed         map[string]time.Time // 每个IPv6地址最近一次被GetIP取用的时间
+}
+
 // LocalIPPool 是一个智能IP地址池，实现了 IPPool 接口。
 // 它能够自动适应当前运行环境，管理静态IPv4地址，并在检测到可用的IPv6子网时，
 // 动态地生成海量的IPv6地址。
@@ -64,8 +76,15 @@ type LocalIPPool struct { // 定义LocalIPPool结构体，实现IPPool接口
 	// maxActiveAddrs 最大活跃地址数量
 	maxActiveAddrs int // 最大活跃地址数
 	// lastCleanupTime 上次清理时间
-	lastCleanupTime time.Time // 上次清理时间
-	cleanupMutex    sync.Mutex // 保护清理时间的互斥锁
+	lastCleanupTime time.Time  // 上次清理时间
+	cleanupMutex    sync.Mutex  // 保护清理时间的互斥锁
+	// addrMgr 按平台实际执行地址增删/枚举，见address_manager.go及其按平台的实现文件
+	addrMgr         addressManager // 平台地址管理器
+	// lastUsedIPv6 记录每个IPv6地址最近一次被GetIP取用的时间，供Stats内省使用
+	lastUsedIPv6      map[string]time.Time // 地址最近一次取用时间映射
+	lastUsedIPv6Mutex sync.RWMutex         // 保护lastUsedIPv6的互斥锁
+	// creationFailures 累计的IPv6地址创建失败次数，原子自增
+	creationFailures int64
 }
 
 // NewLocalIPPool 创建并初始化一个智能IP池。
@@ -85,10 +104,17 @@ type LocalIPPool struct { // 定义LocalIPPool结构体，实现IPPool接口
 //   - 一个实现了 IPPool 接口的实例。
 //   - 如果没有可用的IP地址（既没有有效的IPv4，IPv6环境也不支持），则返回错误。
 func NewLocalIPPool(staticIPv4s []string, ipv6SubnetCIDR string) (IPPool, error) {
+	return NewLocalIPPoolSeeded(staticIPv4s, ipv6SubnetCIDR, time.Now().UnixNano())
+}
+
+// NewLocalIPPoolSeeded 与 NewLocalIPPool 行为一致，但允许调用方显式指定随机数种子。
+// 用于调试场景下复现某次运行中IPv4地址选择和IPv6地址生成的具体序列。
+func NewLocalIPPoolSeeded(staticIPv4s []string, ipv6SubnetCIDR string, seed int64) (IPPool, error) {
 	// 初始化基础结构，包括一个私有的随机数生成器以避免全局锁。
 	pool := &LocalIPPool{ // 创建LocalIPPool实例
-		rand:     mrand.New(mrand.NewSource(time.Now().UnixNano())), // 初始化随机数生成器
-		stopChan: make(chan struct{}),                               // 创建停止信号通道
+		rand:     mrand.New(mrand.NewSource(seed)), // 初始化随机数生成器
+		stopChan: make(chan struct{}),              // 创建停止信号通道
+		addrMgr:  defaultAddressManager,            // 按当前GOOS选定的地址管理器
 	}
 
 	// 如果未提供静态IPv4地址，自动检测系统中可用的IPv4地址
@@ -158,6 +184,7 @@ func NewLocalIPPool(staticIPv4s []string, ipv6SubnetCIDR string) (IPPool, error)
 				pool.createdIPv6Addrs = make(map[string]bool)    // 初始化已创建地址映射
 				pool.usedIPv6Addrs = make(map[string]bool)       // 初始化正在使用地址映射
 				pool.activeIPv6Addrs = make(map[string]bool)     // 初始化活跃地址映射
+				pool.lastUsedIPv6 = make(map[string]time.Time)   // 初始化最近取用时间映射
 				pool.batchSize = 10                              // 默认批量操作大小：10个地址
 				pool.minActiveAddrs = 0                           // 最小活跃地址数（动态设置）
 				pool.maxActiveAddrs = 0                           // 最大活跃地址数（动态设置）
@@ -174,6 +201,7 @@ func NewLocalIPPool(staticIPv4s []string, ipv6SubnetCIDR string) (IPPool, error)
 				go pool.producer()                               // 在后台启动IPv6地址生产者。
 				go pool.manageIPv6Addresses()                    // 在后台启动IPv6地址管理器（热加载）
 			}
+			go pool.ipv6HealthMonitor() // 周期性重新探测IPv6连通性，一旦发现实际不可用则自动降级为仅IPv4模式
 		} else { // 如果子网未配置
 			fmt.Printf("[IP池] 警告: 未在当前网络环境中检测到指定的IPv6子网 %s，已降级为仅IPv4模式。\n", ipv6SubnetCIDR) // 输出日志
 		}
@@ -239,10 +267,8 @@ func (p *LocalIPPool) GetIP() net.IP { // 实现GetIP方法
 					p.activeIPv6Mutex.RUnlock()
 					
 					// 标记地址为正在使用
-					p.usedIPv6Mutex.Lock()
-					p.usedIPv6Addrs[addrStr] = true
-					p.usedIPv6Mutex.Unlock()
-					
+					p.markIPv6Used(addrStr)
+
 					return ip
 				}
 			}
@@ -273,9 +299,7 @@ func (p *LocalIPPool) GetIP() net.IP { // 实现GetIP方法
 						if ip != nil {
 							p.usedIPv6Mutex.RUnlock()
 							p.activeIPv6Mutex.RUnlock()
-							p.usedIPv6Mutex.Lock()
-							p.usedIPv6Addrs[addrStr] = true
-							p.usedIPv6Mutex.Unlock()
+							p.markIPv6Used(addrStr)
 							return ip
 						}
 					}
@@ -320,9 +344,7 @@ func (p *LocalIPPool) GetIP() net.IP { // 实现GetIP方法
 						if reuseIP != nil {
 							p.usedIPv6Mutex.RUnlock()
 							p.activeIPv6Mutex.RUnlock()
-							p.usedIPv6Mutex.Lock()
-							p.usedIPv6Addrs[addrStr] = true
-							p.usedIPv6Mutex.Unlock()
+							p.markIPv6Used(addrStr)
 							return reuseIP
 						}
 					}
@@ -365,9 +387,7 @@ func (p *LocalIPPool) GetIP() net.IP { // 实现GetIP方法
 		}
 		
 		// 标记地址为正在使用
-		p.usedIPv6Mutex.Lock()
-		p.usedIPv6Addrs[ipStr] = true
-		p.usedIPv6Mutex.Unlock()
+		p.markIPv6Used(ipStr)
 		}
 		
 		return ip
@@ -643,8 +663,43 @@ func detectAvailableIPv6Subnets() []string {
 	return subnets
 }
 
-// hasIPv6RoutingSupport 检查系统是否支持IPv6路由（可能通过隧道）
+// ipv6ProbeTargets 是用于主动探测IPv6连通性的知名公网端点（均为固定IP，不依赖DNS解析）。
+// 依次尝试多个目标，避免其中一个因为临时故障或被防火墙拦截而导致误判为IPv6不可用。
+var ipv6ProbeTargets = []string{
+	"[2001:4860:4860::8888]:53", // Google Public DNS
+	"[2606:4700:4700::1111]:53", // Cloudflare DNS
+}
+
+// ipv6ProbeTimeout 是单次探测拨号的超时时间，刻意设置得很短：
+// 探测的目的就是在IPv6实际不可达时快速失败，而不是像完整请求那样等待系统默认超时。
+const ipv6ProbeTimeout = 2 * time.Second
+
+// probeIPv6Connectivity 主动拨号一个知名的IPv6端点，验证本机是否具备真正可用的IPv6出站连接，
+// 而不只是存在隧道接口或本地接口上挂了IPv6地址（这两者都可能无法实际路由到公网）。
+func probeIPv6Connectivity() bool {
+	dialer := net.Dialer{Timeout: ipv6ProbeTimeout}
+	for _, target := range ipv6ProbeTargets {
+		conn, err := dialer.Dial("tcp6", target)
+		if err == nil {
+			conn.Close()
+			return true
+		}
+	}
+	return false
+}
+
+// hasIPv6RoutingSupport 检查系统是否支持IPv6路由（可能通过隧道），
+// 并通过probeIPv6Connectivity主动验证，避免仅凭接口存在就误判为IPv6可用，
+// 导致后续请求在实际不通的IPv6路径上长时间超时。
 func hasIPv6RoutingSupport() bool {
+	if !hasIPv6RoutingInterface() {
+		return false
+	}
+	return probeIPv6Connectivity()
+}
+
+// hasIPv6RoutingInterface 检查系统网络接口是否存在IPv6隧道或已配置IPv6地址的迹象
+func hasIPv6RoutingInterface() bool {
 	interfaces, err := net.Interfaces()
 	if err != nil {
 		return false
@@ -749,7 +804,16 @@ func detectIPv6Interface(subnet *net.IPNet) string {
 	return ""
 }
 
-// ensureIPv6AddressCreated 确保IPv6地址在系统上已创建
+// ensureIPv6AddressCreated 确保IPv6地址在系统上已创建。
+//
+// 注意：本文件里所有地址增删都是靠fork一个"ip"子进程完成的（见下方及
+// batchCreateIPv6Addresses/batchDeleteIPv6Addresses/cleanupOldIPv6Addresses），
+// 系统负载高或地址刷新频繁时这些fork+exec本身就会成为瓶颈，而且解析的是iproute2
+// 命令的文本输出，格式稍有出入就会解析失败。更合适的做法是用
+// github.com/vishvananda/netlink直接发RTM_NEWADDR/RTM_DELADDR，跳过子进程和文本
+// 解析、同时能拿到结构化的错误码（比如一眼区分EEXIST和EPERM）。这个依赖目前不在
+// go.mod里，引入前需要先确认它能覆盖当前exec.Command覆盖的每一种操作（单个/批量增删、
+// 按接口枚举现有地址），再整体替换掉本文件里的"ip"调用，而不是两套机制混着用。
 func (p *LocalIPPool) ensureIPv6AddressCreated(ip net.IP) {
 	if ip == nil {
 		return
@@ -787,10 +851,10 @@ func (p *LocalIPPool) ensureIPv6AddressCreated(ip net.IP) {
 		interfaceName = "ipv6net" // 默认接口名称
 	}
 
-	// 使用 ip addr add 命令创建地址
-	cmd := exec.Command("ip", "addr", "add", ipStr+"/128", "dev", interfaceName)
-	if err := cmd.Run(); err != nil {
+	// 通过平台地址管理器创建地址（Linux为ip addr add，Windows/macOS见address_manager_*.go）
+	if err := p.addrMgr.AddAddress(interfaceName, ip); err != nil {
 		// 创建失败，记录错误但不阻塞
+		atomic.AddInt64(&p.creationFailures, 1)
 		fmt.Printf("[IP池] 警告: 创建IPv6地址 %s 失败: %v\n", ipStr, err)
 		return
 	}
@@ -823,14 +887,17 @@ func (p *LocalIPPool) isIPv6AddressExists(ipStr string) bool {
 		interfaceName = "ipv6net"
 	}
 
-	// 使用 ip addr show 命令检查地址是否存在
-	cmd := exec.Command("ip", "-6", "addr", "show", "dev", interfaceName)
-	output, err := cmd.Output()
+	// 通过平台地址管理器枚举接口上已有的地址
+	addrs, err := p.addrMgr.ListAddresses(interfaceName)
 	if err != nil {
 		return false
 	}
-
-	return strings.Contains(string(output), ipStr)
+	for _, addr := range addrs {
+		if addr.String() == ipStr {
+			return true
+		}
+	}
+	return false
 }
 
 // cleanupCreatedIPv6Addresses 清理所有创建的IPv6地址
@@ -860,9 +927,8 @@ func (p *LocalIPPool) cleanupCreatedIPv6Addresses() {
 			continue
 		}
 		
-		// 使用 ip addr del 命令删除地址
-		cmd := exec.Command("ip", "addr", "del", ipStr+"/128", "dev", interfaceName)
-		if err := cmd.Run(); err != nil {
+		// 通过平台地址管理器删除地址
+		if err := p.addrMgr.DeleteAddress(interfaceName, ip); err != nil {
 			// 删除失败，记录但不阻塞
 			fmt.Printf("[IP池] 警告: 删除IPv6地址 %s 失败: %v\n", ipStr, err)
 		} else {
@@ -920,9 +986,8 @@ func (p *LocalIPPool) ReleaseIP(ip net.IP) {
 		interfaceName = "ipv6net"
 	}
 
-	// 使用 ip addr del 命令删除地址
-	cmd := exec.Command("ip", "addr", "del", ipStr+"/128", "dev", interfaceName)
-	if err := cmd.Run(); err != nil {
+	// 通过平台地址管理器删除地址
+	if err := p.addrMgr.DeleteAddress(interfaceName, ip); err != nil {
 		fmt.Printf("[IP池] 警告: 删除IPv6地址 %s 失败: %v\n", ipStr, err)
 	} else {
 		fmt.Printf("[IP池] 已释放IPv6地址: %s/%s\n", ipStr, interfaceName)
@@ -937,6 +1002,10 @@ func (p *LocalIPPool) ReleaseIP(ip net.IP) {
 	p.activeIPv6Mutex.Lock()
 	delete(p.activeIPv6Addrs, ipStr)
 	p.activeIPv6Mutex.Unlock()
+
+	p.lastUsedIPv6Mutex.Lock()
+	delete(p.lastUsedIPv6, ipStr)
+	p.lastUsedIPv6Mutex.Unlock()
 }
 
 // MarkIPUnused 标记IPv6地址为未使用（不立即删除，等待定期清理）
@@ -958,6 +1027,17 @@ func (p *LocalIPPool) MarkIPUnused(ip net.IP) {
 	p.usedIPv6Mutex.Unlock()
 }
 
+// markIPv6Used 把addrStr标记为正在使用，并记录本次取用时间供Stats内省使用
+func (p *LocalIPPool) markIPv6Used(addrStr string) {
+	p.usedIPv6Mutex.Lock()
+	p.usedIPv6Addrs[addrStr] = true
+	p.usedIPv6Mutex.Unlock()
+
+	p.lastUsedIPv6Mutex.Lock()
+	p.lastUsedIPv6[addrStr] = time.Now()
+	p.lastUsedIPv6Mutex.Unlock()
+}
+
 // isReservedIPv6Address 检查IPv6地址是否是系统保留地址（不应该删除）
 func isReservedIPv6Address(ip net.IP) bool {
 	if ip == nil {
@@ -1003,35 +1083,18 @@ func (p *LocalIPPool) cleanupOldIPv6Addresses(subnet *net.IPNet) {
 	}
 
 	// 获取接口上所有的IPv6地址
-	cmd := exec.Command("ip", "-6", "addr", "show", "dev", interfaceName)
-	output, err := cmd.Output()
+	addrs, err := p.addrMgr.ListAddresses(interfaceName)
 	if err != nil {
 		fmt.Printf("[IP池] 警告: 无法获取接口 %s 的IPv6地址列表: %v\n", interfaceName, err)
 		return
 	}
 
-	// 解析输出，找到所有属于子网的IPv6地址
-	lines := strings.Split(string(output), "\n")
+	// 找到所有属于子网的IPv6地址并清理
 	cleaned := 0
 	skipped := 0
 
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if !strings.HasPrefix(line, "inet6 ") {
-			continue
-		}
-
-		// 解析地址，格式如: inet6 2607:8700:5500:2943::2ca9/128 scope global
-		parts := strings.Fields(line)
-		if len(parts) < 2 {
-			continue
-		}
-
-		addrStr := strings.Split(parts[1], "/")[0] // 提取IP地址部分
-		ip := net.ParseIP(addrStr)
-		if ip == nil {
-			continue
-		}
+	for _, ip := range addrs {
+		addrStr := ip.String()
 
 		// 检查地址是否属于子网
 		if subnet.Contains(ip) {
@@ -1047,10 +1110,9 @@ func (p *LocalIPPool) cleanupOldIPv6Addresses(subnet *net.IPNet) {
 				skipped++
 				continue
 			}
-			
+
 			// 删除地址
-			delCmd := exec.Command("ip", "addr", "del", ip.String()+"/128", "dev", interfaceName)
-			if err := delCmd.Run(); err != nil {
+			if err := p.addrMgr.DeleteAddress(interfaceName, ip); err != nil {
 				// 删除失败，可能是系统保留地址，静默跳过
 				skipped++
 			} else {
@@ -1070,6 +1132,39 @@ func (p *LocalIPPool) cleanupOldIPv6Addresses(subnet *net.IPNet) {
 	}
 }
 
+// ipv6HealthMonitorInterval 是重新探测IPv6连通性的周期。
+const ipv6HealthMonitorInterval = 1 * time.Minute
+
+// ipv6HealthMonitor 周期性地重新执行probeIPv6Connectivity，一旦发现IPv6实际已不可达
+// （例如隧道掉线、ISP临时收回IPv6前缀），立即将连接池降级为仅IPv4模式，
+// 避免上层请求继续尝试走一个已经失效的IPv6路径而长时间超时。
+// 降级是单向的：本次运行中一旦探测失败就不再恢复，符合“自动降级”而非“抖动式切换”的语义。
+func (p *LocalIPPool) ipv6HealthMonitor() {
+	ticker := time.NewTicker(ipv6HealthMonitorInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stopChan:
+			return
+		case <-ticker.C:
+			if probeIPv6Connectivity() {
+				continue
+			}
+
+			p.mu.Lock()
+			wasSupported := p.hasIPv6Support
+			p.hasIPv6Support = false
+			p.mu.Unlock()
+
+			if wasSupported {
+				fmt.Println("[IP池] 重新探测发现IPv6连通性已失效，已自动降级为仅IPv4模式")
+			}
+			return
+		}
+	}
+}
+
 // manageIPv6Addresses 后台管理IPv6地址的热加载（动态创建和删除）
 func (p *LocalIPPool) manageIPv6Addresses() {
 	adjustTicker := time.NewTicker(30 * time.Second)  // 每30秒检查一次地址池大小
@@ -1167,9 +1262,9 @@ func (p *LocalIPPool) batchCreateIPv6Addresses(count int, subnet *net.IPNet, int
 		}
 
 		// 创建地址（批量创建时不打印单个地址的日志，避免日志过多）
-		cmd := exec.Command("ip", "addr", "add", ipStr+"/128", "dev", interfaceName)
-		if err := cmd.Run(); err != nil {
+		if err := p.addrMgr.AddAddress(interfaceName, ip); err != nil {
 			// 创建失败，静默跳过（批量创建时不需要每个都打印）
+			atomic.AddInt64(&p.creationFailures, 1)
 			continue
 		}
 
@@ -1201,8 +1296,7 @@ func (p *LocalIPPool) batchDeleteIPv6Addresses(count int, interfaceName string,
 		}
 		
 		// 删除地址
-		cmd := exec.Command("ip", "addr", "del", ipStr+"/128", "dev", interfaceName)
-		if err := cmd.Run(); err != nil {
+		if err := p.addrMgr.DeleteAddress(interfaceName, ip); err != nil {
 			fmt.Printf("[IP池] 警告: 批量删除IPv6地址 %s 失败: %v\n", ipStr, err)
 			continue
 		}
@@ -1214,6 +1308,9 @@ func (p *LocalIPPool) batchDeleteIPv6Addresses(count int, interfaceName string,
 		p.activeIPv6Mutex.Lock()
 		delete(p.activeIPv6Addrs, ipStr)
 		p.activeIPv6Mutex.Unlock()
+		p.lastUsedIPv6Mutex.Lock()
+		delete(p.lastUsedIPv6, ipStr)
+		p.lastUsedIPv6Mutex.Unlock()
 
 		deleted++
 	}
@@ -1291,6 +1388,41 @@ func (p *LocalIPPool) SetTargetIPCount(count int) {
 	fmt.Printf("[IP池] 已设置目标IP数量: %d，IPv6地址池大小: %d（与RemoteDomainIPPool对等）\n", count, count)
 }
 
+// Stats 返回地址池当前的统计快照，用于metrics端点或运维排查，
+// 取代此前散落各处、只能靠肉眼看fmt.Printf日志才能了解池内部状态的做法。
+func (p *LocalIPPool) Stats() IPPoolStats {
+	stats := IPPoolStats{
+		CreationFailures: atomic.LoadInt64(&p.creationFailures),
+	}
+
+	p.createdIPv6Mutex.RLock()
+	stats.CreatedCount = len(p.createdIPv6Addrs)
+	p.createdIPv6Mutex.RUnlock()
+
+	p.activeIPv6Mutex.RLock()
+	stats.ActiveCount = len(p.activeIPv6Addrs)
+	p.activeIPv6Mutex.RUnlock()
+
+	p.usedIPv6Mutex.RLock()
+	stats.UsedCount = len(p.usedIPv6Addrs)
+	p.usedIPv6Mutex.RUnlock()
+
+	p.mu.RLock()
+	if p.ipv6Queue != nil {
+		stats.QueuedCount = len(p.ipv6Queue)
+	}
+	p.mu.RUnlock()
+
+	p.lastUsedIPv6Mutex.RLock()
+	stats.LastUsed = make(map[string]time.Time, len(p.lastUsedIPv6))
+	for addr, t := range p.lastUsedIPv6 {
+		stats.LastUsed[addr] = t
+	}
+	p.lastUsedIPv6Mutex.RUnlock()
+
+	return stats
+}
+
 // cleanupUnusedIPv6Addresses 每20分钟清理一次未使用的IPv6地址，并替换为新地址
 // 策略：删除空闲地址并创建新地址替换，保持地址池大小与目标IP数量对等
 func (p *LocalIPPool) cleanupUnusedIPv6Addresses() {
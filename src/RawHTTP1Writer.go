@@ -0,0 +1,60 @@
+package src
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// writeRawHTTP1Request 按headerOrder指定的顺序和调用方传入的原始大小写，把请求行与请求头
+// 直接写入w，不经过net/http.Request.Write。标准库会把头部名统一规范化为Canonical-Case
+// 并按自己的内部顺序输出，这会抹掉指纹要掩护的浏览器头部顺序特征，因此这里手写HTTP/1.1报文。
+// headers中未被headerOrder覆盖的头部按名称排序追加在后面，保证同一份headers多次调用输出一致。
+func writeRawHTTP1Request(w io.Writer, method, path, host string, headers map[string]string, headerOrder []string, body []byte) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s %s HTTP/1.1\r\n", method, path)
+
+	written := make(map[string]bool, len(headers))
+	writeHeader := func(name string) {
+		value, ok := headers[name]
+		key := strings.ToLower(name)
+		if !ok || written[key] {
+			return
+		}
+		fmt.Fprintf(&buf, "%s: %s\r\n", name, value)
+		written[key] = true
+	}
+
+	// Host是HTTP/1.1请求里事实上必须最先出现的头部，所有主流浏览器都是如此
+	if host != "" {
+		fmt.Fprintf(&buf, "Host: %s\r\n", host)
+	}
+
+	for _, name := range headerOrder {
+		writeHeader(name)
+	}
+
+	remaining := make([]string, 0, len(headers))
+	for name := range headers {
+		if !written[strings.ToLower(name)] {
+			remaining = append(remaining, name)
+		}
+	}
+	sort.Strings(remaining)
+	for _, name := range remaining {
+		writeHeader(name)
+	}
+
+	if len(body) > 0 {
+		fmt.Fprintf(&buf, "Content-Length: %d\r\n", len(body))
+	}
+	buf.WriteString("\r\n")
+	if len(body) > 0 {
+		buf.Write(body)
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
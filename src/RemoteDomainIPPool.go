@@ -4,7 +4,6 @@ import ( // 导入所需的标准库和第三方库
 	"bytes"         // 用于操作字节缓冲区
 	"encoding/json" // 用于JSON编码解码
 	"fmt"           // 用于格式化输入输出
-	"io"            // 用于基础IO操作
 	"net"           // 用于网络相关功能
 	"net/http"      // 用于HTTP客户端功能
 	"os"            // 用于操作系统功能
@@ -16,6 +15,8 @@ import ( // 导入所需的标准库和第三方库
 	"github.com/BurntSushi/toml" // 用于TOML格式解析
 	"github.com/miekg/dns"       // 用于DNS查询功能
 	"gopkg.in/yaml.v3"           // 用于YAML格式解析
+
+	"utlsProxy/internal/safego"
 )
 
 // --- 1. 接口定义 ---
@@ -31,6 +32,46 @@ type DomainMonitor interface { // 定义DomainMonitor接口
 	// 返回的数据是深拷贝，可以安全地被调用方修改。
 	// 如果找不到该域名的数据，返回的 bool 值为 false。
 	GetDomainPool(domain string) (map[string][]IPRecord, bool) // 获取域名IP池方法
+	// InjectStaticIPs 把运维通过配置或管理接口获得的IP手工注入指定域名的IP池，
+	// 注入的记录带有Source="manual"标记，并与DNS发现的IP走相同的白名单/预热流程。
+	// expiresAt为零值表示永不过期；到期后的记录会在下一次GetDomainPool查询时被自动过滤掉。
+	InjectStaticIPs(domain string, ips []string, expiresAt time.Time) error // 注入静态IP方法
+}
+
+// ResolverHealthProvider 是DomainMonitor的可选扩展接口，由remoteIPMonitor实现，暴露DNS
+// 服务器级别的健康状况，供需要观测或导出这份数据的调用方按类型断言取用（不放进DomainMonitor
+// 本身，是因为"按DNS服务器计分"是DNS解析场景特有的能力，其余DomainMonitor实现不必被迫支持它，
+// 与IPEnricher/BatchIPEnricher的关系相同）。
+type ResolverHealthProvider interface {
+	// ResolverHealth 返回所有出现过查询记录的DNS服务器截至目前的健康状况快照
+	ResolverHealth() map[string]DNSServerHealth
+}
+
+// DNSServerHealth 是单个DNS服务器健康状况的只读快照。
+type DNSServerHealth struct {
+	Server           string // 服务器地址（含端口）
+	SuccessCount     int64  // 累计查询成功次数
+	FailureCount     int64  // 累计查询失败（超时/连接错误等传输层错误）次数
+	AverageLatencyMs int64  // 成功查询的平均耗时（毫秒）
+	Skipped          bool   // 当前是否因连续失败过多被临时跳过，见dnsServerFailThreshold
+}
+
+// AliasProvider 是DomainMonitor的可选扩展接口，由remoteIPMonitor实现，暴露域名解析过程中
+// 发现的CNAME别名链（例如被CDN接管的域名kh.google.com最终指向哪个CDN别名），供需要归因IP池
+// 来源或观察CDN切换事件的调用方按类型断言取用，与ResolverHealthProvider相对DomainMonitor的关系相同。
+type AliasProvider interface {
+	// GetDomainAliases 返回domain当前已知的CNAME别名链，按解析顺序从domain指向的第一个别名
+	// 排列到最终别名目标；domain没有CNAME记录或尚未成功解析过时，ok返回false。
+	GetDomainAliases(domain string) ([]string, bool)
+}
+
+// IPPoolQuerier 是DomainMonitor的可选扩展接口，由remoteIPMonitor实现，在GetDomainPool
+// 的基础上支持按IPPoolFilter筛选，供需要按国家/ASN/IP版本/Anycast挑选IP子集的调用方
+// （例如把筛选结果导出给热连接池）按类型断言取用，与ResolverHealthProvider/AliasProvider
+// 相对DomainMonitor的关系相同。
+type IPPoolQuerier interface {
+	// QueryDomainPool 返回domain的IP池中满足filter的记录；domain不存在时ok返回false。
+	QueryDomainPool(domain string, filter IPPoolFilter) (records []IPRecord, ok bool)
 }
 
 // --- 2. 数据结构定义 ---
@@ -39,6 +80,18 @@ type DomainMonitor interface { // 定义DomainMonitor接口
 type IPRecord struct { // 定义IP记录结构体
 	IP     string          `json:"ip"`      // IP地址
 	IPInfo *IPInfoResponse `json:"ip_info"` // IP信息详情
+	// Source 标记该IP的来源，空值（旧数据或DNS发现）视为"dns"，"manual"表示由InjectStaticIPs注入
+	Source string `json:"source,omitempty"` // IP来源
+	// ExpiresAt 仅对Source="manual"的记录有意义，为零值表示永不过期
+	ExpiresAt time.Time `json:"expires_at,omitempty"` // 过期时间
+}
+
+// manualIPSource 是手工注入的IP记录的Source取值
+const manualIPSource = "manual"
+
+// isExpired 返回该记录是否已过期（仅ExpiresAt非零且早于当前时间时才算过期）
+func (r IPRecord) isExpired(now time.Time) bool {
+	return !r.ExpiresAt.IsZero() && now.After(r.ExpiresAt)
 }
 
 // IPInfoAS 映射了 ipinfo.io API 的ASN信息。
@@ -96,21 +149,72 @@ type MonitorConfig struct { // 定义监视器配置结构体
 	UpdateInterval time.Duration // 更新间隔
 	StorageDir     string        // 存储结果文件的目录
 	StorageFormat  string        // 存储格式 (json, yaml, toml)
+	// Enricher 决定新发现IP的归属地/ASN信息查询走哪家数据源，实现了IPEnricher接口，
+	// 为nil时默认使用NewIPInfoEnricher(httpClient, IPInfoToken)，与升级前的行为一致。
+	// 需要切换到ip-api.com、接入MaxMind本地库或完全关闭富化查询时，传入对应的实现即可，
+	// 见src/IPEnricher.go。
+	Enricher IPEnricher
+	// EnricherCacheTTL 大于0时，用NewCachingEnricher把Enricher包一层TTL内存缓存，
+	// 避免同一个IP在不同域名的解析结果里重复出现时被反复查询；<=0（默认）时不缓存，
+	// 与升级前的行为一致。
+	EnricherCacheTTL time.Duration
+	// EnrichConcurrency 控制enrichIPs回退路径（Enricher不支持批量查询时）单次最多同时进行的
+	// 并发查询数，避免像突然发现200个新IP这种情况下把ip-api.com这类严格限速的数据源打爆配额；
+	// <=0（默认）时用20。
+	EnrichConcurrency int
+	// EnrichMaxRetries 单个IP查询失败后的最大重试次数（不含首次尝试）；<=0（默认）时用2。
+	EnrichMaxRetries int
+	// EnrichRetryBackoff 每次重试前的等待时间，第N次重试等待EnrichRetryBackoff*N（线性退避）；
+	// <=0（默认）时用1秒。
+	EnrichRetryBackoff time.Duration
 }
 
 // remoteIPMonitor 是 DomainMonitor 接口的一个具体实现。
 type remoteIPMonitor struct { // 定义远程IP监视器结构体
-	config     MonitorConfig                    // 监视器配置
-	ticker     *time.Ticker                     // 定时器
-	stopChan   chan struct{}                    // 停止信号通道
-	httpClient *http.Client                     // HTTP客户端
-	mu         sync.RWMutex                     // 读写互斥锁
-	latestData map[string]map[string][]IPRecord // 最新数据缓存
+	config             MonitorConfig                    // 监视器配置
+	ticker             *time.Ticker                     // 定时器
+	stopChan           chan struct{}                    // 停止信号通道
+	httpClient         *http.Client                     // HTTP客户端
+	enricher           IPEnricher                       // 新发现IP的信息富化数据源，见MonitorConfig.Enricher
+	enrichConcurrency  int                              // 见MonitorConfig.EnrichConcurrency
+	enrichMaxRetries   int                              // 见MonitorConfig.EnrichMaxRetries
+	enrichRetryBackoff time.Duration                    // 见MonitorConfig.EnrichRetryBackoff
+	mu                 sync.RWMutex                     // 读写互斥锁
+	latestData         map[string]map[string][]IPRecord // 最新数据缓存
+	dnsHealthMu        sync.RWMutex                     // 保护dnsHealth的读写互斥锁
+	dnsHealth          map[string]*dnsServerStats       // 按DNS服务器地址记录的健康统计数据
+	aliasMu            sync.RWMutex                     // 保护aliases的读写互斥锁
+	aliases            map[string][]string              // 按域名记录的已知CNAME别名链，见AliasProvider
 }
 
+// dnsServerStats 是单个DNS服务器健康统计数据的内部可变状态，受remoteIPMonitor.dnsHealthMu保护。
+type dnsServerStats struct {
+	successCount      int64     // 累计成功次数
+	failureCount      int64     // 累计失败次数
+	totalLatencyNanos int64     // 仅累加成功查询的耗时，用于计算平均延迟
+	consecutiveFails  int       // 连续失败次数，归零于下一次成功
+	skipUntil         time.Time // 非零值且晚于当前时间时，表示该服务器被临时跳过
+}
+
+// dnsServerFailThreshold 连续失败达到这个次数后，开始临时跳过该服务器，不再浪费一整个
+// DNSQueryTimeout等它超时；每多失败一次，跳过时长按dnsServerSkipStep线性增加，
+// 直到dnsServerMaxSkip封顶，避免偶发抖动的服务器被无限期拉黑。
+const dnsServerFailThreshold = 5
+const dnsServerSkipStep = time.Minute
+const dnsServerMaxSkip = 30 * time.Minute
+
 // 确保 remoteIPMonitor 实现了 DomainMonitor 接口 (编译时检查)
 var _ DomainMonitor = (*remoteIPMonitor)(nil) // 编译时接口实现检查
 
+// 确保 remoteIPMonitor 实现了 ResolverHealthProvider 接口 (编译时检查)
+var _ ResolverHealthProvider = (*remoteIPMonitor)(nil) // 编译时接口实现检查
+
+// 确保 remoteIPMonitor 实现了 AliasProvider 接口 (编译时检查)
+var _ AliasProvider = (*remoteIPMonitor)(nil) // 编译时接口实现检查
+
+// 确保 remoteIPMonitor 实现了 IPPoolQuerier 接口 (编译时检查)
+var _ IPPoolQuerier = (*remoteIPMonitor)(nil) // 编译时接口实现检查
+
 // NewRemoteIPMonitor 创建并验证一个新的监视器实例。
 // 参数：config - 监视器配置
 // 返回值：DomainMonitor接口实例和错误信息
@@ -139,14 +243,153 @@ func NewRemoteIPMonitor(config MonitorConfig) (DomainMonitor, error) {
 		},
 	}
 
+	enricher := config.Enricher // 未显式指定Enricher时默认沿用原有的ipinfo.io行为
+	if enricher == nil {
+		enricher = NewIPInfoEnricher(httpClient, config.IPInfoToken)
+	}
+	enricher = NewCachingEnricher(enricher, config.EnricherCacheTTL) // TTL<=0时原样返回，不包装
+
+	enrichConcurrency := config.EnrichConcurrency // 未显式指定时给出合理默认值，避免上百个新IP打满下游API速率限制
+	if enrichConcurrency <= 0 {
+		enrichConcurrency = 20
+	}
+	enrichMaxRetries := config.EnrichMaxRetries // 单个IP查询失败后的最大重试次数
+	if enrichMaxRetries <= 0 {
+		enrichMaxRetries = 2
+	}
+	enrichRetryBackoff := config.EnrichRetryBackoff // 每次重试前的等待时间
+	if enrichRetryBackoff <= 0 {
+		enrichRetryBackoff = time.Second
+	}
+
 	return &remoteIPMonitor{ // 返回远程IP监视器实例
-		config:     config,                                 // 设置配置
-		stopChan:   make(chan struct{}),                    // 创建停止信号通道
-		httpClient: httpClient,                             // 设置HTTP客户端
-		latestData: make(map[string]map[string][]IPRecord), // 初始化最新数据缓存
+		config:             config,                                 // 设置配置
+		stopChan:           make(chan struct{}),                    // 创建停止信号通道
+		httpClient:         httpClient,                             // 设置HTTP客户端
+		enricher:           enricher,                               // 设置IP信息富化数据源
+		enrichConcurrency:  enrichConcurrency,                      // 设置富化查询并发度上限
+		enrichMaxRetries:   enrichMaxRetries,                       // 设置富化查询最大重试次数
+		enrichRetryBackoff: enrichRetryBackoff,                     // 设置富化查询重试退避时间
+		latestData:         make(map[string]map[string][]IPRecord), // 初始化最新数据缓存
+		dnsHealth:          make(map[string]*dnsServerStats),       // 初始化DNS服务器健康统计
+		aliases:            make(map[string][]string),              // 初始化CNAME别名链缓存
 	}, nil
 }
 
+// GetDomainAliases 实现了AliasProvider接口。
+func (m *remoteIPMonitor) GetDomainAliases(domain string) ([]string, bool) {
+	m.aliasMu.RLock()
+	defer m.aliasMu.RUnlock()
+	chain, ok := m.aliases[domain]
+	if !ok {
+		return nil, false
+	}
+	cloned := make([]string, len(chain)) // 返回深拷贝，避免调用方修改内部切片
+	copy(cloned, chain)
+	return cloned, true
+}
+
+// recordDomainAlias 更新domain当前已知的CNAME别名链，并在链发生变化时打印一条事件日志，
+// 供运维观察CDN侧的别名切换（例如域名从一个CDN别名切换到另一个）。
+// chain为空时直接忽略，不会用空链覆盖已知的别名链——本轮全部DNS查询都解析失败时chain也会是空的，
+// 这种情况不应该被误判为"别名被移除"。
+func (m *remoteIPMonitor) recordDomainAlias(domain string, chain []string) {
+	if len(chain) == 0 {
+		return
+	}
+
+	m.aliasMu.Lock()
+	defer m.aliasMu.Unlock()
+
+	previous, existed := m.aliases[domain]
+	if existed && stringSlicesEqual(previous, chain) { // 别名链未发生变化
+		return
+	}
+
+	m.aliases[domain] = chain
+	if existed {
+		fmt.Printf("域名 [%s] 的CNAME别名链发生变化: %v -> %v\n", domain, previous, chain) // 输出别名变更事件日志
+	} else {
+		fmt.Printf("域名 [%s] 首次解析到CNAME别名链: %v\n", domain, chain) // 输出首次发现别名事件日志
+	}
+}
+
+// stringSlicesEqual 比较两个字符串切片的元素和顺序是否完全一致
+func stringSlicesEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// ResolverHealth 实现了ResolverHealthProvider接口。
+func (m *remoteIPMonitor) ResolverHealth() map[string]DNSServerHealth {
+	m.dnsHealthMu.RLock()
+	defer m.dnsHealthMu.RUnlock()
+
+	now := time.Now()
+	health := make(map[string]DNSServerHealth, len(m.dnsHealth)) // 声明健康状况快照映射
+	for server, stats := range m.dnsHealth {                     // 遍历所有已记录的服务器
+		var avgLatencyMs int64
+		if stats.successCount > 0 { // 只对成功的查询计入平均延迟，避免超时耗时拉高数值
+			avgLatencyMs = stats.totalLatencyNanos / stats.successCount / int64(time.Millisecond)
+		}
+		health[server] = DNSServerHealth{ // 构造只读快照
+			Server:           server,
+			SuccessCount:     stats.successCount,
+			FailureCount:     stats.failureCount,
+			AverageLatencyMs: avgLatencyMs,
+			Skipped:          !stats.skipUntil.IsZero() && now.Before(stats.skipUntil),
+		}
+	}
+	return health
+}
+
+// recordDNSQueryResult 记录一次针对某DNS服务器的查询结果，用于更新其健康状况；
+// 连续失败达到dnsServerFailThreshold次后，按dnsServerSkipStep线性增加跳过时长（上限dnsServerMaxSkip），
+// 下一次查询成功后立即清除跳过状态（不等冷却到期）。
+func (m *remoteIPMonitor) recordDNSQueryResult(server string, success bool, latency time.Duration) {
+	m.dnsHealthMu.Lock()
+	defer m.dnsHealthMu.Unlock()
+
+	stats, exists := m.dnsHealth[server] // 查找该服务器已有的统计数据
+	if !exists {                         // 第一次见到这个服务器
+		stats = &dnsServerStats{}   // 创建新的统计数据
+		m.dnsHealth[server] = stats // 加入健康统计映射
+	}
+
+	if success { // 查询成功
+		stats.successCount++                      // 累加成功次数
+		stats.totalLatencyNanos += int64(latency) // 累加耗时
+		stats.consecutiveFails = 0                // 重置连续失败计数
+		stats.skipUntil = time.Time{}             // 恢复正常，清除跳过状态
+		return
+	}
+
+	stats.failureCount++                                  // 累加失败次数
+	stats.consecutiveFails++                              // 累加连续失败次数
+	if stats.consecutiveFails >= dnsServerFailThreshold { // 连续失败次数达到阈值
+		backoff := time.Duration(stats.consecutiveFails-dnsServerFailThreshold+1) * dnsServerSkipStep // 线性递增的跳过时长
+		if backoff > dnsServerMaxSkip {                                                               // 超过上限时封顶
+			backoff = dnsServerMaxSkip
+		}
+		stats.skipUntil = time.Now().Add(backoff) // 设置跳过截止时间
+	}
+}
+
+// shouldSkipDNSServer 判断某DNS服务器当前是否因连续失败过多处于临时跳过状态
+func (m *remoteIPMonitor) shouldSkipDNSServer(server string) bool {
+	m.dnsHealthMu.RLock()
+	defer m.dnsHealthMu.RUnlock()
+	stats, exists := m.dnsHealth[server]
+	return exists && !stats.skipUntil.IsZero() && time.Now().Before(stats.skipUntil)
+}
+
 // Start 实现了 DomainMonitor 接口。
 func (m *remoteIPMonitor) Start() { // 实现Start方法
 	fmt.Println("域名IP监视器已启动...")                       // 输出启动日志
@@ -174,23 +417,82 @@ func (m *remoteIPMonitor) GetDomainPool(domain string) (map[string][]IPRecord, b
 	if !found {                         // 如果未找到
 		return nil, false // 返回nil和false
 	}
-	// 返回深拷贝以保证线程安全
+	// 返回深拷贝以保证线程安全，同时过滤掉已过期的手工注入记录
+	now := time.Now()
 	copiedPool := make(map[string][]IPRecord, len(pool)) // 创建拷贝池
 	for key, records := range pool {                     // 遍历数据
-		copiedRecords := make([]IPRecord, len(records)) // 创建记录拷贝
-		copy(copiedRecords, records)                    // 拷贝记录
-		copiedPool[key] = copiedRecords                 // 设置拷贝池数据
+		copiedRecords := make([]IPRecord, 0, len(records)) // 创建记录拷贝
+		for _, record := range records {                   // 逐条过滤过期记录
+			if record.isExpired(now) {
+				continue
+			}
+			copiedRecords = append(copiedRecords, record)
+		}
+		copiedPool[key] = copiedRecords // 设置拷贝池数据
 	}
 	return copiedPool, true // 返回拷贝池和true
 }
 
+// domainFilePath 返回指定域名数据文件的存储路径，与processSingleDomain使用的规则一致
+func (m *remoteIPMonitor) domainFilePath(domain string) string {
+	fileName := strings.ReplaceAll(domain, ".", "_") + "." + m.config.StorageFormat
+	return filepath.Join(m.config.StorageDir, fileName)
+}
+
+// InjectStaticIPs 实现了 DomainMonitor 接口：把ips以Source="manual"写入指定域名的IP池，
+// 按IPv4/IPv6分类追加到现有数据中（同一IP已存在时更新其过期时间），立即落盘并刷新内存缓存，
+// 使其和DNS发现的IP一样能被PoolManager/domainConnPool的白名单和预热逻辑直接使用。
+func (m *remoteIPMonitor) InjectStaticIPs(domain string, ips []string, expiresAt time.Time) error {
+	if domain == "" {
+		return fmt.Errorf("domain 不能为空")
+	}
+	if len(ips) == 0 {
+		return fmt.Errorf("ips 不能为空")
+	}
+
+	filePath := m.domainFilePath(domain)
+	domainPool := m.loadDomainData(filePath)
+
+	upsert := func(bucket string, ip string) {
+		records := domainPool[bucket]
+		for i := range records {
+			if records[i].IP == ip {
+				records[i].Source = manualIPSource
+				records[i].ExpiresAt = expiresAt
+				domainPool[bucket] = records
+				return
+			}
+		}
+		domainPool[bucket] = append(records, IPRecord{IP: ip, Source: manualIPSource, ExpiresAt: expiresAt})
+	}
+
+	for _, ip := range ips {
+		ip = strings.TrimSpace(ip)
+		if ip == "" {
+			continue
+		}
+		if parsed := net.ParseIP(ip); parsed != nil && parsed.To4() == nil {
+			upsert("ipv6", ip)
+		} else {
+			upsert("ipv4", ip)
+		}
+	}
+
+	m.setLatestDomainData(domain, domainPool)
+	if err := m.saveDomainData(filePath, domainPool); err != nil {
+		return fmt.Errorf("保存手工注入的IP失败: %w", err)
+	}
+	fmt.Printf("域名 [%s]: 手工注入了 %d 个静态IP（过期时间: %v）\n", domain, len(ips), expiresAt)
+	return nil
+}
+
 // run 是在后台goroutine中运行的主循环。
 func (m *remoteIPMonitor) run() { // 运行方法
-	m.updateAllDomains() // 更新所有域名
-	for {                // 无限循环
+	safego.Protect("remoteIPMonitor.updateAllDomains", m.updateAllDomains) // 更新所有域名
+	for {                                                                  // 无限循环
 		select {
 		case <-m.ticker.C: // 定时器触发
-			m.updateAllDomains() // 更新所有域名
+			safego.Protect("remoteIPMonitor.updateAllDomains", m.updateAllDomains) // 更新所有域名
 		case <-m.stopChan: // 收到停止信号
 			return // 退出循环
 		}
@@ -205,8 +507,8 @@ func (m *remoteIPMonitor) updateAllDomains() { // 更新所有域名方法
 	for _, domain := range m.config.Domains { // 遍历域名列表
 		wg.Add(1)           // 增加等待计数
 		go func(d string) { // 启动goroutine处理单个域名
-			defer wg.Done()          // 延迟减少等待计数
-			m.processSingleDomain(d) // 处理单个域名
+			defer wg.Done()                                                                            // 延迟减少等待计数
+			safego.Protect("remoteIPMonitor.processSingleDomain", func() { m.processSingleDomain(d) }) // 处理单个域名，一个域名panic不影响其余域名
 		}(domain) // 传递域名参数
 	}
 	wg.Wait()                                                       // 等待所有域名处理完成
@@ -217,8 +519,7 @@ func (m *remoteIPMonitor) updateAllDomains() { // 更新所有域名方法
 // 参数：domain - 要处理的域名
 func (m *remoteIPMonitor) processSingleDomain(domain string) { // 处理单个域名方法
 	// 1. 构建此域名的专属文件路径
-	fileName := strings.ReplaceAll(domain, ".", "_") + "." + m.config.StorageFormat // 构造文件名
-	filePath := filepath.Join(m.config.StorageDir, fileName)                        // 构造文件路径
+	filePath := m.domainFilePath(domain) // 构造文件路径
 
 	// 2. 加载此域名的历史数据
 	domainPool := m.loadDomainData(filePath) // 加载域名数据
@@ -247,33 +548,37 @@ func (m *remoteIPMonitor) processSingleDomain(domain string) { // 处理单个
 	}
 	fmt.Printf("域名 [%s]: 发现 %d 个新IP需要查询信息。\n", domain, len(newIPsForThisDomain)) // 输出发现新IP日志
 
-	// 5. 只为这些新IP查询信息
-	if len(newIPsForThisDomain) > 0 { // 如果有新IP
-		var wgIPInfo sync.WaitGroup              // 声明IP信息等待组
-		var muDomainPool sync.Mutex              // 使用一个专用于此goroutine的锁来保护domainPool的并发追加
-		for _, ip := range newIPsForThisDomain { // 遍历新IP
-			wgIPInfo.Add(1)         // 增加等待计数
-			go func(ipStr string) { // 启动goroutine查询IP信息
-				defer wgIPInfo.Done()             // 延迟减少等待计数
-				info, err := m.fetchIPInfo(ipStr) // 获取IP信息
-				if err == nil {                   // 如果获取成功
-					record := IPRecord{IP: ipStr, IPInfo: info}   // 创建IP记录
-					isIPv4 := net.ParseIP(record.IP).To4() != nil // 判断是否为IPv4
-
-					muDomainPool.Lock() // 加锁
-					if isIPv4 {         // 如果是IPv4
-						domainPool["ipv4"] = append(domainPool["ipv4"], record) // 添加到IPv4记录
-					} else { // 如果是IPv6
-						domainPool["ipv6"] = append(domainPool["ipv6"], record) // 添加到IPv6记录
-					}
-					muDomainPool.Unlock() // 解锁
-				}
-			}(ip) // 传递IP参数
+	// 5. 加上上一轮重试耗尽仍未查询成功、留待本轮重试的IP
+	pendingFilePath := m.domainPendingFilePath(domain)  // 构造待重试列表文件路径
+	pendingIPs := m.loadPendingRetries(pendingFilePath) // 加载上一轮遗留的失败IP
+	if len(pendingIPs) > 0 {                            // 如果有遗留的失败IP
+		fmt.Printf("域名 [%s]: 另有 %d 个IP上一轮查询失败，本轮一并重试。\n", domain, len(pendingIPs))      // 输出日志
+		newIPsForThisDomain = uniqueStrings(append(newIPsForThisDomain, pendingIPs...)) // 合并去重
+	}
+
+	// 6. 只为这些IP查询信息，查询仍然失败的IP记录下来供下一轮重试
+	var stillFailedIPs []string       // 声明本轮仍然查询失败的IP列表
+	if len(newIPsForThisDomain) > 0 { // 如果有IP需要查询
+		var enriched map[string]*IPInfoResponse                     // 声明查询结果映射
+		enriched, stillFailedIPs = m.enrichIPs(newIPsForThisDomain) // 用有界工作池+重试退避查询
+		for ip, info := range enriched {                            // 遍历查询到信息的IP
+			record := IPRecord{IP: ip, IPInfo: info}      // 创建IP记录
+			isIPv4 := net.ParseIP(record.IP).To4() != nil // 判断是否为IPv4
+			if isIPv4 {                                   // 如果是IPv4
+				domainPool["ipv4"] = append(domainPool["ipv4"], record) // 添加到IPv4记录
+			} else { // 如果是IPv6
+				domainPool["ipv6"] = append(domainPool["ipv6"], record) // 添加到IPv6记录
+			}
+		}
+		if len(stillFailedIPs) > 0 { // 如果仍有查询失败的IP
+			fmt.Printf("域名 [%s]: %d 个IP重试后仍然查询失败，已记录下来留待下一轮重试。\n", domain, len(stillFailedIPs)) // 输出日志
 		}
-		wgIPInfo.Wait() // 等待所有IP信息查询完成
+	}
+	if err := m.savePendingRetries(pendingFilePath, stillFailedIPs); err != nil { // 保存本轮待重试列表
+		fmt.Printf("错误: 域名 [%s] 无法保存待重试IP列表到 %s: %v\n", domain, pendingFilePath, err) // 输出错误日志
 	}
 
-	// 6. 更新内存缓存和文件
+	// 7. 更新内存缓存和文件
 	m.setLatestDomainData(domain, domainPool)
 
 	if err := m.saveDomainData(filePath, domainPool); err != nil { // 保存域名数据
@@ -291,6 +596,9 @@ func (m *remoteIPMonitor) resolveDomainConcurrently(domain string) ([]string, []
 	var wg sync.WaitGroup         // 声明等待组
 	maxWorkers := 50              // 并发DNS查询工作线程数
 
+	var chainMu sync.Mutex  // 保护cnameChain的并发写入
+	var cnameChain []string // 本轮解析中第一个查到的CNAME别名链，用于追踪CDN接管等别名场景
+
 	serverChan := make(chan string, len(m.config.DNSServers)) // 创建服务器通道
 	for _, server := range m.config.DNSServers {              // 遍历DNS服务器列表
 		serverChan <- server // 发送到通道
@@ -313,34 +621,46 @@ func (m *remoteIPMonitor) resolveDomainConcurrently(domain string) ([]string, []
 					addr = net.JoinHostPort(addr, "53") // 添加默认端口53
 				}
 
+				if m.shouldSkipDNSServer(addr) { // 该服务器因连续失败过多被临时跳过，本轮不再查询它
+					continue
+				}
+
 				// 查询A记录
+				startA := time.Now()                                         // 记录查询起始时间，用于计算延迟
 				msgA := new(dns.Msg)                                         // 创建DNS消息
 				msgA.SetQuestion(dns.Fqdn(domain), dns.TypeA)                // 设置查询A记录
 				rA, _, err := client.Exchange(msgA, addr)                    // 执行DNS查询
+				m.recordDNSQueryResult(addr, err == nil, time.Since(startA)) // 记录这次查询的健康状况
 				if err == nil && rA != nil && rA.Rcode == dns.RcodeSuccess { // 如果查询成功
 					for _, ans := range rA.Answer { // 遍历答案
 						if a, ok := ans.(*dns.A); ok { // 如果是A记录
 							ipv4Map.Store(a.A.String(), true) // 存储IPv4地址
 						}
 					}
+					recordCNAMEChain(&chainMu, &cnameChain, rA.Answer) // 记录本次应答中携带的CNAME别名链
 				}
 
 				// 查询AAAA记录
+				startAAAA := time.Now()                                            // 记录查询起始时间，用于计算延迟
 				msgAAAA := new(dns.Msg)                                            // 创建DNS消息
 				msgAAAA.SetQuestion(dns.Fqdn(domain), dns.TypeAAAA)                // 设置查询AAAA记录
 				rAAAA, _, err := client.Exchange(msgAAAA, addr)                    // 执行DNS查询
+				m.recordDNSQueryResult(addr, err == nil, time.Since(startAAAA))    // 记录这次查询的健康状况
 				if err == nil && rAAAA != nil && rAAAA.Rcode == dns.RcodeSuccess { // 如果查询成功
 					for _, ans := range rAAAA.Answer { // 遍历答案
 						if aaaa, ok := ans.(*dns.AAAA); ok { // 如果是AAAA记录
 							ipv6Map.Store(aaaa.AAAA.String(), true) // 存储IPv6地址
 						}
 					}
+					recordCNAMEChain(&chainMu, &cnameChain, rAAAA.Answer) // 记录本次应答中携带的CNAME别名链
 				}
 			}
 		}()
 	}
 	wg.Wait() // 等待所有工作线程完成
 
+	m.recordDomainAlias(domain, cnameChain) // 更新该域名已知的CNAME别名链，别名变化会打印事件日志
+
 	var ipv4s, ipv6s []string                         // 声明IPv4和IPv6地址列表
 	ipv4Map.Range(func(key, value interface{}) bool { // 遍历IPv4映射
 		ipv4s = append(ipv4s, key.(string)) // 添加到IPv4列表
@@ -354,6 +674,27 @@ func (m *remoteIPMonitor) resolveDomainConcurrently(domain string) ([]string, []
 	return ipv4s, ipv6s, nil // 返回IPv4列表、IPv6列表和nil
 }
 
+// recordCNAMEChain 从一次DNS应答的Answer记录中提取CNAME别名链（按应答顺序，即解析跳转顺序），
+// 如果*chain尚未被其他并发查询填充过，则写入第一个非空的链；多个DNS服务器对同一域名通常会
+// 返回相同的别名链，这里只取先到的一份即可，不需要额外去重或合并。
+func recordCNAMEChain(mu *sync.Mutex, chain *[]string, answers []dns.RR) {
+	var found []string
+	for _, ans := range answers { // 遍历应答记录
+		if c, ok := ans.(*dns.CNAME); ok { // 如果是CNAME记录
+			found = append(found, strings.TrimSuffix(c.Target, ".")) // 去掉FQDN末尾的点号后加入链
+		}
+	}
+	if len(found) == 0 { // 这份应答没有携带CNAME记录
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(*chain) == 0 { // 只采用第一个查到的别名链
+		*chain = found
+	}
+}
+
 // loadDomainData 从指定路径加载单个域名的数据。
 // 参数：filePath - 文件路径
 // 返回值：域名数据映射
@@ -375,25 +716,108 @@ func (m *remoteIPMonitor) loadDomainData(filePath string) map[string][]IPRecord
 	return data // 返回数据
 }
 
-// fetchIPInfo 使用共享的HTTP客户端获取单个IP的信息。
-// 参数：ip - 要查询的IP地址
-// 返回值：IP信息响应和错误信息
-func (m *remoteIPMonitor) fetchIPInfo(ip string) (*IPInfoResponse, error) {
-	url := fmt.Sprintf("https://ipinfo.io/%s/json?token=%s", ip, m.config.IPInfoToken) // 构造URL
-	resp, err := m.httpClient.Get(url)                                                 // 发起HTTP GET请求
-	if err != nil {                                                                    // 如果请求失败
-		return nil, err // 返回错误
+// enrichIPs 用m.enricher查询ips的信息，返回值按IP建索引，查询失败或没有结果的IP不会出现
+// 在返回值里，而是出现在第二个返回值（失败IP列表）中，供调用方持久化下来留待下一轮重试。
+// m.enricher支持BatchIPEnricher时走一次批量请求；否则用m.enrichConcurrency限制的有界工作池
+// 并发查询，每个IP失败时按m.enrichMaxRetries/m.enrichRetryBackoff重试退避，
+// 避免像突然发现200个新IP这种情况下一次性打出200个并发请求触发下游API的速率限制。
+func (m *remoteIPMonitor) enrichIPs(ips []string) (map[string]*IPInfoResponse, []string) {
+	if batch, ok := asBatchEnricher(m.enricher); ok { // 优先走批量接口，一次HTTP往返查多个IP
+		result, err := batch.EnrichBatch(ips) // 批量查询
+		if err != nil {                       // 批量接口允许部分失败，已查到的结果仍然可用
+			fmt.Printf("批量查询IP信息部分失败: %v\n", err) // 输出错误日志
+		}
+		failed := make([]string, 0, len(ips)-len(result)) // 声明失败IP列表
+		for _, ip := range ips {                          // 遍历所有请求查询的IP
+			if _, ok := result[ip]; !ok { // 如果没有查到结果
+				failed = append(failed, ip) // 加入失败列表
+			}
+		}
+		return result, failed // 返回结果和失败列表
 	}
-	defer func() { _ = resp.Body.Close() }() // 延迟关闭响应体
-	body, err := io.ReadAll(resp.Body)       // 读取响应体
-	if err != nil {                          // 如果读取失败
-		return nil, err // 返回错误
+
+	result := make(map[string]*IPInfoResponse, len(ips)) // 声明结果映射
+	var failed []string                                  // 声明失败IP列表
+	var mu sync.Mutex                                    // 保护result和failed的并发写入
+	var wg sync.WaitGroup                                // 声明等待组
+	sem := make(chan struct{}, m.enrichConcurrency)      // 有界工作池信号量，控制同时在途的查询数
+
+	for _, ip := range ips { // 遍历IP
+		wg.Add(1)         // 增加等待计数
+		sem <- struct{}{} // 获取一个工作槽位，池满时阻塞在这里而不是无限制地开goroutine
+
+		go func(ipStr string) { // 启动goroutine查询IP信息
+			defer wg.Done()          // 延迟减少等待计数
+			defer func() { <-sem }() // 归还工作槽位
+
+			info, err := m.enrichWithRetry(ipStr) // 按配置的重试次数和退避时间查询
+
+			mu.Lock()                      // 加锁
+			if err == nil && info != nil { // 如果最终查询成功
+				result[ipStr] = info // 写入结果
+			} else { // 重试耗尽仍然失败
+				failed = append(failed, ipStr) // 记录下来留给调用方持久化，供下一轮重试
+			}
+			mu.Unlock() // 解锁
+		}(ip) // 传递IP参数
 	}
-	var info IPInfoResponse                             // 声明IP信息响应变量
-	if err := json.Unmarshal(body, &info); err != nil { // 解析JSON数据
-		return nil, err // 返回错误
+	wg.Wait() // 等待所有IP信息查询完成
+	return result, failed
+}
+
+// enrichWithRetry 对单个IP调用m.enricher.Enrich，失败时按m.enrichRetryBackoff*尝试次数
+// 线性退避后重试，最多重试m.enrichMaxRetries次（不含首次尝试）。
+func (m *remoteIPMonitor) enrichWithRetry(ip string) (*IPInfoResponse, error) {
+	var lastErr error
+	for attempt := 0; attempt <= m.enrichMaxRetries; attempt++ { // 首次尝试加最多enrichMaxRetries次重试
+		if attempt > 0 { // 第一次失败之后才需要退避
+			time.Sleep(m.enrichRetryBackoff * time.Duration(attempt)) // 线性退避
+		}
+		info, err := m.enricher.Enrich(ip) // 查询IP信息
+		if err == nil && info != nil {     // 查询成功
+			return info, nil // 直接返回
+		}
+		lastErr = err // 记录最近一次错误，供外层判断是否彻底失败
 	}
-	return &info, nil // 返回IP信息响应
+	return nil, lastErr // 重试耗尽，返回最后一次错误
+}
+
+// domainPendingFilePath 返回指定域名查询失败、待下一轮重试的IP列表文件路径；
+// 固定用JSON存储（不跟随StorageFormat），因为这只是内部记账文件，不对外暴露。
+func (m *remoteIPMonitor) domainPendingFilePath(domain string) string {
+	fileName := strings.ReplaceAll(domain, ".", "_") + ".pending.json" // 构造文件名
+	return filepath.Join(m.config.StorageDir, fileName)                // 拼接完整路径
+}
+
+// loadPendingRetries 加载上一轮遗留的、查询失败的待重试IP列表；文件不存在或解析失败时返回空列表。
+func (m *remoteIPMonitor) loadPendingRetries(filePath string) []string {
+	fileData, err := os.ReadFile(filePath) // 读取文件数据
+	if err != nil {                        // 文件不存在或读取失败
+		return nil // 视为没有待重试的IP
+	}
+	var ips []string
+	if err := json.Unmarshal(fileData, &ips); err != nil { // 解析JSON数据
+		return nil // 解析失败同样视为没有待重试的IP
+	}
+	return ips
+}
+
+// savePendingRetries 把本轮仍然查询失败的IP列表保存下来，供下一轮更新周期重试；
+// ips为空时直接删除旧文件，避免留下一份空列表也要解析一次。
+func (m *remoteIPMonitor) savePendingRetries(filePath string, ips []string) error {
+	if len(ips) == 0 { // 没有需要重试的IP
+		_ = os.Remove(filePath) // 清理旧的待重试文件，忽略文件本就不存在的错误
+		return nil
+	}
+	dir := filepath.Dir(filePath)                  // 获取目录路径
+	if err := os.MkdirAll(dir, 0755); err != nil { // 创建目录
+		return fmt.Errorf("无法创建存储目录 %s: %w", dir, err) // 返回错误
+	}
+	out, err := json.MarshalIndent(ips, "", "  ") // 序列化为JSON格式
+	if err != nil {                               // 如果序列化失败
+		return err // 返回错误
+	}
+	return os.WriteFile(filePath, out, 0644) // 写入文件
 }
 
 // uniqueStrings 辅助函数，用于字符串切片去重。
@@ -0,0 +1,42 @@
+package src // Package src 定义src包
+
+// StatusAction 描述ReturnConn收到某个HTTP状态码后应该对目标IP和连接采取的动作。
+type StatusAction string
+
+const (
+	// StatusActionWhitelist 把目标IP加入白名单，连接放回健康池复用（原有200的硬编码行为）
+	StatusActionWhitelist StatusAction = "whitelist"
+	// StatusActionBlacklist 把目标IP加入黑名单，连接关闭（原有403的硬编码行为）
+	StatusActionBlacklist StatusAction = "blacklist"
+	// StatusActionRetry 不改变白/黑名单状态，连接放回非健康池供后续请求换一个IP重试
+	// （原有"其余状态码"分支的行为），适合目标站点用来做限速的状态码（如429、503）
+	StatusActionRetry StatusAction = "retry"
+	// StatusActionIgnore 不改变白/黑名单状态，也不放回任何池复用，直接关闭连接
+	StatusActionIgnore StatusAction = "ignore"
+)
+
+// StatusPolicy 把HTTP状态码映射到StatusAction，供ReturnConn按运维配置的规则处理状态码，
+// 取代了引入StatusPolicy之前"200=白名单，403=黑名单，其余一律当成失败重试"的硬编码行为。
+// nil或查不到某个状态码时，回退到defaultStatusAction，与原有硬编码行为完全一致，
+// 因此不配置StatusPolicy时连接池行为不变。
+type StatusPolicy map[int]StatusAction
+
+// defaultStatusAction返回statusCode在policy里没有配置时使用的动作。
+func defaultStatusAction(statusCode int) StatusAction {
+	switch statusCode {
+	case 200:
+		return StatusActionWhitelist
+	case 403:
+		return StatusActionBlacklist
+	default:
+		return StatusActionRetry
+	}
+}
+
+// resolve返回policy对statusCode配置的动作，policy为nil或没有这一项时回退到defaultStatusAction。
+func (policy StatusPolicy) resolve(statusCode int) StatusAction {
+	if action, ok := policy[statusCode]; ok {
+		return action
+	}
+	return defaultStatusAction(statusCode)
+}
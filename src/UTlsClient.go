@@ -9,11 +9,12 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
 	utls "github.com/refraction-networking/utls"
-	"golang.org/x/net/http2"
 )
 
 // UTlsClientApi 定义UTlsClient的接口
@@ -27,6 +28,68 @@ type UTlsClient struct {
 	DialTimeout time.Duration
 	MaxRetries  int
 	HotConnPool HotConnPool
+	// PoolManager 可选，用于按 UTlsRequest.Domain 路由到多个域名各自的连接池。
+	// 为nil或请求的Domain未注册时，回退到使用HotConnPool，保持单域名场景的行为不变。
+	PoolManager *PoolManager
+	// SourcePortRange 指定createConnection拨号时使用的本地源端口闭区间[min,max]，
+	// 零值[0,0]表示沿用系统自动分配的临时端口，语义和用法见DomainConnPoolConfig.SourcePortRange。
+	SourcePortRange [2]int
+	// CookieJar 为nil时不做任何Cookie处理（默认行为，向后兼容）；非nil时Do会在发请求前
+	// 按req.Domain附带上之前已存下的Cookie，并在收到响应后把新的Set-Cookie存回去，见cookie_jar.go
+	CookieJar *CookieJar
+	// MaxRedirects 是Do()自动跟随3xx响应Location的最大跳转次数，<=0（零值）表示维持旧行为：
+	// 3xx原样当作最终响应返回，调用方自己决定怎么处理，不会破坏已有依赖3xx语义的调用方。
+	MaxRedirects int
+	// RedirectSameDomainOnly 为true时只跟随Location指向同一Domain的跳转，跨域名跳转会被当作
+	// 最终响应直接返回而不是报错——跨CDN/跨域跳转往往意味着请求被导到了完全不同的基础设施，
+	// 继续跟随可能偏离调用方原本想探测的目标，交由调用方按返回的3xx自行判断更安全。
+	RedirectSameDomainOnly bool
+	// MaxBodySize 限制单次响应体最多读取的字节数，<=0（零值）表示维持旧行为：不设上限，
+	// 一直读到EOF为止。超过限制时readHTTPResponse/sendHTTP2Request会返回显式错误而不是
+	// 像cmd/Crawler/task_executor.go里对gRPC响应体那样先完整读入内存再静默截断——
+	// 那种处理方式在读完整个超大body之前内存早就已经被占用过了，防OOM已经太迟。
+	MaxBodySize int64
+	// MaxStatusLineBytes/MaxHeaderBytes/MaxHeaderCount 限制readHTTPResponse解析HTTP/1.1
+	// 响应头阶段允许读取的状态行长度、响应头总字节数（含状态行）、响应头字段行数，
+	// 任一项超限都会返回*HTTPLimitExceededError（见src/HTTPHeaderLimits.go），防止畸形或
+	// 恶意的上游响应把读取一直拖下去。与MaxBodySize不同，这三项<=0（零值）不是"不设上限"，
+	// 而是使用defaultMaxStatusLineBytes/defaultMaxHeaderBytes/defaultMaxHeaderCount——
+	// 响应头解析默认就应该带着防护，不应该要求调用方显式配置才生效。
+	MaxStatusLineBytes int
+	MaxHeaderBytes     int
+	MaxHeaderCount     int
+}
+
+func (c *UTlsClient) maxStatusLineBytes() int {
+	if c.MaxStatusLineBytes > 0 {
+		return c.MaxStatusLineBytes
+	}
+	return defaultMaxStatusLineBytes
+}
+
+func (c *UTlsClient) maxHeaderBytes() int {
+	if c.MaxHeaderBytes > 0 {
+		return c.MaxHeaderBytes
+	}
+	return defaultMaxHeaderBytes
+}
+
+func (c *UTlsClient) maxHeaderCount() int {
+	if c.MaxHeaderCount > 0 {
+		return c.MaxHeaderCount
+	}
+	return defaultMaxHeaderCount
+}
+
+// resolvePool 根据请求的Domain选择应使用的连接池：优先尝试PoolManager中与该域名匹配的连接池，
+// 找不到时回退到HotConnPool，从而让多域名和单域名两种部署方式复用同一套请求处理逻辑。
+func (c *UTlsClient) resolvePool(domain string) HotConnPool {
+	if c.PoolManager != nil {
+		if pool, ok := c.PoolManager.PoolForDomain(domain); ok {
+			return pool
+		}
+	}
+	return c.HotConnPool
 }
 
 // NewUTlsClient 创建并初始化一个新的UTLS客户端
@@ -52,6 +115,30 @@ func (c *UTlsClient) getDialTimeout() time.Duration {
 	return 10 * time.Second
 }
 
+func (c *UTlsClient) getSourcePortRange() [2]int {
+	return c.SourcePortRange
+}
+
+// readLimitedBody 从r中读取响应体，受c.MaxBodySize约束：MaxBodySize<=0时等价于io.ReadAll，
+// 否则最多读取MaxBodySize+1字节——多读到的那1字节只用来判断r是否真的还有超出限制的数据，
+// 一旦确认超限立即返回显式错误，不会像完整读完再事后判断那样让超大body先把内存占满。
+// r对应chunked编码的响应体时，Go标准库http.Response.Body已经在内部完成了解码和trailer读取，
+// 这里读到的是解码后的明文数据，无需额外处理chunk边界或trailer。
+func (c *UTlsClient) readLimitedBody(r io.Reader) ([]byte, error) {
+	limit := c.MaxBodySize
+	if limit <= 0 {
+		return io.ReadAll(r)
+	}
+	body, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return body, err
+	}
+	if int64(len(body)) > limit {
+		return nil, fmt.Errorf("响应体超过MaxBodySize限制(%d字节)，已终止读取", limit)
+	}
+	return body, nil
+}
+
 type connInfo struct {
 	conn       net.Conn
 	httpClient *http.Client // For HTTP/2
@@ -83,55 +170,130 @@ type UTlsRequest struct {
 	Fingerprint Profile
 	StartTime   time.Time
 	Timeout     time.Duration
+	// SNI 覆盖TLS握手中使用的ServerName，留空则使用Domain。
+	// 与Host配合可实现域前置（domain fronting）风格的路由实验：TLS层声称连接到SNI，
+	// 但HTTP层的Host头指向真正的目标站点。设置SNI或Host时必须显式指定DomainIP，见validateFrontingRequest。
+	SNI string
+	// Host 覆盖HTTP请求的Host头，留空则使用Domain。
+	Host string
 }
 
 type UTlsResponse struct {
-	WorkID     string
-	StatusCode int
-	Body       []byte
-	Path       string
-	Duration   time.Duration
-	LocalIP    string
+	WorkID      string
+	StatusCode  int
+	Body        []byte
+	Path        string
+	Duration    time.Duration
+	LocalIP     string
+	Fingerprint Profile // 本次请求实际使用的指纹：来自连接池时为连接建立时使用的指纹，而不是调用方传入的初始值
+}
+
+// validateFrontingRequest 校验设置了SNI或Host覆盖的请求：这类请求是有意的域前置（fronting）实验，
+// 必须显式提供DomainIP，不能依赖连接池按Domain自动选出的IP——该IP只保证对Domain本身可达，
+// 并不保证伪装后的SNI/Host组合在这个IP上同样可用。
+func validateFrontingRequest(req *UTlsRequest) error {
+	if req.SNI == "" && req.Host == "" {
+		return nil
+	}
+	if req.DomainIP == "" {
+		return fmt.Errorf("设置SNI或Host覆盖时必须显式指定DomainIP，不能依赖按Domain自动选择的连接池IP")
+	}
+	if net.ParseIP(req.DomainIP) == nil {
+		return fmt.Errorf("无效的DomainIP: %s", req.DomainIP)
+	}
+	return nil
 }
 
 func (c *UTlsClient) Do(req *UTlsRequest) (*UTlsResponse, error) {
+	// Do目前没有接收外部context.Context（UTlsRequest本身也没有携带一个），所以这里的span是
+	// 独立的根span，不会挂在调用方（如handleTaskRequest）的span下面——把ctx一路穿透到这里
+	// 需要改动UTlsRequest/所有调用方的签名，超出本次接入链路追踪的范围，留给以后需要精确
+	// 父子关系时再做。span依然会被导出，按WorkID/Domain能在日志里和上层请求对上，见tracing.go。
+	_, span := startSpan("UTlsClient.Do")
+	span.SetAttr("work_id", req.WorkID)
+	span.SetAttr("domain", req.Domain)
+	defer span.End()
+
+	if err := validateFrontingRequest(req); err != nil {
+		return nil, fmt.Errorf("请求参数校验失败: %w", err)
+	}
+
 	startTime := time.Now()
-	isHTTPS := strings.HasPrefix(strings.ToLower(req.Path), "https://")
 
-	// 设置总超时时间：如果req.Timeout > 0，使用req.Timeout；否则使用ReadTimeout
+	// 设置总超时时间：如果req.Timeout > 0，使用req.Timeout；否则使用ReadTimeout。这个截止时间
+	// 覆盖整条跳转链（原始请求+所有跟随的跳转），不会因为MaxRedirects>0而被重新计时，
+	// 避免一个来回跳转多次的站点把单次Do()调用拖得远超调用方预期。
 	totalTimeout := req.Timeout
 	if totalTimeout <= 0 {
 		totalTimeout = c.getReadTimeout()
 	}
 	deadline := time.Now().Add(totalTimeout)
 
+	currentReq := req
+	for redirectCount := 0; ; redirectCount++ {
+		statusCode, body, headers, localIP, err := c.doAttempt(currentReq, deadline, totalTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		if c.MaxRedirects > 0 && redirectCount < c.MaxRedirects && isRedirectStatus(statusCode) {
+			if nextReq, ok := c.buildRedirectRequest(currentReq, statusCode, headers); ok {
+				span.SetAttr("redirect_to", nextReq.Path)
+				currentReq = nextReq
+				continue
+			}
+		}
+
+		return &UTlsResponse{
+			WorkID:      req.WorkID,
+			StatusCode:  statusCode,
+			Body:        body,
+			Path:        req.Path,
+			Duration:    time.Since(startTime),
+			LocalIP:     localIP,
+			Fingerprint: currentReq.Fingerprint, // 此时currentReq.Fingerprint已在doAttempt中与连接池实际使用的指纹对齐
+		}, nil
+	}
+}
+
+// doAttempt对单个UTlsRequest（原始请求，或者由buildRedirectRequest构造出的下一跳请求）执行一次
+// 完整的建连+收发，内部沿用原有的连接失败自动重试逻辑；deadline/totalTimeout由Do()统一计算，
+// 代表跳转链的总预算，doAttempt本身不重新计时。
+func (c *UTlsClient) doAttempt(req *UTlsRequest, deadline time.Time, totalTimeout time.Duration) (int, []byte, http.Header, string, error) {
+	isHTTPS := strings.HasPrefix(strings.ToLower(req.Path), "https://")
+
 	maxRetries := 3
 	for retry := 0; retry <= maxRetries; retry++ {
 		// 检查是否已经超过总超时时间
 		if time.Now().After(deadline) {
-			return nil, fmt.Errorf("请求超时：超过总超时时间 %v", totalTimeout)
+			return 0, nil, nil, "", fmt.Errorf("请求超时：超过总超时时间 %v", totalTimeout)
 		}
 		var connMeta *ConnMetadata
 		var err error
 		var usePool bool
 
-		if c.HotConnPool != nil && isHTTPS {
+		// 设置了SNI或Host覆盖的请求是有意的域前置实验，必须绕过共享连接池独立建连，
+		// 因为连接池中的连接在建立时已经用Domain完成了TLS握手，无法临时更换SNI。
+		isFronting := req.SNI != "" || req.Host != ""
+
+		pool := c.resolvePool(req.Domain)
+		if pool != nil && isHTTPS && !isFronting {
 			if req.DomainIP != "" {
-				connMeta, err = c.HotConnPool.GetConnByIP(req.DomainIP)
+				connMeta, err = pool.GetConnByIP(req.DomainIP)
 				if err != nil {
 					if retry < maxRetries {
 						time.Sleep(10 * time.Millisecond)
 						continue
 					}
-					return nil, fmt.Errorf("无法从连接池获取匹配IP %s 的连接: %w", req.DomainIP, err)
+					return 0, nil, nil, "", fmt.Errorf("无法从连接池获取匹配IP %s 的连接: %w", req.DomainIP, err)
 				}
 			} else {
-				connMeta, err = c.HotConnPool.GetConn()
+				connMeta, err = pool.GetConn()
 				if err != nil {
 					if retry < maxRetries {
 						continue
 					}
-					return nil, fmt.Errorf("无法从连接池获取连接: %w", err)
+					return 0, nil, nil, "", fmt.Errorf("无法从连接池获取连接: %w", err)
 				}
 			}
 			usePool = true
@@ -149,7 +311,7 @@ func (c *UTlsClient) Do(req *UTlsRequest) (*UTlsResponse, error) {
 				if retry < maxRetries {
 					continue
 				}
-				return nil, fmt.Errorf("无法建立连接: %w", err)
+				return 0, nil, nil, "", fmt.Errorf("无法建立连接: %w", err)
 			}
 			// This path is simplified, assuming pool is always used for HTTPS
 			// For non-pooled connections, we'd need to wrap it in ConnMetadata
@@ -158,14 +320,20 @@ func (c *UTlsClient) Do(req *UTlsRequest) (*UTlsResponse, error) {
 			// This part of logic needs to be aligned with the new ConnMetadata structure if used.
 		}
 
+		if usePool && connMeta.Fingerprint.Name != "" {
+			// 请求头必须与连接建立时使用的指纹保持一致，否则同一连接上会出现TLS与HTTP层指纹不匹配的检测信号
+			req.Fingerprint = connMeta.Fingerprint
+		}
+
 		var statusCode int
 		var body []byte
+		var headers http.Header
 		var localIP string
-		
+
 		// 计算剩余超时时间
 		remainingTimeout := time.Until(deadline)
 		if remainingTimeout <= 0 {
-			return nil, fmt.Errorf("请求超时：超过总超时时间 %v", totalTimeout)
+			return 0, nil, nil, "", fmt.Errorf("请求超时：超过总超时时间 %v", totalTimeout)
 		}
 		// 确保超时时间不超过请求的超时时间
 		if req.Timeout > 0 && remainingTimeout > req.Timeout {
@@ -176,15 +344,19 @@ func (c *UTlsClient) Do(req *UTlsRequest) (*UTlsResponse, error) {
 			ctx, cancel := context.WithTimeout(context.Background(), remainingTimeout)
 			// 确保context在函数返回时被取消，释放相关资源
 			defer cancel()
-			statusCode, body, err = c.sendHTTP2Request(ctx, connMeta.HttpClient, req)
+			statusCode, body, headers, err = c.sendHTTP2Request(ctx, connMeta.HttpClient, req)
 		} else { // HTTP/1.1 path
+			// 同一个截止时间既用于套接字级SetDeadline兜底，也显式传给readHTTPResponse，
+			// 确保上游（executeTask等）设置的per-request超时能真正打断阻塞的读取，
+			// 而不会被readHTTPResponse内部的客户端级ReadTimeout覆盖掉
+			requestDeadline := time.Now().Add(remainingTimeout)
 			if connMeta.Conn != nil {
-				_ = connMeta.Conn.SetDeadline(time.Now().Add(remainingTimeout))
+				_ = connMeta.Conn.SetDeadline(requestDeadline)
 				defer func() { _ = connMeta.Conn.SetDeadline(time.Time{}) }()
 			}
 			err = c.sendHTTPRequest(connMeta.Conn, req)
 			if err == nil {
-				statusCode, body, err = c.readHTTPResponse(connMeta.Conn)
+				statusCode, body, headers, err = c.readHTTPResponse(connMeta.Conn, requestDeadline, req.Domain, connMeta)
 			}
 		}
 
@@ -202,56 +374,155 @@ func (c *UTlsClient) Do(req *UTlsRequest) (*UTlsResponse, error) {
 			}
 		}
 
+		if usePool && pool != nil && connMeta != nil {
+			// 套接字层面的字节数（含TLS握手和帧层开销）无论请求成败都已真实产生，必须计入流量核算
+			readDelta, writtenDelta := connMeta.ConsumeByteDelta()
+			pool.RecordConnBytes(connMeta.TargetIP, localIP, connMeta.Protocol, readDelta, writtenDelta)
+		}
+
 		if err != nil {
 			isConnError := isConnectivityError(err)
-			if usePool && c.HotConnPool != nil {
+			if usePool && pool != nil {
 				if isConnError {
 					// The pool will handle closing the connection.
 					// We just need to signal that it was an error.
-					_ = c.HotConnPool.ReturnConn(connMeta, 0)
+					_ = pool.ReturnConn(connMeta, 0)
 				} else {
-					_ = c.HotConnPool.ReturnConn(connMeta, 0)
+					_ = pool.ReturnConn(connMeta, 0)
 				}
 			}
 			if retry < maxRetries && isConnError {
 				fmt.Printf("[UTlsClient] 连接池连接失效 (%v)，重试...\n", err)
 				continue
 			}
-			return nil, fmt.Errorf("请求执行失败: %w", err)
+			return 0, nil, nil, "", fmt.Errorf("请求执行失败: %w", err)
 		}
 
-		if usePool && c.HotConnPool != nil {
-			_ = c.HotConnPool.ReturnConn(connMeta, statusCode)
+		if usePool && pool != nil {
+			_ = pool.ReturnConnWithRetryAfter(connMeta, statusCode, parseRetryAfter(headers))
 		}
 
-		if !usePool && c.HotConnPool != nil && statusCode > 0 {
-			c.HotConnPool.UpdateIPStats(req.DomainIP, statusCode)
+		if !usePool && pool != nil && statusCode > 0 {
+			pool.UpdateIPStats(req.DomainIP, statusCode)
 		}
 
-		return &UTlsResponse{
-			WorkID:     req.WorkID,
-			StatusCode: statusCode,
-			Body:       body,
-			Path:       req.Path,
-			Duration:   time.Since(startTime),
-			LocalIP:    localIP,
-		}, nil
+		return statusCode, body, headers, localIP, nil
+	}
+
+	return 0, nil, nil, "", fmt.Errorf("请求失败：超过最大重试次数")
+}
+
+// redirectSensitiveHeaderNames 是跨host跳转时必须丢弃的认证类请求头，与net/http自身
+// 处理跨host跳转时剥离的头部集合一致。
+var redirectSensitiveHeaderNames = []string{"Authorization", "Cookie", "Proxy-Authorization"}
+
+// scrubCrossHostRedirectHeaders返回headers的副本，剔除其中的redirectSensitiveHeaderNames
+// （大小写不敏感匹配），供buildRedirectRequest在跳转目标与prev.Domain不同host时调用。
+func scrubCrossHostRedirectHeaders(headers map[string]string) map[string]string {
+	if len(headers) == 0 {
+		return headers
+	}
+	scrubbed := make(map[string]string, len(headers))
+	for key, value := range headers {
+		sensitive := false
+		for _, name := range redirectSensitiveHeaderNames {
+			if strings.EqualFold(key, name) {
+				sensitive = true
+				break
+			}
+		}
+		if !sensitive {
+			scrubbed[key] = value
+		}
+	}
+	return scrubbed
+}
+
+// isRedirectStatus 返回statusCode是否是Do()知道如何跟随的跳转类型
+func isRedirectStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	default:
+		return false
+	}
+}
+
+// buildRedirectRequest 根据收到的跳转响应构造下一跳要发送的UTlsRequest。ok为false表示不应该
+// 跟随这次跳转（没有Location、Location不是合法的http(s) URL、或RedirectSameDomainOnly=true时
+// Location跨了域名），调用方此时应把当前响应当作最终结果返回——跳转解析失败不等于请求失败。
+func (c *UTlsClient) buildRedirectRequest(prev *UTlsRequest, statusCode int, headers http.Header) (*UTlsRequest, bool) {
+	if headers == nil {
+		return nil, false
+	}
+	location := headers.Get("Location")
+	if location == "" {
+		return nil, false
+	}
+
+	base, err := url.Parse(prev.Path)
+	if err != nil {
+		return nil, false
+	}
+	target, err := base.Parse(location)
+	if err != nil {
+		return nil, false
+	}
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return nil, false
+	}
+	if c.RedirectSameDomainOnly && !strings.EqualFold(target.Hostname(), prev.Domain) {
+		return nil, false
 	}
 
-	return nil, fmt.Errorf("请求失败：超过最大重试次数")
+	// 浅拷贝复用指纹、超时等字段；Path/Domain/Host/SNI/DomainIP按跳转目标重新计算，
+	// SNI/Host是前一跳域前置实验特有的覆盖，原样带到新域名上没有意义，必须清空，
+	// DomainIP同理——跳转目标很可能是完全不同的CDN节点，继续钉在旧IP上没有意义。
+	next := *prev
+	next.Path = target.String()
+	next.Domain = target.Hostname()
+	next.Host = ""
+	next.SNI = ""
+	next.DomainIP = ""
+
+	// 跨host的跳转必须丢弃Authorization/Cookie/Proxy-Authorization这类认证头，否则调用方
+	// 为访问prev.Domain设置的凭据会被原样重放给Location指向的任意主机——net/http的
+	// Client.Do对跨host跳转也是同样处理，这里不能例外。RedirectSameDomainOnly只决定要不要
+	// 跟随跳转，不能替代这一步。
+	if !strings.EqualFold(target.Hostname(), prev.Domain) {
+		next.Headers = scrubCrossHostRedirectHeaders(prev.Headers)
+	}
+
+	// 301/302/303对非GET/HEAD请求按浏览器惯例改写为GET并丢弃请求体；307/308严格保留原方法和请求体，
+	// 这是RFC 7231/7238明确区分"不保证方法"和"保证方法"两类跳转语义的地方。
+	if statusCode != http.StatusTemporaryRedirect && statusCode != http.StatusPermanentRedirect {
+		if prev.Method != http.MethodGet && prev.Method != http.MethodHead {
+			next.Method = http.MethodGet
+			next.Body = nil
+		}
+	}
+
+	// Cookie的携带/更新完全交给CookieJar（如果调用方配置了的话）按next.Domain自动处理，
+	// 见cookie_jar.go——这里不需要额外做跨跳转的Cookie搬运。
+	return &next, true
 }
 
 // sendHTTP2Request uses a pre-configured http.Client to send a request.
-func (c *UTlsClient) sendHTTP2Request(ctx context.Context, client *http.Client, req *UTlsRequest) (int, []byte, error) {
+func (c *UTlsClient) sendHTTP2Request(ctx context.Context, client *http.Client, req *UTlsRequest) (int, []byte, http.Header, error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
 	httpReq, err := http.NewRequestWithContext(ctx, req.Method, req.Path, bytes.NewReader(req.Body))
 	if err != nil {
-		return 0, nil, fmt.Errorf("创建HTTP请求失败: %w", err)
+		return 0, nil, nil, fmt.Errorf("创建HTTP请求失败: %w", err)
 	}
 
-	httpReq.Host = req.Domain
+	host := req.Host
+	if host == "" {
+		host = req.Domain
+	}
+	httpReq.Host = host
 	for key, value := range req.Headers {
 		httpReq.Header.Set(key, value)
 	}
@@ -272,13 +543,29 @@ func (c *UTlsClient) sendHTTP2Request(ctx context.Context, client *http.Client,
 		httpReq.Header.Set("Accept-Language", acceptLanguage)
 	}
 
+	// 补齐与指纹一致的Client Hints及Sec-Fetch-*头部，调用方已显式提供的头部优先
+	for key, value := range req.Fingerprint.HeaderSet() {
+		if _, exists := req.Headers[key]; !exists {
+			httpReq.Header.Set(key, value)
+		}
+	}
+
+	if httpReq.Header.Get("Cookie") == "" {
+		if cookieHeader := c.CookieJar.CookieHeader(req.Domain); cookieHeader != "" {
+			httpReq.Header.Set("Cookie", cookieHeader)
+		}
+	}
+
 	resp, err := client.Do(httpReq)
 	// 清理httpReq对象引用，帮助GC回收
 	httpReq = nil
 	if err != nil {
-		return 0, nil, fmt.Errorf("发送HTTP/2请求失败: %w", err)
+		return 0, nil, nil, fmt.Errorf("发送HTTP/2请求失败: %w", err)
 	}
-	
+	c.CookieJar.StoreFromHeader(req.Domain, resp.Header)
+	headers := resp.Header
+	statusCode := resp.StatusCode
+
 	// 确保响应体被完全读取和关闭，以便连接可以复用
 	// 这对于HTTP/2连接复用非常重要
 	defer func() {
@@ -293,14 +580,13 @@ func (c *UTlsClient) sendHTTP2Request(ctx context.Context, client *http.Client,
 		resp = nil
 	}()
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := c.readLimitedBody(resp.Body)
 	if err != nil {
-		// 读取失败时，defer会确保响应体被完全读取以便连接可以复用
-		return resp.StatusCode, nil, fmt.Errorf("读取HTTP/2响应体失败: %w", err)
+		// 读取失败（含超过MaxBodySize）时，defer会确保响应体被完全读取以便连接可以复用
+		return statusCode, nil, headers, fmt.Errorf("读取HTTP/2响应体失败: %w", err)
 	}
 
-	statusCode := resp.StatusCode
-	return statusCode, body, nil
+	return statusCode, body, headers, nil
 }
 
 func (c *UTlsClient) createConnection(req *UTlsRequest, isHTTPS bool, port string) (*connInfo, error) {
@@ -317,22 +603,27 @@ func (c *UTlsClient) createConnection(req *UTlsRequest, isHTTPS bool, port strin
 	}
 	// ... localIP binding logic ...
 
-	tcpConn, err := dialer.Dial("tcp", net.JoinHostPort(req.DomainIP, port))
+	tcpConn, err := dialWithSourcePortRange(dialer, "tcp", net.JoinHostPort(req.DomainIP, port), c.getSourcePortRange())
 	if err != nil {
 		return nil, fmt.Errorf("TCP连接失败: %w", err)
 	}
 
 	if isHTTPS {
-		helloID := req.Fingerprint.HelloID
-		if req.Fingerprint.Name == "" {
-			helloID = fpLibrary.RandomProfile().HelloID
+		fingerprint := req.Fingerprint
+		if fingerprint.Name == "" {
+			fingerprint = fpLibrary.RandomProfile()
+		}
+
+		sni := req.SNI
+		if sni == "" {
+			sni = req.Domain
 		}
 
 		uConn := utls.UClient(tcpConn, &utls.Config{
-			ServerName:         req.Domain,
+			ServerName:         sni,
 			NextProtos:         []string{"h2", "http/1.1"},
 			InsecureSkipVerify: false,
-		}, helloID)
+		}, fingerprint.HelloID)
 
 		if err := uConn.Handshake(); err != nil {
 			_ = uConn.Close()
@@ -347,12 +638,10 @@ func (c *UTlsClient) createConnection(req *UTlsRequest, isHTTPS bool, port strin
 
 		var httpClient *http.Client
 		if protocol == "h2" {
-			transport := &http2.Transport{
-				DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
-					// The connection is already established, just return it.
-					return uConn, nil
-				},
-			}
+			transport := fingerprint.NewHTTP2Transport(func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+				// The connection is already established, just return it.
+				return uConn, nil
+			})
 			httpClient = &http.Client{Transport: transport}
 		}
 
@@ -369,39 +658,105 @@ func (c *UTlsClient) createConnection(req *UTlsRequest, isHTTPS bool, port strin
 }
 
 func (c *UTlsClient) sendHTTPRequest(conn net.Conn, req *UTlsRequest) error {
-	httpReq, err := http.NewRequest(req.Method, req.Path, bytes.NewReader(req.Body))
-	if err != nil {
-		return fmt.Errorf("创建HTTP请求失败: %w", err)
+	host := req.Host
+	if host == "" {
+		host = req.Domain
 	}
 
-	httpReq.Host = req.Domain
+	// 用普通map而不是http.Header/Request.Write输出请求，后者会把头部名规范化为
+	// Canonical-Case并按自身顺序排列，抹掉指纹需要掩护的浏览器头部顺序和大小写特征
+	headers := make(map[string]string, len(req.Headers)+4)
 	for key, value := range req.Headers {
-		httpReq.Header.Set(key, value)
+		headers[key] = value
 	}
-	// ... header setting logic ...
 
-	err = httpReq.Write(conn)
-	// 清理httpReq对象引用，帮助GC回收
-	httpReq = nil
-	return err
+	if _, exists := headers["User-Agent"]; !exists {
+		if req.Fingerprint.UserAgent != "" {
+			headers["User-Agent"] = req.Fingerprint.UserAgent
+		} else {
+			randomFingerprint := fpLibrary.RandomProfile()
+			if randomFingerprint.UserAgent != "" {
+				headers["User-Agent"] = randomFingerprint.UserAgent
+			}
+		}
+	}
+
+	if _, exists := headers["Accept-Language"]; !exists {
+		headers["Accept-Language"] = fpLibrary.RandomAcceptLanguage()
+	}
+
+	// 补齐与指纹一致的Client Hints及Sec-Fetch-*头部，调用方已显式提供的头部优先
+	for key, value := range req.Fingerprint.HeaderSet() {
+		if _, exists := headers[key]; !exists {
+			headers[key] = value
+		}
+	}
+
+	if _, exists := headers["Cookie"]; !exists {
+		if cookieHeader := c.CookieJar.CookieHeader(req.Domain); cookieHeader != "" {
+			headers["Cookie"] = cookieHeader
+		}
+	}
+
+	return writeRawHTTP1Request(conn, req.Method, req.Path, host, headers, req.Fingerprint.HeaderOrder(), req.Body)
 }
 
-func (c *UTlsClient) readHTTPResponse(conn net.Conn) (int, []byte, error) {
-	conn.SetReadDeadline(time.Now().Add(c.getReadTimeout()))
-	reader := bufio.NewReader(conn)
+// readHTTPResponse 读取HTTP/1.1响应。deadline非零时按其设置读取超时，使调用方（如Do）
+// 能把请求剩余的可用时间而不是固定的客户端级ReadTimeout传下来，避免服务端早已放弃的请求
+// 仍然在这里阻塞到ReadTimeout才超时；deadline为零值时回退到ReadTimeout，供没有per-request
+// 截止时间概念的调用方（如healthCheckWithConn）使用。domain非空且c.CookieJar非nil时，
+// 响应中的Set-Cookie会被存入CookieJar，供该域名后续请求回放。connMeta非nil时，会顺带记录
+// Connection/Keep-Alive头部，供ReturnConn判断这个连接是否该在本次请求后直接retire掉，
+// 见ConnMetadata.recordHTTP1KeepAlive/shouldRetireHTTP1。响应体读取受c.MaxBodySize约束，
+// 见readLimitedBody；状态行/响应头本身的大小和字段数受c.MaxStatusLineBytes/MaxHeaderBytes/
+// MaxHeaderCount约束，超限时返回的错误可以用errors.As还原出*HTTPLimitExceededError，
+// 见headerSizeLimitingReader。
+func (c *UTlsClient) readHTTPResponse(conn net.Conn, deadline time.Time, domain string, connMeta *ConnMetadata) (int, []byte, http.Header, error) {
+	if deadline.IsZero() {
+		deadline = time.Now().Add(c.getReadTimeout())
+	}
+	conn.SetReadDeadline(deadline)
+	limited := newHeaderSizeLimitingReader(conn, c.maxStatusLineBytes(), c.maxHeaderBytes(), c.maxHeaderCount())
+	reader := bufio.NewReader(limited)
 	resp, err := http.ReadResponse(reader, nil)
 	if err != nil {
-		return 0, nil, fmt.Errorf("读取HTTP响应失败: %w", err)
+		return 0, nil, nil, fmt.Errorf("读取HTTP响应失败: %w", err)
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	c.CookieJar.StoreFromHeader(domain, resp.Header)
+	connMeta.recordHTTP1KeepAlive(resp.Header)
+
+	body, err := c.readLimitedBody(resp.Body)
 	if err != nil {
-		return resp.StatusCode, nil, fmt.Errorf("读取响应体失败: %w", err)
+		return resp.StatusCode, nil, resp.Header, fmt.Errorf("读取响应体失败: %w", err)
 	}
 	// ... 1xx handling logic ...
 
-	return resp.StatusCode, body, nil
+	return resp.StatusCode, body, resp.Header, nil
+}
+
+// parseRetryAfter从响应头里解析Retry-After（RFC 7231 7.1.3），支持delta-seconds（如"120"）
+// 和HTTP-date（如"Wed, 21 Oct 2026 07:28:00 GMT"）两种格式，供ReturnConnWithRetryAfter把
+// 429/503这类限速响应对应的目标IP临时拉黑到Retry-After声明的时间点；header为空、没有这个
+// 字段、两种格式都解析失败、或者解析出的时间已经过去，均返回0，表示不需要临时拉黑。
+func parseRetryAfter(headers http.Header) time.Duration {
+	value := strings.TrimSpace(headers.Get("Retry-After"))
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if when, err := http.ParseTime(value); err == nil {
+		if d := time.Until(when); d > 0 {
+			return d
+		}
+	}
+	return 0
 }
 
 func isConnectivityError(err error) bool {
@@ -1,13 +1,16 @@
 package src // Package src 定义src包
 
 import ( // 导入所需的标准库和第三方库
-	"fmt"       // 用于格式化输入输出
-	"math/rand" // 用于随机数生成
-	"strings"   // 用于字符串操作
-	"sync"      // 用于同步原语如互斥锁
-	"time"      // 用于时间处理
+	"crypto/tls" // 用于http2.Transport的DialTLS签名
+	"fmt"        // 用于格式化输入输出
+	"math/rand"  // 用于随机数生成
+	"net"        // 用于http2.Transport的DialTLS签名
+	"strings"    // 用于字符串操作
+	"sync"       // 用于同步原语如互斥锁
+	"time"       // 用于时间处理
 
 	utls "github.com/refraction-networking/utls" // 导入utls库用于TLS指纹伪装
+	"golang.org/x/net/http2"                     // 用于构建与指纹匹配的HTTP/2传输
 )
 
 // Profile 定义了浏览器指纹配置文件结构体
@@ -19,8 +22,12 @@ type Profile struct { // 定义配置文件结构体
 	Platform    string             // 平台信息
 	Browser     string             // 浏览器信息
 	Version     string             // 版本信息
+	Weight      int                // 选中权重，近似真实浏览器市场份额，值越大越容易被RandomProfileWeighted选中
 }
 
+// defaultWeight 是未显式设置Weight的配置文件使用的兜底权重
+const defaultWeight = 1
+
 // Library 定义了指纹库结构体
 type Library struct { // 定义指纹库结构体
 	profiles []Profile  // 配置文件列表
@@ -103,12 +110,31 @@ func NewLibrary() *Library {
 	return lib         // 返回指纹库实例
 }
 
+// Seed 用固定种子重置指纹库的随机数生成器，使后续的随机选择结果可复现。
+// 仅用于调试场景下复现某次运行的指纹选择序列，生产环境不应调用。
+func (lib *Library) Seed(seed int64) {
+	lib.mu.Lock()
+	defer lib.mu.Unlock()
+	lib.rand = rand.New(rand.NewSource(seed))
+}
+
+// SeedGlobalRandomness 用固定种子重置全局指纹库（含指纹选择和Accept-Language选择）的随机数生成器。
+func SeedGlobalRandomness(seed int64) {
+	fpLibrary.Seed(seed)
+}
+
 // GetRandomFingerprint 提供一种简单的方法来获取随机指纹配置文件。
 // 返回值：随机选择的配置文件
 func GetRandomFingerprint() Profile {
 	return fpLibrary.RandomProfile() // 从全局指纹库获取随机配置文件
 }
 
+// GetWeightedRandomFingerprint 提供一种简单的方法来获取按市场份额加权的随机指纹配置文件。
+// 返回值：按权重随机选择的配置文件
+func GetWeightedRandomFingerprint() Profile {
+	return fpLibrary.RandomProfileWeighted() // 从全局指纹库按权重获取随机配置文件
+}
+
 // initProfiles 初始化所有支持的浏览器指纹配置文件
 func (lib *Library) initProfiles() {
 	lib.profiles = []Profile{ // 初始化配置文件列表
@@ -120,6 +146,7 @@ func (lib *Library) initProfiles() {
 			Platform:    "Windows",                                                                                                         // 平台信息
 			Browser:     "Chrome",                                                                                                          // 浏览器信息
 			Version:     "133",                                                                                                             // 版本信息
+			Weight:      260,                                                                                                               // 选中权重（近似真实市场份额）
 		},
 		{ // Chrome 133 - macOS配置
 			Name:        "Chrome 133 - macOS",
@@ -129,6 +156,7 @@ func (lib *Library) initProfiles() {
 			Platform:    "macOS",
 			Browser:     "Chrome",
 			Version:     "133",
+			Weight:      70, // 选中权重（近似真实市场份额）
 		},
 		{ // Chrome 131 - Windows配置
 			Name:        "Chrome 131 - Windows",
@@ -138,6 +166,7 @@ func (lib *Library) initProfiles() {
 			Platform:    "Windows",
 			Browser:     "Chrome",
 			Version:     "131",
+			Weight:      120, // 选中权重（近似真实市场份额）
 		},
 		{ // Chrome 131 - macOS配置
 			Name:        "Chrome 131 - macOS",
@@ -147,6 +176,7 @@ func (lib *Library) initProfiles() {
 			Platform:    "macOS",
 			Browser:     "Chrome",
 			Version:     "131",
+			Weight:      35, // 选中权重（近似真实市场份额）
 		},
 		{ // Chrome 120 - Windows配置
 			Name:        "Chrome 120 - Windows",
@@ -156,6 +186,7 @@ func (lib *Library) initProfiles() {
 			Platform:    "Windows",
 			Browser:     "Chrome",
 			Version:     "120",
+			Weight:      60, // 选中权重（近似真实市场份额）
 		},
 		{ // Chrome 120 - Linux配置
 			Name:        "Chrome 120 - Linux",
@@ -165,6 +196,7 @@ func (lib *Library) initProfiles() {
 			Platform:    "Linux",
 			Browser:     "Chrome",
 			Version:     "120",
+			Weight:      15, // 选中权重（近似真实市场份额）
 		},
 		{ // Chrome 115 PQ - Windows配置
 			Name:        "Chrome 115 PQ - Windows",
@@ -174,6 +206,7 @@ func (lib *Library) initProfiles() {
 			Platform:    "Windows",
 			Browser:     "Chrome",
 			Version:     "115-PQ",
+			Weight:      8, // 选中权重（近似真实市场份额）
 		},
 		{ // Chrome 114 - Windows配置
 			Name:        "Chrome 114 - Windows",
@@ -183,6 +216,7 @@ func (lib *Library) initProfiles() {
 			Platform:    "Windows",
 			Browser:     "Chrome",
 			Version:     "114",
+			Weight:      8, // 选中权重（近似真实市场份额）
 		},
 		{ // Chrome 112 - Windows配置
 			Name:        "Chrome 112 - Windows",
@@ -192,6 +226,7 @@ func (lib *Library) initProfiles() {
 			Platform:    "Windows",
 			Browser:     "Chrome",
 			Version:     "112",
+			Weight:      6, // 选中权重（近似真实市场份额）
 		},
 		{ // Chrome 106 Shuffle - Windows配置
 			Name:        "Chrome 106 Shuffle - Windows",
@@ -201,6 +236,7 @@ func (lib *Library) initProfiles() {
 			Platform:    "Windows",
 			Browser:     "Chrome",
 			Version:     "106",
+			Weight:      5, // 选中权重（近似真实市场份额）
 		},
 		{ // Chrome 102 - Windows配置
 			Name:        "Chrome 102 - Windows",
@@ -210,6 +246,7 @@ func (lib *Library) initProfiles() {
 			Platform:    "Windows",
 			Browser:     "Chrome",
 			Version:     "102",
+			Weight:      4, // 选中权重（近似真实市场份额）
 		},
 		{ // Chrome 100 - Windows配置
 			Name:        "Chrome 100 - Windows",
@@ -219,6 +256,7 @@ func (lib *Library) initProfiles() {
 			Platform:    "Windows",
 			Browser:     "Chrome",
 			Version:     "100",
+			Weight:      3, // 选中权重（近似真实市场份额）
 		},
 		{ // Chrome 96 - Windows配置
 			Name:        "Chrome 96 - Windows",
@@ -228,6 +266,7 @@ func (lib *Library) initProfiles() {
 			Platform:    "Windows",
 			Browser:     "Chrome",
 			Version:     "96",
+			Weight:      3, // 选中权重（近似真实市场份额）
 		},
 		{ // Chrome 87 - Windows配置
 			Name:        "Chrome 87 - Windows",
@@ -237,6 +276,7 @@ func (lib *Library) initProfiles() {
 			Platform:    "Windows",
 			Browser:     "Chrome",
 			Version:     "87",
+			Weight:      2, // 选中权重（近似真实市场份额）
 		},
 		{ // Chrome 83 - Windows配置
 			Name:        "Chrome 83 - Windows",
@@ -246,6 +286,7 @@ func (lib *Library) initProfiles() {
 			Platform:    "Windows",
 			Browser:     "Chrome",
 			Version:     "83",
+			Weight:      2, // 选中权重（近似真实市场份额）
 		},
 		{ // Chrome Auto - Windows配置
 			Name:        "Chrome Auto - Windows",
@@ -255,6 +296,7 @@ func (lib *Library) initProfiles() {
 			Platform:    "Windows",
 			Browser:     "Chrome",
 			Version:     "auto",
+			Weight:      40, // 选中权重（近似真实市场份额）
 		},
 		{ // Firefox 120 - Windows配置
 			Name:        "Firefox 120 - Windows",
@@ -264,6 +306,7 @@ func (lib *Library) initProfiles() {
 			Platform:    "Windows",
 			Browser:     "Firefox",
 			Version:     "120",
+			Weight:      35, // 选中权重（近似真实市场份额）
 		},
 		{ // Firefox 120 - macOS配置
 			Name:        "Firefox 120 - macOS",
@@ -273,6 +316,7 @@ func (lib *Library) initProfiles() {
 			Platform:    "macOS",
 			Browser:     "Firefox",
 			Version:     "120",
+			Weight:      10, // 选中权重（近似真实市场份额）
 		},
 		{ // Firefox 105 - Windows配置
 			Name:        "Firefox 105 - Windows",
@@ -282,6 +326,7 @@ func (lib *Library) initProfiles() {
 			Platform:    "Windows",
 			Browser:     "Firefox",
 			Version:     "105",
+			Weight:      6, // 选中权重（近似真实市场份额）
 		},
 		{ // Firefox 102 - Windows配置
 			Name:        "Firefox 102 - Windows",
@@ -291,6 +336,7 @@ func (lib *Library) initProfiles() {
 			Platform:    "Windows",
 			Browser:     "Firefox",
 			Version:     "102",
+			Weight:      5, // 选中权重（近似真实市场份额）
 		},
 		{ // Firefox 99 - Windows配置
 			Name:        "Firefox 99 - Windows",
@@ -300,6 +346,7 @@ func (lib *Library) initProfiles() {
 			Platform:    "Windows",
 			Browser:     "Firefox",
 			Version:     "99",
+			Weight:      4, // 选中权重（近似真实市场份额）
 		},
 		{ // Firefox 65 - Windows配置
 			Name:        "Firefox 65 - Windows",
@@ -309,6 +356,7 @@ func (lib *Library) initProfiles() {
 			Platform:    "Windows",
 			Browser:     "Firefox",
 			Version:     "65",
+			Weight:      2, // 选中权重（近似真实市场份额）
 		},
 		{ // Firefox 63 - Windows配置
 			Name:        "Firefox 63 - Windows",
@@ -318,6 +366,7 @@ func (lib *Library) initProfiles() {
 			Platform:    "Windows",
 			Browser:     "Firefox",
 			Version:     "63",
+			Weight:      2, // 选中权重（近似真实市场份额）
 		},
 		{ // Firefox 56 - Windows配置
 			Name:        "Firefox 56 - Windows",
@@ -327,6 +376,7 @@ func (lib *Library) initProfiles() {
 			Platform:    "Windows",
 			Browser:     "Firefox",
 			Version:     "56",
+			Weight:      1, // 选中权重（近似真实市场份额）
 		},
 		{ // Firefox 55 - Windows配置
 			Name:        "Firefox 55 - Windows",
@@ -336,6 +386,7 @@ func (lib *Library) initProfiles() {
 			Platform:    "Windows",
 			Browser:     "Firefox",
 			Version:     "55",
+			Weight:      1, // 选中权重（近似真实市场份额）
 		},
 		{ // Firefox Auto - Windows配置
 			Name:        "Firefox Auto - Windows",
@@ -345,6 +396,7 @@ func (lib *Library) initProfiles() {
 			Platform:    "Windows",
 			Browser:     "Firefox",
 			Version:     "auto",
+			Weight:      10, // 选中权重（近似真实市场份额）
 		},
 		{ // Edge 106 - Windows配置
 			Name:        "Edge 106 - Windows",
@@ -354,6 +406,7 @@ func (lib *Library) initProfiles() {
 			Platform:    "Windows",
 			Browser:     "Edge",
 			Version:     "106",
+			Weight:      45, // 选中权重（近似真实市场份额）
 		},
 		{ // Edge 85 - Windows配置
 			Name:        "Edge 85 - Windows",
@@ -363,6 +416,7 @@ func (lib *Library) initProfiles() {
 			Platform:    "Windows",
 			Browser:     "Edge",
 			Version:     "85",
+			Weight:      5, // 选中权重（近似真实市场份额）
 		},
 		{ // Edge Auto - Windows配置
 			Name:        "Edge Auto - Windows",
@@ -372,6 +426,7 @@ func (lib *Library) initProfiles() {
 			Platform:    "Windows",
 			Browser:     "Edge",
 			Version:     "auto",
+			Weight:      15, // 选中权重（近似真实市场份额）
 		},
 		{ // Safari 17 - macOS配置
 			Name:        "Safari 17 - macOS",
@@ -381,6 +436,7 @@ func (lib *Library) initProfiles() {
 			Platform:    "macOS",
 			Browser:     "Safari",
 			Version:     "17",
+			Weight:      90, // 选中权重（近似真实市场份额）
 		},
 		{ // iOS Safari 14 - iPhone配置
 			Name:        "iOS Safari 14 - iPhone",
@@ -390,6 +446,7 @@ func (lib *Library) initProfiles() {
 			Platform:    "iOS",
 			Browser:     "Safari",
 			Version:     "14",
+			Weight:      35, // 选中权重（近似真实市场份额）
 		},
 		{ // iOS Safari 13 - iPhone配置
 			Name:        "iOS Safari 13 - iPhone",
@@ -399,6 +456,7 @@ func (lib *Library) initProfiles() {
 			Platform:    "iOS",
 			Browser:     "Safari",
 			Version:     "13",
+			Weight:      10, // 选中权重（近似真实市场份额）
 		},
 		{ // iOS Safari 12 - iPhone配置
 			Name:        "iOS Safari 12 - iPhone",
@@ -408,6 +466,7 @@ func (lib *Library) initProfiles() {
 			Platform:    "iOS",
 			Browser:     "Safari",
 			Version:     "12",
+			Weight:      5, // 选中权重（近似真实市场份额）
 		},
 		{ // Randomized - Chrome Like配置
 			Name:        "Randomized - Chrome Like",
@@ -417,6 +476,7 @@ func (lib *Library) initProfiles() {
 			Platform:    "Random",
 			Browser:     "Random",
 			Version:     "random",
+			Weight:      1, // 选中权重（近似真实市场份额）
 		},
 		{ // Randomized ALPN - Chrome Like配置
 			Name:        "Randomized ALPN - Chrome Like",
@@ -426,6 +486,7 @@ func (lib *Library) initProfiles() {
 			Platform:    "Random",
 			Browser:     "Random",
 			Version:     "random",
+			Weight:      1, // 选中权重（近似真实市场份额）
 		},
 		{ // Randomized No ALPN - Firefox Like配置
 			Name:        "Randomized No ALPN - Firefox Like",
@@ -435,6 +496,7 @@ func (lib *Library) initProfiles() {
 			Platform:    "Random",
 			Browser:     "Random",
 			Version:     "random",
+			Weight:      1, // 选中权重（近似真实市场份额）
 		},
 	}
 }
@@ -483,6 +545,44 @@ func (lib *Library) RandomProfile() Profile {
 	return realProfiles[lib.randomIndex(len(realProfiles))] // 从真实浏览器指纹中随机选择
 }
 
+// RandomProfileWeighted 按权重随机返回一个配置文件（只返回真实浏览器的指纹）
+// 权重越大的配置文件越容易被选中，用于模拟真实世界的浏览器市场份额分布
+// 返回值：按权重随机选择的配置文件
+func (lib *Library) RandomProfileWeighted() Profile {
+	realProfiles := lib.getRealBrowserProfiles()
+	if len(realProfiles) == 0 { // 如果真实浏览器指纹列表为空，复用与RandomProfile相同的兜底逻辑
+		return lib.RandomProfile()
+	}
+
+	totalWeight := 0
+	for _, profile := range realProfiles {
+		totalWeight += profileWeight(profile)
+	}
+	if totalWeight <= 0 { // 所有权重均无效时退化为均匀随机
+		return realProfiles[lib.randomIndex(len(realProfiles))]
+	}
+
+	lib.mu.Lock()
+	pick := lib.rand.Intn(totalWeight)
+	lib.mu.Unlock()
+
+	for _, profile := range realProfiles {
+		pick -= profileWeight(profile)
+		if pick < 0 {
+			return profile
+		}
+	}
+	return realProfiles[len(realProfiles)-1] // 理论上不会到达，兜底返回最后一个
+}
+
+// profileWeight 返回配置文件的有效选中权重，未显式设置时使用defaultWeight
+func profileWeight(profile Profile) int {
+	if profile.Weight <= 0 {
+		return defaultWeight
+	}
+	return profile.Weight
+}
+
 // ProfileByName 根据名称查找配置文件
 // 参数：name - 配置文件名称
 // 返回值：配置文件指针和错误信息
@@ -628,3 +728,212 @@ func (lib *Library) RandomAcceptLanguage() string {
 	}
 	return builder.String() // 返回构建的字符串
 }
+
+// HeaderSet 生成与该指纹配置一致的完整请求头集合（Client Hints、Accept、Sec-Fetch-*等）
+// 用于保证TLS握手特征与HTTP请求头特征保持一致，避免指纹不匹配被风控识别
+// 返回值：头部名称到值的映射
+func (p Profile) HeaderSet() map[string]string {
+	headers := map[string]string{
+		"Accept":         "*/*",
+		"Sec-Fetch-Dest": "empty",
+		"Sec-Fetch-Mode": "cors",
+		"Sec-Fetch-Site": "same-site",
+	}
+
+	switch p.Browser {
+	case "Chrome":
+		headers["sec-ch-ua"] = chromiumBrandList("Google Chrome", p.Version)
+		headers["sec-ch-ua-mobile"] = chromiumMobileHint(p.Platform)
+		headers["sec-ch-ua-platform"] = chromiumPlatformHint(p.Platform)
+	case "Edge":
+		headers["sec-ch-ua"] = chromiumBrandList("Microsoft Edge", p.Version)
+		headers["sec-ch-ua-mobile"] = chromiumMobileHint(p.Platform)
+		headers["sec-ch-ua-platform"] = chromiumPlatformHint(p.Platform)
+	case "Firefox", "Safari":
+		// Firefox和Safari均未实现User-Agent Client Hints，不发送sec-ch-ua系列头部
+	}
+
+	return headers
+}
+
+// HeaderOrder 返回该浏览器家族发送HTTP/1.1请求头的大致顺序（不含Host，Host固定置于最前）。
+// net/http的Request.Write会把所有头部按自身规则重新排序并规范化大小写，这会抹掉浏览器的
+// 头部顺序特征；调用方应配合writeRawHTTP1Request按此顺序逐个写入，而不是交给标准库输出。
+// 列表中未出现的头部由调用方自行决定追加顺序。
+func (p Profile) HeaderOrder() []string {
+	switch p.Browser {
+	case "Chrome", "Edge":
+		return []string{
+			"Connection",
+			"sec-ch-ua",
+			"sec-ch-ua-mobile",
+			"sec-ch-ua-platform",
+			"Upgrade-Insecure-Requests",
+			"User-Agent",
+			"Accept",
+			"Sec-Fetch-Site",
+			"Sec-Fetch-Mode",
+			"Sec-Fetch-User",
+			"Sec-Fetch-Dest",
+			"Accept-Encoding",
+			"Accept-Language",
+		}
+	case "Firefox":
+		return []string{
+			"User-Agent",
+			"Accept",
+			"Accept-Language",
+			"Accept-Encoding",
+			"Connection",
+			"Sec-Fetch-Dest",
+			"Sec-Fetch-Mode",
+			"Sec-Fetch-Site",
+		}
+	case "Safari":
+		return []string{
+			"Accept",
+			"Accept-Encoding",
+			"Accept-Language",
+			"Connection",
+			"User-Agent",
+		}
+	default:
+		return nil
+	}
+}
+
+// chromiumMajorVersion 从版本字符串中提取Chromium主版本号，无法解析时返回空字符串
+func chromiumMajorVersion(version string) string {
+	major := strings.Builder{}
+	for _, r := range version {
+		if r < '0' || r > '9' {
+			break
+		}
+		major.WriteRune(r)
+	}
+	return major.String()
+}
+
+// chromiumBrandList 生成Chromium系浏览器的sec-ch-ua品牌列表，格式与真实浏览器一致
+func chromiumBrandList(brand, version string) string {
+	major := chromiumMajorVersion(version)
+	if major == "" {
+		major = "133" // 无法解析版本号时回退到当前基线版本
+	}
+	return fmt.Sprintf(`"Not:A-Brand";v="24", "Chromium";v="%s", "%s";v="%s"`, major, brand, major)
+}
+
+// H2SettingsFingerprint 描述浏览器在HTTP/2连接上呈现的可观测SETTINGS特征。
+// 标准库golang.org/x/net/http2的Transport不支持自定义完整的SETTINGS帧
+// （如HEADER_TABLE_SIZE、伪首部顺序、PRIORITY帧等），这里只暴露当前可控的字段，
+// 尽量让本地SETTINGS_MAX_HEADER_LIST_SIZE/SETTINGS_MAX_FRAME_SIZE贴近真实浏览器
+type H2SettingsFingerprint struct {
+	MaxHeaderListSize uint32 // 对应SETTINGS_MAX_HEADER_LIST_SIZE
+	MaxReadFrameSize  uint32 // 本端愿意接收的最大帧大小，对应SETTINGS_MAX_FRAME_SIZE
+}
+
+// H2Settings 返回与该配置文件所属浏览器家族相匹配的HTTP/2 SETTINGS特征
+func (p Profile) H2Settings() H2SettingsFingerprint {
+	switch p.Browser {
+	case "Firefox":
+		return H2SettingsFingerprint{MaxHeaderListSize: 393216, MaxReadFrameSize: 16384}
+	case "Safari":
+		return H2SettingsFingerprint{MaxHeaderListSize: 131072, MaxReadFrameSize: 16384}
+	default: // Chrome、Edge等Chromium内核浏览器
+		return H2SettingsFingerprint{MaxHeaderListSize: 262144, MaxReadFrameSize: 16384}
+	}
+}
+
+// NewHTTP2Transport 创建与该指纹配置的HTTP/2特征相匹配的http2.Transport
+// dialTLS用于复用已经完成uTLS握手的连接
+func (p Profile) NewHTTP2Transport(dialTLS func(network, addr string, cfg *tls.Config) (net.Conn, error)) *http2.Transport {
+	settings := p.H2Settings()
+	return &http2.Transport{
+		DialTLS:           dialTLS,
+		AllowHTTP:         true,
+		MaxHeaderListSize: settings.MaxHeaderListSize,
+		MaxReadFrameSize:  settings.MaxReadFrameSize,
+	}
+}
+
+// chromiumMobileHint 根据平台信息返回sec-ch-ua-mobile取值
+func chromiumMobileHint(platform string) string {
+	if platform == "iPhone" || platform == "Android" {
+		return "?1"
+	}
+	return "?0"
+}
+
+// chromiumPlatformHint 根据平台信息返回sec-ch-ua-platform取值
+func chromiumPlatformHint(platform string) string {
+	switch platform {
+	case "Windows":
+		return `"Windows"`
+	case "macOS":
+		return `"macOS"`
+	case "Linux":
+		return `"Linux"`
+	case "iPhone":
+		return `"iOS"`
+	case "Android":
+		return `"Android"`
+	default:
+		return `"Unknown"`
+	}
+}
+
+// stickyFingerprintSession 记录一个key当前粘滞的指纹及其会话过期时间
+type stickyFingerprintSession struct {
+	profile   Profile
+	expiresAt time.Time // 零值表示会话永不过期
+}
+
+// StickyFingerprintSelector 按key（通常是目标IP）缓存指纹选择结果，使同一个key在
+// 会话有效期内始终复用同一个指纹，避免同一连接/目标上出现TLS与UA/头部不一致的混合指纹特征
+type StickyFingerprintSelector struct {
+	mu       sync.Mutex
+	sessions map[string]stickyFingerprintSession
+	ttl      time.Duration // <=0表示会话在进程生命周期内永不过期
+}
+
+// NewStickyFingerprintSelector 创建一个粘滞指纹选择器，ttl为每个key的会话有效期
+func NewStickyFingerprintSelector(ttl time.Duration) *StickyFingerprintSelector {
+	return &StickyFingerprintSelector{
+		sessions: make(map[string]stickyFingerprintSession),
+		ttl:      ttl,
+	}
+}
+
+// Get 返回key当前粘滞的指纹；如果会话不存在或已过期，则按权重随机选择一个新指纹并开启新会话
+func (s *StickyFingerprintSelector) Get(key string) Profile {
+	return s.GetAvoiding(key, nil)
+}
+
+// GetAvoiding与Get语义相同，额外接受一个avoid谓词（nil等价于Get，不做任何回避）：如果当前
+// 粘滞的指纹命中avoid（比如刚好处于fingerprintAvoidanceTracker的冷却期），则跳出粘滞重新
+// 按权重选择，最多重试fingerprintRerollAttempts次，直到选出不命中avoid的指纹；重试次数用尽
+// 仍然只能选出被回避的指纹时，接受这个结果并开启新会话——好过完全选不出指纹导致连接建立不了。
+func (s *StickyFingerprintSelector) GetAvoiding(key string, avoid func(Profile) bool) Profile {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if session, ok := s.sessions[key]; ok && (session.expiresAt.IsZero() || now.Before(session.expiresAt)) {
+		if avoid == nil || !avoid(session.profile) {
+			return session.profile
+		}
+	}
+
+	profile := fpLibrary.RandomProfileWeighted()
+	for attempt := 0; avoid != nil && avoid(profile) && attempt < fingerprintRerollAttempts; attempt++ {
+		profile = fpLibrary.RandomProfileWeighted()
+	}
+
+	session := stickyFingerprintSession{profile: profile}
+	if s.ttl > 0 {
+		session.expiresAt = now.Add(s.ttl)
+	}
+	s.sessions[key] = session
+	return profile
+}
@@ -0,0 +1,159 @@
+// Package src 定义了utlsProxy的核心源码。
+package src
+
+import ( // 导入所需的标准库和第三方库
+	"context"       // 用于构造http.Transport的DialContext
+	"crypto/tls"    // 用于http2.Transport的DialTLS签名
+	"encoding/json" // 用于解析自检端点返回的JSON响应
+	"fmt"           // 用于格式化错误信息
+	"io"            // 用于读取响应体
+	"net"           // 用于建立TCP连接
+	"net/http"      // 用于发起HTTP请求
+	"net/url"       // 用于解析自检端点地址
+	"time"          // 用于超时控制
+
+	utls "github.com/refraction-networking/utls" // 导入utls库用于TLS指纹伪装
+)
+
+// VerifierResult 描述单个Profile在自检端点上实际产生的指纹信息。
+type VerifierResult struct {
+	ProfileName  string // 被检测的Profile名称
+	JA3          string // JA3指纹原始字符串
+	JA3Hash      string // JA3指纹的哈希值
+	JA4          string // JA4指纹
+	HTTP2        string // HTTP/2（Akamai风格）指纹
+	ErrorMessage string // 非空表示本次自检失败及原因
+}
+
+// peetTLSResponse 映射 tls.peet.ws /api/all 接口返回的JSON结构中，自检所需的子集字段。
+type peetTLSResponse struct {
+	TLS struct {
+		JA3     string `json:"ja3"`
+		JA3Hash string `json:"ja3_hash"`
+		JA4     string `json:"ja4"`
+	} `json:"tls"`
+	HTTP2 struct {
+		AkamaiFingerprint string `json:"akamai_fingerprint"`
+	} `json:"http2"`
+}
+
+// FingerprintVerifier 使用可配置的JA3/JA4回显服务，对Profile实际建立的TLS/HTTP2连接进行指纹自检，
+// 用于在投入生产前确认utls的HelloID确实产出了预期的指纹特征，而不是凭经验假设。
+type FingerprintVerifier struct {
+	EchoURL     string        // 自检端点地址，例如 https://tls.peet.ws/api/all
+	DialTimeout time.Duration // 建立TCP连接的超时时间
+	ReadTimeout time.Duration // 等待自检端点响应的超时时间
+}
+
+// NewFingerprintVerifier 创建一个指纹自检器，echoURL为空时使用tls.peet.ws的默认接口
+func NewFingerprintVerifier(echoURL string) *FingerprintVerifier {
+	if echoURL == "" {
+		echoURL = "https://tls.peet.ws/api/all"
+	}
+	return &FingerprintVerifier{
+		EchoURL:     echoURL,
+		DialTimeout: 10 * time.Second,
+		ReadTimeout: 10 * time.Second,
+	}
+}
+
+// Verify 使用指定Profile与自检端点建立一次独立连接，并返回该Profile实际产生的指纹信息
+func (v *FingerprintVerifier) Verify(profile Profile) (*VerifierResult, error) {
+	endpoint, err := url.Parse(v.EchoURL)
+	if err != nil {
+		return nil, fmt.Errorf("解析自检端点地址失败: %w", err)
+	}
+	port := endpoint.Port()
+	if port == "" {
+		port = "443"
+	}
+
+	dialer := net.Dialer{Timeout: v.DialTimeout}
+	tcpConn, err := dialer.Dial("tcp", net.JoinHostPort(endpoint.Hostname(), port))
+	if err != nil {
+		return nil, fmt.Errorf("连接自检端点失败: %w", err)
+	}
+
+	uConn := utls.UClient(tcpConn, &utls.Config{
+		ServerName: endpoint.Hostname(),
+		NextProtos: []string{"h2", "http/1.1"},
+	}, profile.HelloID)
+	if err := uConn.Handshake(); err != nil {
+		_ = uConn.Close()
+		return nil, fmt.Errorf("TLS握手失败: %w", err)
+	}
+
+	state := uConn.ConnectionState()
+	protocol := state.NegotiatedProtocol
+	if protocol == "" {
+		protocol = "http/1.1"
+	}
+
+	var httpClient *http.Client
+	if protocol == "h2" {
+		transport := profile.NewHTTP2Transport(func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+			return uConn, nil
+		})
+		httpClient = &http.Client{Transport: transport, Timeout: v.ReadTimeout}
+	} else {
+		httpClient = &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+					return uConn, nil
+				},
+			},
+			Timeout: v.ReadTimeout,
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodGet, v.EchoURL, nil)
+	if err != nil {
+		_ = uConn.Close()
+		return nil, fmt.Errorf("创建自检请求失败: %w", err)
+	}
+	if profile.UserAgent != "" {
+		req.Header.Set("User-Agent", profile.UserAgent)
+	}
+	for key, value := range profile.HeaderSet() {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		_ = uConn.Close()
+		return nil, fmt.Errorf("请求自检端点失败: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取自检响应失败: %w", err)
+	}
+
+	var parsed peetTLSResponse
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return nil, fmt.Errorf("解析自检响应失败: %w", err)
+	}
+
+	return &VerifierResult{
+		ProfileName: profile.Name,
+		JA3:         parsed.TLS.JA3,
+		JA3Hash:     parsed.TLS.JA3Hash,
+		JA4:         parsed.TLS.JA4,
+		HTTP2:       parsed.HTTP2.AkamaiFingerprint,
+	}, nil
+}
+
+// VerifyAll 依次对一组Profile执行自检，单个Profile失败不会中断其余Profile的检测
+func (v *FingerprintVerifier) VerifyAll(profiles []Profile) []VerifierResult {
+	results := make([]VerifierResult, 0, len(profiles))
+	for _, profile := range profiles {
+		result, err := v.Verify(profile)
+		if err != nil {
+			results = append(results, VerifierResult{ProfileName: profile.Name, ErrorMessage: err.Error()})
+			continue
+		}
+		results = append(results, *result)
+	}
+	return results
+}
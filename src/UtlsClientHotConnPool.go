@@ -2,12 +2,14 @@
 package src
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"io"
 	"math/rand"
 	"net"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -15,6 +17,55 @@ import (
 
 	utls "github.com/refraction-networking/utls"
 	"golang.org/x/net/http2"
+
+	"utlsProxy/internal/safego"
+)
+
+// emergencyResolveTimeout 是ensureTargetIPs现场解析域名时的超时时间
+const emergencyResolveTimeout = 5 * time.Second
+
+// systemResolveDomain 是EmergencyResolver的默认实现：用进程的系统默认解析器（遵循/etc/resolv.conf
+// 等本机DNS配置）解析一次domain，按地址族拆分成ipv4/ipv6两个列表
+func systemResolveDomain(ctx context.Context, domain string) (ipv4, ipv6 []string, err error) {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, domain)
+	if err != nil {
+		return nil, nil, fmt.Errorf("系统解析器解析域名 [%s] 失败: %w", domain, err)
+	}
+	for _, addr := range addrs {
+		if ip4 := addr.IP.To4(); ip4 != nil {
+			ipv4 = append(ipv4, ip4.String())
+		} else {
+			ipv6 = append(ipv6, addr.IP.String())
+		}
+	}
+	if len(ipv4) == 0 && len(ipv6) == 0 {
+		return nil, nil, fmt.Errorf("系统解析器未返回域名 [%s] 的任何IP", domain)
+	}
+	return ipv4, ipv6, nil
+}
+
+const (
+	// tcpKeepAlivePeriod 是池化连接的TCP keepalive探测间隔，用于让内核层面及时发现
+	// 对端已经消失（网络中断、对端进程崩溃等）而本地四元组仍然“看起来”存活的半开连接
+	tcpKeepAlivePeriod = 30 * time.Second
+
+	// livenessProbeInterval 是后台探活任务的轮询周期，每轮对当前闲置在各连接桶里的连接
+	// 各做一次探活，而不是等到下次被GetConn取用或业务请求失败时才发现连接已经失效
+	livenessProbeInterval = 30 * time.Second
+
+	// h2PingTimeout 是单次HTTP/2 PING帧探活的超时时间
+	h2PingTimeout = 3 * time.Second
+
+	// h2PingRTTDegradeThreshold 是h2 PING往返时延的退化阈值，超过该值即使PING本身成功
+	// 也认为连接质量已经劣化到不值得继续复用，直接关闭由后续请求重新建连
+	h2PingRTTDegradeThreshold = 1500 * time.Millisecond
+
+	// replenisherInterval 是后台连接补充任务的轮询周期，每轮把健康连接数补到maxConns附近，
+	// 让GetConn在正常情况下总能直接从池里取到连接，而不必在请求路径上同步建连握手
+	replenisherInterval = 10 * time.Second
+
+	// closeDrainPollInterval 是CloseGracefully等待在途连接归还时的轮询间隔
+	closeDrainPollInterval = 50 * time.Millisecond
 )
 
 // HotConnPool defines the hot connection pool interface
@@ -22,26 +73,205 @@ type HotConnPool interface {
 	GetConn() (*ConnMetadata, error)
 	GetConnByIP(targetIP string) (*ConnMetadata, error)
 	ReturnConn(connMeta *ConnMetadata, statusCode int) error
+	// ReturnConnWithRetryAfter 与ReturnConn语义相同，额外支持retryAfter>0时（典型来源：
+	// 目标站点429/503响应携带的Retry-After头）把目标IP临时加入黑名单retryAfter这么久，
+	// 到期后自动恢复，不受StatusPolicy对该状态码配置的动作影响；retryAfter<=0时等价于
+	// 直接调用ReturnConn。
+	ReturnConnWithRetryAfter(connMeta *ConnMetadata, statusCode int, retryAfter time.Duration) error
 	UpdateIPStats(targetIP string, statusCode int)
 	Close() error
-	Warmup() error
+	// Warmup 对完整的目标IP列表做一轮预热，返回按IP列出的结构化结果报告
+	Warmup() (*WarmupReport, error)
+	// WarmupIncremental 只预热ips给出的这部分IP，不重新遍历完整目标IP列表，
+	// 用于新发现的IP单独补热的场景
+	WarmupIncremental(ips []string) (*WarmupReport, error)
+	// SetWarmupProgress 注册一个每完成一个IP的预热就同步调用一次的回调，传nil取消注册；
+	// 调用方可以据此在预热尚未全部完成时就提前判断"已经有足够多连接可用了"
+	SetWarmupProgress(cb func(WarmupResult))
+	// WarmupStats 返回预热/健康检查这类内部控制流量累计的尝试数、成功数和失败数，
+	// 用于将其与业务请求统计区分开来单独观测
+	WarmupStats() (attempts, success, failed int64)
+	// RecordConnBytes 按目标IP、本地IP和协议（"h2"/"http/1.1"）累加一次套接字级读写字节数，
+	// 由调用方在每次请求后传入该连接自上次统计以来新增的字节数（包含TLS和帧层开销），
+	// 用于按量计费场景下比仅统计HTTP body大小更准确的流量核算。localIP为空时跳过按本地IP的累加
+	// （例如IPv6RotatePerRequest之外没有显式记录本地IP的路径）。
+	RecordConnBytes(targetIP, localIP, protocol string, read, written int64)
+	// TrafficByIP 返回按目标IP聚合的套接字级读写字节数快照
+	TrafficByIP() map[string]IPTraffic
+	// TrafficByLocalIP 返回按本地出口IP聚合的套接字级读写字节数快照，用于观测多本地IP出口
+	// 场景下各出口实际分摊的流量
+	TrafficByLocalIP() map[string]IPTraffic
+	// TrafficByProtocol 返回按协议（"h2"/"http/1.1"）聚合的套接字级读写字节数快照
+	TrafficByProtocol() map[string]ProtocolTraffic
+	// HandshakeStatsByIP 返回按目标IP聚合的TLS握手统计快照（完整/恢复握手次数、失败次数、
+	// 平均耗时、协商出的ALPN/密码套件分布），用于定位哪些目标IP握手经常失败或总走完整握手
+	HandshakeStatsByIP() map[string]HandshakeStats
+	// HandshakeStatsByFingerprint 返回按指纹名聚合的TLS握手统计快照，用于定位哪个指纹
+	// 在当前目标上握手失败率异常或协商不到期望的ALPN/密码套件
+	HandshakeStatsByFingerprint() map[string]HandshakeStats
+	// DailyBytesUsed 返回当天（按进程本地时区的自然日）已累计的套接字级读写字节总数，
+	// 用于配合DomainConnPoolConfig.DailyByteCap对外展示当前用量，见RecordConnBytes
+	DailyBytesUsed() int64
+	// HealthyConnCount 返回当前处于健康状态、可直接取用的连接数
+	HealthyConnCount() int
+	// CheckedOutCount 返回当前已通过GetConn/GetConnByIP租出、尚未经ReturnConn归还的连接数
+	CheckedOutCount() int64
+	// CloseGracefully 与Close语义相同，但会先等待CheckedOutCount归零（即所有在途请求都已经
+	// ReturnConn归还连接）或ctx到期，再执行硬关闭，使调用方可以在关闭前给在途任务留出收尾时间，
+	// 不必像直接调用Close那样可能让正在使用的连接被拦腰关掉、相应任务直接失败。
+	CloseGracefully(ctx context.Context) error
 }
 
-// ConnMetadata contains connection metadata
+// ConnMetadata 是GetConn/GetConnByIP租出的连接句柄：除了底层*utls.UConn本身，
+// 还携带TargetIP、LocalIP、Protocol、CreatedAt等元数据，调用方全程持有这一份
+// 句柄，不需要也不应该从RemoteAddr反推目标IP或另外记录建连时间——借用结束后
+// 通过Release（或等价的pool.ReturnConn）归还，而不是自己关闭底层连接。
 type ConnMetadata struct {
-	Conn       *utls.UConn
-	HttpClient *http.Client // For HTTP/2 connections
-	Protocol   string       // "h2" or "http/1.1"
-	TargetIP   string
-	LocalIP    string
-	CreatedAt  time.Time
-	LastUsed   time.Time
+	// Conn 通常是*utls.UConn（PlaintextHTTP为false，即默认行为），但PlaintextHTTP为true（见
+	// DomainConnPoolConfig.PlaintextHTTP）时是裸的*countingConn（明文TCP，没有TLS层）——
+	// 统一声明成net.Conn接口，createConnection据此分流，其余调用方（sendHTTPRequest/
+	// readHTTPResponse等）本来就只依赖net.Conn接口，不需要关心具体是哪一种。
+	Conn        net.Conn
+	HttpClient  *http.Client // For HTTP/2 connections
+	Protocol    string       // "h2" or "http/1.1"
+	TargetIP    string
+	LocalIP     string
+	CreatedAt   time.Time
+	LastUsed    time.Time
+	Fingerprint Profile // 建立该连接时实际使用的指纹，请求头必须与此保持一致，避免同一连接上出现混合指纹
+
+	http2Conn   *http2.ClientConn // 仅h2连接非nil，供后台探活任务发送PING帧测RTT
+	lastPingRTT time.Duration     // 最近一次PING探活的往返时延，仅h2连接有意义
+
+	socketConn          *countingConn // 包裹在原始TCP连接外层，统计该连接套接字级别的读写字节数
+	lastRecordedRead    int64
+	lastRecordedWritten int64
+
+	ephemeral bool // 由DomainConnPoolConfig.IPv6RotatePerRequest产生的一次性连接，ReturnConn时直接关闭并释放本地IP，不入池复用
+
+	// 以下字段仅HTTP/1.1连接需要：h2连接的帧级多路复用和GOAWAY机制由golang.org/x/net/http2
+	// 自己管理生命周期，不需要在这里单独记账。
+	requestCount    int64     // 已在该连接上完成的请求数，配合keepAliveMax判断是否到达服务端声明的上限
+	keepAliveMax    int64     // 服务端通过"Keep-Alive: max=N"声明的该连接最多可服务请求数，0表示未声明
+	keepAliveExpiry time.Time // 服务端通过"Keep-Alive: timeout=N"声明的预计失效时间，零值表示未声明
+	closeRequested  int32     // 最近一次响应带有"Connection: close"，原子标记供shouldRetireHTTP1读取
+}
+
+// recordHTTP1KeepAlive根据一次HTTP/1.1响应的Header更新该连接的keep-alive记账：请求计数加一，
+// 并解析Connection/Keep-Alive头部。记账结果由shouldRetireHTTP1在ReturnConn时读取，
+// 决定这个连接是该放回池子复用，还是该在服务端真的强制断开之前就主动关闭。
+func (m *ConnMetadata) recordHTTP1KeepAlive(header http.Header) {
+	if m == nil {
+		return
+	}
+	atomic.AddInt64(&m.requestCount, 1)
+
+	if hasCloseToken(header.Get("Connection")) {
+		atomic.StoreInt32(&m.closeRequested, 1)
+		return
+	}
+
+	// Keep-Alive: timeout=5, max=100 —— 两个参数都是可选的，服务端可能只给出其中一个
+	for _, field := range strings.Split(header.Get("Keep-Alive"), ",") {
+		kv := strings.SplitN(strings.TrimSpace(field), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		value := strings.TrimSpace(kv[1])
+		switch strings.ToLower(strings.TrimSpace(kv[0])) {
+		case "timeout":
+			if seconds, err := strconv.Atoi(value); err == nil {
+				m.keepAliveExpiry = time.Now().Add(time.Duration(seconds) * time.Second)
+			}
+		case "max":
+			if max, err := strconv.Atoi(value); err == nil {
+				atomic.StoreInt64(&m.keepAliveMax, int64(max))
+			}
+		}
+	}
+}
+
+// shouldRetireHTTP1 返回这个HTTP/1.1连接是否应该在本次请求后直接关闭而不是放回连接池复用：
+// 服务端显式要求Connection: close、已达到/超过Keep-Alive: max声明的请求数上限、或者已经过了
+// Keep-Alive: timeout预告的存活时间——满足任意一条都说明服务端很可能随时单方面断开这个连接，
+// 继续把它放回池子只会让下一个使用者在请求中途撞见一次连接错误，retire掉换成新连接更划算。
+func (m *ConnMetadata) shouldRetireHTTP1() bool {
+	if m == nil {
+		return false
+	}
+	if atomic.LoadInt32(&m.closeRequested) != 0 {
+		return true
+	}
+	if max := atomic.LoadInt64(&m.keepAliveMax); max > 0 && atomic.LoadInt64(&m.requestCount) >= max {
+		return true
+	}
+	if !m.keepAliveExpiry.IsZero() && time.Now().After(m.keepAliveExpiry) {
+		return true
+	}
+	return false
+}
+
+// hasCloseToken判断Connection头部的逗号分隔token列表中是否包含"close"（大小写不敏感）
+func hasCloseToken(connectionHeader string) bool {
+	for _, token := range strings.Split(connectionHeader, ",") {
+		if strings.EqualFold(strings.TrimSpace(token), "close") {
+			return true
+		}
+	}
+	return false
+}
+
+// ConsumeByteDelta 返回自上次调用以来该连接在套接字层面新增的读写字节数（含TLS握手和记录层开销），
+// 用于按请求增量统计流量，而不是每次都重新读取连接建立以来的全部累计值
+func (m *ConnMetadata) ConsumeByteDelta() (read, written int64) {
+	if m == nil || m.socketConn == nil {
+		return 0, 0
+	}
+	curRead := m.socketConn.BytesRead()
+	curWritten := m.socketConn.BytesWritten()
+	read = curRead - atomic.LoadInt64(&m.lastRecordedRead)
+	written = curWritten - atomic.LoadInt64(&m.lastRecordedWritten)
+	atomic.StoreInt64(&m.lastRecordedRead, curRead)
+	atomic.StoreInt64(&m.lastRecordedWritten, curWritten)
+	return read, written
+}
+
+// Age 返回该连接自建立以来经过的时长
+func (m *ConnMetadata) Age() time.Duration {
+	if m == nil || m.CreatedAt.IsZero() {
+		return 0
+	}
+	return time.Since(m.CreatedAt)
+}
+
+// Release 把连接归还给pool，等价于直接调用pool.ReturnConn(m, statusCode)；
+// 提供这个方法是为了让调用方能链式地在借出的连接句柄本身上完成归还，
+// 不必另外持有一份pool引用周转
+func (m *ConnMetadata) Release(pool HotConnPool, statusCode int) error {
+	if m == nil || pool == nil {
+		return fmt.Errorf("连接句柄或连接池不能为空")
+	}
+	return pool.ReturnConn(m, statusCode)
 }
 
 // ipStats contains IP statistics
 type ipStats struct {
 	SuccessCount int64
 	FailureCount int64
+	BytesRead    int64 // 套接字层面从该IP读取的字节数，含TLS和帧层开销
+	BytesWritten int64 // 套接字层面向该IP写入的字节数，含TLS和帧层开销
+}
+
+// ProtocolTraffic 是按协议（"h2"/"http/1.1"）聚合的套接字级读写字节数快照
+type ProtocolTraffic struct {
+	BytesRead    int64
+	BytesWritten int64
+}
+
+// IPTraffic 是按目标IP聚合的套接字级读写字节数快照
+type IPTraffic struct {
+	BytesRead    int64
+	BytesWritten int64
 }
 
 // ipWarmupJob represents an IP to be warmed up
@@ -50,19 +280,53 @@ type ipWarmupJob struct {
 	isIPv6 bool
 }
 
+// WarmupResult 是单个目标IP一次预热尝试的结构化结果
+type WarmupResult struct {
+	IP       string
+	IsIPv6   bool
+	Success  bool
+	Protocol string        // "h2"或"http/1.1"，连接创建失败时为空
+	Latency  time.Duration // 从开始预热这个IP到结果产生（成功放入连接池或判定失败）的耗时
+	Err      error         // Success为false时记录失败原因，成功时为nil
+}
+
+// WarmupReport 汇总一轮Warmup/WarmupIncremental调用中所有IP各自的WarmupResult
+type WarmupReport struct {
+	Results   []WarmupResult
+	Attempted int
+	Succeeded int
+	Failed    int
+	Duration  time.Duration
+}
+
 // domainConnPool implements the HotConnPool interface
 type domainConnPool struct {
 	healthyConns     chan *ConnMetadata
 	unhealthyConns   chan *ConnMetadata
 	ipConnPools      map[string]chan *ConnMetadata
+	ipConnOrder      []string // 拥有专属连接桶的目标IP顺序，用于GetConn的轮询取连接
+	nextIPIndex      int      // 下一次轮询的起始下标
 	ipConnPoolsMutex sync.RWMutex
 
-	domainMonitor   DomainMonitor
-	ipAccessControl IPAccessController
-	fingerprint     Profile
-	localIPv4Pool   IPPool
-	localIPv6Pool   IPPool
-	hasIPv6Support  bool
+	domainMonitor        DomainMonitor
+	ipAccessControl      IPAccessController
+	fingerprint          Profile
+	fingerprintSelector  *StickyFingerprintSelector // 非nil时按目标IP粘滞选择指纹，nil时所有连接复用fingerprint字段
+	localIPv4Pool        IPPool
+	localIPv6Pool        IPPool
+	hasIPv6Support       bool
+	ipv6RotatePerRequest bool          // 见DomainConnPoolConfig.IPv6RotatePerRequest
+	plaintextHTTP        bool          // 见DomainConnPoolConfig.PlaintextHTTP
+	freebind             bool          // 见DomainConnPoolConfig.Freebind
+	sourcePortRange      [2]int        // 见DomainConnPoolConfig.SourcePortRange
+	socketMark           int           // 见DomainConnPoolConfig.SocketMark
+	dscp                 int           // 见DomainConnPoolConfig.DSCP
+	happyEyeballs        bool          // 见DomainConnPoolConfig.HappyEyeballs
+	happyEyeballsDelay   time.Duration // 见DomainConnPoolConfig.HappyEyeballsDelay
+	preferredCountries   []string      // 见DomainConnPoolConfig.PreferredCountries，已统一转为大写
+	preferredASNs        []string      // 见DomainConnPoolConfig.PreferredASNs，已统一转为大写
+	recoveryWarmupCount  int           // 见DomainConnPoolConfig.RecoveryWarmupCount
+	statusPolicy         StatusPolicy  // 见DomainConnPoolConfig.StatusPolicy
 
 	targetIPv6List []string
 	targetIPv4List []string
@@ -77,6 +341,10 @@ type domainConnPool struct {
 	wg       sync.WaitGroup
 	closed   bool
 
+	// checkedOut 记录当前通过GetConn/GetConnByIP租出、尚未经ReturnConn归还的连接数，
+	// 供CloseGracefully判断是否还有在途请求，原子读写，见CheckedOutCount/CloseGracefully
+	checkedOut int64
+
 	maxConns          int
 	idleTime          time.Duration
 	domain            string
@@ -85,15 +353,48 @@ type domainConnPool struct {
 	warmupMethod      string
 	warmupHeaders     map[string]string
 	warmupConcurrency int
+	warmupValidator   *WarmupValidator // 见DomainConnPoolConfig.WarmupValidator
 
 	blacklistTestInterval time.Duration
 	ipRefreshInterval     time.Duration
 
-	healthCheckClient *UTlsClient
-	rand              *rand.Rand
-	ipStatsMap        map[string]*ipStats
-	ipStatsMutex      sync.RWMutex
-	sessionCache      utls.ClientSessionCache
+	healthCheckClient    *UTlsClient
+	rand                 *rand.Rand
+	ipStatsMap           map[string]*ipStats
+	ipStatsMutex         sync.RWMutex
+	protocolStatsMap     map[string]*ProtocolTraffic
+	localIPStatsMap      map[string]*ipStats // 按本地出口IP聚合的字节数，复用ipStats结构但只用到Bytes*字段
+	sessionCache         utls.ClientSessionCache
+	handshakeStats       *handshakeStatsTracker       // 按目标IP、按指纹聚合的TLS握手统计，见HandshakeStats.go
+	fingerprintAvoidance *fingerprintAvoidanceTracker // (指纹, 目标IP)连续握手失败后的冷却跟踪，见FingerprintAvoidance.go
+
+	// dailyByteCap/dailyBytesUsed/dailyCapDate共同实现DailyByteCap：dailyBytesUsed和
+	// dailyCapDate与ipStatsMap等其他流量统计共用ipStatsMutex保护，在RecordConnBytes里
+	// 按自然日切换时一并重置，不需要额外起一个后台任务
+	dailyByteCap   int64
+	dailyBytesUsed int64
+	dailyCapDate   string
+
+	// 预热/健康检查是内部控制流量，不代表业务爬取请求，因此单独计数，
+	// 不计入Crawler侧按任务统计的CrawlerStats，避免预热流量拉低业务成功率指标
+	warmupAttempts int64
+	warmupSuccess  int64
+	warmupFailed   int64
+
+	warmupProgressMu sync.RWMutex
+	warmupProgressCb func(WarmupResult) // 非nil时，每个IP预热完成都会同步回调一次，见SetWarmupProgress
+
+	rotateIndex int64 // IPv6RotatePerRequest模式下在targetIPv6List中轮询选目标IP的计数器，原子自增，避免和p.rand共享同一把锁
+
+	// emergencyResolver 非nil时供ensureTargetIPs在targetIPv4List/targetIPv6List都为空时
+	// 现场直接解析一次域名自举，见DomainConnPoolConfig.EmergencyResolveEnabled/EmergencyResolver
+	emergencyResolver func(ctx context.Context, domain string) (ipv4, ipv6 []string, err error)
+}
+
+// WarmupStats 返回本连接池累计的预热/健康检查请求数、成功数和失败数，
+// 供上层观测预热行为而不必把这部分流量混入业务请求统计
+func (p *domainConnPool) WarmupStats() (attempts, success, failed int64) {
+	return atomic.LoadInt64(&p.warmupAttempts), atomic.LoadInt64(&p.warmupSuccess), atomic.LoadInt64(&p.warmupFailed)
 }
 
 // DomainConnPoolConfig defines the configuration for the domain connection pool
@@ -114,6 +415,82 @@ type DomainConnPoolConfig struct {
 	BlacklistTestInterval time.Duration
 	IPRefreshInterval     time.Duration
 	DialTimeout           time.Duration
+	FingerprintStickyIP   bool          // 是否按目标IP粘滞指纹，而不是整个连接池共用同一个指纹
+	FingerprintSessionTTL time.Duration // 粘滞指纹的会话有效期，<=0表示在进程生命周期内永不过期
+	RandomSeed            int64         // 连接池内部随机数生成器的种子，0表示使用基于时间的种子
+	// IPv6RotatePerRequest 为true时，GetConn/GetConnByIP对该域名完全绕过healthyConns/ipConnPools复用，
+	// 每次都用一个新分配的本地IPv6地址现建连接，并在ReturnConn时立即关闭连接、释放本地地址，
+	// 代价是放弃连接复用带来的握手开销节省，换取每个请求都使用不同的出口地址。
+	IPv6RotatePerRequest bool
+	// Freebind 为true时，创建连接前通过IP_FREEBIND/IPV6_FREEBIND（仅Linux，见freebind_linux.go）
+	// 绑定本地地址，而不要求该地址已经用ip addr add加到网卡上，可以和IPv6RotatePerRequest
+	// 搭配使用以彻底跳过LocalIPPool的地址创建/清理机制；其余平台上这是no-op，回退到原有行为。
+	Freebind bool
+	// SourcePortRange 指定拨号时使用的本地源端口闭区间[min,max]，零值[0,0]表示沿用系统自动分配的
+	// 临时端口。配置后createConnection会在区间内随机选一个端口并设置SO_REUSEADDR（仅Linux，见
+	// reuseaddr_linux.go），失败时在区间内重试几次，用于在单个源IP上短连接量很大、系统临时端口
+	// 容易耗尽或来不及从TIME_WAIT恢复的场景下分散端口占用。
+	SourcePortRange [2]int
+	// SocketMark 为正数时，createConnection在connect(2)之前给出站套接字设置SO_MARK（仅Linux，
+	// 见sockopts_linux.go），供拥有多条上联线路的宿主机用ip rule/ip route按fwmark把本池的流量
+	// 引到指定上联，不必为此单独维护iptables标记规则；<=0（默认）时不设置，保持原有行为。
+	SocketMark int
+	// DSCP 为正数时，createConnection在connect(2)之前把该值写入出站套接字的IP_TOS（IPv4）/
+	// IPV6_TCLASS（IPv6）（仅Linux，见sockopts_linux.go），取值范围0-63，供中间路由设备按
+	// DSCP做QoS分级；<=0（默认）时不设置，保持原有行为（沿用系统默认TOS/TCLASS）。
+	DSCP int
+	// DailyByteCap 大于0时，GetConn/GetConnByIP在当天（按进程本地时区的自然日）套接字级
+	// 读写字节总数（见RecordConnBytes/DailyBytesUsed）达到该值后直接返回错误、拒绝再签出
+	// 新连接，次日自然日切换时额度自动重置；用于限制metered egress服务器上单个域名的
+	// 日流量。<=0（默认）时不设上限，保持原有行为。这里只统计套接字层面的读写字节（含TLS和
+	// 帧层开销），与RecordConnBytes按IP/协议聚合用的是同一份计数来源。
+	DailyByteCap int64
+	// HappyEyeballs 为true时，createConnectionWithFallback按RFC 8305的思路同时竞速一个IPv6候选
+	// 和一个IPv4候选，而不是把所有候选随机排序后逐个顺序尝试；为false（默认）时保持原有的顺序
+	// 尝试行为不变。
+	HappyEyeballs bool
+	// HappyEyeballsDelay 是HappyEyeballs模式下IPv4候选相对IPv6候选的起跑延迟（RFC 8305建议的
+	// "Connection Attempt Delay"），IPv6候选先失败时会立即唤醒IPv4候选而不必等满这个延迟；
+	// <=0时使用默认值250毫秒。
+	HappyEyeballsDelay time.Duration
+	// WarmupValidator定义预热/健康检查判断一次探测是否成功的规则，为nil时使用
+	// defaultWarmupValidator()（状态码200且body恰好13字节，即原有的PlanetoidMetadata规则）。
+	WarmupValidator *WarmupValidator
+	// PlaintextHTTP 为true时，该连接池对外维护的是裸TCP连接而不是uTLS连接，用于内部镜像、
+	// 测试环境等只提供HTTP（80端口）而没有TLS的场景；为false（默认）时保持原有行为：
+	// 每条连接都按uTLS+ClientHello指纹完成TLS握手。PlaintextHTTP模式下无法通过ALPN协商
+	// 到h2（明文场景下的h2c不在支持范围内），所有连接固定为"http/1.1"，健康检查走
+	// 与TLS模式相同的HTTP/1.1路径，只是跳过了握手这一步。注意Port不会因为PlaintextHTTP
+	// 而自动改成80，Port为空时仍然默认"443"（见NewDomainHotConnPool），明文场景需要显式
+	// 指定Port: "80"（或实际的明文端口）。
+	PlaintextHTTP bool
+	// EmergencyResolveEnabled 为true时，如果targetIPv4List/targetIPv6List都为空（典型场景：
+	// DomainMonitor依赖的存储在进程刚启动时暂不可达，还没完成过哪怕一轮更新），createConnection
+	// 会在现建连接前先现场直接解析一次域名，用解析结果临时填充这两个列表，使连接池能够自举，
+	// 而不是直接报"域名尚无可用IP"的错误一直等到DomainMonitor追上来。为false（默认）时保持
+	// 原有行为不变。
+	EmergencyResolveEnabled bool
+	// EmergencyResolver 配合EmergencyResolveEnabled使用，为nil时使用内置的系统默认解析器
+	// （net.DefaultResolver），调用方可以传入自定义实现（例如改走DoH）替换默认行为。
+	EmergencyResolver func(ctx context.Context, domain string) (ipv4, ipv6 []string, err error)
+	// PreferredCountries 非空时，createConnectionHappyEyeballs/createConnectionSequential优先从
+	// DomainMonitor已采集的IPInfo国家代码（IPInfoResponse.CountryCode）落在这份列表里的候选IP中
+	// 选择目标，降低RTT；大小写不敏感。当前候选里一个匹配的IP都没有时（典型场景：DomainMonitor
+	// 还没来得及查询IPInfo，或目标站点确实没有这些地区的出口）退回不做偏好过滤的原有行为，而不是
+	// 报错或返回空列表。可以和PreferredASNs同时配置，两者是"或"的关系。
+	PreferredCountries []string
+	// PreferredASNs 非空时，按IPInfoResponse.AS.ASN（形如"AS15169"）做与PreferredCountries相同的
+	// 偏好过滤，大小写不敏感，候选里没有匹配项时同样退回不做偏好过滤。
+	PreferredASNs []string
+	// RecoveryWarmupCount 大于0时，testBlacklistedIPs发现某个黑名单IP恢复（探测通过重新加回
+	// 白名单）后，额外再对该IP并发预热这么多条连接，供紧跟着涌入的请求直接复用，而不必每个都从
+	// 零握手；探测本身用掉的那条连接已经由warmupSingleIP放进健康池，不在这个数量之内。
+	// <=0（默认）时不做额外预热，只恢复探测本身那一条连接。
+	RecoveryWarmupCount int
+	// StatusPolicy 非nil时，ReturnConn按这份映射决定每个状态码对应的StatusAction
+	// （whitelist/blacklist/retry/ignore），覆盖掉某个状态码未配置时沿用的默认规则
+	// （200=whitelist，403=blacklist，其余=retry），用于适配用429/503等状态码做限速的目标站点。
+	StatusPolicy StatusPolicy
 }
 
 // NewDomainHotConnPool creates a new domain-based hot connection pool
@@ -146,9 +523,28 @@ func NewDomainHotConnPool(config DomainConnPoolConfig) (HotConnPool, error) {
 	if config.DialTimeout == 0 {
 		config.DialTimeout = 10 * time.Second
 	}
+	if config.HappyEyeballsDelay <= 0 {
+		config.HappyEyeballsDelay = 250 * time.Millisecond
+	}
+	if config.WarmupValidator == nil {
+		config.WarmupValidator = defaultWarmupValidator()
+	}
+	if config.EmergencyResolveEnabled && config.EmergencyResolver == nil {
+		config.EmergencyResolver = systemResolveDomain
+	}
 
 	sessionCache := utls.NewLRUClientSessionCache(1000)
 
+	var fingerprintSelector *StickyFingerprintSelector
+	if config.FingerprintStickyIP {
+		fingerprintSelector = NewStickyFingerprintSelector(config.FingerprintSessionTTL)
+	}
+
+	poolRandSeed := config.RandomSeed
+	if poolRandSeed == 0 {
+		poolRandSeed = time.Now().UnixNano()
+	}
+
 	pool := &domainConnPool{
 		healthyConns:          make(chan *ConnMetadata, config.MaxConns),
 		unhealthyConns:        make(chan *ConnMetadata, config.MaxConns),
@@ -156,26 +552,46 @@ func NewDomainHotConnPool(config DomainConnPoolConfig) (HotConnPool, error) {
 		domainMonitor:         config.DomainMonitor,
 		ipAccessControl:       config.IPAccessControl,
 		fingerprint:           config.Fingerprint,
+		fingerprintSelector:   fingerprintSelector,
 		localIPv4Pool:         config.LocalIPv4Pool,
 		localIPv6Pool:         config.LocalIPv6Pool,
 		hasIPv6Support:        config.LocalIPv6Pool != nil,
+		ipv6RotatePerRequest:  config.IPv6RotatePerRequest,
+		plaintextHTTP:         config.PlaintextHTTP,
+		freebind:              config.Freebind,
+		sourcePortRange:       config.SourcePortRange,
+		socketMark:            config.SocketMark,
+		dscp:                  config.DSCP,
+		dailyByteCap:          config.DailyByteCap,
+		happyEyeballs:         config.HappyEyeballs,
+		happyEyeballsDelay:    config.HappyEyeballsDelay,
+		preferredCountries:    toUpperStrings(config.PreferredCountries),
+		preferredASNs:         toUpperStrings(config.PreferredASNs),
+		recoveryWarmupCount:   config.RecoveryWarmupCount,
+		statusPolicy:          config.StatusPolicy,
 		maxConns:              config.MaxConns,
 		ipStatsMap:            make(map[string]*ipStats),
+		protocolStatsMap:      make(map[string]*ProtocolTraffic),
+		localIPStatsMap:       make(map[string]*ipStats),
+		handshakeStats:        newHandshakeStatsTracker(),
+		fingerprintAvoidance:  newFingerprintAvoidanceTracker(),
 		idleTime:              config.IdleTimeout,
 		domain:                config.Domain,
 		port:                  config.Port,
 		warmupPath:            config.WarmupPath,
 		warmupMethod:          config.WarmupMethod,
 		warmupHeaders:         config.WarmupHeaders,
+		warmupValidator:       config.WarmupValidator,
 		warmupConcurrency:     config.WarmupConcurrency,
 		blacklistTestInterval: config.BlacklistTestInterval,
 		ipRefreshInterval:     config.IPRefreshInterval,
 		stopChan:              make(chan struct{}),
-		rand:                  rand.New(rand.NewSource(time.Now().UnixNano())),
+		rand:                  rand.New(rand.NewSource(poolRandSeed)),
 		healthCheckClient:     NewUTlsClient(),
 		closed:                false,
 		sessionCache:          sessionCache,
 		knownTargetIPs:        make(map[string]struct{}),
+		emergencyResolver:     config.EmergencyResolver,
 	}
 
 	pool.healthCheckClient.DialTimeout = config.DialTimeout
@@ -187,8 +603,39 @@ func NewDomainHotConnPool(config DomainConnPoolConfig) (HotConnPool, error) {
 	return pool, nil
 }
 
+// fingerprintForIP 返回用于与targetIP建立新连接的指纹。
+// 启用粘滞模式时，同一个targetIP在会话有效期内始终复用同一个指纹；
+// 否则回退到连接池级别的固定指纹（未配置时随机选择一个）。
+// 命中p.fingerprintAvoidance冷却期的(指纹, targetIP)组合会被跳过，改选其他指纹，见
+// FingerprintAvoidance.go；只有固定指纹（未启用粘滞且显式配置了单一指纹）没有其他指纹可选，
+// 冷却对这条路径没有意义。
+func (p *domainConnPool) fingerprintForIP(targetIP string) Profile {
+	avoid := func(profile Profile) bool {
+		return p.fingerprintAvoidance.IsAvoided(profile.Name, targetIP)
+	}
+
+	if p.fingerprintSelector != nil {
+		return p.fingerprintSelector.GetAvoiding(targetIP, avoid)
+	}
+	if p.fingerprint.HelloID.Client == "" {
+		profile := GetRandomFingerprint()
+		for attempt := 0; avoid(profile) && attempt < fingerprintRerollAttempts; attempt++ {
+			profile = GetRandomFingerprint()
+		}
+		return profile
+	}
+	return p.fingerprint
+}
+
 // createConnection creates a single UTLS connection and wraps it in ConnMetadata
 func (p *domainConnPool) createConnection(localIP, targetIP string, skipWhitelistCheck bool) (*ConnMetadata, error) {
+	// 涵盖了本函数里TCP拨号和紧接着的uTLS握手两段，拆成两个span的意义不大——两者总是
+	// 背靠背执行、中间没有可能单独耗时的其他逻辑，合成一个span已经足够定位是拨号慢还是握手慢
+	// （span的exporter日志里如果后续需要更细的拆分，可以在span.End()前再加一条attr）。
+	_, span := startSpan("pool.createConnection")
+	span.SetAttr("target_ip", targetIP)
+	defer span.End()
+
 	if !skipWhitelistCheck && !p.ipAccessControl.IsIPAllowed(targetIP) {
 		// If whitelist is not empty, we must adhere to it.
 		// If it's empty (e.g., during initial startup), allow attempts.
@@ -198,18 +645,27 @@ func (p *domainConnPool) createConnection(localIP, targetIP string, skipWhitelis
 	}
 
 	dialer := net.Dialer{Timeout: p.healthCheckClient.DialTimeout}
+	if p.freebind {
+		dialer.Control = freebindControl
+	}
+	if p.socketMark > 0 {
+		dialer.Control = chainControl(dialer.Control, socketMarkControl(p.socketMark))
+	}
+	if p.dscp > 0 {
+		dialer.Control = chainControl(dialer.Control, dscpControl(p.dscp))
+	}
 	if localIP != "" {
 		localIPAddr := net.ParseIP(localIP)
 		if localIPAddr == nil {
 			return nil, fmt.Errorf("无效的本地IP地址: %s", localIP)
 		}
-		
+
 		// 检查本地IP和目标IP的类型是否匹配
 		targetIPAddr := net.ParseIP(targetIP)
 		if targetIPAddr != nil {
 			localIsIPv6 := localIPAddr.To4() == nil && localIPAddr.To16() != nil
 			targetIsIPv6 := targetIPAddr.To4() == nil && targetIPAddr.To16() != nil
-			
+
 			// 如果类型不匹配，不绑定本地IP，让系统自动选择
 			if localIsIPv6 != targetIsIPv6 {
 				localIP = "" // 清空本地IP，让系统自动选择
@@ -222,7 +678,7 @@ func (p *domainConnPool) createConnection(localIP, targetIP string, skipWhitelis
 	}
 
 	// 尝试使用指定的本地IP连接
-	tcpConn, err := dialer.Dial("tcp", net.JoinHostPort(targetIP, p.port))
+	tcpConn, err := dialWithSourcePortRange(dialer, "tcp", net.JoinHostPort(targetIP, p.port), p.sourcePortRange)
 	if err != nil {
 		// 如果连接失败且使用了IPv6本地地址，标记为未使用（不立即删除）
 		if localIP != "" {
@@ -234,14 +690,15 @@ func (p *domainConnPool) createConnection(localIP, targetIP string, skipWhitelis
 				}
 			}
 		}
-		
+
 		// 如果绑定本地IP失败（通常是IPv6地址未在系统上配置），尝试不绑定本地IP
-		if localIP != "" && (strings.Contains(err.Error(), "cannot assign requested address") || 
+		if localIP != "" && (strings.Contains(err.Error(), "cannot assign requested address") ||
 			strings.Contains(err.Error(), "bind: cannot assign requested address") ||
 			strings.Contains(err.Error(), "no suitable address found")) {
-			// 回退到不绑定本地IP的方式
-			dialerWithoutLocal := net.Dialer{Timeout: p.healthCheckClient.DialTimeout}
-			tcpConn, err = dialerWithoutLocal.Dial("tcp", net.JoinHostPort(targetIP, p.port))
+			// 回退到不绑定本地IP的方式；Control（Freebind/SocketMark/DSCP）与LocalAddr无关，沿用原dialer的设置
+			dialerWithoutLocal := dialer
+			dialerWithoutLocal.LocalAddr = nil
+			tcpConn, err = dialWithSourcePortRange(dialerWithoutLocal, "tcp", net.JoinHostPort(targetIP, p.port), p.sourcePortRange)
 			if err != nil {
 				return nil, fmt.Errorf("TCP连接失败（已尝试回退）: %w", err)
 			}
@@ -251,12 +708,29 @@ func (p *domainConnPool) createConnection(localIP, targetIP string, skipWhitelis
 		}
 	}
 
-	fingerprint := p.fingerprint
-	if fingerprint.HelloID.Client == "" {
-		fingerprint = GetRandomFingerprint()
+	tuneTCPKeepAlive(tcpConn)
+
+	fingerprint := p.fingerprintForIP(targetIP)
+
+	countingTCP := newCountingConn(tcpConn)
+
+	// PlaintextHTTP为true时目标端口通常就是裸HTTP（比如内部镜像的80端口），跳过uTLS握手，
+	// 直接把TCP连接当作明文连接使用；没有TLS的ALPN协商，协议固定为http/1.1
+	if p.plaintextHTTP {
+		meta := &ConnMetadata{
+			Conn:        countingTCP,
+			Protocol:    "http/1.1",
+			TargetIP:    targetIP,
+			LocalIP:     localIP,
+			CreatedAt:   time.Now(),
+			LastUsed:    time.Now(),
+			Fingerprint: fingerprint,
+			socketConn:  countingTCP,
+		}
+		return meta, nil
 	}
 
-	uConn := utls.UClient(tcpConn, &utls.Config{
+	uConn := utls.UClient(countingTCP, &utls.Config{
 		ServerName:         p.domain,
 		NextProtos:         []string{"h2", "http/1.1"},
 		InsecureSkipVerify: false,
@@ -264,7 +738,19 @@ func (p *domainConnPool) createConnection(localIP, targetIP string, skipWhitelis
 		ClientSessionCache: p.sessionCache,
 	}, fingerprint.HelloID)
 
-	if err := uConn.Handshake(); err != nil {
+	handshakeStart := time.Now()
+	handshakeErr := uConn.Handshake()
+	handshakeDuration := time.Since(handshakeStart)
+	if handshakeErr != nil {
+		p.handshakeStats.record(handshakeRecord{
+			targetIP:    targetIP,
+			fingerprint: fingerprint.Name,
+			success:     false,
+		})
+		if p.fingerprintAvoidance.RecordFailure(fingerprint.Name, targetIP) {
+			fmt.Printf("[连接池] 指纹[%s]在目标IP %s上连续握手失败达到阈值，接下来%v内改选其他指纹\n",
+				fingerprint.Name, targetIP, fingerprintCooldownDuration)
+		}
 		_ = uConn.Close()
 		// TLS握手失败，如果是IPv6地址，标记为未使用（不立即删除）
 		if localIP != "" {
@@ -275,33 +761,51 @@ func (p *domainConnPool) createConnection(localIP, targetIP string, skipWhitelis
 				}
 			}
 		}
-		return nil, fmt.Errorf("TLS握手失败: %w", err)
+		return nil, fmt.Errorf("TLS握手失败: %w", handshakeErr)
 	}
 
 	state := uConn.ConnectionState()
+	p.handshakeStats.record(handshakeRecord{
+		targetIP:    targetIP,
+		fingerprint: fingerprint.Name,
+		success:     true,
+		resumed:     state.DidResume,
+		duration:    handshakeDuration,
+		cipherSuite: state.CipherSuite,
+		alpn:        state.NegotiatedProtocol,
+	})
+	p.fingerprintAvoidance.RecordSuccess(fingerprint.Name, targetIP)
 	protocol := state.NegotiatedProtocol
 	if protocol == "" {
 		protocol = "http/1.1"
 	}
 
 	meta := &ConnMetadata{
-		Conn:      uConn,
-		Protocol:  protocol,
-		TargetIP:  targetIP,
-		LocalIP:   localIP,
-		CreatedAt: time.Now(),
-		LastUsed:  time.Now(),
+		Conn:        uConn,
+		Protocol:    protocol,
+		TargetIP:    targetIP,
+		LocalIP:     localIP,
+		CreatedAt:   time.Now(),
+		LastUsed:    time.Now(),
+		Fingerprint: fingerprint,
+		socketConn:  countingTCP,
 	}
 
 	if protocol == "h2" {
-		transport := &http2.Transport{
-			DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
-				return uConn, nil
-			},
-			AllowHTTP: true,
+		transport := fingerprint.NewHTTP2Transport(func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+			return uConn, nil
+		})
+		// 显式拿到这条连接自己的http2.ClientConn（而不是让Transport在RoundTrip时按需创建并池化），
+		// 这样后台探活任务才能直接对它发PING帧测RTT；http2.ClientConn本身实现了RoundTrip，
+		// 可以直接当http.Client的Transport用
+		http2Conn, err := transport.NewClientConn(uConn)
+		if err != nil {
+			_ = uConn.Close()
+			return nil, fmt.Errorf("创建HTTP/2客户端连接失败: %w", err)
 		}
+		meta.http2Conn = http2Conn
 		meta.HttpClient = &http.Client{
-			Transport: transport,
+			Transport: http2Conn,
 			Timeout:   p.healthCheckClient.ReadTimeout,
 		}
 	}
@@ -309,17 +813,71 @@ func (p *domainConnPool) createConnection(localIP, targetIP string, skipWhitelis
 	return meta, nil
 }
 
+// tuneTCPKeepAlive 为池化的出站连接打开TCP keepalive并设置探测间隔，让内核在应用层
+// 感知到之前就能发现对端已经消失的半开连接，弥补isConnValid只能在本地套接字状态
+// 正常时才有效的局限
+func tuneTCPKeepAlive(conn net.Conn) {
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		return
+	}
+	_ = tcpConn.SetKeepAlive(true)
+	_ = tcpConn.SetKeepAlivePeriod(tcpKeepAlivePeriod)
+}
+
 // GetConn gets a connection from the pool
+// checkDailyByteCap在DailyByteCap>0且当天用量已达到上限时返回错误，供GetConn/GetConnByIP
+// 在签出新连接之前统一拦截，已经签出、正在使用的连接不受影响（不会被拦腰关闭）。
+func (p *domainConnPool) checkDailyByteCap() error {
+	if p.dailyByteCap <= 0 {
+		return nil
+	}
+	if p.DailyBytesUsed() >= p.dailyByteCap {
+		return fmt.Errorf("域名 [%s] 已达到当天流量上限(%d字节)，暂停签出新连接", p.domain, p.dailyByteCap)
+	}
+	return nil
+}
+
 func (p *domainConnPool) GetConn() (*ConnMetadata, error) {
+	if err := p.checkDailyByteCap(); err != nil {
+		return nil, err
+	}
+	connMeta, err := p.getConn()
+	if err == nil && connMeta != nil {
+		atomic.AddInt64(&p.checkedOut, 1)
+	}
+	return connMeta, err
+}
+
+// getConn是GetConn去掉租出计数后的实际实现，递归重试（见下面的// Retry注释）都走这个
+// 不计数的版本，避免一次逻辑上的GetConn调用因为内部重试而被重复计入checkedOut。
+func (p *domainConnPool) getConn() (*ConnMetadata, error) {
+	_, span := startSpan("pool.GetConn")
+	span.SetAttr("domain", p.domain)
+	defer span.End()
+
 	if p.isClosed() {
 		return nil, fmt.Errorf("连接池已关闭")
 	}
 
+	if p.ipv6RotatePerRequest {
+		return p.createRotatingConn()
+	}
+
+	if connMeta := p.nextFromIPPoolsRoundRobin(); connMeta != nil {
+		if !p.isConnValid(connMeta.Conn) {
+			_ = connMeta.Conn.Close()
+			return p.getConn() // Retry
+		}
+		connMeta.LastUsed = time.Now()
+		return connMeta, nil
+	}
+
 	select {
 	case connMeta := <-p.healthyConns:
 		if !p.isConnValid(connMeta.Conn) {
 			_ = connMeta.Conn.Close()
-			return p.GetConn() // Retry
+			return p.getConn() // Retry
 		}
 		connMeta.LastUsed = time.Now()
 		return connMeta, nil
@@ -330,7 +888,7 @@ func (p *domainConnPool) GetConn() (*ConnMetadata, error) {
 	case connMeta := <-p.unhealthyConns:
 		if !p.isConnValid(connMeta.Conn) {
 			_ = connMeta.Conn.Close()
-			return p.GetConn() // Retry
+			return p.getConn() // Retry
 		}
 		connMeta.LastUsed = time.Now()
 		return connMeta, nil
@@ -347,8 +905,76 @@ func (p *domainConnPool) GetConn() (*ConnMetadata, error) {
 	return p.createConnection("", targetIP, false)
 }
 
+// nextFromIPPoolsRoundRobin 以轮询方式尝试从各目标IP专属连接桶中取出一个可用连接，
+// 使请求均匀分散到白名单内的所有IP，避免少数IP承担过多流量、其余IP闲置老化
+func (p *domainConnPool) nextFromIPPoolsRoundRobin() *ConnMetadata {
+	p.ipConnPoolsMutex.Lock()
+	order := p.ipConnOrder
+	start := p.nextIPIndex
+	p.ipConnPoolsMutex.Unlock()
+
+	n := len(order)
+	if n == 0 {
+		return nil
+	}
+
+	for i := 0; i < n; i++ {
+		idx := (start + i) % n
+		ip := order[idx]
+
+		p.ipConnPoolsMutex.RLock()
+		ipPool := p.ipConnPools[ip]
+		p.ipConnPoolsMutex.RUnlock()
+		if ipPool == nil {
+			continue
+		}
+
+		select {
+		case connMeta := <-ipPool:
+			p.ipConnPoolsMutex.Lock()
+			p.nextIPIndex = (idx + 1) % n
+			p.ipConnPoolsMutex.Unlock()
+			return connMeta
+		default:
+		}
+	}
+	return nil
+}
+
+// getOrCreateIPPool 返回目标IP专属的连接桶，首次访问时惰性创建并加入轮询顺序
+func (p *domainConnPool) getOrCreateIPPool(targetIP string) chan *ConnMetadata {
+	p.ipConnPoolsMutex.RLock()
+	ipPool, exists := p.ipConnPools[targetIP]
+	p.ipConnPoolsMutex.RUnlock()
+	if exists {
+		return ipPool
+	}
+
+	p.ipConnPoolsMutex.Lock()
+	defer p.ipConnPoolsMutex.Unlock()
+	if ipPool, exists := p.ipConnPools[targetIP]; exists {
+		return ipPool
+	}
+	ipPool = make(chan *ConnMetadata, p.maxConns)
+	p.ipConnPools[targetIP] = ipPool
+	p.ipConnOrder = append(p.ipConnOrder, targetIP)
+	return ipPool
+}
+
 // GetConnByIP gets a connection for a specific IP from the pool
 func (p *domainConnPool) GetConnByIP(targetIP string) (*ConnMetadata, error) {
+	if err := p.checkDailyByteCap(); err != nil {
+		return nil, err
+	}
+	connMeta, err := p.getConnByIP(targetIP)
+	if err == nil && connMeta != nil {
+		atomic.AddInt64(&p.checkedOut, 1)
+	}
+	return connMeta, err
+}
+
+// getConnByIP是GetConnByIP去掉租出计数后的实际实现，理由同getConn
+func (p *domainConnPool) getConnByIP(targetIP string) (*ConnMetadata, error) {
 	if targetIP == "" {
 		return nil, fmt.Errorf("目标IP不能为空")
 	}
@@ -356,23 +982,31 @@ func (p *domainConnPool) GetConnByIP(targetIP string) (*ConnMetadata, error) {
 		return nil, fmt.Errorf("连接池已关闭")
 	}
 
-	p.ipConnPoolsMutex.RLock()
-	ipPool, exists := p.ipConnPools[targetIP]
-	p.ipConnPoolsMutex.RUnlock()
-
-	if exists {
-		select {
-		case connMeta := <-ipPool:
-			if !p.isConnValid(connMeta.Conn) {
-				_ = connMeta.Conn.Close()
-				return p.GetConnByIP(targetIP) // Retry
+	if p.ipv6RotatePerRequest {
+		targetIPAddr := net.ParseIP(targetIP)
+		if targetIPAddr != nil && targetIPAddr.To4() == nil && targetIPAddr.To16() != nil {
+			localIP := p.getLocalIPForTarget(targetIP, true)
+			connMeta, err := p.createConnection(localIP, targetIP, false)
+			if err != nil {
+				return nil, err
 			}
-			connMeta.LastUsed = time.Now()
+			connMeta.ephemeral = true
 			return connMeta, nil
-		default:
 		}
 	}
 
+	ipPool := p.getOrCreateIPPool(targetIP)
+	select {
+	case connMeta := <-ipPool:
+		if !p.isConnValid(connMeta.Conn) {
+			_ = connMeta.Conn.Close()
+			return p.getConnByIP(targetIP) // Retry
+		}
+		connMeta.LastUsed = time.Now()
+		return connMeta, nil
+	default:
+	}
+
 	// 判断目标IP类型
 	targetIPAddr := net.ParseIP(targetIP)
 	isTargetIPv6 := targetIPAddr != nil && targetIPAddr.To4() == nil && targetIPAddr.To16() != nil
@@ -386,6 +1020,10 @@ func (p *domainConnPool) ReturnConn(connMeta *ConnMetadata, statusCode int) erro
 	if connMeta == nil || connMeta.Conn == nil {
 		return fmt.Errorf("连接元数据或连接不能为空")
 	}
+	// 无论接下来走哪条分支（入池复用、直接关闭、还是连接池已关闭的错误路径），这个连接
+	// 对应的GetConn/GetConnByIP租出都已经结束，统一在这里减计数，避免每条分支各自维护一份
+	atomic.AddInt64(&p.checkedOut, -1)
+
 	if p.isClosed() {
 		_ = connMeta.Conn.Close()
 		return fmt.Errorf("连接池已关闭")
@@ -403,54 +1041,104 @@ func (p *domainConnPool) ReturnConn(connMeta *ConnMetadata, statusCode int) erro
 
 	p.UpdateIPStats(connMeta.TargetIP, statusCode)
 
-	if statusCode == 200 {
+	action := p.statusPolicy.resolve(statusCode)
+
+	if connMeta.ephemeral {
+		// 轮换模式下连接只使用一次：不入池，直接关闭并把本地IPv6地址交还地址池
+		_ = connMeta.Conn.Close()
+		switch action {
+		case StatusActionBlacklist:
+			p.ipAccessControl.AddIP(connMeta.TargetIP, false)
+		case StatusActionWhitelist:
+			p.ipAccessControl.AddIP(connMeta.TargetIP, true)
+		}
+		if connMeta.LocalIP != "" && p.localIPv6Pool != nil {
+			if localIPAddr := net.ParseIP(connMeta.LocalIP); localIPAddr != nil {
+				p.localIPv6Pool.ReleaseIP(localIPAddr)
+			}
+		}
+		return nil
+	}
+
+	switch action {
+	case StatusActionWhitelist:
 		p.ipAccessControl.AddIP(connMeta.TargetIP, true)
-		p.returnToPool(connMeta, p.healthyConns)
+		if connMeta.Protocol != "h2" && connMeta.shouldRetireHTTP1() {
+			// 服务端已经表明这个连接活不过下一次请求了（Connection: close、达到Keep-Alive
+			// 的max、或者超过了timeout），与其放回池子等下个使用者发现连接失效再重试，
+			// 不如现在就主动关闭，腾出位置给一个新连接。
+			_ = connMeta.Conn.Close()
+		} else {
+			p.returnToPool(connMeta, p.healthyConns)
+		}
 		// 不再立即释放IPv6地址，由定期清理机制负责
-	} else if statusCode == 403 {
+	case StatusActionBlacklist:
 		p.ipAccessControl.AddIP(connMeta.TargetIP, false)
 		_ = connMeta.Conn.Close()
 		fmt.Printf("[连接池] IP加入黑名单 [%s]，连接已关闭\n", connMeta.TargetIP)
-		// 403错误时标记地址为未使用，但不立即删除
-		if connMeta.LocalIP != "" {
-			localIPAddr := net.ParseIP(connMeta.LocalIP)
-			if localIPAddr != nil && localIPAddr.To4() == nil && localIPAddr.To16() != nil {
-				if p.localIPv6Pool != nil {
-					p.localIPv6Pool.MarkIPUnused(localIPAddr)
-				}
-			}
-		}
-	} else {
-		p.returnToPool(connMeta, p.unhealthyConns)
-		// 其他错误状态码，标记为未使用，但不立即删除
-		if connMeta.LocalIP != "" {
-			localIPAddr := net.ParseIP(connMeta.LocalIP)
-			if localIPAddr != nil && localIPAddr.To4() == nil && localIPAddr.To16() != nil {
-				if p.localIPv6Pool != nil {
-					p.localIPv6Pool.MarkIPUnused(localIPAddr)
-				}
-			}
+		p.markLocalIPUnused(connMeta)
+	case StatusActionIgnore:
+		_ = connMeta.Conn.Close()
+		p.markLocalIPUnused(connMeta)
+	default: // StatusActionRetry，以及StatusPolicy里配置了未知取值时的兜底
+		if connMeta.Protocol != "h2" && connMeta.shouldRetireHTTP1() {
+			_ = connMeta.Conn.Close()
+		} else {
+			p.returnToPool(connMeta, p.unhealthyConns)
 		}
+		p.markLocalIPUnused(connMeta)
 	}
 	return nil
 }
 
-func (p *domainConnPool) returnToPool(connMeta *ConnMetadata, pool chan<- *ConnMetadata) {
-	p.ipConnPoolsMutex.RLock()
-	ipPool, exists := p.ipConnPools[connMeta.TargetIP]
-	p.ipConnPoolsMutex.RUnlock()
+// ttlBlacklister是*WhiteBlackIPPool等支持临时拉黑的IPAccessController具体类型需要满足的
+// 最小接口，不放进IPAccessController本身是因为只有ReturnConnWithRetryAfter这一个场景需要，
+// 见AddIPWithTTL的注释（分布式名单同步场景下的同一个需求：临时拉黑，到期自动恢复）。
+type ttlBlacklister interface {
+	AddIPWithTTL(ip string, isWhite bool, ttl time.Duration)
+}
 
-	if exists {
-		select {
-		case ipPool <- connMeta:
-		default:
-			select {
-			case pool <- connMeta:
-			default:
-				_ = connMeta.Conn.Close()
-			}
+// isRateLimitStatus判断statusCode是不是目标站点常用来做限速的状态码（429 Too Many Requests、
+// 503 Service Unavailable）。这两个状态码配合Retry-After表达的是"这个IP暂时别碰，过一会再来"，
+// 而不是"这个IP已经失败"，所以单独识别出来走"临时安静"的路径，不跟其余携带Retry-After的状态码
+// （语义上不是限速信号）混在一起自动拉黑。
+func isRateLimitStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable
+}
+
+// ReturnConnWithRetryAfter 实现HotConnPool接口。statusCode是429/503且retryAfter>0、
+// ipAccessControl的具体类型又支持AddIPWithTTL时，先把目标IP临时拉黑（"安静"）到retryAfter
+// 到期为止（到期后由PruneExpired自动恢复），而不是像ReturnConn默认那样把这次失败当成一次
+// 普通的不健康连接、任由下一次请求立刻再选中同一个被限速的IP；再走和ReturnConn完全相同的
+// 后续流程。其余状态码即使携带了Retry-After也不触发临时拉黑，交给ReturnConn/StatusPolicy处理。
+func (p *domainConnPool) ReturnConnWithRetryAfter(connMeta *ConnMetadata, statusCode int, retryAfter time.Duration) error {
+	if retryAfter > 0 && connMeta != nil && isRateLimitStatus(statusCode) {
+		if ttlController, ok := p.ipAccessControl.(ttlBlacklister); ok {
+			ttlController.AddIPWithTTL(connMeta.TargetIP, false, retryAfter)
+			fmt.Printf("[连接池] IP [%s] 限速中，临时安静 %v（状态码 %d 携带Retry-After）\n", connMeta.TargetIP, retryAfter, statusCode)
 		}
-	} else {
+	}
+	return p.ReturnConn(connMeta, statusCode)
+}
+
+// markLocalIPUnused在连接没有被判定为健康（即不会放回healthyConns复用）时，把它绑定的本地
+// IPv6地址标记为未使用但不立即删除，由定期清理机制负责真正回收；是blacklist/retry/ignore
+// 三种StatusAction共用的收尾步骤，抽出来避免三处重复。
+func (p *domainConnPool) markLocalIPUnused(connMeta *ConnMetadata) {
+	if connMeta.LocalIP == "" || p.localIPv6Pool == nil {
+		return
+	}
+	localIPAddr := net.ParseIP(connMeta.LocalIP)
+	if localIPAddr != nil && localIPAddr.To4() == nil && localIPAddr.To16() != nil {
+		p.localIPv6Pool.MarkIPUnused(localIPAddr)
+	}
+}
+
+func (p *domainConnPool) returnToPool(connMeta *ConnMetadata, pool chan<- *ConnMetadata) {
+	ipPool := p.getOrCreateIPPool(connMeta.TargetIP)
+	select {
+	case ipPool <- connMeta:
+	default:
 		select {
 		case pool <- connMeta:
 		default:
@@ -459,9 +1147,10 @@ func (p *domainConnPool) returnToPool(connMeta *ConnMetadata, pool chan<- *ConnM
 	}
 }
 
-// Warmup pre-warms the connection pool
-func (p *domainConnPool) Warmup() error {
+// Warmup pre-warms the connection pool against the full target IP list
+func (p *domainConnPool) Warmup() (*WarmupReport, error) {
 	fmt.Printf("[连接池] 开始预热域名 [%s]，并发数: %d\n", p.domain, p.warmupConcurrency)
+	start := time.Now()
 	p.refreshTargetIPList()
 
 	p.ipListMutex.RLock()
@@ -474,20 +1163,53 @@ func (p *domainConnPool) Warmup() error {
 	}
 	p.ipListMutex.RUnlock()
 
-	p.runWarmupJobs(jobs)
+	report := p.runWarmupJobs(jobs)
+	report.Duration = time.Since(start)
 
 	fmt.Printf("[连接池] 域名 [%s] 预热完成\n", p.domain)
 	p.printPoolStats()
 
 	atomic.StoreInt32(&p.autoWarmupEnabled, 1)
 	p.processPendingWarmups()
-	return nil
+	return report, nil
+}
+
+// WarmupIncremental 只预热jobs里给出的IP，不重新遍历整个目标IP列表，
+// 供发现新IP（如refreshTargetIPList发现域名解析出了新地址）时单独补热，
+// 而不必为此重新跑一遍全量Warmup
+func (p *domainConnPool) WarmupIncremental(ips []string) (*WarmupReport, error) {
+	if len(ips) == 0 {
+		return &WarmupReport{}, nil
+	}
+
+	start := time.Now()
+	jobs := make([]ipWarmupJob, 0, len(ips))
+	for _, ip := range ips {
+		ipAddr := net.ParseIP(ip)
+		isIPv6 := ipAddr != nil && ipAddr.To4() == nil && ipAddr.To16() != nil
+		jobs = append(jobs, ipWarmupJob{ip: ip, isIPv6: isIPv6})
+	}
+
+	report := p.runWarmupJobs(jobs)
+	report.Duration = time.Since(start)
+	return report, nil
+}
+
+// SetWarmupProgress 注册一个在每个IP完成预热（无论成功失败）后都会被同步调用的回调，
+// 传nil取消注册；回调会在预热所用的worker goroutine上直接执行，耗时操作请自行另起goroutine
+func (p *domainConnPool) SetWarmupProgress(cb func(WarmupResult)) {
+	p.warmupProgressMu.Lock()
+	p.warmupProgressCb = cb
+	p.warmupProgressMu.Unlock()
 }
 
-func (p *domainConnPool) runWarmupJobs(jobs []ipWarmupJob) {
+func (p *domainConnPool) runWarmupJobs(jobs []ipWarmupJob) *WarmupReport {
+	report := &WarmupReport{}
 	if len(jobs) == 0 {
-		return
+		return report
 	}
+
+	var resultsMu sync.Mutex
 	semaphore := make(chan struct{}, p.warmupConcurrency)
 	var wg sync.WaitGroup
 	for _, job := range jobs {
@@ -496,45 +1218,148 @@ func (p *domainConnPool) runWarmupJobs(jobs []ipWarmupJob) {
 		go func(job ipWarmupJob) {
 			defer wg.Done()
 			defer func() { <-semaphore }()
-			p.warmupSingleIP(job.ip, job.isIPv6)
+			result := p.warmupSingleIP(job.ip, job.isIPv6)
+			resultsMu.Lock()
+			report.Results = append(report.Results, result)
+			resultsMu.Unlock()
 		}(job)
 	}
 	wg.Wait()
+
+	for _, result := range report.Results {
+		report.Attempted++
+		if result.Success {
+			report.Succeeded++
+		} else {
+			report.Failed++
+		}
+	}
+	return report
 }
 
-func (p *domainConnPool) warmupSingleIP(targetIP string, isIPv6 bool) {
-	// 根据目标IP类型选择相应的本地IP
-	localIP := p.getLocalIPForTarget(targetIP, isIPv6)
-	connMeta, err := p.createConnection(localIP, targetIP, true)
-	if err != nil {
-		fmt.Printf("[预热] 连接创建失败 [%s]: %v\n", targetIP, err)
-		// 连接失败，IPv6地址已在createConnection中释放
+// totalHealthyConnCount 返回healthyConns加上全部目标IP专属桶里排队的连接总数，
+// 即GetConn当前可以直接取用、无需新建连接的健康连接总量
+func (p *domainConnPool) totalHealthyConnCount() int {
+	total := len(p.healthyConns)
+	p.ipConnPoolsMutex.RLock()
+	for _, ipPool := range p.ipConnPools {
+		total += len(ipPool)
+	}
+	p.ipConnPoolsMutex.RUnlock()
+	return total
+}
+
+// replenishPool 把健康连接总数补到maxConns附近：新建的连接按目标IP轮询分摊，
+// 不会把新增连接全部堆在同一个IP上。直接复用runWarmupJobs/warmupSingleIP而不是
+// 另起一套建连逻辑，这样补充出来的连接和手动Warmup出来的在计数、进度回调等方面行为一致。
+func (p *domainConnPool) replenishPool() {
+	if p.isClosed() {
 		return
 	}
 
-	if connMeta.Protocol == "http/1.1" {
-		statusCode, bodyLen, err := p.healthCheckWithConn(connMeta, targetIP)
-		if err != nil {
-			_ = connMeta.Conn.Close()
-			fmt.Printf("[预热] 健康检查失败 [%s]: %v\n", targetIP, err)
-			// 健康检查失败，标记为未使用（不立即删除）
-			if connMeta.LocalIP != "" {
-				localIPAddr := net.ParseIP(connMeta.LocalIP)
-				if localIPAddr != nil && localIPAddr.To4() == nil && localIPAddr.To16() != nil {
-					if p.localIPv6Pool != nil {
-						p.localIPv6Pool.MarkIPUnused(localIPAddr)
-					}
+	deficit := p.maxConns - p.totalHealthyConnCount()
+	if deficit <= 0 {
+		return
+	}
+
+	p.ipListMutex.RLock()
+	var ips []ipWarmupJob
+	for _, ip := range p.targetIPv6List {
+		ips = append(ips, ipWarmupJob{ip: ip, isIPv6: true})
+	}
+	for _, ip := range p.targetIPv4List {
+		ips = append(ips, ipWarmupJob{ip: ip, isIPv6: false})
+	}
+	p.ipListMutex.RUnlock()
+	if len(ips) == 0 {
+		return
+	}
+
+	jobs := make([]ipWarmupJob, deficit)
+	for i := range jobs {
+		jobs[i] = ips[i%len(ips)]
+	}
+	p.runWarmupJobs(jobs)
+}
+
+// testBlacklistedIPs 对黑名单中的每个IP重新探测一次：探测复用warmupSingleIP，成功时
+// warmupSingleIP本身就会把这次探测用掉的连接直接放进健康池、并把IP重新加回白名单
+// （见warmupSingleIP末尾的p.ipAccessControl.AddIP(targetIP, true)+p.returnToPool），
+// 而不是像过去那样只改白名单状态、把刚刚验证过可用的连接直接丢弃重建。
+// 确认恢复后再按RecoveryWarmupCount额外并发预热几条连接，降低恢复后紧跟着的首批请求的建连延迟。
+func (p *domainConnPool) testBlacklistedIPs() {
+	if p.isClosed() || p.ipAccessControl == nil {
+		return
+	}
+
+	for _, ip := range p.ipAccessControl.GetBlockedIPs() {
+		parsed := net.ParseIP(ip)
+		isIPv6 := parsed != nil && parsed.To4() == nil
+		result := p.warmupSingleIP(ip, isIPv6)
+		if !result.Success || p.recoveryWarmupCount <= 0 {
+			continue
+		}
+		fmt.Printf("[连接池] 域名 [%s] 黑名单IP [%s] 已恢复，额外预热 %d 条连接\n", p.domain, ip, p.recoveryWarmupCount)
+		extraJobs := make([]ipWarmupJob, p.recoveryWarmupCount)
+		for i := range extraJobs {
+			extraJobs[i] = ipWarmupJob{ip: ip, isIPv6: isIPv6}
+		}
+		p.runWarmupJobs(extraJobs)
+	}
+}
+
+// warmupSingleIP 预热单个目标IP并返回本次预热的结构化结果；调用方（runWarmupJobs）
+// 负责把结果汇总进WarmupReport，并在设置了进度回调时通知调用方
+func (p *domainConnPool) warmupSingleIP(targetIP string, isIPv6 bool) WarmupResult {
+	atomic.AddInt64(&p.warmupAttempts, 1)
+	start := time.Now()
+	result := WarmupResult{IP: targetIP, IsIPv6: isIPv6}
+
+	finish := func() WarmupResult {
+		result.Latency = time.Since(start)
+		p.notifyWarmupProgress(result)
+		return result
+	}
+
+	// 根据目标IP类型选择相应的本地IP
+	localIP := p.getLocalIPForTarget(targetIP, isIPv6)
+	connMeta, err := p.createConnection(localIP, targetIP, true)
+	if err != nil {
+		atomic.AddInt64(&p.warmupFailed, 1)
+		fmt.Printf("[预热] 连接创建失败 [%s]: %v\n", targetIP, err)
+		// 连接失败，IPv6地址已在createConnection中释放
+		result.Err = err
+		return finish()
+	}
+	result.Protocol = connMeta.Protocol
+
+	if connMeta.Protocol == "http/1.1" {
+		checkStart := time.Now()
+		statusCode, body, err := p.healthCheckWithConn(connMeta, targetIP)
+		if err != nil {
+			atomic.AddInt64(&p.warmupFailed, 1)
+			_ = connMeta.Conn.Close()
+			fmt.Printf("[预热] 健康检查失败 [%s]: %v\n", targetIP, err)
+			// 健康检查失败，标记为未使用（不立即删除）
+			if connMeta.LocalIP != "" {
+				localIPAddr := net.ParseIP(connMeta.LocalIP)
+				if localIPAddr != nil && localIPAddr.To4() == nil && localIPAddr.To16() != nil {
+					if p.localIPv6Pool != nil {
+						p.localIPv6Pool.MarkIPUnused(localIPAddr)
+					}
 				}
 			}
-			return
+			result.Err = err
+			return finish()
 		}
-		if statusCode != 200 || bodyLen != 13 {
+		if validateErr := p.warmupValidator.Validate(statusCode, body, time.Since(checkStart)); validateErr != nil {
+			atomic.AddInt64(&p.warmupFailed, 1)
 			_ = connMeta.Conn.Close()
-			fmt.Printf("[预热] 警告 [%s]: 状态码 %d, Body %d字节 -> 连接已关闭\n", targetIP, statusCode, bodyLen)
+			fmt.Printf("[预热] 警告 [%s]: %v（状态码 %d, Body %d字节）-> 连接已关闭\n", targetIP, validateErr, statusCode, len(body))
 			if statusCode == 403 {
 				p.ipAccessControl.AddIP(targetIP, false)
 			}
-			// 状态码不是200，标记为未使用（不立即删除）
+			// 校验不通过，标记为未使用（不立即删除）
 			if connMeta.LocalIP != "" {
 				localIPAddr := net.ParseIP(connMeta.LocalIP)
 				if localIPAddr != nil && localIPAddr.To4() == nil && localIPAddr.To16() != nil {
@@ -543,40 +1368,59 @@ func (p *domainConnPool) warmupSingleIP(targetIP string, isIPv6 bool) {
 					}
 				}
 			}
-			return
+			result.Err = fmt.Errorf("健康检查未通过校验规则: %w", validateErr)
+			return finish()
 		}
 	}
 
+	atomic.AddInt64(&p.warmupSuccess, 1)
 	p.ipAccessControl.AddIP(targetIP, true)
 	p.returnToPool(connMeta, p.healthyConns)
 	fmt.Printf("[预热] 成功 [%s]: %s -> 连接已放入健康池\n", targetIP, connMeta.Protocol)
 	// 不再立即释放IPv6地址，由定期清理机制负责
+	result.Success = true
+	return finish()
 }
 
-func (p *domainConnPool) healthCheckWithConn(connMeta *ConnMetadata, targetIP string) (int, int, error) {
+// notifyWarmupProgress 如果调用方通过SetWarmupProgress注册了进度回调，则把这个IP
+// 刚完成的预热结果同步回调出去；Crawler据此可以在预热尚未全部完成时，
+// 一旦已就绪的连接数达到阈值就提前开始对外服务，而不必固定睡眠等待
+func (p *domainConnPool) notifyWarmupProgress(result WarmupResult) {
+	p.warmupProgressMu.RLock()
+	cb := p.warmupProgressCb
+	p.warmupProgressMu.RUnlock()
+	if cb != nil {
+		cb(result)
+	}
+}
+
+func (p *domainConnPool) healthCheckWithConn(connMeta *ConnMetadata, targetIP string) (int, []byte, error) {
 	req := &UTlsRequest{
 		Domain:      p.domain,
 		Method:      p.warmupMethod,
 		Path:        fmt.Sprintf("https://%s%s", p.domain, p.warmupPath),
 		Headers:     p.warmupHeaders,
-		Fingerprint: p.fingerprint,
+		Fingerprint: connMeta.Fingerprint, // 必须与建连时使用的指纹一致，避免同一连接上出现混合指纹
 	}
 
 	if connMeta.Protocol == "h2" {
 		resp, err := connMeta.HttpClient.Do(&http.Request{}) // Simplified
 		if err != nil {
-			return 0, 0, err
+			return 0, nil, err
 		}
 		defer resp.Body.Close()
 		body, _ := io.ReadAll(resp.Body)
-		return resp.StatusCode, len(body), nil
+		return resp.StatusCode, body, nil
 	}
 
 	if err := p.healthCheckClient.sendHTTPRequest(connMeta.Conn, req); err != nil {
-		return 0, 0, err
+		return 0, nil, err
 	}
-	statusCode, body, err := p.healthCheckClient.readHTTPResponse(connMeta.Conn)
-	return statusCode, len(body), err
+	// 预热/健康检查没有上游per-request截止时间可传，零值让readHTTPResponse退回
+	// healthCheckClient自身的ReadTimeout；healthCheckClient没有配置CookieJar，这里传入
+	// p.domain对Set-Cookie的处理也是无操作
+	statusCode, body, _, err := p.healthCheckClient.readHTTPResponse(connMeta.Conn, time.Time{}, p.domain, connMeta)
+	return statusCode, body, err
 }
 
 func (p *domainConnPool) isClosed() bool {
@@ -640,15 +1484,15 @@ func (p *domainConnPool) refreshTargetIPList() {
 	p.targetIPv4List = newIPv4
 	p.targetIPv6List = newIPv6
 	p.knownTargetIPs = newKnown
-	
+
 	// 更新IPv6地址池的目标IP数量（与RemoteDomainIPPool的IP数量对等）
 	totalTargetIPs := len(newIPv6) + len(newIPv4)
 	if p.localIPv6Pool != nil && totalTargetIPs > 0 {
-		fmt.Printf("[连接池] 域名 [%s]: RemoteDomainIPPool有 %d 个IP（IPv4: %d, IPv6: %d），设置IPv6地址池大小为 %d\n", 
+		fmt.Printf("[连接池] 域名 [%s]: RemoteDomainIPPool有 %d 个IP（IPv4: %d, IPv6: %d），设置IPv6地址池大小为 %d\n",
 			p.domain, totalTargetIPs, len(newIPv4), len(newIPv6), totalTargetIPs)
 		p.localIPv6Pool.SetTargetIPCount(totalTargetIPs)
 	}
-	
+
 	p.ipListMutex.Unlock()
 }
 func (p *domainConnPool) startBackgroundTasks() {
@@ -663,17 +1507,38 @@ func (p *domainConnPool) startBackgroundTasks() {
 			for {
 				select {
 				case <-ticker.C:
-					p.refreshTargetIPList()
-					if atomic.LoadInt32(&p.autoWarmupEnabled) == 1 {
-						p.processPendingWarmups()
-					}
+					safego.Protect("domainConnPool.ipRefresh", func() {
+						p.refreshTargetIPList()
+						if atomic.LoadInt32(&p.autoWarmupEnabled) == 1 {
+							p.processPendingWarmups()
+						}
+					})
 				case <-p.stopChan:
 					return
 				}
 			}
 		}()
 	}
-	
+
+	// 黑名单复活探测任务：周期性地对黑名单里的每个IP重新探测一次，确认恢复后自动重新白名单
+	if p.blacklistTestInterval > 0 {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			ticker := time.NewTicker(p.blacklistTestInterval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ticker.C:
+					safego.Protect("domainConnPool.blacklistTest", p.testBlacklistedIPs)
+				case <-p.stopChan:
+					return
+				}
+			}
+		}()
+	}
+
 	// IP统计清理任务：每30分钟清理一次旧的统计信息
 	p.wg.Add(1)
 	go func() {
@@ -684,7 +1549,43 @@ func (p *domainConnPool) startBackgroundTasks() {
 		for {
 			select {
 			case <-ticker.C:
-				p.cleanupIPStats()
+				safego.Protect("domainConnPool.cleanupIPStats", p.cleanupIPStats)
+				safego.Protect("domainConnPool.cleanupFingerprintAvoidance", p.fingerprintAvoidance.cleanup)
+			case <-p.stopChan:
+				return
+			}
+		}
+	}()
+
+	// 连接探活任务：周期性地对闲置连接做PING/可用性探测，主动淘汰半开或RTT劣化的连接
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(livenessProbeInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				safego.Protect("domainConnPool.livenessProbe", p.runLivenessProbe)
+			case <-p.stopChan:
+				return
+			}
+		}
+	}()
+
+	// 连接补充任务：周期性地把健康连接数补到maxConns附近，让GetConn在正常情况下
+	// 总能直接从池里取到连接，不必在请求路径上同步建连握手
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(replenisherInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				safego.Protect("domainConnPool.replenishPool", p.replenishPool)
 			case <-p.stopChan:
 				return
 			}
@@ -696,9 +1597,9 @@ func (p *domainConnPool) startBackgroundTasks() {
 func (p *domainConnPool) cleanupIPStats() {
 	p.ipStatsMutex.Lock()
 	defer p.ipStatsMutex.Unlock()
-	
+
 	const maxStatsEntries = 5000 // 最多保存5000个IP的统计信息
-	
+
 	if len(p.ipStatsMap) > maxStatsEntries {
 		// 如果超过最大条目数，清理一半
 		toDelete := len(p.ipStatsMap) - maxStatsEntries/2
@@ -756,7 +1657,182 @@ func (p *domainConnPool) getLocalIPForTarget(targetIP string, isTargetIPv6 bool)
 		return ""
 	}
 }
+
+// createRotatingConn 为IPv6RotatePerRequest模式现建一个一次性连接：从targetIPv6List轮询选一个目标IP，
+// 现领一个本地IPv6地址与之配对，连接建立后既不放入healthyConns也不放入ipConnPools，
+// 由ReturnConn负责在使用完毕后立即关闭并释放本地地址（见ConnMetadata.ephemeral）
+func (p *domainConnPool) createRotatingConn() (*ConnMetadata, error) {
+	p.ipListMutex.RLock()
+	candidates := p.filterAllowedIPs(p.targetIPv6List)
+	if len(candidates) == 0 {
+		candidates = append([]string(nil), p.targetIPv6List...)
+	}
+	p.ipListMutex.RUnlock()
+
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("域名 [%s] 无可用IPv6地址用于轮换", p.domain)
+	}
+
+	idx := int(atomic.AddInt64(&p.rotateIndex, 1)-1) % len(candidates)
+	targetIP := candidates[idx]
+	localIP := p.getLocalIPForTarget(targetIP, true)
+
+	connMeta, err := p.createConnection(localIP, targetIP, false)
+	if err != nil {
+		return nil, fmt.Errorf("为域名 [%s] 创建轮换连接失败: %w", p.domain, err)
+	}
+	connMeta.ephemeral = true
+	return connMeta, nil
+}
+
+// createConnectionWithFallback是GetConn新建连接时的统一入口：HappyEyeballs开启时
+// 竞速IPv6/IPv4候选（见createConnectionHappyEyeballs），否则保持原有的顺序尝试。
 func (p *domainConnPool) createConnectionWithFallback(skipWhitelistCheck bool) (*ConnMetadata, string, error) {
+	p.ensureTargetIPs()
+	if p.happyEyeballs {
+		return p.createConnectionHappyEyeballs(skipWhitelistCheck)
+	}
+	return p.createConnectionSequential(skipWhitelistCheck)
+}
+
+// ensureTargetIPs 在targetIPv4List和targetIPv6List都为空、且配置了emergencyResolver时，
+// 现场直接解析一次域名，用结果临时填充这两个列表，使连接池能够自举而不必一直等到
+// DomainMonitor完成第一轮更新；DomainMonitor后续一旦产出数据，会在下次refreshTargetIPList
+// 时按正常逻辑覆盖掉这里填的临时结果。解析失败只打印警告，留给调用方按原有的
+// "域名尚无可用IP"路径报错。
+func (p *domainConnPool) ensureTargetIPs() {
+	if p.emergencyResolver == nil {
+		return
+	}
+	p.ipListMutex.RLock()
+	hasTargets := len(p.targetIPv4List) > 0 || len(p.targetIPv6List) > 0
+	p.ipListMutex.RUnlock()
+	if hasTargets {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), emergencyResolveTimeout)
+	ipv4, ipv6, err := p.emergencyResolver(ctx, p.domain)
+	cancel()
+	if err != nil {
+		fmt.Printf("[连接池] 域名 [%s] 应急解析失败: %v\n", p.domain, err)
+		return
+	}
+
+	p.ipListMutex.Lock()
+	defer p.ipListMutex.Unlock()
+	// 解析这段时间里domainMonitor可能已经先一步产出了数据，这种情况下以domainMonitor的数据
+	// 为准，不用应急解析结果覆盖掉
+	if len(p.targetIPv4List) > 0 || len(p.targetIPv6List) > 0 {
+		return
+	}
+	p.targetIPv4List = ipv4
+	p.targetIPv6List = ipv6
+	if p.knownTargetIPs == nil {
+		p.knownTargetIPs = make(map[string]struct{}, len(ipv4)+len(ipv6))
+	}
+	for _, ip := range ipv4 {
+		p.knownTargetIPs[ip] = struct{}{}
+	}
+	for _, ip := range ipv6 {
+		p.knownTargetIPs[ip] = struct{}{}
+	}
+	fmt.Printf("[连接池] 域名 [%s] DomainMonitor暂无数据，应急解析得到 %d 个IPv4、%d 个IPv6地址\n",
+		p.domain, len(ipv4), len(ipv6))
+}
+
+// dialAttempt记录一次拨号尝试的结果，用于createConnectionHappyEyeballs里汇总v6/v4两路竞速结果
+type dialAttempt struct {
+	candidate ipWarmupJob
+	connMeta  *ConnMetadata
+	err       error
+}
+
+// createConnectionHappyEyeballs按RFC 8305的思路，为IPv6候选和IPv4候选各发起一次拨号并竞速：
+// IPv6立即开始，IPv4在happyEyeballsDelay后开始（如果IPv6提前失败则立即唤醒IPv4，不必等满延迟），
+// 哪个先握手成功就用哪个，另一个如果后续也成功了会在后台被直接关闭丢弃。只有单一地址族可用、
+// 或者两路都失败时，退回createConnectionSequential对全部候选的顺序尝试。
+func (p *domainConnPool) createConnectionHappyEyeballs(skipWhitelistCheck bool) (*ConnMetadata, string, error) {
+	p.ipListMutex.RLock()
+	currentIPv6 := append([]string(nil), p.targetIPv6List...)
+	currentIPv4 := append([]string(nil), p.targetIPv4List...)
+	p.ipListMutex.RUnlock()
+
+	filteredIPv6 := p.filterAllowedIPs(currentIPv6)
+	filteredIPv4 := p.filterAllowedIPs(currentIPv4)
+	if len(filteredIPv6) == 0 && len(filteredIPv4) == 0 {
+		filteredIPv6 = currentIPv6
+		filteredIPv4 = currentIPv4
+	}
+	if len(filteredIPv6) == 0 || len(filteredIPv4) == 0 {
+		// 只有一种地址族可用，没有什么好竞速的
+		return p.createConnectionSequential(skipWhitelistCheck)
+	}
+	filteredIPv6 = p.preferGeoIPs(filteredIPv6)
+	filteredIPv4 = p.preferGeoIPs(filteredIPv4)
+
+	v6Candidate := ipWarmupJob{ip: filteredIPv6[p.rand.Intn(len(filteredIPv6))], isIPv6: true}
+	v4Candidate := ipWarmupJob{ip: filteredIPv4[p.rand.Intn(len(filteredIPv4))], isIPv6: false}
+
+	dial := func(candidate ipWarmupJob) dialAttempt {
+		localIP := p.getLocalIPForTarget(candidate.ip, candidate.isIPv6)
+		connMeta, err := p.createConnection(localIP, candidate.ip, skipWhitelistCheck)
+		return dialAttempt{candidate: candidate, connMeta: connMeta, err: err}
+	}
+
+	results := make(chan dialAttempt, 2)
+	startV4 := make(chan struct{}, 1)
+
+	go func() {
+		attempt := dial(v6Candidate)
+		if attempt.err != nil {
+			select {
+			case startV4 <- struct{}{}:
+			default:
+			}
+		}
+		results <- attempt
+	}()
+	go func() {
+		timer := time.NewTimer(p.happyEyeballsDelay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-startV4:
+		}
+		results <- dial(v4Candidate)
+	}()
+
+	var winner *dialAttempt
+	var lastErr error
+	for i := 0; i < 2; i++ {
+		attempt := <-results
+		if attempt.err != nil {
+			lastErr = attempt.err
+			continue
+		}
+		winner = &attempt
+		if i == 0 {
+			// 另一路还在进行中，后台等它结束后把多余的连接关掉，避免连接泄漏
+			go func() {
+				if loser := <-results; loser.err == nil && loser.connMeta != nil {
+					_ = loser.connMeta.Conn.Close()
+				}
+			}()
+		}
+		break
+	}
+	if winner != nil {
+		return winner.connMeta, winner.candidate.ip, nil
+	}
+
+	fmt.Printf("[连接池] 域名 [%s] Happy Eyeballs竞速两路均失败（v6=%s: %v），退回顺序尝试\n", p.domain, v6Candidate.ip, lastErr)
+	return p.createConnectionSequential(skipWhitelistCheck)
+}
+
+// createConnectionSequential是HappyEyeballs关闭时使用的原有行为：把所有候选IP随机打乱后
+// 逐个顺序尝试，第一个拨号成功的即返回。
+func (p *domainConnPool) createConnectionSequential(skipWhitelistCheck bool) (*ConnMetadata, string, error) {
 	p.ipListMutex.RLock()
 	currentIPv6 := append([]string(nil), p.targetIPv6List...)
 	currentIPv4 := append([]string(nil), p.targetIPv4List...)
@@ -773,6 +1849,8 @@ func (p *domainConnPool) createConnectionWithFallback(skipWhitelistCheck bool) (
 		filteredIPv6 = currentIPv6
 		filteredIPv4 = currentIPv4
 	}
+	filteredIPv6 = p.preferGeoIPs(filteredIPv6)
+	filteredIPv4 = p.preferGeoIPs(filteredIPv4)
 
 	candidates := make([]ipWarmupJob, 0, len(filteredIPv6)+len(filteredIPv4))
 	for _, ip := range filteredIPv6 {
@@ -805,6 +1883,73 @@ func (p *domainConnPool) createConnectionWithFallback(skipWhitelistCheck bool) (
 	}
 	return nil, "", fmt.Errorf("为域名 [%s] 创建连接失败: %w", p.domain, lastErr)
 }
+
+// toUpperStrings返回values的大写副本，供PreferredCountries/PreferredASNs的大小写不敏感匹配使用
+func toUpperStrings(values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+	result := make([]string, len(values))
+	for i, v := range values {
+		result[i] = strings.ToUpper(v)
+	}
+	return result
+}
+
+// preferGeoIPs在ips中挑出IPInfo国家代码落在preferredCountries、或AS.ASN落在preferredASNs里的
+// 候选（两者是"或"的关系），用于把RTT更低的地区IP优先喂给拨号逻辑；未配置任何偏好，或者
+// DomainMonitor里还查不到ips对应的IPInfo，或者筛完一个匹配都没有时，原样返回ips，
+// 与filterAllowedIPs的"过滤结果为空就退回原列表"是同一种优雅降级方式。
+func (p *domainConnPool) preferGeoIPs(ips []string) []string {
+	if len(ips) == 0 || (len(p.preferredCountries) == 0 && len(p.preferredASNs) == 0) || p.domainMonitor == nil {
+		return ips
+	}
+
+	domainPool, ok := p.domainMonitor.GetDomainPool(p.domain)
+	if !ok {
+		return ips
+	}
+
+	ipInfos := make(map[string]*IPInfoResponse, len(ips))
+	for _, records := range domainPool {
+		for _, record := range records {
+			if record.IPInfo != nil {
+				ipInfos[record.IP] = record.IPInfo
+			}
+		}
+	}
+
+	matches := func(info *IPInfoResponse) bool {
+		if info == nil {
+			return false
+		}
+		for _, country := range p.preferredCountries {
+			if strings.EqualFold(info.CountryCode, country) {
+				return true
+			}
+		}
+		if info.AS != nil {
+			for _, asn := range p.preferredASNs {
+				if strings.EqualFold(info.AS.ASN, asn) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	preferred := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		if matches(ipInfos[ip]) {
+			preferred = append(preferred, ip)
+		}
+	}
+	if len(preferred) == 0 {
+		return ips
+	}
+	return preferred
+}
+
 func (p *domainConnPool) filterAllowedIPs(ips []string) []string {
 	if len(ips) == 0 {
 		return nil
@@ -831,17 +1976,23 @@ func (p *domainConnPool) filterAllowedIPs(ips []string) []string {
 	}
 	return filtered
 }
-func (p *domainConnPool) isConnValid(conn *utls.UConn) bool {
+func (p *domainConnPool) isConnValid(conn net.Conn) bool {
 	if conn == nil {
 		return false
 	}
-	
-	// 检查连接状态
-	state := conn.ConnectionState()
-	if !state.HandshakeComplete || conn.RemoteAddr() == nil {
+
+	// PlaintextHTTP为true时conn是裸TCP连接，没有TLS握手状态可检查，只能退化为下面的
+	// SetReadDeadline存活探测；PlaintextHTTP为false（默认）时额外确认TLS握手确实已经完成。
+	if uConn, ok := conn.(*utls.UConn); ok {
+		state := uConn.ConnectionState()
+		if !state.HandshakeComplete {
+			return false
+		}
+	}
+	if conn.RemoteAddr() == nil {
 		return false
 	}
-	
+
 	// 尝试设置一个很短的读取超时来检测连接是否真的可用
 	// 如果连接已经被关闭，SetReadDeadline会立即返回错误
 	originalDeadline := time.Now().Add(100 * time.Millisecond)
@@ -850,9 +2001,76 @@ func (p *domainConnPool) isConnValid(conn *utls.UConn) bool {
 	}
 	// 恢复deadline
 	_ = conn.SetReadDeadline(time.Time{})
-	
+
 	return true
 }
+
+// probeLiveness 对一条闲置连接做一次主动探活：h2连接发HTTP/2 PING帧，既能检测出
+// isConnValid无法发现的半开连接（对端已消失但本地套接字状态看起来正常），也顺带
+// 量出一个RTT，RTT劣化超过h2PingRTTDegradeThreshold的连接即使PING本身成功也判定
+// 为不健康；非h2连接没有PING机制，退化为isConnValid的普通检查
+func (p *domainConnPool) probeLiveness(connMeta *ConnMetadata) bool {
+	if connMeta == nil || connMeta.Conn == nil {
+		return false
+	}
+	if connMeta.http2Conn == nil {
+		return p.isConnValid(connMeta.Conn)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h2PingTimeout)
+	defer cancel()
+
+	start := time.Now()
+	if err := connMeta.http2Conn.Ping(ctx); err != nil {
+		return false
+	}
+	connMeta.lastPingRTT = time.Since(start)
+	return connMeta.lastPingRTT <= h2PingRTTDegradeThreshold
+}
+
+// runLivenessProbe 对当前闲置在各连接桶（healthyConns、unhealthyConns以及每个目标IP的
+// 专属桶）里的连接各探活一次，淘汰半开或RTT劣化的连接，而不是等到被GetConn取用或
+// 业务请求失败时才发现
+func (p *domainConnPool) runLivenessProbe() {
+	p.probePoolChan(p.healthyConns)
+	p.probePoolChan(p.unhealthyConns)
+
+	p.ipConnPoolsMutex.RLock()
+	ipPools := make([]chan *ConnMetadata, 0, len(p.ipConnPools))
+	for _, ipPool := range p.ipConnPools {
+		ipPools = append(ipPools, ipPool)
+	}
+	p.ipConnPoolsMutex.RUnlock()
+
+	for _, ipPool := range ipPools {
+		p.probePoolChan(ipPool)
+	}
+}
+
+// probePoolChan 取出pool里当前排队的连接逐个探活，存活的放回原pool，淘汰的直接关闭；
+// 取出数量是调用时刻的队列长度快照，不会连带探测探活过程中新放入的连接
+func (p *domainConnPool) probePoolChan(pool chan *ConnMetadata) {
+	n := len(pool)
+	for i := 0; i < n; i++ {
+		var connMeta *ConnMetadata
+		select {
+		case connMeta = <-pool:
+		default:
+			return
+		}
+
+		if p.probeLiveness(connMeta) {
+			select {
+			case pool <- connMeta:
+			default:
+				_ = connMeta.Conn.Close()
+			}
+		} else {
+			_ = connMeta.Conn.Close()
+		}
+	}
+}
+
 func (p *domainConnPool) UpdateIPStats(targetIP string, statusCode int) {
 	if targetIP == "" {
 		return
@@ -872,6 +2090,119 @@ func (p *domainConnPool) UpdateIPStats(targetIP string, statusCode int) {
 	}
 	p.ipStatsMutex.Unlock()
 }
+
+// RecordConnBytes 将一次请求在套接字层面新增的读写字节数分别累加到按目标IP、按本地IP和
+// 按协议的统计中，顺带累加到当天的DailyByteCap用量里（按自然日切换重置，见dailyCapDate）
+func (p *domainConnPool) RecordConnBytes(targetIP, localIP, protocol string, read, written int64) {
+	if read == 0 && written == 0 {
+		return
+	}
+
+	p.ipStatsMutex.Lock()
+	today := time.Now().Format("2006-01-02")
+	if p.dailyCapDate != today {
+		p.dailyCapDate = today
+		p.dailyBytesUsed = 0
+	}
+	p.dailyBytesUsed += read + written
+	if targetIP != "" {
+		stats, exists := p.ipStatsMap[targetIP]
+		if !exists {
+			stats = &ipStats{}
+			p.ipStatsMap[targetIP] = stats
+		}
+		stats.BytesRead += read
+		stats.BytesWritten += written
+	}
+	if localIP != "" {
+		stats, exists := p.localIPStatsMap[localIP]
+		if !exists {
+			stats = &ipStats{}
+			p.localIPStatsMap[localIP] = stats
+		}
+		stats.BytesRead += read
+		stats.BytesWritten += written
+	}
+	if protocol != "" {
+		stats, exists := p.protocolStatsMap[protocol]
+		if !exists {
+			stats = &ProtocolTraffic{}
+			p.protocolStatsMap[protocol] = stats
+		}
+		stats.BytesRead += read
+		stats.BytesWritten += written
+	}
+	p.ipStatsMutex.Unlock()
+}
+
+// TrafficByProtocol 返回按协议（"h2"/"http/1.1"）聚合的套接字级读写字节数快照
+// TrafficByIP 返回按目标IP聚合的套接字级读写字节数快照
+func (p *domainConnPool) TrafficByIP() map[string]IPTraffic {
+	p.ipStatsMutex.RLock()
+	defer p.ipStatsMutex.RUnlock()
+	snapshot := make(map[string]IPTraffic, len(p.ipStatsMap))
+	for ip, stats := range p.ipStatsMap {
+		snapshot[ip] = IPTraffic{BytesRead: stats.BytesRead, BytesWritten: stats.BytesWritten}
+	}
+	return snapshot
+}
+
+// TrafficByLocalIP 返回按本地出口IP聚合的套接字级读写字节数快照
+func (p *domainConnPool) TrafficByLocalIP() map[string]IPTraffic {
+	p.ipStatsMutex.RLock()
+	defer p.ipStatsMutex.RUnlock()
+	snapshot := make(map[string]IPTraffic, len(p.localIPStatsMap))
+	for ip, stats := range p.localIPStatsMap {
+		snapshot[ip] = IPTraffic{BytesRead: stats.BytesRead, BytesWritten: stats.BytesWritten}
+	}
+	return snapshot
+}
+
+// HandshakeStatsByIP 返回按目标IP聚合的TLS握手统计快照，见HandshakeStats.go
+func (p *domainConnPool) HandshakeStatsByIP() map[string]HandshakeStats {
+	return p.handshakeStats.byIPSnapshot()
+}
+
+// HandshakeStatsByFingerprint 返回按指纹名聚合的TLS握手统计快照，见HandshakeStats.go
+func (p *domainConnPool) HandshakeStatsByFingerprint() map[string]HandshakeStats {
+	return p.handshakeStats.byFingerprintSnapshot()
+}
+
+func (p *domainConnPool) TrafficByProtocol() map[string]ProtocolTraffic {
+	p.ipStatsMutex.RLock()
+	defer p.ipStatsMutex.RUnlock()
+	snapshot := make(map[string]ProtocolTraffic, len(p.protocolStatsMap))
+	for protocol, stats := range p.protocolStatsMap {
+		snapshot[protocol] = *stats
+	}
+	return snapshot
+}
+
+// DailyBytesUsed 返回当天已累计的套接字级读写字节总数；如果距离上次RecordConnBytes已经跨了
+// 自然日（比如昨晚之后这个域名一直没有新请求），这里会先做一次和RecordConnBytes相同的日期
+// 切换重置，避免返回昨天遗留的旧值。
+func (p *domainConnPool) DailyBytesUsed() int64 {
+	p.ipStatsMutex.Lock()
+	defer p.ipStatsMutex.Unlock()
+	today := time.Now().Format("2006-01-02")
+	if p.dailyCapDate != today {
+		p.dailyCapDate = today
+		p.dailyBytesUsed = 0
+	}
+	return p.dailyBytesUsed
+}
+
+// HealthyConnCount 返回healthyConns通道中当前排队的连接数，即可以被GetConn直接取用而无需
+// 新建或恢复的连接数量
+func (p *domainConnPool) HealthyConnCount() int {
+	return len(p.healthyConns)
+}
+
+// CheckedOutCount 返回当前已租出、尚未归还的连接数，见checkedOut字段
+func (p *domainConnPool) CheckedOutCount() int64 {
+	return atomic.LoadInt64(&p.checkedOut)
+}
+
 func (p *domainConnPool) printPoolStats() {
 	healthy := len(p.healthyConns)
 	unhealthy := len(p.unhealthyConns)
@@ -880,7 +2211,10 @@ func (p *domainConnPool) printPoolStats() {
 	totalIPs := len(p.ipStatsMap)
 	p.ipStatsMutex.RUnlock()
 
-	fmt.Printf("[连接池] 状态: 健康连接=%d, 待恢复连接=%d, 已跟踪IP=%d\n", healthy, unhealthy, totalIPs)
+	attempts, success, failed := p.WarmupStats()
+
+	fmt.Printf("[连接池] 状态: 健康连接=%d, 待恢复连接=%d, 已跟踪IP=%d, 预热请求=%d(成功%d/失败%d)\n",
+		healthy, unhealthy, totalIPs, attempts, success, failed)
 }
 func (p *domainConnPool) Close() error {
 	p.mutex.Lock()
@@ -892,6 +2226,13 @@ func (p *domainConnPool) Close() error {
 	close(p.stopChan)
 	p.mutex.Unlock()
 
+	return p.closeLocked()
+}
+
+// closeLocked 是Close/CloseGracefully共用的硬关闭收尾逻辑：等待后台任务退出、清空各连接桶、
+// 逐个关闭连接。调用前p.closed必须已经被设置为true（即closed标志的置位由调用方各自负责，
+// 这里不重复处理，避免CloseGracefully在等待租约归还期间重复触发这部分逻辑）。
+func (p *domainConnPool) closeLocked() error {
 	p.wg.Wait()
 
 cleanupChannels:
@@ -923,10 +2264,42 @@ cleanupUnhealthy:
 		close(pool)
 		delete(p.ipConnPools, key)
 	}
+	p.ipConnOrder = nil
+	p.nextIPIndex = 0
 	p.ipConnPoolsMutex.Unlock()
 
 	return nil
 }
+
+// CloseGracefully 在硬关闭前先等待CheckedOutCount归零，让已经通过GetConn/GetConnByIP租出的
+// 连接有机会被正在进行的请求ReturnConn归还，不会被拦腰关掉导致请求直接失败；ctx到期时
+// 不管是否还有未归还的连接，都直接退化为Close()的行为，保证CloseGracefully本身总会返回。
+// 关闭标志在等待阶段就已经生效（isClosed后GetConn/GetConnByIP会直接报错），所以等待期间
+// 不会有新的连接被租出，checkedOut只会单调递减。
+func (p *domainConnPool) CloseGracefully(ctx context.Context) error {
+	p.mutex.Lock()
+	if p.closed {
+		p.mutex.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.stopChan)
+	p.mutex.Unlock()
+
+	ticker := time.NewTicker(closeDrainPollInterval)
+	defer ticker.Stop()
+waitForLeases:
+	for atomic.LoadInt64(&p.checkedOut) > 0 {
+		select {
+		case <-ctx.Done():
+			break waitForLeases
+		case <-ticker.C:
+		}
+	}
+
+	return p.closeLocked()
+}
+
 func (p *domainConnPool) processPendingWarmups() {
 	p.ipListMutex.Lock()
 	if len(p.pendingWarmups) == 0 {
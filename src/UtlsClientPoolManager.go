@@ -0,0 +1,267 @@
+package src
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// PoolManager 管理多个域名各自独立的热连接池，使RockTree、EarthImagery等不同业务可以在共享同一个
+// DomainMonitor和本地IP池的前提下，按UTlsRequest.Domain把请求路由到对应域名的连接池，
+// 而不必像DomainConnPoolConfig那样只支持单一Domain。
+type PoolManager struct {
+	mu    sync.RWMutex
+	pools map[string]HotConnPool
+}
+
+// NewPoolManager 创建一个空的连接池管理器，调用方通过AddDomain逐个注册需要支持的域名
+func NewPoolManager() *PoolManager {
+	return &PoolManager{
+		pools: make(map[string]HotConnPool),
+	}
+}
+
+// AddDomain 为指定域名创建并注册一个独立的热连接池。
+// config中的DomainMonitor、LocalIPv4Pool、LocalIPv6Pool等通常应在多次调用间传入同一份实例，
+// 以便不同域名的连接池共享域名监控和本地IP资源。
+// 如果该域名已经注册过连接池（例如RockTree和EarthImagery恰好配置了相同的HostName），
+// 则直接复用已有连接池，不会重复创建。
+func (m *PoolManager) AddDomain(domain string, config DomainConnPoolConfig) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.pools[domain]; exists {
+		return nil
+	}
+	config.Domain = domain
+	pool, err := NewDomainHotConnPool(config)
+	if err != nil {
+		return fmt.Errorf("为域名 [%s] 创建连接池失败: %w", domain, err)
+	}
+	m.pools[domain] = pool
+	return nil
+}
+
+// Register 将一个已经创建好的连接池注册到指定域名下，供调用方复用已有连接池（而不是重新构建）的场景，
+// 例如把现存的单域名连接池原地纳入PoolManager管理。如果该域名已经注册过，则保留已有的连接池。
+func (m *PoolManager) Register(domain string, pool HotConnPool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.pools[domain]; exists {
+		return
+	}
+	m.pools[domain] = pool
+}
+
+// PoolForDomain 返回指定域名对应的连接池；如果该域名未注册，ok返回false
+func (m *PoolManager) PoolForDomain(domain string) (pool HotConnPool, ok bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	pool, ok = m.pools[domain]
+	return pool, ok
+}
+
+// Replace 将指定域名的连接池原子替换为newPool，并返回被替换下来的旧连接池（调用方负责关闭）。
+// 如果该域名此前未注册，则等同于注册一个新的连接池，并返回nil。
+func (m *PoolManager) Replace(domain string, newPool HotConnPool) HotConnPool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	old := m.pools[domain]
+	m.pools[domain] = newPool
+	return old
+}
+
+// Domains 返回当前已注册的所有域名
+func (m *PoolManager) Domains() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	domains := make([]string, 0, len(m.pools))
+	for domain := range m.pools {
+		domains = append(domains, domain)
+	}
+	return domains
+}
+
+// Warmup 依次预热所有已注册域名的连接池，单个域名预热失败不会中断其余域名，
+// 返回按域名汇总的预热报告
+func (m *PoolManager) Warmup() (map[string]*WarmupReport, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	reports := make(map[string]*WarmupReport, len(m.pools))
+	var failures []string
+	for domain, pool := range m.pools {
+		report, err := pool.Warmup()
+		reports[domain] = report
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("[%s] %v", domain, err))
+		}
+	}
+	if len(failures) > 0 {
+		return reports, fmt.Errorf("部分域名预热失败: %s", strings.Join(failures, "; "))
+	}
+	return reports, nil
+}
+
+// SetWarmupProgress 为所有已注册域名的连接池注册同一个预热进度回调；cb每次被调用时都
+// 会收到触发回调的那个域名，供Crawler按"全部域名合计就绪数"之类的口径提前结束等待
+func (m *PoolManager) SetWarmupProgress(cb func(domain string, result WarmupResult)) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for domain, pool := range m.pools {
+		domain := domain
+		pool.SetWarmupProgress(func(result WarmupResult) {
+			cb(domain, result)
+		})
+	}
+}
+
+// WarmupStats 汇总所有已注册域名连接池的预热/健康检查请求数，
+// 用于在多域名场景下统一观测这部分独立于业务统计之外的控制流量
+func (m *PoolManager) WarmupStats() (attempts, success, failed int64) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, pool := range m.pools {
+		a, s, f := pool.WarmupStats()
+		attempts += a
+		success += s
+		failed += f
+	}
+	return attempts, success, failed
+}
+
+// TrafficByIP 汇总所有已注册域名连接池按目标IP聚合的套接字级读写字节数
+func (m *PoolManager) TrafficByIP() map[string]IPTraffic {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	total := make(map[string]IPTraffic)
+	for _, pool := range m.pools {
+		for ip, traffic := range pool.TrafficByIP() {
+			agg := total[ip]
+			agg.BytesRead += traffic.BytesRead
+			agg.BytesWritten += traffic.BytesWritten
+			total[ip] = agg
+		}
+	}
+	return total
+}
+
+// TrafficByLocalIP 汇总所有已注册域名连接池按本地出口IP聚合的套接字级读写字节数
+func (m *PoolManager) TrafficByLocalIP() map[string]IPTraffic {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	total := make(map[string]IPTraffic)
+	for _, pool := range m.pools {
+		for ip, traffic := range pool.TrafficByLocalIP() {
+			agg := total[ip]
+			agg.BytesRead += traffic.BytesRead
+			agg.BytesWritten += traffic.BytesWritten
+			total[ip] = agg
+		}
+	}
+	return total
+}
+
+// TrafficByProtocol 汇总所有已注册域名连接池按协议聚合的套接字级读写字节数
+func (m *PoolManager) TrafficByProtocol() map[string]ProtocolTraffic {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	total := make(map[string]ProtocolTraffic)
+	for _, pool := range m.pools {
+		for protocol, traffic := range pool.TrafficByProtocol() {
+			agg := total[protocol]
+			agg.BytesRead += traffic.BytesRead
+			agg.BytesWritten += traffic.BytesWritten
+			total[protocol] = agg
+		}
+	}
+	return total
+}
+
+// HandshakeStatsByIP 汇总所有已注册域名连接池按目标IP聚合的TLS握手统计
+func (m *PoolManager) HandshakeStatsByIP() map[string]HandshakeStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	total := make(map[string]HandshakeStats)
+	for _, pool := range m.pools {
+		for ip, stats := range pool.HandshakeStatsByIP() {
+			agg := total[ip]
+			mergeHandshakeStats(&agg, stats)
+			total[ip] = agg
+		}
+	}
+	return total
+}
+
+// HandshakeStatsByFingerprint 汇总所有已注册域名连接池按指纹聚合的TLS握手统计
+func (m *PoolManager) HandshakeStatsByFingerprint() map[string]HandshakeStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	total := make(map[string]HandshakeStats)
+	for _, pool := range m.pools {
+		for fp, stats := range pool.HandshakeStatsByFingerprint() {
+			agg := total[fp]
+			mergeHandshakeStats(&agg, stats)
+			total[fp] = agg
+		}
+	}
+	return total
+}
+
+// DailyBytesUsedByDomain 返回每个已注册域名当天累计的套接字级读写字节总数，
+// 用于统一观测各域名距离各自DailyByteCap还有多少余量，见domainConnPool.DailyBytesUsed
+func (m *PoolManager) DailyBytesUsedByDomain() map[string]int64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	usage := make(map[string]int64, len(m.pools))
+	for domain, pool := range m.pools {
+		usage[domain] = pool.DailyBytesUsed()
+	}
+	return usage
+}
+
+// Close 关闭所有已注册域名的连接池
+func (m *PoolManager) Close() error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var failures []string
+	for domain, pool := range m.pools {
+		if err := pool.Close(); err != nil {
+			failures = append(failures, fmt.Sprintf("[%s] %v", domain, err))
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("部分域名连接池关闭失败: %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// CloseGracefully 对所有已注册域名的连接池并发地调用CloseGracefully，等待各自的在途连接
+// 归还（或ctx到期）后再硬关闭，用于进程退出时给尚未完成的爬取任务留出收尾时间，
+// 而不是像Close那样可能直接拦腰关掉正在使用的连接。
+func (m *PoolManager) CloseGracefully(ctx context.Context) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	errs := make(chan string, len(m.pools))
+	for domain, pool := range m.pools {
+		wg.Add(1)
+		go func(domain string, pool HotConnPool) {
+			defer wg.Done()
+			if err := pool.CloseGracefully(ctx); err != nil {
+				errs <- fmt.Sprintf("[%s] %v", domain, err)
+			}
+		}(domain, pool)
+	}
+	wg.Wait()
+	close(errs)
+
+	var failures []string
+	for failure := range errs {
+		failures = append(failures, failure)
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("部分域名连接池关闭失败: %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package src
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// WarmupValidator描述预热/健康检查阶段用来判断一次探测是否"成功"的规则。
+// 此前这个判断是硬编码的"状态码200且body恰好13字节"，只适配Planetoid元数据接口；
+// 通过DomainConnPoolConfig.WarmupValidator传入自定义规则后，同一套连接池就能用于
+// 返回不同状态码、不同body格式的其它endpoint。
+type WarmupValidator struct {
+	// ExpectedStatuses是被视为成功的状态码集合；为空时默认只接受200。
+	ExpectedStatuses []int
+	// MinBodyLen/MaxBodyLen是body长度的允许闭区间；任一项<=0表示对应方向不做限制。
+	MinBodyLen int
+	MaxBodyLen int
+	// BodyPrefix非空时要求body以该前缀开头。
+	BodyPrefix []byte
+	// BodyRegex非空时要求body匹配该正则。
+	BodyRegex *regexp.Regexp
+	// MaxLatency>0时要求本次探测的耗时不超过该值，用于把响应慢到不可用的IP也当成失败处理。
+	MaxLatency time.Duration
+}
+
+// defaultWarmupValidator复刻原先的硬编码规则："状态码200且body恰好13字节"，
+// 在DomainConnPoolConfig未设置WarmupValidator时使用，保持已有部署的行为不变。
+func defaultWarmupValidator() *WarmupValidator {
+	return &WarmupValidator{
+		ExpectedStatuses: []int{200},
+		MinBodyLen:       13,
+		MaxBodyLen:       13,
+	}
+}
+
+// Validate检查一次探测的状态码、body和耗时是否满足v的规则，满足返回nil，
+// 否则返回一个说明具体哪一项不满足的错误。v为nil时视为没有任何限制，总是通过。
+func (v *WarmupValidator) Validate(statusCode int, body []byte, latency time.Duration) error {
+	if v == nil {
+		return nil
+	}
+
+	if len(v.ExpectedStatuses) > 0 {
+		matched := false
+		for _, s := range v.ExpectedStatuses {
+			if s == statusCode {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("状态码 %d 不在期望范围 %v 内", statusCode, v.ExpectedStatuses)
+		}
+	}
+
+	if v.MinBodyLen > 0 && len(body) < v.MinBodyLen {
+		return fmt.Errorf("body长度 %d 小于下限 %d", len(body), v.MinBodyLen)
+	}
+	if v.MaxBodyLen > 0 && len(body) > v.MaxBodyLen {
+		return fmt.Errorf("body长度 %d 超过上限 %d", len(body), v.MaxBodyLen)
+	}
+	if len(v.BodyPrefix) > 0 && !bytes.HasPrefix(body, v.BodyPrefix) {
+		return fmt.Errorf("body不以期望的前缀开头")
+	}
+	if v.BodyRegex != nil && !v.BodyRegex.Match(body) {
+		return fmt.Errorf("body不匹配期望的正则 %s", v.BodyRegex.String())
+	}
+	if v.MaxLatency > 0 && latency > v.MaxLatency {
+		return fmt.Errorf("探测耗时 %s 超过上限 %s", latency, v.MaxLatency)
+	}
+
+	return nil
+}
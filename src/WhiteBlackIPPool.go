@@ -1,6 +1,9 @@
 package src // Package src 定义src包
 
-import "sync" // 导入同步原语如互斥锁
+import ( // 导入依赖包
+	"sync" // 导入同步原语如互斥锁
+	"time" // 用于TTL过期时间计算
+)
 
 // IPAccessController 定义了IP访问控制的行为接口。
 // 通过该接口，可以将IP访问控制的具体实现与业务逻辑解耦。
@@ -26,36 +29,125 @@ type IPAccessController interface { // 定义IP访问控制器接口
 // IPSet 用于高效地存储和查询IP地址集合。
 type IPSet map[string]bool // 定义IP集合类型，映射IP地址到布尔值
 
+// IPChangeListener 是AddIP/AddIPWithTTL每次变更名单时触发的回调，isWhite标明变更发生在
+// 白名单还是黑名单。不放进IPAccessController接口是因为大多数使用场景（单机运行、
+// coordinator侧合并远端上报）都不需要关心本地变更事件，只有需要把本地观测到的IP状态
+// 向其他Crawler实例广播的场景（见internal/ipsync、cmd/Crawler/ipsync.go）才用
+// SetChangeListener挂一个监听器，按需通过类型断言拿到*WhiteBlackIPPool使用。
+type IPChangeListener func(ip string, isWhite bool)
+
 // WhiteBlackIPPool 是 IPAccessController 接口的一个具体实现。
 // 它在内存中使用两个集合来维护IP黑白名单，并保证并发安全。
 type WhiteBlackIPPool struct { // 定义黑白名单IP池结构体
-	whiteList IPSet        // 白名单集合
-	blackList IPSet        // 黑名单集合
-	mutex     sync.RWMutex // 读写互斥锁，保护集合并发安全
+	whiteList      IPSet                // 白名单集合
+	blackList      IPSet                // 黑名单集合
+	whiteExpiry    map[string]time.Time // 白名单条目的过期时间，不存在或零值表示永不过期
+	blackExpiry    map[string]time.Time // 黑名单条目的过期时间，不存在或零值表示永不过期
+	mutex          sync.RWMutex         // 读写互斥锁，保护集合并发安全
+	changeListener IPChangeListener     // 名单变更回调，默认nil（不通知任何人）
 }
 
 // NewWhiteBlackIPPool 创建并返回一个基于内存的IP访问控制器实例。
 // 返回值：IP访问控制器接口实例
 func NewWhiteBlackIPPool() IPAccessController {
 	return &WhiteBlackIPPool{ // 创建并返回黑白名单IP池实例
-		whiteList: make(IPSet), // 初始化白名单集合
-		blackList: make(IPSet), // 初始化黑名单集合
+		whiteList:   make(IPSet),                // 初始化白名单集合
+		blackList:   make(IPSet),                // 初始化黑名单集合
+		whiteExpiry: make(map[string]time.Time), // 初始化白名单过期时间表
+		blackExpiry: make(map[string]time.Time), // 初始化黑名单过期时间表
 	}
 }
 
-// AddIP 将一个IP地址添加到指定的名单中。
+// SetChangeListener 设置名单变更回调，传nil可取消监听。只在*WhiteBlackIPPool具体类型上
+// 暴露（不在IPAccessController接口中），需要时通过类型断言获取。
+func (pool *WhiteBlackIPPool) SetChangeListener(listener IPChangeListener) {
+	pool.mutex.Lock()              // 加写锁，与AddIP/AddIPWithTTL读取changeListener互斥
+	defer pool.mutex.Unlock()      // 延迟解锁
+	pool.changeListener = listener // 替换监听器
+}
+
+// AddIP 将一个IP地址添加到指定的名单中，永不过期。
 // 如果 isWhite 为 true，IP被添加到白名单；否则，添加到黑名单。
 // 参数：
 // ip - 要添加的IP地址
 // isWhite - 是否添加到白名单的标志
 func (pool *WhiteBlackIPPool) AddIP(ip string, isWhite bool) {
-	pool.mutex.Lock()         // 加写锁
-	defer pool.mutex.Unlock() // 延迟解锁
-	if isWhite {              // 如果添加到白名单
+	pool.addIP(ip, isWhite, 0) // 永不过期，TTL传0
+}
+
+// AddIPWithTTL 将一个IP地址添加到指定的名单中，ttl>0时在ttl之后自动失效（由PruneExpired
+// 清理），ttl<=0时等价于AddIP（永不过期）。供分布式IP名单同步场景使用，见
+// cmd/Crawler/ipsync.go——远端上报的封禁默认带TTL，避免某个节点对目标IP的误判永久污染
+// 全部实例的黑名单。
+func (pool *WhiteBlackIPPool) AddIPWithTTL(ip string, isWhite bool, ttl time.Duration) {
+	pool.addIP(ip, isWhite, ttl) // 转发到内部实现
+}
+
+func (pool *WhiteBlackIPPool) addIP(ip string, isWhite bool, ttl time.Duration) {
+	pool.mutex.Lock()       // 加写锁
+	var expiresAt time.Time // 过期时间，零值表示永不过期
+	if ttl > 0 {            // 如果指定了正的TTL
+		expiresAt = time.Now().Add(ttl) // 计算过期时间点
+	}
+	if isWhite { // 如果添加到白名单
 		pool.whiteList[ip] = true // 将IP添加到白名单
+		if expiresAt.IsZero() {   // 永不过期
+			delete(pool.whiteExpiry, ip) // 清除此前可能存在的过期时间
+		} else { // 有TTL
+			pool.whiteExpiry[ip] = expiresAt // 记录过期时间
+		}
 	} else { // 如果添加到黑名单
 		pool.blackList[ip] = true // 将IP添加到黑名单
+		if expiresAt.IsZero() {   // 永不过期
+			delete(pool.blackExpiry, ip) // 清除此前可能存在的过期时间
+		} else { // 有TTL
+			pool.blackExpiry[ip] = expiresAt // 记录过期时间
+		}
+	}
+	listener := pool.changeListener // 在持锁期间取出监听器引用
+	pool.mutex.Unlock()             // 解锁后再回调，避免监听器反过来调用本pool的方法时死锁
+	if listener != nil {            // 如果设置了监听器
+		listener(ip, isWhite) // 通知名单发生了变更
+	}
+}
+
+// MergeIP 应用一条来自gossip复制的远端IP事件（见internal/ipsync、cmd/Crawler/ipsync.go），
+// expiresAtUnix为0表示永不过期，否则是Unix秒。冲突解决策略是"更晚过期的事件胜出"：本地
+// 该IP已经是永不过期、或本地过期时间不早于这条事件时直接忽略，避免一条过期更早的旧事件
+// 把本地更长效的封禁意外缩短。与AddIP/AddIPWithTTL不同，MergeIP不会触发changeListener——
+// 应用远端事件不应该再被当作本地新变更重新广播出去，否则gossip会在节点之间无限循环放大。
+// 返回true表示事件被实际应用（名单或过期时间发生了变化）。
+func (pool *WhiteBlackIPPool) MergeIP(ip string, isWhite bool, expiresAtUnix int64) bool {
+	pool.mutex.Lock()         // 加写锁
+	defer pool.mutex.Unlock() // 延迟解锁
+
+	list, expiry := pool.blackList, pool.blackExpiry // 默认按黑名单取引用
+	if isWhite {                                     // 如果事件针对白名单
+		list, expiry = pool.whiteList, pool.whiteExpiry // 改取白名单的引用
+	}
+
+	var newExpiresAt time.Time // 事件声明的过期时间，零值表示永不过期
+	if expiresAtUnix > 0 {     // Unix秒大于0才有意义
+		newExpiresAt = time.Unix(expiresAtUnix, 0) // 换算成time.Time
+	}
+
+	if _, inList := list[ip]; inList { // 本地已经在该名单中，需要判断是否应该被这条事件覆盖
+		existingExpiresAt, hasTTL := expiry[ip] // 查本地现有的过期时间
+		if !hasTTL {                            // 本地是永不过期
+			return false // 任何带TTL的远端事件都不能缩短永久封禁/放行，忽略
+		}
+		if !newExpiresAt.IsZero() && !newExpiresAt.After(existingExpiresAt) { // 新事件没有更晚的过期时间
+			return false // 旧事件更新（或更长效），忽略这条
+		}
+	}
+
+	list[ip] = true            // 应用事件：加入（或保留在）名单中
+	if newExpiresAt.IsZero() { // 新事件永不过期
+		delete(expiry, ip) // 清除过期时间记录
+	} else { // 新事件带TTL
+		expiry[ip] = newExpiresAt // 记录/更新过期时间
 	}
+	return true // 事件已应用
 }
 
 // RemoveIP 从指定的名单中删除一个IP地址。
@@ -67,10 +159,38 @@ func (pool *WhiteBlackIPPool) RemoveIP(ip string, isWhite bool) {
 	pool.mutex.Lock()         // 加写锁
 	defer pool.mutex.Unlock() // 延迟解锁
 	if isWhite {              // 如果从白名单删除
-		delete(pool.whiteList, ip) // 从白名单删除IP
+		delete(pool.whiteList, ip)   // 从白名单删除IP
+		delete(pool.whiteExpiry, ip) // 一并清除过期时间记录
 	} else { // 如果从黑名单删除
-		delete(pool.blackList, ip) // 从黑名单删除IP
+		delete(pool.blackList, ip)   // 从黑名单删除IP
+		delete(pool.blackExpiry, ip) // 一并清除过期时间记录
+	}
+}
+
+// PruneExpired 清理已过期（AddIPWithTTL设置了TTL且已到期）的白/黑名单条目，
+// 返回本次清理掉的条目总数。永不过期的条目（未调用过AddIPWithTTL，或TTL<=0）不受影响。
+// 供后台定期调用，见cmd/Crawler/ipsync.go的reapExpiredIPs。
+func (pool *WhiteBlackIPPool) PruneExpired() int {
+	pool.mutex.Lock()         // 加写锁
+	defer pool.mutex.Unlock() // 延迟解锁
+
+	now := time.Now()                             // 统一取一次当前时间，避免遍历期间时间漂移
+	pruned := 0                                   // 本次清理的条目数
+	for ip, expiresAt := range pool.whiteExpiry { // 遍历白名单过期时间表
+		if now.After(expiresAt) { // 已过期
+			delete(pool.whiteList, ip)   // 从白名单删除
+			delete(pool.whiteExpiry, ip) // 从过期时间表删除
+			pruned++                     // 计数
+		}
+	}
+	for ip, expiresAt := range pool.blackExpiry { // 遍历黑名单过期时间表
+		if now.After(expiresAt) { // 已过期
+			delete(pool.blackList, ip)   // 从黑名单删除
+			delete(pool.blackExpiry, ip) // 从过期时间表删除
+			pruned++                     // 计数
+		}
 	}
+	return pruned // 返回清理总数
 }
 
 // IsIPAllowed 检查一个IP地址是否被允许访问。
@@ -0,0 +1,17 @@
+package src
+
+import "net"
+
+// addressManager 把"往一个网络接口上增删IPv6地址、列出该接口已有的IPv6地址"这三个操作
+// 从LocalIPPool里抽出来，按平台各自实现（见address_manager_linux.go/_windows.go/_darwin.go）。
+// LocalIPPool本身不再关心具体用的是iproute2、netsh还是ifconfig/route，非Linux平台上
+// 如果对应命令不存在或执行失败，实现应返回错误而不是panic，由调用方按"创建失败记录一条
+// 警告但不阻塞"的既有惯例处理，使地址池能优雅降级而不是在非Linux主机上直接失效。
+type addressManager interface {
+	// AddAddress 把ip以/128前缀添加到iface上
+	AddAddress(iface string, ip net.IP) error
+	// DeleteAddress 从iface上删除ip
+	DeleteAddress(iface string, ip net.IP) error
+	// ListAddresses 列出当前绑定在iface上的全部IPv6地址
+	ListAddresses(iface string) ([]net.IP, error)
+}
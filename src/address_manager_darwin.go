@@ -0,0 +1,56 @@
+//go:build darwin
+
+package src
+
+import (
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+)
+
+// darwinAddressManager 是addressManager在macOS上的实现，用ifconfig代替Linux上的iproute2。
+type darwinAddressManager struct{}
+
+var defaultAddressManager addressManager = darwinAddressManager{}
+
+func (darwinAddressManager) AddAddress(iface string, ip net.IP) error {
+	cmd := exec.Command("ifconfig", iface, "inet6", ip.String(), "prefixlen", "128", "alias")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("ifconfig alias失败: %w（%s）", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (darwinAddressManager) DeleteAddress(iface string, ip net.IP) error {
+	cmd := exec.Command("ifconfig", iface, "inet6", ip.String(), "-alias")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("ifconfig -alias失败: %w（%s）", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (darwinAddressManager) ListAddresses(iface string) ([]net.IP, error) {
+	cmd := exec.Command("ifconfig", iface)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("ifconfig失败: %w", err)
+	}
+
+	var addrs []net.IP
+	for _, line := range strings.Split(string(output), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "inet6 ") {
+			continue
+		}
+		parts := strings.Fields(line)
+		if len(parts) < 2 {
+			continue
+		}
+		addrStr := strings.Split(parts[1], "%")[0] // 去掉形如fe80::1%en0里的zone id
+		if ip := net.ParseIP(addrStr); ip != nil {
+			addrs = append(addrs, ip)
+		}
+	}
+	return addrs, nil
+}
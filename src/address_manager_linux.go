@@ -0,0 +1,58 @@
+//go:build linux
+
+package src
+
+import (
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+)
+
+// linuxAddressManager 是addressManager在Linux上的实现，直接复用LocalIPPool此前
+// 一直在用的iproute2命令（"ip addr add/del/show"），只是把调用点集中到这一个文件里。
+type linuxAddressManager struct{}
+
+// defaultAddressManager 是NewLocalIPPool为当前平台选用的addressManager实现。
+var defaultAddressManager addressManager = linuxAddressManager{}
+
+func (linuxAddressManager) AddAddress(iface string, ip net.IP) error {
+	cmd := exec.Command("ip", "addr", "add", ip.String()+"/128", "dev", iface)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("ip addr add失败: %w（%s）", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (linuxAddressManager) DeleteAddress(iface string, ip net.IP) error {
+	cmd := exec.Command("ip", "addr", "del", ip.String()+"/128", "dev", iface)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("ip addr del失败: %w（%s）", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (linuxAddressManager) ListAddresses(iface string) ([]net.IP, error) {
+	cmd := exec.Command("ip", "-6", "addr", "show", "dev", iface)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ip -6 addr show失败: %w", err)
+	}
+
+	var addrs []net.IP
+	for _, line := range strings.Split(string(output), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "inet6 ") {
+			continue
+		}
+		parts := strings.Fields(line)
+		if len(parts) < 2 {
+			continue
+		}
+		addrStr := strings.Split(parts[1], "/")[0]
+		if ip := net.ParseIP(addrStr); ip != nil {
+			addrs = append(addrs, ip)
+		}
+	}
+	return addrs, nil
+}
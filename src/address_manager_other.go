@@ -0,0 +1,27 @@
+//go:build !linux && !windows && !darwin
+
+package src
+
+import (
+	"fmt"
+	"net"
+)
+
+// unsupportedAddressManager 是addressManager在未适配平台（Linux/Windows/macOS之外）上的
+// 实现：所有操作都返回明确的错误，而不是静默什么都不做或尝试执行一个大概率不存在的命令。
+// LocalIPPool据此走现有的"创建失败记录一条警告但不阻塞"路径，自动退化为仅使用静态IPv4地址。
+type unsupportedAddressManager struct{}
+
+var defaultAddressManager addressManager = unsupportedAddressManager{}
+
+func (unsupportedAddressManager) AddAddress(iface string, ip net.IP) error {
+	return fmt.Errorf("当前平台不支持IPv6地址动态管理")
+}
+
+func (unsupportedAddressManager) DeleteAddress(iface string, ip net.IP) error {
+	return fmt.Errorf("当前平台不支持IPv6地址动态管理")
+}
+
+func (unsupportedAddressManager) ListAddresses(iface string) ([]net.IP, error) {
+	return nil, fmt.Errorf("当前平台不支持IPv6地址动态管理")
+}
@@ -0,0 +1,52 @@
+//go:build windows
+
+package src
+
+import (
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+)
+
+// windowsAddressManager 是addressManager在Windows上的实现，用netsh代替Linux上的iproute2。
+// 这条路径在原生CI环境里未经真实网络环境验证，格式以`netsh interface ipv6 show address`的
+// 标准输出为准，如遇系统本地化导致的文案差异，ListAddresses的解析需要相应调整。
+type windowsAddressManager struct{}
+
+var defaultAddressManager addressManager = windowsAddressManager{}
+
+func (windowsAddressManager) AddAddress(iface string, ip net.IP) error {
+	cmd := exec.Command("netsh", "interface", "ipv6", "add", "address", iface, ip.String())
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("netsh add address失败: %w（%s）", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (windowsAddressManager) DeleteAddress(iface string, ip net.IP) error {
+	cmd := exec.Command("netsh", "interface", "ipv6", "delete", "address", iface, ip.String())
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("netsh delete address失败: %w（%s）", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (windowsAddressManager) ListAddresses(iface string) ([]net.IP, error) {
+	cmd := exec.Command("netsh", "interface", "ipv6", "show", "address", iface)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("netsh show address失败: %w", err)
+	}
+
+	var addrs []net.IP
+	for _, line := range strings.Split(string(output), "\n") {
+		for _, field := range strings.Fields(line) {
+			field = strings.TrimSuffix(field, "%"+iface)
+			if ip := net.ParseIP(field); ip != nil && ip.To4() == nil {
+				addrs = append(addrs, ip)
+			}
+		}
+	}
+	return addrs, nil
+}
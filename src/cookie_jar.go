@@ -0,0 +1,121 @@
+package src
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// CookieJar 按域名聚合存储UTlsClient.Do收到的Set-Cookie，并在同一域名后续请求时原样回放，
+// 满足部分反爬系统要求"同一会话内请求必须携带一致Cookie"的校验。没有直接用标准库
+// net/http/cookiejar，是因为它面向的是浏览器多站点场景的Domain/Path/子域匹配规则，
+// 而这里每个UTlsRequest本来就只携带一个Domain，额外引入那套匹配逻辑只会增加复杂度、
+// 不会带来实际收益——按Domain做一个简单的map就足够了，这与PoolManager按Domain
+// 聚合连接池是同一种取舍。
+type CookieJar struct {
+	mu      sync.RWMutex
+	cookies map[string]map[string]*http.Cookie // domain -> cookie名 -> cookie
+	// path非空时，Save会把当前内容写回这个文件，NewPersistentCookieJar构造时也会先从
+	// 这个文件加载一次，使Cookie能跨进程重启复用，免去反爬系统为每次重启后的首个请求
+	// 重新走一遍登录/质询流程。
+	path string
+}
+
+// NewCookieJar 创建一个仅保存在内存中的CookieJar，不具备持久化能力
+func NewCookieJar() *CookieJar {
+	return &CookieJar{cookies: make(map[string]map[string]*http.Cookie)}
+}
+
+// NewPersistentCookieJar 创建一个CookieJar并尝试从path加载上次退出时保存的内容；
+// path不存在时视为空jar而不是错误，与persistentTaskQueue首次启动时WAL文件不存在的处理方式一致
+func NewPersistentCookieJar(path string) (*CookieJar, error) {
+	jar := &CookieJar{cookies: make(map[string]map[string]*http.Cookie), path: path}
+	if path == "" {
+		return jar, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return jar, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取Cookie jar文件失败: %w", err)
+	}
+	if len(data) == 0 {
+		return jar, nil
+	}
+	if err := json.Unmarshal(data, &jar.cookies); err != nil {
+		return nil, fmt.Errorf("解析Cookie jar文件失败: %w", err)
+	}
+	return jar, nil
+}
+
+// StoreFromHeader 从一次响应的Header中提取Set-Cookie，合并进domain对应的集合；
+// 同名Cookie以最新一次响应为准，MaxAge<0（或Expires已过期）的Cookie按惯例直接删除而不是存空值
+func (j *CookieJar) StoreFromHeader(domain string, header http.Header) {
+	if j == nil || domain == "" {
+		return
+	}
+	resp := http.Response{Header: header}
+	cookies := resp.Cookies()
+	if len(cookies) == 0 {
+		return
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	bucket, ok := j.cookies[domain]
+	if !ok {
+		bucket = make(map[string]*http.Cookie)
+		j.cookies[domain] = bucket
+	}
+	for _, cookie := range cookies {
+		if cookie.MaxAge < 0 {
+			delete(bucket, cookie.Name)
+			continue
+		}
+		bucket[cookie.Name] = cookie
+	}
+}
+
+// CookieHeader 把domain当前持有的所有Cookie拼成一个可直接塞进请求头的Cookie值，
+// 没有Cookie时返回空字符串，调用方据此判断是否需要设置该头部
+func (j *CookieJar) CookieHeader(domain string) string {
+	if j == nil || domain == "" {
+		return ""
+	}
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	bucket, ok := j.cookies[domain]
+	if !ok || len(bucket) == 0 {
+		return ""
+	}
+
+	header := ""
+	for _, cookie := range bucket {
+		if header != "" {
+			header += "; "
+		}
+		header += cookie.Name + "=" + cookie.Value
+	}
+	return header
+}
+
+// Save 把当前所有Cookie写回构造时指定的path，path为空（即NewCookieJar创建的内存jar）时直接跳过
+func (j *CookieJar) Save() error {
+	if j == nil || j.path == "" {
+		return nil
+	}
+	j.mu.RLock()
+	data, err := json.Marshal(j.cookies)
+	j.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("序列化Cookie jar失败: %w", err)
+	}
+	if err := os.WriteFile(j.path, data, 0644); err != nil {
+		return fmt.Errorf("写入Cookie jar文件失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+//go:build linux
+
+package src
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// freebindControl 是net.Dialer.Control回调，在connect(2)之前对套接字设置
+// IP_FREEBIND/IPV6_FREEBIND，使内核允许绑定一个当前并未配置在任何网卡上的本地地址。
+// 这样LocalIPPool就不必再为每个要用的IPv6地址先ensureIPv6AddressCreated、
+// 用完后再走cleanupOldIPv6Addresses把它从网卡上删掉——只要地址落在路由可达的/64内，
+// 直接绑定、直接丢弃即可，省掉整套创建/清理机制。仅Linux支持这两个socket选项，
+// 其余平台见freebind_other.go。
+func freebindControl(network, address string, c syscall.RawConn) error {
+	var sockErr error
+	ctrlErr := c.Control(func(fd uintptr) {
+		switch network {
+		case "tcp6", "udp6", "ip6":
+			sockErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IPV6, unix.IPV6_FREEBIND, 1)
+		default:
+			sockErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IP, unix.IP_FREEBIND, 1)
+		}
+	})
+	if ctrlErr != nil {
+		return ctrlErr
+	}
+	return sockErr
+}
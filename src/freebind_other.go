@@ -0,0 +1,11 @@
+//go:build !linux
+
+package src
+
+import "syscall"
+
+// freebindControl 在非Linux平台上没有IP_FREEBIND/IPV6_FREEBIND等价物，保留原有的
+// "先在网卡上创建地址、再绑定"行为（即什么都不做），见freebind_linux.go。
+func freebindControl(network, address string, c syscall.RawConn) error {
+	return nil
+}
@@ -0,0 +1,23 @@
+//go:build linux
+
+package src
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// reuseAddrControl是net.Dialer.Control回调，在connect(2)之前给套接字设置SO_REUSEADDR，
+// 使得SourcePortRange（见sourceport.go）里随机选中的端口即使还处于TIME_WAIT，也能被立即
+// 复用，而不必等它自然超时释放——这正是短连接量大、临时端口容易被占满场景下需要的行为。
+func reuseAddrControl(network, address string, c syscall.RawConn) error {
+	var sockErr error
+	ctrlErr := c.Control(func(fd uintptr) {
+		sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEADDR, 1)
+	})
+	if ctrlErr != nil {
+		return ctrlErr
+	}
+	return sockErr
+}
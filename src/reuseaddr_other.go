@@ -0,0 +1,11 @@
+//go:build !linux
+
+package src
+
+import "syscall"
+
+// reuseAddrControl在非Linux平台上没有实现，SourcePortRange（见sourceport.go）仍然生效，
+// 只是重试时遇到TIME_WAIT中的端口会照常失败并换下一个端口，而不会强制复用。
+func reuseAddrControl(network, address string, c syscall.RawConn) error {
+	return nil
+}
@@ -0,0 +1,49 @@
+//go:build linux
+
+package src
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// socketMarkControl返回一个net.Dialer.Control回调，在connect(2)之前对套接字设置SO_MARK，
+// 供拥有多条上联线路的宿主机按fwmark把爬虫流量引到指定上联（配合ip rule/ip route做策略路由），
+// 不必再为这一份流量专门写iptables标记规则。mark<=0时调用方不应该走到这里，
+// 见DomainConnPoolConfig.SocketMark。仅Linux支持SO_MARK，其余平台见sockopts_other.go。
+func socketMarkControl(mark int) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		var sockErr error
+		ctrlErr := c.Control(func(fd uintptr) {
+			sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_MARK, mark)
+		})
+		if ctrlErr != nil {
+			return ctrlErr
+		}
+		return sockErr
+	}
+}
+
+// dscpControl返回一个net.Dialer.Control回调，在connect(2)之前把DSCP值写入IPv4的IP_TOS
+// 或IPv6的IPV6_TCLASS，使中间路由设备可以按DSCP做QoS分级。DSCP占用TOS/TCLASS字节的高6位，
+// 底2位是ECN，留给内核/对端协商，这里固定填0，所以要左移2位再写入。dscp取值范围是0-63，
+// 调用方（见DomainConnPoolConfig.DSCP）负责保证<=0时不设置这个Control。
+func dscpControl(dscp int) func(network, address string, c syscall.RawConn) error {
+	tos := dscp << 2
+	return func(network, address string, c syscall.RawConn) error {
+		var sockErr error
+		ctrlErr := c.Control(func(fd uintptr) {
+			switch network {
+			case "tcp6", "udp6", "ip6":
+				sockErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IPV6, unix.IPV6_TCLASS, tos)
+			default:
+				sockErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IP, unix.IP_TOS, tos)
+			}
+		})
+		if ctrlErr != nil {
+			return ctrlErr
+		}
+		return sockErr
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+
+package src
+
+import "syscall"
+
+// socketMarkControl在非Linux平台上没有SO_MARK等价物，返回一个no-op Control，
+// 见sockopts_linux.go。SocketMark配置在这些平台上会被静默忽略。
+func socketMarkControl(mark int) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		return nil
+	}
+}
+
+// dscpControl在非Linux平台上没有实现，返回一个no-op Control，见sockopts_linux.go。
+// DSCP配置在这些平台上会被静默忽略。
+func dscpControl(dscp int) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		return nil
+	}
+}
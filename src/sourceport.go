@@ -0,0 +1,82 @@
+package src
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"syscall"
+)
+
+// sourcePortMaxAttempts 是在SourcePortRange区间内重试拨号的最大次数，超过后放弃并把最后一次
+// 的错误返回给调用方，交由上层既有的失败处理逻辑（标记IP未使用、回退不绑本地IP等）接管。
+const sourcePortMaxAttempts = 5
+
+// sourcePortRangeValid 判断r是否指定了一个非空的源端口区间；[0,0]表示沿用系统自动分配。
+func sourcePortRangeValid(r [2]int) bool {
+	return r[0] > 0 && r[1] >= r[0]
+}
+
+// pickSourcePort 在r指定的闭区间内随机选一个端口，让并发拨号尽量分散到不同端口，
+// 而不是让内核按固定顺序从临时端口区间分配，缓解短连接量大时端口被瞬时复用或耗尽的问题。
+func pickSourcePort(r [2]int) int {
+	return r[0] + rand.Intn(r[1]-r[0]+1)
+}
+
+// withPort返回一个与addr类型相同、但端口换成port的net.Addr。目前只需要覆盖
+// net.Dialer.LocalAddr实际会用到的*net.TCPAddr（含nil，即原本未绑定本地IP）两种情况。
+func withPort(addr net.Addr, port int) (net.Addr, error) {
+	switch a := addr.(type) {
+	case nil:
+		return &net.TCPAddr{Port: port}, nil
+	case *net.TCPAddr:
+		return &net.TCPAddr{IP: a.IP, Port: port, Zone: a.Zone}, nil
+	default:
+		return nil, fmt.Errorf("SourcePortRange不支持的本地地址类型: %T", addr)
+	}
+}
+
+// chainControl依次调用a、b两个net.Dialer.Control回调，其中任意一个为nil时直接用另一个；
+// 用于freebindControl和reuseAddrControl需要同时生效的场景（见dialWithSourcePortRange）。
+func chainControl(a, b func(network, address string, c syscall.RawConn) error) func(string, string, syscall.RawConn) error {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	return func(network, address string, c syscall.RawConn) error {
+		if err := a(network, address, c); err != nil {
+			return err
+		}
+		return b(network, address, c)
+	}
+}
+
+// dialWithSourcePortRange按r指定的端口区间拨号：区间为空（[0,0]）时等价于直接dialer.Dial，
+// 保持原有"让系统自动分配临时端口"的行为；否则每次随机挑一个端口、设置SO_REUSEADDR
+// （仅Linux有实现，见reuseaddr_linux.go/reuseaddr_other.go），拨号失败就换一个端口重试，
+// 直到成功或用尽sourcePortMaxAttempts次。dialer.Control如果已经设置了（比如Freebind），
+// 会通过chainControl和reuseAddrControl一起生效，而不是互相覆盖。
+func dialWithSourcePortRange(dialer net.Dialer, network, address string, r [2]int) (net.Conn, error) {
+	if !sourcePortRangeValid(r) {
+		return dialer.Dial(network, address)
+	}
+
+	dialer.Control = chainControl(dialer.Control, reuseAddrControl)
+
+	var lastErr error
+	for attempt := 0; attempt < sourcePortMaxAttempts; attempt++ {
+		localAddr, err := withPort(dialer.LocalAddr, pickSourcePort(r))
+		if err != nil {
+			return nil, err
+		}
+		dialer.LocalAddr = localAddr
+
+		conn, err := dialer.Dial(network, address)
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, fmt.Errorf("在源端口区间[%d,%d]内拨号%d次均失败: %w", r[0], r[1], sourcePortMaxAttempts, lastErr)
+}
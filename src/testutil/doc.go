@@ -0,0 +1,14 @@
+// Package testutil为热连接池（src.DomainConnPool）、UTlsClient以及依赖它们的执行器
+// （cmd/Crawler的任务处理逻辑）提供确定性的集成测试替身：FakeUpstream是一个进程内TLS
+// 监听器，可以配置按请求返回的状态码、延迟和"IP被封"行为；FakeDomainMonitor/FakeIPPool
+// 分别是src.DomainMonitor/src.IPPool的内存实现，不依赖真实DNS解析或本地网卡地址。
+//
+// 这三者组合起来，调用方可以在不联网的情况下搭出一条完整的请求路径：
+// FakeDomainMonitor把域名解析到FakeUpstream监听的回环地址，FakeIPPool提供本地源地址，
+// src.NewDomainHotConnPool用这两者加上指向FakeUpstream的Domain/Port建池，最终UTlsClient.Do
+// 打到的就是FakeUpstream，可以按测试场景随时调整它的响应行为。
+//
+// test/目录下已有的mockDomainMonitor/mockIPPool是更早、只覆盖单个测试文件需要的最小实现，
+// 本包不替换它们，只是给需要更完整行为（尤其是真实TLS握手）的集成测试提供一个跨测试文件
+// 可复用、并且实现了完整接口的版本。
+package testutil
@@ -0,0 +1,101 @@
+package testutil
+
+import (
+	"sync"
+	"time"
+
+	"utlsProxy/src"
+)
+
+// FakeDomainMonitor是src.DomainMonitor的确定性测试替身：域名->IP的映射完全由测试通过
+// SetDomainPool/InjectStaticIPs直接灌入，不做任何真实DNS查询，Start/Stop只记录状态，
+// 供测试断言连接池确实在正确的时机启动/停止了监视器。
+type FakeDomainMonitor struct {
+	mu      sync.RWMutex
+	pools   map[string]map[string][]src.IPRecord
+	started bool
+}
+
+var _ src.DomainMonitor = (*FakeDomainMonitor)(nil) // 编译时接口实现检查
+
+// NewFakeDomainMonitor创建一个空的FakeDomainMonitor，调用方随后用SetDomainPool灌入数据。
+func NewFakeDomainMonitor() *FakeDomainMonitor {
+	return &FakeDomainMonitor{
+		pools: make(map[string]map[string][]src.IPRecord),
+	}
+}
+
+// Start实现src.DomainMonitor接口，只记录已启动，不做任何后台工作。
+func (m *FakeDomainMonitor) Start() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.started = true
+}
+
+// Stop实现src.DomainMonitor接口。
+func (m *FakeDomainMonitor) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.started = false
+}
+
+// Started返回Start是否已经被调用且尚未Stop，供测试断言连接池生命周期管理是否正确。
+func (m *FakeDomainMonitor) Started() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.started
+}
+
+// SetDomainPool直接设置domain的ipv4/ipv6地址列表（不带IPInfo富化数据），覆盖之前的设置，
+// 是大多数测试场景唯一需要调用的方法。
+func (m *FakeDomainMonitor) SetDomainPool(domain string, ipv4, ipv6 []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pools[domain] = map[string][]src.IPRecord{
+		"ipv4": toIPRecords(ipv4, ""),
+		"ipv6": toIPRecords(ipv6, ""),
+	}
+}
+
+// GetDomainPool实现src.DomainMonitor接口，返回深拷贝，domain不存在时ok为false。
+func (m *FakeDomainMonitor) GetDomainPool(domain string) (map[string][]src.IPRecord, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	pool, ok := m.pools[domain]
+	if !ok {
+		return nil, false
+	}
+	copied := make(map[string][]src.IPRecord, len(pool))
+	for key, records := range pool {
+		copiedRecords := make([]src.IPRecord, len(records))
+		copy(copiedRecords, records)
+		copied[key] = copiedRecords
+	}
+	return copied, true
+}
+
+// InjectStaticIPs实现src.DomainMonitor接口，把ips追加到domain现有的ipv4列表里，
+// Source标记为"manual"，与RemoteDomainIPPool里InjectStaticIPs的真实实现保持同样的语义。
+func (m *FakeDomainMonitor) InjectStaticIPs(domain string, ips []string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pool, ok := m.pools[domain]
+	if !ok {
+		pool = map[string][]src.IPRecord{"ipv4": nil, "ipv6": nil}
+	}
+	records := toIPRecords(ips, "manual")
+	for i := range records {
+		records[i].ExpiresAt = expiresAt
+	}
+	pool["ipv4"] = append(pool["ipv4"], records...)
+	m.pools[domain] = pool
+	return nil
+}
+
+func toIPRecords(ips []string, source string) []src.IPRecord {
+	records := make([]src.IPRecord, len(ips))
+	for i, ip := range ips {
+		records[i] = src.IPRecord{IP: ip, Source: source}
+	}
+	return records
+}
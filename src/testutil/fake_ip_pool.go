@@ -0,0 +1,110 @@
+package testutil
+
+import (
+	"net"
+	"sync"
+
+	"utlsProxy/src"
+)
+
+// FakeIPPool是src.IPPool的确定性测试替身：GetIP按构造时给定的IP列表循环返回，不做任何
+// 真实的本地地址生成/绑定；ReleaseIP/MarkIPUnused/SetTargetIPCount只记录调用参数供测试
+// 用对应的访问器断言，不产生任何副作用。
+type FakeIPPool struct {
+	mu sync.Mutex
+
+	ips    []net.IP
+	next   int
+	closed bool
+
+	released     []net.IP
+	markedUnused []net.IP
+	targetCount  int
+}
+
+var _ src.IPPool = (*FakeIPPool)(nil) // 编译时接口实现检查
+
+// NewFakeIPPool用ipStrings构造一个FakeIPPool，解析失败的字符串会被直接跳过。
+func NewFakeIPPool(ipStrings ...string) *FakeIPPool {
+	pool := &FakeIPPool{}
+	for _, s := range ipStrings {
+		if ip := net.ParseIP(s); ip != nil {
+			pool.ips = append(pool.ips, ip)
+		}
+	}
+	return pool
+}
+
+// GetIP实现src.IPPool接口，按构造顺序循环返回；没有任何IP时返回nil。
+func (p *FakeIPPool) GetIP() net.IP {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.ips) == 0 {
+		return nil
+	}
+	ip := p.ips[p.next%len(p.ips)]
+	p.next++
+	return ip
+}
+
+// ReleaseIP实现src.IPPool接口，只记录被释放的IP。
+func (p *FakeIPPool) ReleaseIP(ip net.IP) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.released = append(p.released, ip)
+}
+
+// MarkIPUnused实现src.IPPool接口，只记录被标记未使用的IP。
+func (p *FakeIPPool) MarkIPUnused(ip net.IP) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.markedUnused = append(p.markedUnused, ip)
+}
+
+// SetTargetIPCount实现src.IPPool接口，只记录最近一次设置的目标数量。
+func (p *FakeIPPool) SetTargetIPCount(count int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.targetCount = count
+}
+
+// Stats实现src.IPPool接口，FakeIPPool不维护真实的动态地址统计，恒为零值。
+func (p *FakeIPPool) Stats() src.IPPoolStats {
+	return src.IPPoolStats{}
+}
+
+// Close实现src.IPPool接口（经io.Closer嵌入）。
+func (p *FakeIPPool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	return nil
+}
+
+// Closed返回Close是否已经被调用过，供测试断言连接池关闭时一并关闭了本地IP池。
+func (p *FakeIPPool) Closed() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.closed
+}
+
+// ReleasedIPs返回迄今为止ReleaseIP收到的所有IP，按调用顺序排列。
+func (p *FakeIPPool) ReleasedIPs() []net.IP {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]net.IP(nil), p.released...)
+}
+
+// MarkedUnusedIPs返回迄今为止MarkIPUnused收到的所有IP，按调用顺序排列。
+func (p *FakeIPPool) MarkedUnusedIPs() []net.IP {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]net.IP(nil), p.markedUnused...)
+}
+
+// TargetIPCount返回最近一次SetTargetIPCount收到的值，从未调用过时为0。
+func (p *FakeIPPool) TargetIPCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.targetCount
+}
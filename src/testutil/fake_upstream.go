@@ -0,0 +1,101 @@
+package testutil
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+)
+
+// UpstreamBehavior描述FakeUpstream对收到的请求应该怎么响应。
+type UpstreamBehavior struct {
+	// StatusCode是正常情况下（未触发BanAfterRequests）返回的HTTP状态码，<=0时默认200。
+	StatusCode int
+	// Latency是处理每个请求前人为引入的延迟，用于测试超时/慢连接场景，<=0表示不延迟。
+	Latency time.Duration
+	// BanAfterRequests大于0时，同一个来源IP的请求数超过这个阈值后，后续请求一律返回
+	// BanStatusCode，模拟这个IP被目标站点封禁；<=0（默认）表示永不封禁。
+	BanAfterRequests int
+	// BanStatusCode是触发BanAfterRequests后返回的状态码，<=0时默认403（与连接池
+	// ReturnConn未配置StatusPolicy时把403当成封禁信号的默认行为保持一致，见src/StatusPolicy.go）。
+	BanStatusCode int
+}
+
+// FakeUpstream是一个进程内的TLS HTTP服务器，专供集成测试当作connection pool/UTlsClient
+// 的拨号目标：证书是httptest自动生成的自签名证书，不需要调用方准备证书文件；响应行为由
+// UpstreamBehavior描述，可以随时用SetBehavior在一次测试中间切换（比如先模拟被封再模拟恢复）。
+type FakeUpstream struct {
+	server *httptest.Server
+
+	mu            sync.Mutex
+	behavior      UpstreamBehavior
+	requestCounts map[string]int
+}
+
+// NewFakeUpstream启动一个带初始behavior的FakeUpstream，调用方用完后必须调用Close
+// 释放监听的端口。
+func NewFakeUpstream(behavior UpstreamBehavior) *FakeUpstream {
+	u := &FakeUpstream{
+		behavior:      behavior,
+		requestCounts: make(map[string]int),
+	}
+	u.server = httptest.NewUnstartedServer(http.HandlerFunc(u.handle))
+	u.server.StartTLS()
+	return u
+}
+
+func (u *FakeUpstream) handle(w http.ResponseWriter, r *http.Request) {
+	u.mu.Lock()
+	behavior := u.behavior
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	u.requestCounts[host]++
+	count := u.requestCounts[host]
+	u.mu.Unlock()
+
+	if behavior.Latency > 0 {
+		time.Sleep(behavior.Latency)
+	}
+
+	statusCode := behavior.StatusCode
+	if statusCode <= 0 {
+		statusCode = http.StatusOK
+	}
+	if behavior.BanAfterRequests > 0 && count > behavior.BanAfterRequests {
+		banStatus := behavior.BanStatusCode
+		if banStatus <= 0 {
+			banStatus = http.StatusForbidden
+		}
+		statusCode = banStatus
+	}
+	w.WriteHeader(statusCode)
+}
+
+// SetBehavior线程安全地替换后续请求要遵循的行为，已经在飞行中的请求不受影响。
+func (u *FakeUpstream) SetBehavior(behavior UpstreamBehavior) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.behavior = behavior
+}
+
+// Addr返回形如"127.0.0.1:端口"的监听地址，调用方据此设置
+// src.DomainConnPoolConfig.Domain/Port或直接拨号。
+func (u *FakeUpstream) Addr() string {
+	return u.server.Listener.Addr().String()
+}
+
+// RequestCount返回截至目前来自ip（不含端口）的请求次数，供测试断言BanAfterRequests
+// 确实在预期的那次请求开始生效。
+func (u *FakeUpstream) RequestCount(ip string) int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.requestCounts[ip]
+}
+
+// Close关闭监听并等待所有处理中的请求结束。
+func (u *FakeUpstream) Close() {
+	u.server.Close()
+}
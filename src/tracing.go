@@ -0,0 +1,34 @@
+package src
+
+import (
+	"context"
+	"sync/atomic"
+
+	"utlsProxy/internal/tracing"
+)
+
+// TracingEnabled和tracingServiceName是UTlsClient.Do、domainConnPool.GetConn/createConnection
+// 记录span时用到的全局开关，由cmd/Crawler在启动时和SIGHUP热重载（见config_reload.go）时
+// 写入——这一层（src包）不直接依赖*Crawler或config.Config，包级原子变量是让这两处调用点
+// 不用额外改签名/不用把*Crawler一路传进连接池内部的最小接入方式。
+var (
+	TracingEnabled     atomic.Bool
+	tracingServiceName atomic.Value
+)
+
+// SetTracingConfig设置/更新全局追踪开关和服务名，serviceName为空时保留上一次的值
+// （避免SIGHUP热重载时config里忘了填TracingServiceName而把已经生效的服务名清空）。
+func SetTracingConfig(enabled bool, serviceName string) {
+	TracingEnabled.Store(enabled)
+	if serviceName != "" {
+		tracingServiceName.Store(serviceName)
+	}
+}
+
+// startSpan是internal/tracing.StartSpan的包内瘦封装，自动注入当前的全局追踪开关/服务名，
+// 返回的span总是非nil，未启用追踪时span.End()直接跳过。
+func startSpan(name string) (context.Context, *tracing.Span) {
+	serviceName, _ := tracingServiceName.Load().(string)
+	ctx := tracing.WithTracer(context.Background(), TracingEnabled.Load(), serviceName)
+	return tracing.StartSpan(ctx, name)
+}
@@ -0,0 +1,122 @@
+package test
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"utlsProxy/src"
+)
+
+// TestCookieJarStoreAndReplay 测试CookieJar能把响应Header里的Set-Cookie存下来，
+// 并在CookieHeader中按同一域名原样回放
+func TestCookieJarStoreAndReplay(t *testing.T) {
+	jar := src.NewCookieJar()
+
+	t.Run("空jar没有Cookie可回放", func(t *testing.T) {
+		if got := jar.CookieHeader("example.com"); got != "" {
+			t.Errorf("期望空字符串，实际得到 %q", got)
+		}
+	})
+
+	t.Run("存入Set-Cookie后可回放", func(t *testing.T) {
+		header := http.Header{}
+		header.Add("Set-Cookie", "session=abc123; Path=/")
+		jar.StoreFromHeader("example.com", header)
+
+		got := jar.CookieHeader("example.com")
+		if got != "session=abc123" {
+			t.Errorf("期望 session=abc123，实际得到 %q", got)
+		}
+	})
+
+	t.Run("不同域名互不影响", func(t *testing.T) {
+		if got := jar.CookieHeader("other.com"); got != "" {
+			t.Errorf("other.com不应该有example.com的Cookie，实际得到 %q", got)
+		}
+	})
+
+	t.Run("同名Cookie以最新一次响应为准", func(t *testing.T) {
+		header := http.Header{}
+		header.Add("Set-Cookie", "session=updated; Path=/")
+		jar.StoreFromHeader("example.com", header)
+
+		got := jar.CookieHeader("example.com")
+		if got != "session=updated" {
+			t.Errorf("期望 session=updated，实际得到 %q", got)
+		}
+	})
+
+	t.Run("MaxAge小于0的Cookie被删除", func(t *testing.T) {
+		header := http.Header{}
+		header.Add("Set-Cookie", "session=updated; Max-Age=-1")
+		jar.StoreFromHeader("example.com", header)
+
+		if got := jar.CookieHeader("example.com"); got != "" {
+			t.Errorf("被删除的Cookie不应该再被回放，实际得到 %q", got)
+		}
+	})
+}
+
+// TestCookieJarNilSafe 测试nil *CookieJar上调用方法不panic，
+// 这样UTlsClient.CookieJar为默认零值nil时调用方无需额外判空
+func TestCookieJarNilSafe(t *testing.T) {
+	var jar *src.CookieJar
+
+	if got := jar.CookieHeader("example.com"); got != "" {
+		t.Errorf("nil jar应该返回空字符串，实际得到 %q", got)
+	}
+
+	// 不应panic
+	jar.StoreFromHeader("example.com", http.Header{"Set-Cookie": {"a=b"}})
+
+	if err := jar.Save(); err != nil {
+		t.Errorf("nil jar的Save不应该返回错误: %v", err)
+	}
+}
+
+// TestPersistentCookieJar 测试CookieJar能把内容持久化到文件，并在重新加载后恢复
+func TestPersistentCookieJar(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cookie_jar.json")
+
+	jar, err := src.NewPersistentCookieJar(path)
+	if err != nil {
+		t.Fatalf("创建Cookie jar失败: %v", err)
+	}
+
+	header := http.Header{}
+	header.Add("Set-Cookie", "session=persisted")
+	jar.StoreFromHeader("example.com", header)
+
+	if err := jar.Save(); err != nil {
+		t.Fatalf("保存Cookie jar失败: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("期望Cookie jar文件已写入: %v", err)
+	}
+
+	reloaded, err := src.NewPersistentCookieJar(path)
+	if err != nil {
+		t.Fatalf("重新加载Cookie jar失败: %v", err)
+	}
+	if got := reloaded.CookieHeader("example.com"); got != "session=persisted" {
+		t.Errorf("期望重新加载后得到 session=persisted，实际得到 %q", got)
+	}
+}
+
+// TestPersistentCookieJarMissingFile 测试path指向的文件不存在时当作空jar处理，而不是报错，
+// 与首次启动、尚未落盘过任何Cookie的场景对应
+func TestPersistentCookieJarMissingFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "does-not-exist.json")
+
+	jar, err := src.NewPersistentCookieJar(path)
+	if err != nil {
+		t.Fatalf("文件不存在时不应该返回错误: %v", err)
+	}
+	if got := jar.CookieHeader("example.com"); got != "" {
+		t.Errorf("期望空jar，实际得到 %q", got)
+	}
+}
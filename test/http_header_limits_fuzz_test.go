@@ -0,0 +1,84 @@
+package test
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"utlsProxy/src"
+)
+
+// TestReadHTTPHeaderLimitedEnforcesLimits 覆盖三项限制各自的基本触发场景：状态行过长、
+// 响应头总字节数过大、响应头字段行数过多，均应返回*src.HTTPLimitExceededError而不是
+// 把畸形输入一路喂给http.ReadResponse。
+func TestReadHTTPHeaderLimitedEnforcesLimits(t *testing.T) {
+	t.Run("status_line", func(t *testing.T) {
+		raw := "HTTP/1.1 200 " + strings.Repeat("A", 100) + "\r\n\r\n"
+		_, err := src.ReadHTTPHeaderLimited(strings.NewReader(raw), 16, 1<<20, 200)
+		assertLimitExceeded(t, err, "status_line")
+	})
+
+	t.Run("header_bytes", func(t *testing.T) {
+		raw := "HTTP/1.1 200 OK\r\nX-Big: " + strings.Repeat("A", 1000) + "\r\n\r\n"
+		_, err := src.ReadHTTPHeaderLimited(strings.NewReader(raw), 8*1024, 64, 200)
+		assertLimitExceeded(t, err, "header_bytes")
+	})
+
+	t.Run("header_count", func(t *testing.T) {
+		var b strings.Builder
+		b.WriteString("HTTP/1.1 200 OK\r\n")
+		for i := 0; i < 10; i++ {
+			b.WriteString("X-Field: value\r\n")
+		}
+		b.WriteString("\r\n")
+		_, err := src.ReadHTTPHeaderLimited(strings.NewReader(b.String()), 8*1024, 1<<20, 3)
+		assertLimitExceeded(t, err, "header_count")
+	})
+}
+
+// TestReadHTTPHeaderLimitedPassesThroughBody 确认头部限制通过后，响应体不受这三项限制
+// 影响，即便响应体比响应头限制本身还大。
+func TestReadHTTPHeaderLimitedPassesThroughBody(t *testing.T) {
+	body := strings.Repeat("B", 4096)
+	raw := "HTTP/1.1 200 OK\r\nContent-Length: " + strconv.Itoa(len(body)) + "\r\n\r\n" + body
+	resp, err := src.ReadHTTPHeaderLimited(strings.NewReader(raw), 64, 64, 10)
+	if err != nil {
+		t.Fatalf("头部在限制内时不应该报错: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		t.Errorf("期望状态码200，实际得到%d", resp.StatusCode)
+	}
+}
+
+func assertLimitExceeded(t *testing.T, err error, wantLimit string) {
+	t.Helper()
+	if err == nil {
+		t.Fatal("期望返回错误，实际没有错误")
+	}
+	var limitErr *src.HTTPLimitExceededError
+	if !errors.As(err, &limitErr) {
+		t.Fatalf("期望*src.HTTPLimitExceededError，实际得到: %v", err)
+	}
+	if limitErr.Limit != wantLimit {
+		t.Errorf("期望触发%s限制，实际触发了%s", wantLimit, limitErr.Limit)
+	}
+}
+
+// FuzzReadHTTPHeaderLimited 用随机字节流驱动响应头解析路径，目标是确认
+// headerSizeLimitingReader在任意输入下都不会panic、死循环，一旦触发限制就老老实实返回
+// *src.HTTPLimitExceededError（或者http.ReadResponse自身的解析错误），不会返回损坏的响应。
+func FuzzReadHTTPHeaderLimited(f *testing.F) {
+	f.Add([]byte("HTTP/1.1 200 OK\r\nContent-Length: 0\r\n\r\n"))
+	f.Add([]byte("HTTP/1.1 200 OK\r\n\r\n"))
+	f.Add([]byte("garbage not even http"))
+	f.Add([]byte("HTTP/1.1 200 OK\r\n" + strings.Repeat("X-A: b\r\n", 500) + "\r\n"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		resp, err := src.ReadHTTPHeaderLimited(strings.NewReader(string(data)), 256, 4096, 20)
+		if err == nil {
+			resp.Body.Close()
+		}
+	})
+}
@@ -0,0 +1,64 @@
+package test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+	"utlsProxy/src"
+)
+
+// countingEnricher 是用于测试的IPEnricher，记录Enrich被调用的次数
+type countingEnricher struct {
+	calls int32
+}
+
+func (e *countingEnricher) Enrich(ip string) (*src.IPInfoResponse, error) {
+	atomic.AddInt32(&e.calls, 1)
+	return &src.IPInfoResponse{IP: ip}, nil
+}
+
+// TestNoopEnricherReturnsNil 测试NewNoopEnricher返回的IPEnricher不做任何查询
+func TestNoopEnricherReturnsNil(t *testing.T) {
+	enricher := src.NewNoopEnricher()
+	info, err := enricher.Enrich("1.1.1.1")
+	if err != nil {
+		t.Fatalf("noopEnricher不应该返回错误: %v", err)
+	}
+	if info != nil {
+		t.Error("noopEnricher查询结果应该始终为nil")
+	}
+}
+
+// TestCachingEnricherCachesWithinTTL 测试TTL内对同一IP的重复查询会命中缓存，不再调用内层Enricher
+func TestCachingEnricherCachesWithinTTL(t *testing.T) {
+	inner := &countingEnricher{}
+	cached := src.NewCachingEnricher(inner, time.Minute)
+
+	if _, err := cached.Enrich("8.8.8.8"); err != nil {
+		t.Fatalf("首次查询不应该出错: %v", err)
+	}
+	if _, err := cached.Enrich("8.8.8.8"); err != nil {
+		t.Fatalf("第二次查询不应该出错: %v", err)
+	}
+
+	if calls := atomic.LoadInt32(&inner.calls); calls != 1 {
+		t.Errorf("TTL内重复查询同一IP应该只调用内层Enricher一次，实际: %d", calls)
+	}
+}
+
+// TestCachingEnricherDisabledWhenTTLNotPositive 测试TTL<=0时不做缓存，直接透传到内层Enricher
+func TestCachingEnricherDisabledWhenTTLNotPositive(t *testing.T) {
+	inner := &countingEnricher{}
+	cached := src.NewCachingEnricher(inner, 0)
+
+	if _, err := cached.Enrich("8.8.4.4"); err != nil {
+		t.Fatalf("首次查询不应该出错: %v", err)
+	}
+	if _, err := cached.Enrich("8.8.4.4"); err != nil {
+		t.Fatalf("第二次查询不应该出错: %v", err)
+	}
+
+	if calls := atomic.LoadInt32(&inner.calls); calls != 2 {
+		t.Errorf("TTL<=0时每次查询都应该透传到内层Enricher，实际调用次数: %d", calls)
+	}
+}
@@ -0,0 +1,61 @@
+package test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"utlsProxy/src"
+)
+
+// TestExportIPRecordsJSON 测试ExportIPRecords的JSON导出分支
+func TestExportIPRecordsJSON(t *testing.T) {
+	records := []src.IPRecord{{IP: "1.2.3.4"}}
+
+	var buf bytes.Buffer
+	if err := src.ExportIPRecords(&buf, records, "json"); err != nil {
+		t.Fatalf("导出JSON失败: %v", err)
+	}
+	if !strings.Contains(buf.String(), "1.2.3.4") {
+		t.Errorf("导出的JSON应该包含IP地址，实际: %s", buf.String())
+	}
+}
+
+// TestExportIPRecordsCSV 测试ExportIPRecords的CSV导出分支，包括表头和IPInfo为nil时的留空处理
+func TestExportIPRecordsCSV(t *testing.T) {
+	records := []src.IPRecord{
+		{
+			IP: "1.2.3.4",
+			IPInfo: &src.IPInfoResponse{
+				CountryCode: "US",
+				Org:         "Test Org",
+				Anycast:     true,
+				AS:          &src.IPInfoAS{ASN: "AS15169"},
+			},
+		},
+		{IP: "5.6.7.8"}, // IPInfo为nil
+	}
+
+	var buf bytes.Buffer
+	if err := src.ExportIPRecords(&buf, records, "csv"); err != nil {
+		t.Fatalf("导出CSV失败: %v", err)
+	}
+
+	output := buf.String()
+	if !strings.HasPrefix(output, "ip,country,asn,org,anycast\n") {
+		t.Errorf("CSV表头不匹配，实际: %s", output)
+	}
+	if !strings.Contains(output, "1.2.3.4,US,AS15169,Test Org,true") {
+		t.Errorf("CSV应该包含完整的IP信息行，实际: %s", output)
+	}
+	if !strings.Contains(output, "5.6.7.8,,,,false") {
+		t.Errorf("IPInfo为nil时对应列应该留空，实际: %s", output)
+	}
+}
+
+// TestExportIPRecordsUnsupportedFormat 测试ExportIPRecords对不支持格式返回错误
+func TestExportIPRecordsUnsupportedFormat(t *testing.T) {
+	var buf bytes.Buffer
+	if err := src.ExportIPRecords(&buf, nil, "xml"); err == nil {
+		t.Error("不支持的格式应该返回错误")
+	}
+}
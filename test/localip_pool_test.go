@@ -156,3 +156,28 @@ func TestGenerateRandomIPInSubnet(t *testing.T) {
 	// 我们只能确保代码不会panic
 	t.Logf("生成随机IP测试完成")
 }
+
+// TestNewLocalIPPoolSeededIsReproducible 测试相同种子下多次创建的IP池产生相同的选择序列
+func TestNewLocalIPPoolSeededIsReproducible(t *testing.T) {
+	staticIPv4s := []string{"192.168.1.1", "192.168.1.2", "192.168.1.3", "192.168.1.4"}
+
+	poolA, err := src.NewLocalIPPoolSeeded(staticIPv4s, "", 42)
+	if err != nil {
+		t.Fatalf("创建LocalIPPool失败: %v", err)
+	}
+	defer poolA.Close()
+
+	poolB, err := src.NewLocalIPPoolSeeded(staticIPv4s, "", 42)
+	if err != nil {
+		t.Fatalf("创建LocalIPPool失败: %v", err)
+	}
+	defer poolB.Close()
+
+	for i := 0; i < 10; i++ {
+		ipA := poolA.GetIP()
+		ipB := poolB.GetIP()
+		if ipA.String() != ipB.String() {
+			t.Fatalf("相同种子下第%d次选择的IP不一致: %s != %s", i, ipA, ipB)
+		}
+	}
+}
@@ -183,3 +183,85 @@ func TestIPRecordStructure(t *testing.T) {
 		t.Errorf("IPInfo中的IP不匹配，期望: 192.168.1.1, 实际: %s", record.IPInfo.IP)
 	}
 }
+
+// TestRemoteIPMonitorImplementsResolverHealthProvider 测试RemoteIPMonitor实现了ResolverHealthProvider接口，
+// 且在尚未发起任何DNS查询时ResolverHealth返回空映射
+func TestRemoteIPMonitorImplementsResolverHealthProvider(t *testing.T) {
+	config := src.MonitorConfig{
+		Domains:        []string{"example.com"},
+		DNSServers:     []string{"8.8.8.8"},
+		IPInfoToken:    "test-token",
+		UpdateInterval: 2 * time.Minute,
+		StorageDir:     ".",
+		StorageFormat:  "json",
+	}
+
+	monitor, err := src.NewRemoteIPMonitor(config)
+	if err != nil {
+		t.Fatalf("创建RemoteIPMonitor失败: %v", err)
+	}
+
+	provider, ok := monitor.(src.ResolverHealthProvider)
+	if !ok {
+		t.Fatal("RemoteIPMonitor应该实现ResolverHealthProvider接口")
+	}
+
+	health := provider.ResolverHealth()
+	if len(health) != 0 {
+		t.Errorf("尚未发起任何DNS查询时ResolverHealth应该返回空映射，实际: %d 条", len(health))
+	}
+}
+
+// TestRemoteIPMonitorImplementsAliasProvider 测试RemoteIPMonitor实现了AliasProvider接口，
+// 且在尚未解析过该域名时GetDomainAliases返回false
+func TestRemoteIPMonitorImplementsAliasProvider(t *testing.T) {
+	config := src.MonitorConfig{
+		Domains:        []string{"example.com"},
+		DNSServers:     []string{"8.8.8.8"},
+		IPInfoToken:    "test-token",
+		UpdateInterval: 2 * time.Minute,
+		StorageDir:     ".",
+		StorageFormat:  "json",
+	}
+
+	monitor, err := src.NewRemoteIPMonitor(config)
+	if err != nil {
+		t.Fatalf("创建RemoteIPMonitor失败: %v", err)
+	}
+
+	provider, ok := monitor.(src.AliasProvider)
+	if !ok {
+		t.Fatal("RemoteIPMonitor应该实现AliasProvider接口")
+	}
+
+	if _, found := provider.GetDomainAliases("example.com"); found {
+		t.Error("尚未解析过该域名时GetDomainAliases应该返回false")
+	}
+}
+
+// TestRemoteIPMonitorImplementsIPPoolQuerier 测试RemoteIPMonitor实现了IPPoolQuerier接口，
+// 且对不存在的域名QueryDomainPool返回false
+func TestRemoteIPMonitorImplementsIPPoolQuerier(t *testing.T) {
+	config := src.MonitorConfig{
+		Domains:        []string{"example.com"},
+		DNSServers:     []string{"8.8.8.8"},
+		IPInfoToken:    "test-token",
+		UpdateInterval: 2 * time.Minute,
+		StorageDir:     ".",
+		StorageFormat:  "json",
+	}
+
+	monitor, err := src.NewRemoteIPMonitor(config)
+	if err != nil {
+		t.Fatalf("创建RemoteIPMonitor失败: %v", err)
+	}
+
+	querier, ok := monitor.(src.IPPoolQuerier)
+	if !ok {
+		t.Fatal("RemoteIPMonitor应该实现IPPoolQuerier接口")
+	}
+
+	if _, found := querier.QueryDomainPool("nonexistent.com", src.IPPoolFilter{}); found {
+		t.Error("不应该找到不存在的域名")
+	}
+}
@@ -0,0 +1,125 @@
+package test
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"utlsProxy/src/testutil"
+)
+
+// TestFakeUpstreamDefaultBehavior 测试FakeUpstream默认按配置的状态码响应
+func TestFakeUpstreamDefaultBehavior(t *testing.T) {
+	upstream := testutil.NewFakeUpstream(testutil.UpstreamBehavior{StatusCode: http.StatusOK})
+	defer upstream.Close()
+
+	resp, err := insecureHTTPClient().Get("https://" + upstream.Addr())
+	if err != nil {
+		t.Fatalf("请求FakeUpstream失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("期望状态码200，实际得到%d", resp.StatusCode)
+	}
+}
+
+// TestFakeUpstreamBanAfterRequests 测试FakeUpstream在超过BanAfterRequests次请求后
+// 改为返回BanStatusCode，模拟IP被封禁
+func TestFakeUpstreamBanAfterRequests(t *testing.T) {
+	upstream := testutil.NewFakeUpstream(testutil.UpstreamBehavior{
+		StatusCode:       http.StatusOK,
+		BanAfterRequests: 2,
+		BanStatusCode:    http.StatusForbidden,
+	})
+	defer upstream.Close()
+
+	client := insecureHTTPClient()
+	var lastStatus int
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get("https://" + upstream.Addr())
+		if err != nil {
+			t.Fatalf("第%d次请求失败: %v", i+1, err)
+		}
+		lastStatus = resp.StatusCode
+		resp.Body.Close()
+	}
+	if lastStatus != http.StatusForbidden {
+		t.Errorf("超过BanAfterRequests次数后期望返回403，实际得到%d", lastStatus)
+	}
+}
+
+// TestFakeIPPoolCyclesAndRecordsCalls 测试FakeIPPool按顺序循环返回IP，并记录
+// ReleaseIP/MarkIPUnused/SetTargetIPCount/Close的调用
+func TestFakeIPPoolCyclesAndRecordsCalls(t *testing.T) {
+	pool := testutil.NewFakeIPPool("192.168.1.1", "192.168.1.2")
+
+	first := pool.GetIP()
+	second := pool.GetIP()
+	third := pool.GetIP()
+	if !first.Equal(net.ParseIP("192.168.1.1")) || !second.Equal(net.ParseIP("192.168.1.2")) || !third.Equal(net.ParseIP("192.168.1.1")) {
+		t.Errorf("GetIP应该按顺序循环返回，实际得到%v, %v, %v", first, second, third)
+	}
+
+	pool.ReleaseIP(first)
+	pool.MarkIPUnused(second)
+	pool.SetTargetIPCount(5)
+
+	if len(pool.ReleasedIPs()) != 1 {
+		t.Errorf("ReleaseIP应该被记录一次，实际记录了%d次", len(pool.ReleasedIPs()))
+	}
+	if len(pool.MarkedUnusedIPs()) != 1 {
+		t.Errorf("MarkIPUnused应该被记录一次，实际记录了%d次", len(pool.MarkedUnusedIPs()))
+	}
+	if pool.TargetIPCount() != 5 {
+		t.Errorf("TargetIPCount应该是5，实际是%d", pool.TargetIPCount())
+	}
+
+	if err := pool.Close(); err != nil {
+		t.Fatalf("Close失败: %v", err)
+	}
+	if !pool.Closed() {
+		t.Error("Close后Closed()应该返回true")
+	}
+}
+
+// TestFakeDomainMonitorSetAndInject 测试FakeDomainMonitor的SetDomainPool/InjectStaticIPs/GetDomainPool
+func TestFakeDomainMonitorSetAndInject(t *testing.T) {
+	monitor := testutil.NewFakeDomainMonitor()
+	monitor.Start()
+	if !monitor.Started() {
+		t.Error("Start后Started()应该返回true")
+	}
+
+	monitor.SetDomainPool("test.com", []string{"1.1.1.1"}, []string{"2001:db8::1"})
+	if err := monitor.InjectStaticIPs("test.com", []string{"1.1.1.2"}, time.Time{}); err != nil {
+		t.Fatalf("InjectStaticIPs失败: %v", err)
+	}
+
+	pool, ok := monitor.GetDomainPool("test.com")
+	if !ok {
+		t.Fatal("GetDomainPool应该能找到test.com")
+	}
+	if len(pool["ipv4"]) != 2 {
+		t.Errorf("ipv4列表应该包含原始IP和注入的IP，共2个，实际得到%d个", len(pool["ipv4"]))
+	}
+	if len(pool["ipv6"]) != 1 {
+		t.Errorf("ipv6列表应该包含1个IP，实际得到%d个", len(pool["ipv6"]))
+	}
+
+	monitor.Stop()
+	if monitor.Started() {
+		t.Error("Stop后Started()应该返回false")
+	}
+}
+
+// insecureHTTPClient返回一个跳过证书校验的http.Client，专供测试里请求FakeUpstream的
+// 自签名证书使用。
+func insecureHTTPClient() *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+}
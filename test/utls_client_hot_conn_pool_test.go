@@ -46,6 +46,25 @@ func (m *mockDomainMonitor) GetDomainPool(domain string) (map[string][]src.IPRec
 	return copiedPool, true
 }
 
+// InjectStaticIPs 实现DomainMonitor接口
+func (m *mockDomainMonitor) InjectStaticIPs(domain string, ips []string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pool, ok := m.domainPool[domain]
+	if !ok {
+		pool = make(map[string][]src.IPRecord)
+	}
+	for _, ip := range ips {
+		bucket := "ipv4"
+		if parsed := net.ParseIP(ip); parsed != nil && parsed.To4() == nil {
+			bucket = "ipv6"
+		}
+		pool[bucket] = append(pool[bucket], src.IPRecord{IP: ip, Source: "manual", ExpiresAt: expiresAt})
+	}
+	m.domainPool[domain] = pool
+	return nil
+}
+
 // setDomainPool 设置域名的IP池（测试辅助方法）
 func (m *mockDomainMonitor) setDomainPool(domain string, ipv4List, ipv6List []string) {
 	m.mu.Lock()
@@ -254,6 +273,47 @@ func TestDomainConnPoolReturnConn(t *testing.T) {
 	// 但可以测试错误处理逻辑
 }
 
+// TestDomainConnPoolReturnConnWithRetryAfter 测试配置了StatusPolicy时ReturnConnWithRetryAfter
+// 仍然保留ReturnConn原有的参数校验（由于无法创建真实的UTLS连接，无法测试正常归还流程）
+func TestDomainConnPoolReturnConnWithRetryAfter(t *testing.T) {
+	mockMonitor := newMockDomainMonitor()
+	mockMonitor.setDomainPool("test.com", []string{"192.168.1.1"}, []string{})
+	mockIPv4Pool := newMockIPPool([]string{"192.168.1.100"})
+
+	config := src.DomainConnPoolConfig{
+		DomainMonitor:     mockMonitor,
+		IPAccessControl:   src.NewWhiteBlackIPPool(),
+		LocalIPv4Pool:     mockIPv4Pool,
+		Fingerprint:       src.GetRandomFingerprint(),
+		Domain:            "test.com",
+		Port:              "443",
+		MaxConns:          2,
+		IdleTimeout:       5 * time.Minute,
+		WarmupPath:        "/test",
+		WarmupMethod:      "GET",
+		WarmupHeaders:     make(map[string]string),
+		WarmupConcurrency: 5,
+		IPRefreshInterval: 1 * time.Minute,
+		DialTimeout:       100 * time.Millisecond,
+		StatusPolicy: src.StatusPolicy{
+			429: src.StatusActionRetry,
+			503: src.StatusActionIgnore,
+		},
+	}
+
+	pool, err := src.NewDomainHotConnPool(config)
+	if err != nil {
+		t.Fatalf("创建连接池失败: %v", err)
+	}
+	defer pool.Close()
+
+	// 测试归还nil连接，应与ReturnConn保持一致的校验行为
+	err = pool.ReturnConnWithRetryAfter(nil, 429, 30*time.Second)
+	if err == nil {
+		t.Error("归还nil连接应该返回错误")
+	}
+}
+
 // TestDomainConnPoolClose 测试关闭连接池
 func TestDomainConnPoolClose(t *testing.T) {
 	mockMonitor := newMockDomainMonitor()
@@ -340,6 +400,96 @@ func TestDomainConnPoolWarmup(t *testing.T) {
 	if err != nil {
 		t.Logf("预热失败（预期行为）: %v", err)
 	}
+
+	if attempts, _, failed := pool.WarmupStats(); attempts == 0 || failed == 0 {
+		t.Errorf("预热统计应记录尝试和失败次数，实际 attempts=%d failed=%d", attempts, failed)
+	}
+}
+
+// TestDomainConnPoolRecordConnBytes 测试按IP和协议聚合套接字级字节数统计
+func TestDomainConnPoolRecordConnBytes(t *testing.T) {
+	mockMonitor := newMockDomainMonitor()
+	mockMonitor.setDomainPool("test.com", []string{"192.168.1.1"}, []string{})
+	mockIPv4Pool := newMockIPPool([]string{"192.168.1.100"})
+
+	config := src.DomainConnPoolConfig{
+		DomainMonitor:   mockMonitor,
+		IPAccessControl: src.NewWhiteBlackIPPool(),
+		LocalIPv4Pool:   mockIPv4Pool,
+		Fingerprint:     src.GetRandomFingerprint(),
+		Domain:          "test.com",
+		Port:            "443",
+	}
+
+	pool, err := src.NewDomainHotConnPool(config)
+	if err != nil {
+		t.Fatalf("创建连接池失败: %v", err)
+	}
+	defer pool.Close()
+
+	pool.RecordConnBytes("192.168.1.1", "10.0.0.1", "h2", 100, 50)
+	pool.RecordConnBytes("192.168.1.1", "10.0.0.1", "h2", 200, 30)
+	pool.RecordConnBytes("192.168.1.2", "10.0.0.2", "http/1.1", 10, 5)
+
+	ipTraffic := pool.TrafficByIP()
+	if got := ipTraffic["192.168.1.1"]; got.BytesRead != 300 || got.BytesWritten != 80 {
+		t.Errorf("192.168.1.1的流量统计不正确: 读=%d 写=%d", got.BytesRead, got.BytesWritten)
+	}
+	if got := ipTraffic["192.168.1.2"]; got.BytesRead != 10 || got.BytesWritten != 5 {
+		t.Errorf("192.168.1.2的流量统计不正确: 读=%d 写=%d", got.BytesRead, got.BytesWritten)
+	}
+
+	localIPTraffic := pool.TrafficByLocalIP()
+	if got := localIPTraffic["10.0.0.1"]; got.BytesRead != 300 || got.BytesWritten != 80 {
+		t.Errorf("10.0.0.1的本地IP流量统计不正确: 读=%d 写=%d", got.BytesRead, got.BytesWritten)
+	}
+	if got := localIPTraffic["10.0.0.2"]; got.BytesRead != 10 || got.BytesWritten != 5 {
+		t.Errorf("10.0.0.2的本地IP流量统计不正确: 读=%d 写=%d", got.BytesRead, got.BytesWritten)
+	}
+
+	protocolTraffic := pool.TrafficByProtocol()
+	if got := protocolTraffic["h2"]; got.BytesRead != 300 || got.BytesWritten != 80 {
+		t.Errorf("h2协议的流量统计不正确: 读=%d 写=%d", got.BytesRead, got.BytesWritten)
+	}
+	if got := protocolTraffic["http/1.1"]; got.BytesRead != 10 || got.BytesWritten != 5 {
+		t.Errorf("http/1.1协议的流量统计不正确: 读=%d 写=%d", got.BytesRead, got.BytesWritten)
+	}
+
+	if got := pool.DailyBytesUsed(); got != 340 {
+		t.Errorf("DailyBytesUsed不正确: got=%d want=340", got)
+	}
+}
+
+// TestDomainConnPoolDailyByteCap 测试DailyByteCap达到上限后GetConn/GetConnByIP拒绝签出新连接
+func TestDomainConnPoolDailyByteCap(t *testing.T) {
+	mockMonitor := newMockDomainMonitor()
+	mockMonitor.setDomainPool("test.com", []string{"192.168.1.1"}, []string{})
+	mockIPv4Pool := newMockIPPool([]string{"192.168.1.100"})
+
+	config := src.DomainConnPoolConfig{
+		DomainMonitor:   mockMonitor,
+		IPAccessControl: src.NewWhiteBlackIPPool(),
+		LocalIPv4Pool:   mockIPv4Pool,
+		Fingerprint:     src.GetRandomFingerprint(),
+		Domain:          "test.com",
+		Port:            "443",
+		DailyByteCap:    100,
+	}
+
+	pool, err := src.NewDomainHotConnPool(config)
+	if err != nil {
+		t.Fatalf("创建连接池失败: %v", err)
+	}
+	defer pool.Close()
+
+	pool.RecordConnBytes("192.168.1.1", "10.0.0.1", "h2", 60, 60)
+
+	if _, err := pool.GetConn(); err == nil {
+		t.Error("已超过DailyByteCap时GetConn应该返回错误")
+	}
+	if _, err := pool.GetConnByIP("192.168.1.1"); err == nil {
+		t.Error("已超过DailyByteCap时GetConnByIP应该返回错误")
+	}
 }
 
 // TestDomainConnPoolIPRefresh 测试IP列表刷新
@@ -426,6 +576,45 @@ func TestDomainConnPoolBlacklistTest(t *testing.T) {
 	t.Log("黑名单测试任务已执行")
 }
 
+// TestDomainConnPoolBlacklistRecoveryWarmup 测试配置了RecoveryWarmupCount时黑名单复活探测
+// 任务不会panic（探测本身依赖真实网络，沙箱环境下必然失败，这里只验证配置生效不影响池的
+// 正常创建和关闭，与TestDomainConnPoolBlacklistTest相同的行为验证方式）
+func TestDomainConnPoolBlacklistRecoveryWarmup(t *testing.T) {
+	mockMonitor := newMockDomainMonitor()
+	mockMonitor.setDomainPool("test.com", []string{"192.168.1.1"}, []string{})
+	mockIPv4Pool := newMockIPPool([]string{"192.168.1.100"})
+	ipAccessControl := src.NewWhiteBlackIPPool()
+	ipAccessControl.AddIP("192.168.1.1", false)
+
+	config := src.DomainConnPoolConfig{
+		DomainMonitor:         mockMonitor,
+		IPAccessControl:       ipAccessControl,
+		LocalIPv4Pool:         mockIPv4Pool,
+		Fingerprint:           src.GetRandomFingerprint(),
+		Domain:                "test.com",
+		Port:                  "443",
+		MaxConns:              10,
+		IdleTimeout:           5 * time.Minute,
+		WarmupPath:            "/test",
+		WarmupMethod:          "GET",
+		WarmupHeaders:         make(map[string]string),
+		WarmupConcurrency:     5,
+		BlacklistTestInterval: 100 * time.Millisecond,
+		RecoveryWarmupCount:   3,
+		IPRefreshInterval:     1 * time.Minute,
+		DialTimeout:           100 * time.Millisecond,
+	}
+
+	pool, err := src.NewDomainHotConnPool(config)
+	if err != nil {
+		t.Fatalf("创建连接池失败: %v", err)
+	}
+	defer pool.Close()
+
+	time.Sleep(150 * time.Millisecond)
+	t.Log("黑名单复活探测任务已执行")
+}
+
 // TestDomainConnPoolIPv6Priority 测试IPv6优先策略
 func TestDomainConnPoolIPv6Priority(t *testing.T) {
 	mockMonitor := newMockDomainMonitor()
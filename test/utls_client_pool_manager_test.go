@@ -0,0 +1,173 @@
+package test // 定义test包
+
+import ( // 导入所需的标准库和第三方库
+	"testing" // 用于测试
+	"time"    // 用于时间处理
+
+	"utlsProxy/src" // 导入自定义的src包
+)
+
+// newTestPoolConfig 构造一个使用模拟依赖的DomainConnPoolConfig，供PoolManager测试复用
+func newTestPoolConfig(domain string) src.DomainConnPoolConfig {
+	mockMonitor := newMockDomainMonitor()
+	mockMonitor.setDomainPool(domain, []string{"192.168.1.1"}, []string{})
+	mockIPv4Pool := newMockIPPool([]string{"192.168.1.100"})
+
+	return src.DomainConnPoolConfig{
+		DomainMonitor:     mockMonitor,
+		IPAccessControl:   src.NewWhiteBlackIPPool(),
+		LocalIPv4Pool:     mockIPv4Pool,
+		Fingerprint:       src.GetRandomFingerprint(),
+		Domain:            domain,
+		Port:              "443",
+		MaxConns:          10,
+		IdleTimeout:       5 * time.Minute,
+		WarmupPath:        "/test",
+		WarmupMethod:      "GET",
+		WarmupHeaders:     make(map[string]string),
+		WarmupConcurrency: 5,
+		DialTimeout:       1 * time.Second,
+	}
+}
+
+// TestPoolManagerAddDomainAndRoute 测试PoolManager能为不同域名注册独立的连接池，并按域名路由到对应连接池
+func TestPoolManagerAddDomainAndRoute(t *testing.T) {
+	manager := src.NewPoolManager()
+	defer manager.Close()
+
+	if err := manager.AddDomain("a.example.com", newTestPoolConfig("a.example.com")); err != nil {
+		t.Fatalf("注册域名a失败: %v", err)
+	}
+	if err := manager.AddDomain("b.example.com", newTestPoolConfig("b.example.com")); err != nil {
+		t.Fatalf("注册域名b失败: %v", err)
+	}
+
+	poolA, ok := manager.PoolForDomain("a.example.com")
+	if !ok || poolA == nil {
+		t.Fatal("应该能找到域名a对应的连接池")
+	}
+	poolB, ok := manager.PoolForDomain("b.example.com")
+	if !ok || poolB == nil {
+		t.Fatal("应该能找到域名b对应的连接池")
+	}
+	if poolA == poolB {
+		t.Fatal("不同域名应该拥有各自独立的连接池")
+	}
+
+	if _, ok := manager.PoolForDomain("c.example.com"); ok {
+		t.Fatal("未注册的域名不应该返回连接池")
+	}
+
+	domains := manager.Domains()
+	if len(domains) != 2 {
+		t.Fatalf("应该注册了2个域名，实际: %d", len(domains))
+	}
+}
+
+// TestPoolManagerAddDomainDedup 测试重复调用AddDomain注册同一域名时会复用已有连接池，而不会重复创建
+func TestPoolManagerAddDomainDedup(t *testing.T) {
+	manager := src.NewPoolManager()
+	defer manager.Close()
+
+	if err := manager.AddDomain("shared.example.com", newTestPoolConfig("shared.example.com")); err != nil {
+		t.Fatalf("注册域名失败: %v", err)
+	}
+	first, _ := manager.PoolForDomain("shared.example.com")
+
+	if err := manager.AddDomain("shared.example.com", newTestPoolConfig("shared.example.com")); err != nil {
+		t.Fatalf("重复注册同一域名不应该返回错误: %v", err)
+	}
+	second, _ := manager.PoolForDomain("shared.example.com")
+
+	if first != second {
+		t.Fatal("重复注册同一域名应该复用已有连接池，而不是创建新的")
+	}
+}
+
+// TestPoolManagerReplace 测试Replace能原子替换指定域名的连接池，并返回被替换下来的旧连接池
+func TestPoolManagerReplace(t *testing.T) {
+	manager := src.NewPoolManager()
+
+	oldPool, err := src.NewDomainHotConnPool(newTestPoolConfig("replace.example.com"))
+	if err != nil {
+		t.Fatalf("创建连接池失败: %v", err)
+	}
+	manager.Register("replace.example.com", oldPool)
+
+	newPool, err := src.NewDomainHotConnPool(newTestPoolConfig("replace.example.com"))
+	if err != nil {
+		t.Fatalf("创建连接池失败: %v", err)
+	}
+	defer manager.Close()
+
+	replaced := manager.Replace("replace.example.com", newPool)
+	if replaced != oldPool {
+		t.Fatal("Replace应该返回被替换下来的旧连接池")
+	}
+
+	current, ok := manager.PoolForDomain("replace.example.com")
+	if !ok || current != newPool {
+		t.Fatal("Replace之后应该能查到新连接池")
+	}
+	_ = oldPool.Close()
+}
+
+// TestPoolManagerTrafficAggregation 测试TrafficByIP/TrafficByProtocol能跨多个域名的连接池汇总套接字级字节数
+func TestPoolManagerTrafficAggregation(t *testing.T) {
+	manager := src.NewPoolManager()
+	defer manager.Close()
+
+	if err := manager.AddDomain("a.example.com", newTestPoolConfig("a.example.com")); err != nil {
+		t.Fatalf("注册域名a失败: %v", err)
+	}
+	if err := manager.AddDomain("b.example.com", newTestPoolConfig("b.example.com")); err != nil {
+		t.Fatalf("注册域名b失败: %v", err)
+	}
+
+	poolA, _ := manager.PoolForDomain("a.example.com")
+	poolB, _ := manager.PoolForDomain("b.example.com")
+
+	poolA.RecordConnBytes("192.168.1.1", "10.0.0.1", "h2", 100, 10)
+	poolB.RecordConnBytes("192.168.1.1", "10.0.0.1", "h2", 50, 5)
+	poolB.RecordConnBytes("192.168.1.2", "10.0.0.2", "http/1.1", 20, 2)
+
+	ipTraffic := manager.TrafficByIP()
+	if got := ipTraffic["192.168.1.1"]; got.BytesRead != 150 || got.BytesWritten != 15 {
+		t.Errorf("跨域名聚合192.168.1.1的流量不正确: 读=%d 写=%d", got.BytesRead, got.BytesWritten)
+	}
+	if got := ipTraffic["192.168.1.2"]; got.BytesRead != 20 || got.BytesWritten != 2 {
+		t.Errorf("192.168.1.2的流量不正确: 读=%d 写=%d", got.BytesRead, got.BytesWritten)
+	}
+
+	protocolTraffic := manager.TrafficByProtocol()
+	if got := protocolTraffic["h2"]; got.BytesRead != 150 || got.BytesWritten != 15 {
+		t.Errorf("跨域名聚合h2协议流量不正确: 读=%d 写=%d", got.BytesRead, got.BytesWritten)
+	}
+	if got := protocolTraffic["http/1.1"]; got.BytesRead != 20 || got.BytesWritten != 2 {
+		t.Errorf("http/1.1协议流量不正确: 读=%d 写=%d", got.BytesRead, got.BytesWritten)
+	}
+}
+
+// TestPoolManagerRegisterKeepsFirst 测试Register在域名已存在时保留已有连接池，不会覆盖
+func TestPoolManagerRegisterKeepsFirst(t *testing.T) {
+	manager := src.NewPoolManager()
+	defer manager.Close()
+
+	poolA, err := src.NewDomainHotConnPool(newTestPoolConfig("keep.example.com"))
+	if err != nil {
+		t.Fatalf("创建连接池失败: %v", err)
+	}
+	poolB, err := src.NewDomainHotConnPool(newTestPoolConfig("keep.example.com"))
+	if err != nil {
+		t.Fatalf("创建连接池失败: %v", err)
+	}
+	defer poolB.Close()
+
+	manager.Register("keep.example.com", poolA)
+	manager.Register("keep.example.com", poolB)
+
+	current, _ := manager.PoolForDomain("keep.example.com")
+	if current != poolA {
+		t.Fatal("Register在域名已存在时应该保留先注册的连接池")
+	}
+}
@@ -109,6 +109,27 @@ func TestUTlsRequest(t *testing.T) {
 		}
 	})
 
+	t.Run("SNI与Host覆盖用于域前置", func(t *testing.T) {
+		req := &src.UTlsRequest{
+			WorkID:      "test-004",
+			Domain:      "google.com",
+			Method:      "GET",
+			Path:        "https://google.com/",
+			DomainIP:    "192.0.2.1",
+			Fingerprint: fingerprint,
+			StartTime:   time.Now(),
+			SNI:         "google.com",
+			Host:        "kh.google.com",
+		}
+
+		if req.SNI != "google.com" {
+			t.Errorf("期望SNI为google.com，实际为%s", req.SNI)
+		}
+		if req.Host != "kh.google.com" {
+			t.Errorf("期望Host为kh.google.com，实际为%s", req.Host)
+		}
+	})
+
 	t.Run("POST请求体", func(t *testing.T) {
 		body := `{"key": "value"}`
 		req := &src.UTlsRequest{
@@ -176,6 +197,22 @@ func TestUTlsResponse(t *testing.T) {
 		}
 	})
 
+	t.Run("携带实际使用的指纹", func(t *testing.T) {
+		fingerprint := src.GetRandomFingerprint()
+		resp := &src.UTlsResponse{
+			WorkID:      "test-005",
+			StatusCode:  200,
+			Body:        []byte("ok"),
+			Path:        "https://example.com/",
+			Duration:    time.Second,
+			Fingerprint: fingerprint,
+		}
+
+		if resp.Fingerprint.Name != fingerprint.Name {
+			t.Errorf("期望Fingerprint.Name为%s，实际为%s", fingerprint.Name, resp.Fingerprint.Name)
+		}
+	})
+
 	t.Run("空响应体", func(t *testing.T) {
 		resp := &src.UTlsResponse{
 			WorkID:     "test-003",
@@ -251,6 +288,23 @@ func TestUTlsRequestValidation(t *testing.T) {
 		}
 	})
 
+	t.Run("设置SNI或Host覆盖时必须显式指定DomainIP", func(t *testing.T) {
+		client := src.NewUTlsClient()
+		req := &src.UTlsRequest{
+			Domain: "google.com",
+			Method: "GET",
+			Path:   "https://google.com/",
+			SNI:    "google.com",
+			Host:   "kh.google.com",
+			// DomainIP 故意留空
+		}
+
+		_, err := client.Do(req)
+		if err == nil {
+			t.Fatal("未指定DomainIP时，带SNI/Host覆盖的请求应该返回校验错误")
+		}
+	})
+
 	t.Run("请求路径格式", func(t *testing.T) {
 		testCases := []struct {
 			path    string
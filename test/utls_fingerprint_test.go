@@ -3,6 +3,7 @@ package test
 import (
 	"strings"
 	"testing"
+	"time"
 	"utlsProxy/src"
 )
 
@@ -49,6 +50,28 @@ func TestLibraryRandomProfile(t *testing.T) {
 	}
 }
 
+// TestLibraryRandomProfileWeighted 测试按权重随机获取配置文件
+func TestLibraryRandomProfileWeighted(t *testing.T) {
+	library := src.NewLibrary()
+
+	seenChrome133 := false
+	for i := 0; i < 200; i++ {
+		profile := library.RandomProfileWeighted()
+		if profile.Name == "" {
+			t.Error("按权重随机配置文件应该有名称")
+		}
+		if profile.Browser == "Random" || profile.Platform == "Random" {
+			t.Errorf("按权重随机配置文件不应该是Randomized类型: %s", profile.Name)
+		}
+		if profile.Name == "Chrome 133 - Windows" {
+			seenChrome133 = true
+		}
+	}
+	if !seenChrome133 {
+		t.Error("高权重的Chrome 133 - Windows配置文件应该在多次抽取中至少出现一次")
+	}
+}
+
 // TestLibraryProfileByName 测试根据名称获取配置文件
 func TestLibraryProfileByName(t *testing.T) {
 	library := src.NewLibrary()
@@ -247,3 +270,159 @@ func TestAllLanguagesList(t *testing.T) {
 		t.Error("应该能够生成Accept-Language头部")
 	}
 }
+
+// TestProfileHeaderSet 测试生成与指纹一致的完整请求头集合
+func TestProfileHeaderSet(t *testing.T) {
+	library := src.NewLibrary()
+
+	chromeProfile, err := library.ProfileByName("Chrome 133 - Windows")
+	if err != nil {
+		t.Fatalf("应该找到Chrome 133 - Windows配置文件: %v", err)
+	}
+	headers := chromeProfile.HeaderSet()
+	if headers["sec-ch-ua"] == "" {
+		t.Error("Chrome配置文件应该生成sec-ch-ua头部")
+	}
+	if headers["sec-ch-ua-platform"] != `"Windows"` {
+		t.Errorf("sec-ch-ua-platform应该为Windows，实际: %s", headers["sec-ch-ua-platform"])
+	}
+	if headers["Sec-Fetch-Mode"] != "cors" {
+		t.Error("应该生成Sec-Fetch-Mode头部")
+	}
+
+	firefoxProfile, err := library.ProfileByName("Firefox 120 - Windows")
+	if err != nil {
+		t.Fatalf("应该找到Firefox 120 - Windows配置文件: %v", err)
+	}
+	if headers, ok := firefoxProfile.HeaderSet()["sec-ch-ua"]; ok && headers != "" {
+		t.Error("Firefox配置文件不应该生成sec-ch-ua头部")
+	}
+}
+
+// TestProfileH2Settings 测试HTTP/2指纹相关设置按浏览器区分
+func TestProfileH2Settings(t *testing.T) {
+	library := src.NewLibrary()
+
+	chromeProfile, err := library.ProfileByName("Chrome 133 - Windows")
+	if err != nil {
+		t.Fatalf("应该找到Chrome 133 - Windows配置文件: %v", err)
+	}
+	chromeSettings := chromeProfile.H2Settings()
+	if chromeSettings.MaxHeaderListSize == 0 {
+		t.Error("Chrome配置文件应该有非零的MaxHeaderListSize")
+	}
+
+	firefoxProfile, err := library.ProfileByName("Firefox 120 - Windows")
+	if err != nil {
+		t.Fatalf("应该找到Firefox 120 - Windows配置文件: %v", err)
+	}
+	firefoxSettings := firefoxProfile.H2Settings()
+	if firefoxSettings.MaxHeaderListSize == chromeSettings.MaxHeaderListSize {
+		t.Error("Firefox与Chrome的H2指纹参数不应该相同")
+	}
+
+	transport := chromeProfile.NewHTTP2Transport(nil)
+	if transport == nil {
+		t.Error("NewHTTP2Transport应该返回一个有效的http2.Transport")
+	}
+	if transport.MaxHeaderListSize != chromeSettings.MaxHeaderListSize {
+		t.Error("NewHTTP2Transport构建的transport应该沿用H2Settings中的MaxHeaderListSize")
+	}
+}
+
+// TestProfileHeaderOrder 测试不同浏览器家族返回各自的HTTP/1.1头部顺序
+func TestProfileHeaderOrder(t *testing.T) {
+	library := src.NewLibrary()
+
+	chromeProfile, err := library.ProfileByName("Chrome 133 - Windows")
+	if err != nil {
+		t.Fatalf("应该找到Chrome 133 - Windows配置文件: %v", err)
+	}
+	chromeOrder := chromeProfile.HeaderOrder()
+	if len(chromeOrder) == 0 {
+		t.Fatal("Chrome配置文件应该返回非空的头部顺序")
+	}
+	if chromeOrder[0] != "Connection" {
+		t.Errorf("Chrome头部顺序应该以Connection开头，实际: %s", chromeOrder[0])
+	}
+
+	firefoxProfile, err := library.ProfileByName("Firefox 120 - Windows")
+	if err != nil {
+		t.Fatalf("应该找到Firefox 120 - Windows配置文件: %v", err)
+	}
+	firefoxOrder := firefoxProfile.HeaderOrder()
+	if len(firefoxOrder) == 0 {
+		t.Fatal("Firefox配置文件应该返回非空的头部顺序")
+	}
+	if firefoxOrder[0] != "User-Agent" {
+		t.Errorf("Firefox头部顺序应该以User-Agent开头，实际: %s", firefoxOrder[0])
+	}
+
+	if chromeOrder[0] == firefoxOrder[0] {
+		t.Error("Chrome与Firefox的头部顺序不应该相同")
+	}
+}
+
+// TestStickyFingerprintSelector 测试同一个key在会话有效期内始终复用同一个指纹
+func TestStickyFingerprintSelector(t *testing.T) {
+	selector := src.NewStickyFingerprintSelector(time.Hour)
+
+	first := selector.Get("1.2.3.4")
+	for i := 0; i < 20; i++ {
+		again := selector.Get("1.2.3.4")
+		if again.Name != first.Name {
+			t.Fatalf("同一个key在会话有效期内应该返回相同的指纹，第一次: %s，本次: %s", first.Name, again.Name)
+		}
+	}
+
+	// 不同key之间相互独立，不要求不同，但至少要能各自拿到有效指纹
+	other := selector.Get("5.6.7.8")
+	if other.Name == "" {
+		t.Error("不同key也应该获得有效的粘滞指纹")
+	}
+
+	// 会话过期后应该重新选择（允许结果与之前相同，但不应该panic或返回空指纹）
+	shortLived := src.NewStickyFingerprintSelector(time.Nanosecond)
+	shortLived.Get("9.9.9.9")
+	time.Sleep(time.Millisecond)
+	afterExpiry := shortLived.Get("9.9.9.9")
+	if afterExpiry.Name == "" {
+		t.Error("会话过期后重新选择的指纹不应该为空")
+	}
+}
+
+// TestStickyFingerprintSelectorGetAvoidingSkipsAvoidedProfile 测试当前粘滞的指纹命中avoid
+// 谓词时，GetAvoiding会跳出粘滞重新选择，而不是像Get一样无条件复用
+func TestStickyFingerprintSelectorGetAvoidingSkipsAvoidedProfile(t *testing.T) {
+	selector := src.NewStickyFingerprintSelector(time.Hour)
+
+	first := selector.Get("1.2.3.4")
+
+	avoidFirst := func(p src.Profile) bool { return p.Name == first.Name }
+	rerolled := selector.GetAvoiding("1.2.3.4", avoidFirst)
+	if rerolled.Name == "" {
+		t.Fatal("GetAvoiding应该返回一个有效指纹，而不是空值")
+	}
+
+	// avoid谓词为nil时应该与Get行为一致：继续复用粘滞的指纹
+	again := selector.GetAvoiding("1.2.3.4", nil)
+	if again.Name != rerolled.Name {
+		t.Fatalf("avoid为nil时GetAvoiding应该复用会话里的指纹，期望: %s，实际: %s", rerolled.Name, again.Name)
+	}
+}
+
+// TestLibrarySeedIsReproducible 测试固定种子下的随机选择序列可复现
+func TestLibrarySeedIsReproducible(t *testing.T) {
+	libraryA := src.NewLibrary()
+	libraryA.Seed(42)
+	libraryB := src.NewLibrary()
+	libraryB.Seed(42)
+
+	for i := 0; i < 10; i++ {
+		profileA := libraryA.RandomProfileWeighted()
+		profileB := libraryB.RandomProfileWeighted()
+		if profileA.Name != profileB.Name {
+			t.Fatalf("相同种子下第%d次选择的指纹不一致: %s != %s", i, profileA.Name, profileB.Name)
+		}
+	}
+}